@@ -3,11 +3,14 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+	_ "time/tzdata" // the runtime image has no zoneinfo, and dosing schedules need it
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -19,10 +22,18 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 
 	"github.com/RitwikGupta-0501/vital-watch/internal/api"
+	"github.com/RitwikGupta-0501/vital-watch/internal/envelope"
+	"github.com/RitwikGupta-0501/vital-watch/internal/filecheck"
+	"github.com/RitwikGupta-0501/vital-watch/internal/formulary"
+	"github.com/RitwikGupta-0501/vital-watch/internal/jobs"
+	"github.com/RitwikGupta-0501/vital-watch/internal/labs"
 	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
-
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/RitwikGupta-0501/vital-watch/internal/rxpdf"
+	"github.com/RitwikGupta-0501/vital-watch/internal/signing"
+	"github.com/RitwikGupta-0501/vital-watch/internal/storage"
+	"github.com/RitwikGupta-0501/vital-watch/internal/terminology"
+	"github.com/RitwikGupta-0501/vital-watch/internal/vitals"
+	"github.com/RitwikGupta-0501/vital-watch/utils"
 )
 
 /*
@@ -98,6 +109,86 @@ func run_migrations(db *sql.DB) {
 	log.Println("Database migrations finished successfully.")
 }
 
+/*
+========================================
+=            Config Helpers            =
+========================================
+*/
+func getEnvDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+	return d
+}
+
+func getEnvInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+	return n
+}
+
+// ensureAdmin creates an admin overseeing every clinic with the email, unless
+// one already exists.
+func ensureAdmin(repo *repository.Repository, email, password string) error {
+	_, err := repo.GetAdminByEmail(email)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if password == "" {
+		return errors.New("ADMIN_PASSWORD is required to create the admin")
+	}
+
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if _, err := repo.CreateAdmin("Admin", "", email, hashed, nil); err != nil {
+		return err
+	}
+	log.Printf("Created admin %s", email)
+	return nil
+}
+
+// importTerminology loads a code system release file, for
+// `main import-terminology <system> <file>`.
+func importTerminology(repo *repository.Repository, args []string) error {
+	if len(args) != 2 || !terminology.Systems[args[0]] {
+		return fmt.Errorf("usage: import-terminology %s|%s <file>", terminology.SystemICD10CM, terminology.SystemSNOMED)
+	}
+	system, path := args[0], args[1]
+
+	codes, err := terminology.Load(system, path)
+	if err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return fmt.Errorf("no codes found in %s", path)
+	}
+
+	deactivated, err := repo.ImportTerminology(system, codes)
+	if err != nil {
+		return err
+	}
+	log.Printf("Imported %d %s codes from %s; %d no longer in the release were marked inactive", len(codes), system, path, deactivated)
+	return nil
+}
+
 /*
 ========================================
 =                Main                  =
@@ -117,34 +208,214 @@ func main() {
 	// Run DB migrations
 	run_migrations(db)
 
-	// Initialize AWS S3 Client
-	log.Println("Initializing AWS Config...")
-	cfg, err := config.LoadDefaultConfig(context.TODO())
-	if err != nil {
-		log.Fatal("Failed to load AWS config:", err)
+	// Load a code system and exit instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "import-terminology" {
+		if err := importTerminology(&repository.Repository{DB: db}, os.Args[2:]); err != nil {
+			log.Fatal("Failed to import terminology: ", err)
+		}
+		return
 	}
 
-	s3Client := s3.NewFromConfig(cfg)
-	bucketName := os.Getenv("S3_BUCKET_NAME")
-	if bucketName == "" {
-		log.Fatal("S3_BUCKET_NAME environment variable is not set")
+	// Initialize object storage
+	storageCfg := storage.Config{
+		Backend:   os.Getenv("STORAGE_BACKEND"),
+		Bucket:    os.Getenv("S3_BUCKET_NAME"),
+		Endpoint:  os.Getenv("S3_ENDPOINT"),
+		LocalPath: os.Getenv("LOCAL_STORAGE_PATH"),
+	}
+	// S3-compatible servers such as MinIO usually need path-style addressing
+	storageCfg.UsePathStyle = os.Getenv("S3_FORCE_PATH_STYLE") == "true" ||
+		(storageCfg.Endpoint != "" && os.Getenv("S3_FORCE_PATH_STYLE") != "false")
+	if storageCfg.Backend == "" {
+		storageCfg.Backend = storage.BackendS3
+	}
+	if storageCfg.LocalPath == "" {
+		storageCfg.LocalPath = "storage"
 	}
-	log.Println("Successfully initialized S3 Client")
+
+	log.Printf("Initializing %s storage...", storageCfg.Backend)
+	store, err := storage.New(context.TODO(), storageCfg)
+	if err != nil {
+		log.Fatal("Failed to initialize storage: ", err)
+	}
+	if storageCfg.Backend == storage.BackendMemory {
+		log.Println("WARNING: using in-memory storage, uploaded files will be lost on restart")
+	}
+	log.Println("Successfully initialized storage")
 
 	// Initialize repository
 	repo := &repository.Repository{
 		DB: db,
 	}
 
+	// Diagnosis codes of a system that hasn't been imported are refused
+	for system := range terminology.Systems {
+		loaded, err := repo.HasTerminology(system)
+		if err != nil {
+			log.Println("Failed to check terminology:", err)
+			break
+		}
+		if !loaded {
+			log.Printf("Warning: the %s code system hasn't been imported; diagnosis codes of it will be refused until it is (main import-terminology %s <file>)", system, system)
+		}
+	}
+
+	// Import jobs run in-process, so any left running by a previous process are dead
+	if err := repo.FailInterruptedVitalImportJobs(); err != nil {
+		log.Println("Failed to clean up interrupted import jobs:", err)
+	}
+	// Exports are rebuilt from scratch, so any interrupted ones just go back in the queue
+	if err := repo.RequeueInterruptedDataExports(); err != nil {
+		log.Println("Failed to requeue interrupted data exports:", err)
+	}
+
+	// Create the first admin, who can then add the others
+	if email := os.Getenv("ADMIN_EMAIL"); email != "" {
+		if err := ensureAdmin(repo, email, os.Getenv("ADMIN_PASSWORD")); err != nil {
+			log.Fatal("Failed to create admin: ", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Encrypt stored files if master keys are configured
+	if path := os.Getenv("ENCRYPTION_KEYS_FILE"); path != "" {
+		keys, err := envelope.LoadLocalKeyProvider(path)
+		if err != nil {
+			log.Fatal("Failed to load encryption keys: ", err)
+		}
+		store = storage.NewEncryptedStore(store, keys, repo)
+		log.Printf("Encrypting stored files with master key %s", keys.CurrentKeyID())
+
+		// Moves data keys onto the current master key after a rotation
+		keyRewrapper := jobs.NewKeyRewrapper(repo, keys)
+		go jobs.Every(ctx, "key-rewrapping", getEnvDuration("REWRAP_INTERVAL", time.Hour), keyRewrapper.Run)
+	} else {
+		log.Println("WARNING: ENCRYPTION_KEYS_FILE is not set, stored files are not encrypted by the application")
+	}
+
+	// Start background jobs
+	anomalyDetector := jobs.NewAnomalyDetector(repo)
+	go jobs.Every(ctx, "anomaly-detection", getEnvDuration("ANOMALY_DETECTION_INTERVAL", time.Hour), anomalyDetector.Run)
+
+	news2Scorer := jobs.NewNEWS2Scorer(repo, vitals.NEWS2Bands{
+		Medium: getEnvInt("NEWS2_MEDIUM_SCORE", vitals.DefaultNEWS2Bands.Medium),
+		High:   getEnvInt("NEWS2_HIGH_SCORE", vitals.DefaultNEWS2Bands.High),
+	})
+	go jobs.Every(ctx, "news2-scoring", getEnvDuration("NEWS2_INTERVAL", 15*time.Minute), news2Scorer.Run)
+
+	doseScheduler := jobs.NewDoseScheduler(repo)
+	go jobs.Every(ctx, "dose-scheduling", getEnvDuration("DOSE_SCHEDULING_INTERVAL", time.Hour), doseScheduler.Run)
+
+	retention := repository.RetentionPolicy{
+		PatientData:     getEnvDuration("RETENTION_PATIENT_DATA", 30*24*time.Hour),
+		ClinicalRecords: getEnvDuration("RETENTION_CLINICAL_RECORDS", 10*365*24*time.Hour),
+	}
+	retentionPurger := jobs.NewRetentionPurger(repo, store, retention, os.Getenv("RETENTION_PURGE_DRY_RUN") == "true")
+	go jobs.Every(ctx, "retention-purge", getEnvDuration("RETENTION_PURGE_INTERVAL", 24*time.Hour), retentionPurger.Run)
+
+	uploadCleaner := jobs.NewUploadCleaner(repo, store)
+	go jobs.Every(ctx, "upload-cleanup", getEnvDuration("UPLOAD_CLEANUP_INTERVAL", time.Hour), uploadCleaner.Run)
+
+	dataExporter := jobs.NewDataExporter(repo, store, getEnvDuration("EXPORT_RETENTION", 7*24*time.Hour))
+	go jobs.Every(ctx, "data-exports", getEnvDuration("EXPORT_INTERVAL", time.Minute), dataExporter.Run)
+
+	// Load the prescription signing key
+	var signer *signing.Signer
+	if key := os.Getenv("PRESCRIPTION_SIGNING_KEY"); key != "" {
+		signer, err = signing.NewSigner(key)
+		if err != nil {
+			log.Fatal("Invalid PRESCRIPTION_SIGNING_KEY: ", err)
+		}
+	} else {
+		log.Println("WARNING: PRESCRIPTION_SIGNING_KEY is not set, using a temporary key. Prescriptions signed now will fail verification after a restart")
+		signer, err = signing.NewEphemeralSigner()
+		if err != nil {
+			log.Fatal("Failed to generate signing key: ", err)
+		}
+	}
+	log.Printf("Prescription signing key %s loaded", signer.KeyID)
+
+	// Load the drug formulary
+	formularyPath := os.Getenv("FORMULARY_PATH")
+	if formularyPath == "" {
+		formularyPath = "data/formulary.json"
+	}
+	drugFormulary, err := formulary.Load(formularyPath)
+	if err != nil {
+		log.Fatal("Failed to load formulary: ", err)
+	}
+	log.Printf("Loaded formulary with %d drugs", len(drugFormulary.Drugs))
+
+	// Load the lab test catalogue
+	labCatalogPath := os.Getenv("LAB_CATALOG_PATH")
+	if labCatalogPath == "" {
+		labCatalogPath = "data/lab_panels.json"
+	}
+	labCatalog, err := labs.Load(labCatalogPath)
+	if err != nil {
+		log.Fatal("Failed to load lab catalogue: ", err)
+	}
+	log.Printf("Loaded lab catalogue with %d panels", len(labCatalog.Panels))
+
+	// Set up malware scanning of uploaded files
+	var scanner filecheck.Scanner
+	scanAsync := false
+	if addr := os.Getenv("CLAMD_ADDRESS"); addr != "" {
+		scanner = filecheck.NewClamd(addr)
+		switch mode := os.Getenv("SCAN_MODE"); mode {
+		case "", "sync":
+		case "async":
+			scanAsync = true
+			fileScanner := jobs.NewFileScanner(repo, store, scanner)
+			go jobs.Every(ctx, "file-scanning", getEnvDuration("SCAN_INTERVAL", time.Minute), fileScanner.Run)
+		default:
+			log.Fatalf("Invalid SCAN_MODE %q, expected sync or async", mode)
+		}
+		log.Printf("Scanning uploads with clamd at %s", addr)
+	} else {
+		log.Println("WARNING: CLAMD_ADDRESS is not set, uploaded files won't be scanned for malware")
+	}
+
 	// Create the API Handler
 	h := &api.Handler{
-		Repo:       repo,
-		S3Client:   s3Client,
-		BucketName: bucketName,
+		Repo:  repo,
+		Store: store,
+		Clinic: rxpdf.Clinic{
+			Name:    os.Getenv("CLINIC_NAME"),
+			Address: os.Getenv("CLINIC_ADDRESS"),
+			Phone:   os.Getenv("CLINIC_PHONE"),
+		},
+		PublicBaseURL:        os.Getenv("PUBLIC_BASE_URL"),
+		Signer:               signer,
+		PrescriptionValidity: getEnvDuration("PRESCRIPTION_VALIDITY", 180*24*time.Hour),
+		Formulary:            drugFormulary,
+		Labs:                 labCatalog,
+		PresignTTL:           getEnvDuration("PRESIGNED_URL_TTL", 15*time.Minute),
+		MaxUploadSize:        int64(getEnvInt("MAX_UPLOAD_SIZE_MB", 25)) << 20,
+		Scanner:              scanner,
+		ScanAsync:            scanAsync,
+		BreakGlassTTL:        getEnvDuration("BREAK_GLASS_DURATION", 4*time.Hour),
+		ExportLinkTTL:        getEnvDuration("EXPORT_LINK_TTL", time.Hour),
+		Retention:            retention,
+		RequireSignedNote:    os.Getenv("REQUIRE_SIGNED_ENCOUNTER_NOTE") == "true",
 	}
 
 	// Set up Gin Server
 	r := gin.Default()
+	// Client addresses, which the audit log records and failed device pairings
+	// are limited by, are only taken from X-Forwarded-For set by these proxies.
+	// With none configured the connection's own address is used.
+	var trustedProxies []string
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		for _, p := range strings.Split(proxies, ",") {
+			trustedProxies = append(trustedProxies, strings.TrimSpace(p))
+		}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
 
 	// Enable CORS middleware
 	r.Use(cors.New(cors.Config{
@@ -161,12 +432,36 @@ func main() {
 	r.GET("/api/ping", h.Ping)
 	r.POST("/api/register", h.Register)
 	r.POST("/api/login", h.Login)
+	r.POST("/api/devices/pair", h.PairDevice)
+	r.GET("/api/verify/:code", h.VerifyPrescription)
+
+	// Export download tokens are their own credential
+	r.POST("/api/exports/download", h.AuditMiddleware(), h.DownloadDataExport)
+
+	// --- Device Routes ---
+	deviceGroup := r.Group("/api/devices")
+
+	// Devices authenticate with the credential issued when they were paired
+	deviceGroup.Use(h.DeviceAuthMiddleware(), h.AuditMiddleware())
+	{
+		deviceGroup.POST("/readings", h.SubmitDeviceReadings)
+	}
+
+	// --- Integration Routes ---
+	integrationGroup := r.Group("/api/integrations")
+
+	// External systems such as labs authenticate with an API key an admin issued
+	integrationGroup.Use(h.IntegrationAuthMiddleware(), h.AuditMiddleware())
+	{
+		integrationGroup.POST("/lab-results", h.ReceiveHL7Results)
+		integrationGroup.POST("/lab-orders/:orderId/reports", h.UploadIntegrationLabReport)
+	}
 
 	// --- Protected Routes ---
 	authGroup := r.Group("/api")
 
-	// All routes inside this block will require authentication
-	authGroup.Use(api.AuthMiddleware())
+	// All routes inside this block will require authentication and an open account, and are audited
+	authGroup.Use(api.AuthMiddleware(), h.AuditMiddleware(), h.ClosedAccountMiddleware())
 	{
 		authGroup.GET("/profile", h.GetUserProfile)
 		authGroup.GET("/doctors", h.GetDoctors)
@@ -176,13 +471,142 @@ func main() {
 		authGroup.GET("/doctor/patients", h.GetDoctorPatients)
 
 		authGroup.POST("/appointments", h.CreateAppointment)
-		authGroup.POST("/prescriptions", h.CreatePrescription)
+		authGroup.POST("/prescriptions", api.RequireRole("doctor"), h.CreatePrescription)
+		authGroup.POST("/prescriptions/generate", api.RequireRole("doctor"), h.GeneratePrescription)
+		authGroup.POST("/prescriptions/uploads", api.RequireRole("doctor"), h.CreatePrescriptionUpload)
+		authGroup.POST("/prescriptions/uploads/:id/confirm", api.RequireRole("doctor"), h.ConfirmPrescriptionUpload)
+		authGroup.GET("/patient/doses", api.RequireRole("patient"), h.GetPatientDoses)
+		authGroup.POST("/patient/doses/:id", api.RequireRole("patient"), h.LogDose)
+		authGroup.GET("/doctor/patients/:id/prescriptions/:prescriptionId/adherence", api.RequireRole("doctor"), h.GetPrescriptionAdherence)
+		authGroup.GET("/patient/prescriptions/:id/history", api.RequireRole("patient"), h.GetPatientPrescriptionHistory)
+		authGroup.GET("/doctor/patients/:id/prescriptions/:prescriptionId/history", api.RequireRole("doctor"), h.GetPatientHistoryPrescriptionVersions)
+		authGroup.POST("/doctor/patients/:id/prescriptions/:prescriptionId/amend", api.RequireRole("doctor"), h.AmendPrescription)
+		authGroup.POST("/doctor/patients/:id/prescriptions/:prescriptionId/revoke", api.RequireRole("doctor"), h.RevokePrescription)
+		authGroup.POST("/doctor/patients/:id/prescriptions/check", api.RequireRole("doctor"), h.CheckPrescription)
+		authGroup.GET("/formulary/drugs", api.RequireRole("doctor"), h.SearchFormulary)
+		authGroup.GET("/terminology/codes", api.RequireRole("doctor"), h.SearchTerminologyCodes)
+		authGroup.GET("/terminology/search", api.RequireRole("doctor"), h.SearchTerminology)
+		authGroup.GET("/doctor/terminology/favourites", api.RequireRole("doctor"), h.GetTerminologyFavourites)
+		authGroup.PUT("/doctor/terminology/favourites/:system/:code", api.RequireRole("doctor"), h.AddTerminologyFavourite)
+		authGroup.DELETE("/doctor/terminology/favourites/:system/:code", api.RequireRole("doctor"), h.RemoveTerminologyFavourite)
+
+		authGroup.GET("/patient/allergies", api.RequireRole("patient"), h.GetPatientAllergies)
+		authGroup.POST("/patient/allergies", api.RequireRole("patient"), h.CreatePatientAllergy)
+		authGroup.DELETE("/patient/allergies/:id", api.RequireRole("patient"), h.DeletePatientAllergy)
+		authGroup.PUT("/patient/allergies/:id", api.RequireRole("patient"), h.UpdatePatientAllergy)
+		authGroup.GET("/doctor/patients/:id/allergies", api.RequireRole("doctor"), h.GetPatientHistoryAllergies)
+		authGroup.POST("/doctor/patients/:id/allergies", api.RequireRole("doctor"), h.CreatePatientHistoryAllergy)
+		authGroup.PUT("/doctor/patients/:id/allergies/:allergyId", api.RequireRole("doctor"), h.UpdatePatientHistoryAllergy)
+		authGroup.DELETE("/doctor/patients/:id/allergies/:allergyId", api.RequireRole("doctor"), h.DeletePatientHistoryAllergy)
+
+		authGroup.GET("/patient/chart", api.RequireRole("patient"), h.GetPatientChart)
+		authGroup.GET("/patient/conditions", api.RequireRole("patient"), h.GetPatientConditions)
+		authGroup.POST("/patient/conditions", api.RequireRole("patient"), h.CreatePatientCondition)
+		authGroup.PUT("/patient/conditions/:id", api.RequireRole("patient"), h.UpdatePatientCondition)
+		authGroup.DELETE("/patient/conditions/:id", api.RequireRole("patient"), h.DeletePatientCondition)
+		authGroup.GET("/patient/medications", api.RequireRole("patient"), h.GetPatientMedications)
+		authGroup.POST("/patient/medications", api.RequireRole("patient"), h.CreatePatientMedication)
+		authGroup.PUT("/patient/medications/:id", api.RequireRole("patient"), h.UpdatePatientMedication)
+		authGroup.DELETE("/patient/medications/:id", api.RequireRole("patient"), h.DeletePatientMedication)
+		authGroup.GET("/doctor/patients/:id/chart", api.RequireRole("doctor"), h.GetPatientHistoryChart)
+		authGroup.GET("/doctor/patients/:id/conditions", api.RequireRole("doctor"), h.GetPatientHistoryConditions)
+		authGroup.POST("/doctor/patients/:id/conditions", api.RequireRole("doctor"), h.CreatePatientHistoryCondition)
+		authGroup.PUT("/doctor/patients/:id/conditions/:conditionId", api.RequireRole("doctor"), h.UpdatePatientHistoryCondition)
+		authGroup.DELETE("/doctor/patients/:id/conditions/:conditionId", api.RequireRole("doctor"), h.DeletePatientHistoryCondition)
+		authGroup.GET("/doctor/patients/:id/medications", api.RequireRole("doctor"), h.GetPatientHistoryMedications)
+		authGroup.POST("/doctor/patients/:id/medications", api.RequireRole("doctor"), h.CreatePatientHistoryMedication)
+		authGroup.PUT("/doctor/patients/:id/medications/:medicationId", api.RequireRole("doctor"), h.UpdatePatientHistoryMedication)
+		authGroup.DELETE("/doctor/patients/:id/medications/:medicationId", api.RequireRole("doctor"), h.DeletePatientHistoryMedication)
+
+		authGroup.POST("/patient/prescriptions/:id/refill-requests", api.RequireRole("patient"), h.CreateRefillRequest)
+		authGroup.GET("/patient/refill-requests", api.RequireRole("patient"), h.GetPatientRefillRequests)
+		authGroup.POST("/patient/appointments/:id/confirm", api.RequireRole("patient"), h.ConfirmAppointment)
+		authGroup.GET("/doctor/refill-requests", api.RequireRole("doctor"), h.GetDoctorRefillRequests)
+		authGroup.POST("/doctor/refill-requests/:id/approve", api.RequireRole("doctor"), h.ApproveRefillRequest)
+		authGroup.POST("/doctor/refill-requests/:id/deny", api.RequireRole("doctor"), h.DenyRefillRequest)
+		authGroup.POST("/doctor/refill-requests/:id/request-visit", api.RequireRole("doctor"), h.RequestVisitForRefill)
+
+		authGroup.GET("/notifications", h.GetNotifications)
+		authGroup.POST("/notifications/read-all", h.MarkAllNotificationsRead)
+		authGroup.POST("/notifications/:id/read", h.MarkNotificationRead)
 
 		authGroup.GET("/prescriptions/:filename", h.DownloadPrescription)
 		authGroup.GET("/doctor/prescriptions/:filename", h.DoctorDownloadPrescription)
 		authGroup.GET("/doctor/patients/:id/appointments", h.GetPatientHistoryAppointments)
 		authGroup.GET("/doctor/patients/:id/prescriptions", h.GetPatientHistoryPrescriptions)
-		authGroup.PATCH("/appointments/:id", h.MarkAppointmentAsCompleted)
+		authGroup.PATCH("/appointments/:id", api.RequireRole("doctor"), h.MarkAppointmentAsCompleted)
+
+		authGroup.GET("/patient/notes", api.RequireRole("patient"), h.GetPatientNotes)
+		authGroup.GET("/doctor/patients/:id/notes", api.RequireRole("doctor"), h.GetPatientHistoryNotes)
+		authGroup.GET("/doctor/appointments/:id/note", api.RequireRole("doctor"), h.GetAppointmentNote)
+		authGroup.PUT("/doctor/appointments/:id/note", api.RequireRole("doctor"), h.SaveAppointmentNote)
+		authGroup.POST("/doctor/appointments/:id/note/sign", api.RequireRole("doctor"), h.SignAppointmentNote)
+		authGroup.POST("/doctor/appointments/:id/note/amendments", api.RequireRole("doctor"), h.AmendAppointmentNote)
+
+		authGroup.GET("/lab/panels", api.RequireRole("doctor"), h.GetLabPanels)
+		authGroup.GET("/patient/lab-orders", api.RequireRole("patient"), h.GetPatientLabOrders)
+		authGroup.GET("/patient/lab-orders/:orderId/reports/:reportId/file", api.RequireRole("patient"), h.DownloadPatientLabReport)
+		authGroup.GET("/patient/lab-trends", api.RequireRole("patient"), h.GetPatientLabTrends)
+		authGroup.GET("/doctor/patients/:id/lab-orders", api.RequireRole("doctor"), h.GetPatientHistoryLabOrders)
+		authGroup.POST("/doctor/patients/:id/lab-orders", api.RequireRole("doctor"), h.CreateLabOrder)
+		authGroup.POST("/doctor/patients/:id/lab-orders/:orderId/cancel", api.RequireRole("doctor"), h.CancelLabOrder)
+		authGroup.POST("/doctor/patients/:id/lab-orders/:orderId/results", api.RequireRole("doctor"), h.EnterLabResults)
+		authGroup.POST("/doctor/patients/:id/lab-orders/:orderId/reports", api.RequireRole("doctor"), h.UploadLabReport)
+		authGroup.GET("/doctor/patients/:id/lab-orders/:orderId/reports/:reportId/file", api.RequireRole("doctor"), h.DoctorDownloadLabReport)
+		authGroup.GET("/doctor/patients/:id/lab-trends", api.RequireRole("doctor"), h.GetPatientHistoryLabTrends)
+
+		authGroup.GET("/patient/documents", api.RequireRole("patient"), h.GetPatientDocuments)
+		authGroup.POST("/patient/documents", api.RequireRole("patient"), h.UploadPatientDocument)
+		authGroup.GET("/patient/documents/:id", api.RequireRole("patient"), h.GetPatientDocument)
+		authGroup.DELETE("/patient/documents/:id", api.RequireRole("patient"), h.DeletePatientDocument)
+		authGroup.GET("/patient/documents/:id/file", api.RequireRole("patient"), h.DownloadPatientDocument)
+		authGroup.POST("/patient/documents/:id/shares", api.RequireRole("patient"), h.SharePatientDocument)
+		authGroup.DELETE("/patient/documents/:id/shares/:doctorId", api.RequireRole("patient"), h.UnsharePatientDocument)
+		authGroup.GET("/doctor/patients/:id/documents", api.RequireRole("doctor"), h.GetPatientHistoryDocuments)
+		authGroup.GET("/doctor/documents/:id/file", api.RequireRole("doctor"), h.DoctorDownloadDocument)
+
+		authGroup.GET("/clinics", h.GetClinics)
+		authGroup.GET("/patient/consents", api.RequireRole("patient"), h.GetPatientConsents)
+		authGroup.POST("/patient/consents", api.RequireRole("patient"), h.GrantPatientConsent)
+		authGroup.DELETE("/patient/consents/:id", api.RequireRole("patient"), h.RevokePatientConsent)
+		authGroup.GET("/doctor/consents", api.RequireRole("doctor"), h.GetDoctorConsents)
+
+		authGroup.POST("/doctor/patients/:id/break-glass", api.RequireRole("doctor"), h.BreakGlass)
+		authGroup.GET("/patient/break-glass", api.RequireRole("patient"), h.GetPatientBreakGlassAccesses)
+		authGroup.GET("/admin/break-glass", api.RequireRole("admin"), h.GetBreakGlassReviewQueue)
+		authGroup.POST("/admin/break-glass/:id/review", api.RequireRole("admin"), h.ReviewBreakGlass)
+		authGroup.POST("/admin/clinics", api.RequireRole("admin"), h.CreateClinic)
+		authGroup.PUT("/admin/doctors/:id/clinic", api.RequireRole("admin"), h.SetDoctorClinic)
+		authGroup.POST("/admin/admins", api.RequireRole("admin"), h.CreateAdmin)
+		authGroup.POST("/admin/integration-keys", api.RequireRole("admin"), h.CreateIntegrationKey)
+		authGroup.GET("/admin/integration-keys", api.RequireRole("admin"), h.GetIntegrationKeys)
+		authGroup.DELETE("/admin/integration-keys/:id", api.RequireRole("admin"), h.RevokeIntegrationKey)
+
+		authGroup.GET("/patient/access-log", api.RequireRole("patient"), h.GetPatientAccessLog)
+		authGroup.POST("/patient/exports", api.RequireRole("patient"), h.CreateDataExport)
+		authGroup.GET("/patient/exports", api.RequireRole("patient"), h.GetDataExports)
+		authGroup.POST("/patient/exports/:id/link", api.RequireRole("patient"), h.CreateDataExportLink)
+		authGroup.POST("/patient/account/close", api.RequireRole("patient"), h.ClosePatientAccount)
+		authGroup.GET("/admin/retention", api.RequireRole("admin"), h.GetRetentionReport)
+		authGroup.GET("/admin/audit", api.RequireRole("admin"), h.GetAuditLog)
+		authGroup.GET("/admin/audit/verify", api.RequireRole("admin"), h.VerifyAuditLog)
+		authGroup.GET("/admin/prescriptions/verify/:code", api.RequireRole("admin"), h.VerifyPrescriptionFile)
+
+		authGroup.GET("/patient/devices", api.RequireRole("patient"), h.GetPatientDevices)
+		authGroup.POST("/patient/devices/pairing-code", api.RequireRole("patient"), h.CreatePairingCode)
+		authGroup.DELETE("/patient/devices/:id", api.RequireRole("patient"), h.RevokePatientDevice)
+		authGroup.GET("/patient/vitals", api.RequireRole("patient"), h.GetPatientVitals)
+		authGroup.POST("/patient/vitals/imports", api.RequireRole("patient"), h.CreateVitalImport)
+		authGroup.GET("/patient/vitals/imports", api.RequireRole("patient"), h.GetVitalImports)
+		authGroup.GET("/patient/vitals/imports/:id", api.RequireRole("patient"), h.GetVitalImport)
+		authGroup.GET("/doctor/patients/:id/devices", api.RequireRole("doctor"), h.GetPatientHistoryDevices)
+		authGroup.DELETE("/doctor/patients/:id/devices/:deviceId", api.RequireRole("doctor"), h.DoctorRevokePatientDevice)
+		authGroup.GET("/doctor/patients/:id/vitals", api.RequireRole("doctor"), h.GetPatientHistoryVitals)
+		authGroup.GET("/doctor/patients/:id/alerts", api.RequireRole("doctor"), h.GetPatientHistoryAlerts)
+		authGroup.GET("/doctor/patients/:id/news2", api.RequireRole("doctor"), h.GetPatientHistoryNEWS2)
+		authGroup.PUT("/doctor/patients/:id/news2/spo2-scale", api.RequireRole("doctor"), h.UpdatePatientSpO2Scale)
+		authGroup.GET("/doctor/alerts", api.RequireRole("doctor"), h.GetDoctorAlerts)
+		authGroup.POST("/doctor/alerts/:id/acknowledge", api.RequireRole("doctor"), h.AcknowledgeAlert)
 	}
 
 	// Run the server
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/utils"
+)
+
+// ClosedAccountMiddleware rejects patients whose account has been closed,
+// since their tokens outlive the closure. It must run after AuthMiddleware.
+func (h *Handler) ClosedAccountMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != "patient" {
+			c.Next()
+			return
+		}
+
+		closed, err := h.Repo.IsPatientAccountClosed(c.GetInt("userID"))
+		if errors.Is(err, sql.ErrNoRows) || closed {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Account is closed"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account", "err": err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Patient Portal Handlers
+
+// ClosePatientAccount closes the patient's account once they confirm with
+// their password. Their details are pseudonymized straight away; what they
+// recorded themselves is deleted after Retention.PatientData and their
+// clinical record after Retention.ClinicalRecords.
+func (h *Handler) ClosePatientAccount(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+
+	patient, err := h.Repo.GetPatientByID(patientID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch patient", "err": err.Error()})
+		return
+	}
+	if !utils.CheckPasswordHash(req.Password, patient.HashedPassword) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	closedAt, err := h.Repo.ClosePatientAccount(patient.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Account is already closed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close account", "err": err.Error()})
+		return
+	}
+	log.Printf("Patient %d closed their account", patient.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":               "Account closed",
+		"closed_at":             closedAt,
+		"data_deleted_after":    closedAt.Add(h.Retention.PatientData),
+		"records_deleted_after": closedAt.Add(h.Retention.ClinicalRecords),
+	})
+}
+
+// Admin Portal Handlers
+
+// GetRetentionReport lists the closed accounts' data the purge job would
+// delete now, without deleting any of it.
+func (h *Handler) GetRetentionReport(c *gin.Context) {
+	if _, ok := h.globalAdmin(c); !ok {
+		return
+	}
+
+	candidates, err := h.Repo.GetRetentionReport(h.Retention, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build retention report", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"patient_data_retention":     h.Retention.PatientData.String(),
+		"clinical_records_retention": h.Retention.ClinicalRecords.String(),
+		"due":                        candidates,
+	})
+}
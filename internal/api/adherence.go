@@ -0,0 +1,215 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+)
+
+const (
+	// lateAfter is how long after its scheduled time a dose counts as late
+	lateAfter = time.Hour
+	// earlyLogWindow is how far ahead of time a dose may be logged
+	earlyLogWindow = 2 * time.Hour
+	// maxLogAge is how long after its scheduled time a dose may still be
+	// logged
+	maxLogAge = 7 * 24 * time.Hour
+)
+
+// parseDosingSchedule decodes and validates the optional "schedule" form
+// field of a prescription. An empty value means no schedule.
+func parseDosingSchedule(raw string) (*models.DosingSchedule, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var schedule models.DosingSchedule
+	if err := json.Unmarshal([]byte(raw), &schedule); err != nil {
+		return nil, fmt.Errorf("schedule is not valid JSON: %w", err)
+	}
+
+	if len(schedule.Times) == 0 || len(schedule.Times) > 24 {
+		return nil, errors.New("schedule needs between 1 and 24 times of day")
+	}
+	for _, t := range schedule.Times {
+		if _, err := time.Parse("15:04", t); err != nil {
+			return nil, fmt.Errorf("invalid time of day %q, expected HH:MM", t)
+		}
+	}
+
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", schedule.Timezone)
+	}
+
+	if schedule.StartDate == "" {
+		schedule.StartDate = time.Now().In(loc).Format("2006-01-02")
+	}
+	start, err := time.Parse("2006-01-02", schedule.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start_date %q, expected YYYY-MM-DD", schedule.StartDate)
+	}
+	if schedule.EndDate != nil {
+		end, err := time.Parse("2006-01-02", *schedule.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end_date %q, expected YYYY-MM-DD", *schedule.EndDate)
+		}
+		if end.Before(start) {
+			return nil, errors.New("end_date is before start_date")
+		}
+	}
+
+	return &schedule, nil
+}
+
+// Patient Portal Handlers
+func (h *Handler) GetPatientDoses(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	// Defaults to the last day plus whatever has been generated ahead
+	from, to, err := parseTimeRange(c, 72*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time range", "err": err.Error()})
+		return
+	}
+	if c.Query("to") == "" {
+		to = to.Add(48 * time.Hour)
+	}
+
+	doses, err := h.Repo.GetDosesByPatientID(patientID.(int), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doses"})
+		return
+	}
+	c.JSON(http.StatusOK, doses)
+}
+
+func (h *Handler) LogDose(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	doseID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dose ID"})
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Status != "taken" && req.Status != "skipped" && req.Status != "late" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of taken, skipped or late"})
+		return
+	}
+
+	dose, err := h.Repo.GetDoseForPatient(patientID.(int), doseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dose not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dose"})
+		return
+	}
+
+	now := time.Now()
+	if dose.ScheduledAt.Sub(now) > earlyLogWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This dose isn't due yet"})
+		return
+	}
+	if now.Sub(dose.ScheduledAt) > maxLogAge {
+		c.JSON(http.StatusConflict, gin.H{"error": "This dose is too old to log"})
+		return
+	}
+	if dose.Status != "due" && dose.Status != "missed" {
+		c.JSON(http.StatusConflict, gin.H{"error": "This dose has already been logged"})
+		return
+	}
+	// A dose reported as taken well after its slot is recorded as late
+	if req.Status == "taken" && now.Sub(dose.ScheduledAt) > lateAfter {
+		req.Status = "late"
+	}
+
+	err = h.Repo.LogDose(patientID.(int), doseID, req.Status, req.Note, now.Add(-maxLogAge), now.Add(earlyLogWindow))
+	if errors.Is(err, repository.ErrDoseNotLoggable) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This dose has already been logged"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log dose", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": doseID, "status": req.Status})
+}
+
+// Doctor Portal Handlers
+func (h *Handler) GetPrescriptionAdherence(c *gin.Context) {
+	patientID, ok := patientParam(c)
+	if !ok {
+		return
+	}
+
+	prescriptionID, err := strconv.Atoi(c.Param("prescriptionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prescription ID"})
+		return
+	}
+
+	period := c.DefaultQuery("period", "week")
+	if period != "day" && period != "week" && period != "month" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be one of day, week or month"})
+		return
+	}
+
+	from, to, err := parseTimeRange(c, 90*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time range", "err": err.Error()})
+		return
+	}
+
+	pres, err := h.Repo.GetPrescriptionByID(prescriptionID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && pres.PatientID != patientID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prescription not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescription"})
+		return
+	}
+	if !h.authorizeDoctorForPrescription(c, pres) {
+		return
+	}
+
+	overall, periods, err := h.Repo.GetAdherence(prescriptionID, period, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute adherence", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prescription_id": prescriptionID, "overall": overall, "periods": periods})
+}
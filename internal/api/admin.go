@@ -0,0 +1,155 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/utils"
+)
+
+// currentAdmin loads the calling admin, whose clinic scopes what they can
+// see. On failure it writes the response and returns false.
+func (h *Handler) currentAdmin(c *gin.Context) (models.Admin, bool) {
+	admin, err := h.Repo.GetAdminByID(c.GetInt("userID"))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		return models.Admin{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch admin", "err": err.Error()})
+		return models.Admin{}, false
+	}
+	return admin, true
+}
+
+// globalAdmin loads the calling admin and checks they oversee every clinic,
+// which managing clinics and admins takes. On failure it writes the response
+// and returns false.
+func (h *Handler) globalAdmin(c *gin.Context) (models.Admin, bool) {
+	admin, ok := h.currentAdmin(c)
+	if !ok {
+		return models.Admin{}, false
+	}
+	if admin.ClinicID != nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Only admins overseeing every clinic can do this"})
+		return models.Admin{}, false
+	}
+	return admin, true
+}
+
+// checkClinic writes a 404 and returns false when clinicID is set but isn't
+// a clinic.
+func (h *Handler) checkClinic(c *gin.Context, clinicID *int) bool {
+	if clinicID == nil {
+		return true
+	}
+	_, err := h.Repo.GetClinicByID(*clinicID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Clinic not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch clinic", "err": err.Error()})
+		return false
+	}
+	return true
+}
+
+// Admin Portal Handlers
+func (h *Handler) CreateClinic(c *gin.Context) {
+	if _, ok := h.globalAdmin(c); !ok {
+		return
+	}
+
+	var req struct {
+		Name    string `json:"name" binding:"required"`
+		Address string `json:"address"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+
+	id, err := h.Repo.CreateClinic(strings.TrimSpace(req.Name), strings.TrimSpace(req.Address))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create clinic", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// SetDoctorClinic moves a doctor into a clinic, or out of any with a null
+// clinic_id.
+func (h *Handler) SetDoctorClinic(c *gin.Context) {
+	if _, ok := h.globalAdmin(c); !ok {
+		return
+	}
+	doctorID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+
+	var req struct {
+		ClinicID *int `json:"clinic_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	if !h.checkClinic(c, req.ClinicID) {
+		return
+	}
+
+	err = h.Repo.SetDoctorClinic(doctorID, req.ClinicID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update doctor", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Doctor updated"})
+}
+
+// CreateAdmin adds an admin for a clinic, or one overseeing every clinic
+// without a clinic_id.
+func (h *Handler) CreateAdmin(c *gin.Context) {
+	if _, ok := h.globalAdmin(c); !ok {
+		return
+	}
+
+	var req struct {
+		FirstName string `json:"first_name" binding:"required"`
+		LastName  string `json:"last_name" binding:"required"`
+		Email     string `json:"email" binding:"required"`
+		Password  string `json:"password" binding:"required"`
+		ClinicID  *int   `json:"clinic_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	if !h.checkClinic(c, req.ClinicID) {
+		return
+	}
+
+	hashed, err := utils.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	id, err := h.Repo.CreateAdmin(req.FirstName, req.LastName, req.Email, hashed, req.ClinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create admin"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
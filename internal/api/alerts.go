@@ -0,0 +1,68 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+)
+
+// Doctor Portal Handlers
+func (h *Handler) GetDoctorAlerts(c *gin.Context) {
+	doctorID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	alerts, err := h.Repo.GetAlertsForDoctor(doctorID.(int), c.Query("all") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, alerts)
+}
+
+func (h *Handler) GetPatientHistoryAlerts(c *gin.Context) {
+	patientID, ok := h.authorizeDoctorForPatient(c, repository.ConsentVitals)
+	if !ok {
+		return
+	}
+
+	alerts, err := h.Repo.GetAlertsByPatientID(patientID, c.Query("all") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, alerts)
+}
+
+func (h *Handler) AcknowledgeAlert(c *gin.Context) {
+	doctorID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	alertID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	err = h.Repo.AcknowledgeAlert(doctorID.(int), alertID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge alert", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert acknowledged"})
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+)
+
+const (
+	auditPatientKey = "auditPatientID"
+	auditActionKey  = "auditAction"
+
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 1000
+)
+
+// setAuditPatient records whose data the request touches, for handlers that
+// only find out from the record itself rather than the route.
+func setAuditPatient(c *gin.Context, patientID int) {
+	c.Set(auditPatientKey, patientID)
+}
+
+// setAuditAction overrides the action the request method implies, for
+// POSTs that only read.
+func setAuditAction(c *gin.Context, action string) {
+	c.Set(auditActionKey, action)
+}
+
+// auditActions maps request methods to what they do to the resource.
+var auditActions = map[string]string{
+	http.MethodGet:    "read",
+	http.MethodHead:   "read",
+	http.MethodPost:   "create",
+	http.MethodPut:    "update",
+	http.MethodPatch:  "update",
+	http.MethodDelete: "delete",
+}
+
+// auditOutcome classifies a response status.
+func auditOutcome(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "denied"
+	case status >= 500:
+		return "error"
+	case status >= 400:
+		return "failed"
+	default:
+		return "success"
+	}
+}
+
+// auditResource names the resource and ID a route acts on from its
+// template: the first path segment after the role prefix and any
+// patients/:id, and the parameter following it. So
+// /api/doctor/patients/:id/prescriptions/:prescriptionId/history is the
+// prescription in :prescriptionId, and /api/doctor/patients/:id the patient.
+func auditResource(c *gin.Context) (resource, resourceID string) {
+	segments := strings.Split(strings.TrimPrefix(c.FullPath(), "/api/"), "/")
+	if len(segments) > 0 && (segments[0] == "patient" || segments[0] == "doctor" || segments[0] == "admin" || segments[0] == "integrations") {
+		segments = segments[1:]
+	}
+	if len(segments) > 2 && segments[0] == "patients" && segments[1] == ":id" {
+		segments = segments[2:]
+	}
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			continue
+		}
+		resource = segment
+		if i+1 < len(segments) && strings.HasPrefix(segments[i+1], ":") {
+			resourceID = c.Param(segments[i+1][1:])
+		}
+		return resource, resourceID
+	}
+	return "", ""
+}
+
+// AuditMiddleware appends every request to the audit log once it has been
+// handled: who made it, whose data it touched and how it went. It must run
+// after AuthMiddleware, DeviceAuthMiddleware for devices, or
+// IntegrationAuthMiddleware for integrations.
+func (h *Handler) AuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		resource, resourceID := auditResource(c)
+		if resource == "" {
+			return
+		}
+		entry := models.AuditEntry{
+			OccurredAt: time.Now(),
+			ActorRole:  c.GetString("role"),
+			ActorID:    c.GetInt("userID"),
+			Resource:   resource,
+			ResourceID: resourceID,
+			Action:     auditActions[c.Request.Method],
+			Method:     c.Request.Method,
+			// The route template rather than the concrete path, so secrets
+			// in path params never reach the append-only log; the IDs that
+			// matter are already in ResourceID and PatientID
+			Path:       c.FullPath(),
+			StatusCode: c.Writer.Status(),
+			Outcome:    auditOutcome(c.Writer.Status()),
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+		}
+		if action := c.GetString(auditActionKey); action != "" {
+			entry.Action = action
+		}
+		if entry.Action == "" {
+			entry.Action = strings.ToLower(c.Request.Method)
+		}
+
+		switch {
+		case c.GetInt("deviceID") != 0:
+			entry.ActorRole = "device"
+			entry.ActorID = c.GetInt("deviceID")
+			patientID := c.GetInt("patientID")
+			entry.PatientID = &patientID
+		case entry.ActorRole == "patient":
+			entry.PatientID = &entry.ActorID
+		case strings.HasPrefix(c.FullPath(), "/api/doctor/patients/:id"):
+			if patientID, err := strconv.Atoi(c.Param("id")); err == nil {
+				entry.PatientID = &patientID
+			}
+		}
+		if patientID, ok := c.Get(auditPatientKey); ok {
+			id := patientID.(int)
+			entry.PatientID = &id
+		}
+
+		if err := h.Repo.AppendAuditEntry(entry); err != nil {
+			log.Printf("Failed to write audit entry for %s %s by %s %d: %v",
+				entry.Method, entry.Path, entry.ActorRole, entry.ActorID, err)
+		}
+	}
+}
+
+// auditPage reads the ?before= and ?limit= paging params.
+func auditPage(c *gin.Context) (beforeID int64, limit int, ok bool) {
+	var err error
+	if s := c.Query("before"); s != "" {
+		if beforeID, err = strconv.ParseInt(s, 10, 64); err != nil || beforeID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before ID"})
+			return 0, 0, false
+		}
+	}
+	limit = defaultAuditPageSize
+	if s := c.Query("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 || limit > maxAuditPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxAuditPageSize)})
+			return 0, 0, false
+		}
+	}
+	return beforeID, limit, true
+}
+
+// optionalIntQuery parses an optional integer query param, writing a 400
+// when it is invalid.
+func optionalIntQuery(c *gin.Context, name string) (*int, bool) {
+	s := c.Query(name)
+	if s == "" {
+		return nil, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return nil, false
+	}
+	return &n, true
+}
+
+// Patient Portal Handlers
+
+// GetPatientAccessLog shows the patient who else read or changed their data.
+func (h *Handler) GetPatientAccessLog(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+	beforeID, limit, ok := auditPage(c)
+	if !ok {
+		return
+	}
+
+	entries, err := h.Repo.GetPatientAccessLog(patientID.(int), beforeID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch access log", "err": err.Error()})
+		return
+	}
+	for i := range entries {
+		entries[i].Method = ""
+		entries[i].Path = ""
+		entries[i].StatusCode = 0
+		entries[i].IPAddress = ""
+		entries[i].UserAgent = ""
+		entries[i].PrevHash = nil
+		entries[i].Hash = nil
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// Admin Portal Handlers
+
+// GetAuditLog lists audit entries, newest first, filtered by the patient_id,
+// actor_role, actor_id, resource, action, from and to query params and paged
+// with before and limit. Clinic admins only see what their clinic's doctors
+// did.
+func (h *Handler) GetAuditLog(c *gin.Context) {
+	admin, ok := h.currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	filter := repository.AuditFilter{
+		ActorRole: c.Query("actor_role"),
+		Resource:  c.Query("resource"),
+		Action:    c.Query("action"),
+		ClinicID:  admin.ClinicID,
+	}
+	if filter.PatientID, ok = optionalIntQuery(c, "patient_id"); !ok {
+		return
+	}
+	if filter.ActorID, ok = optionalIntQuery(c, "actor_id"); !ok {
+		return
+	}
+	if filter.BeforeID, filter.Limit, ok = auditPage(c); !ok {
+		return
+	}
+	var err error
+	if s := c.Query("from"); s != "" {
+		if filter.From, err = time.Parse(time.RFC3339, s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from time, expected RFC 3339"})
+			return
+		}
+	}
+	if s := c.Query("to"); s != "" {
+		if filter.To, err = time.Parse(time.RFC3339, s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to time, expected RFC 3339"})
+			return
+		}
+	}
+
+	entries, err := h.Repo.GetAuditEntries(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// VerifyAuditLog recomputes the audit log's hash chain and reports the first
+// entry that doesn't match, if any.
+func (h *Handler) VerifyAuditLog(c *gin.Context) {
+	if _, ok := h.globalAdmin(c); !ok {
+		return
+	}
+
+	checked, brokenID, err := h.Repo.VerifyAuditChain()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify audit log", "err": err.Error()})
+		return
+	}
+	if brokenID != 0 {
+		c.JSON(http.StatusOK, gin.H{"intact": false, "checked": checked, "first_broken_id": brokenID})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"intact": true, "checked": checked})
+}
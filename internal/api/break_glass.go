@@ -0,0 +1,184 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// minJustificationLength keeps break-glass justifications from being a
+// token word; the review needs enough to judge the emergency by.
+const minJustificationLength = 20
+
+// Doctor Portal Handlers
+
+// BreakGlass gives the doctor access to the patient's whole record for
+// BreakGlassTTL without their consent, for emergencies. The justification is
+// mandatory, the patient and the clinic's admins are told, and the access
+// waits in the admins' review queue.
+func (h *Handler) BreakGlass(c *gin.Context) {
+	doctorID := c.GetInt("userID")
+	patientID, ok := patientParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Justification string `json:"justification" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	justification, ok := validateReason(req.Justification)
+	if !ok || utf8.RuneCountInString(justification) < minJustificationLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A justification of " + strconv.Itoa(minJustificationLength) + " to " + strconv.Itoa(maxReasonLength) + " characters is required"})
+		return
+	}
+
+	patient, err := h.Repo.GetPatientByID(patientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch patient", "err": err.Error()})
+		return
+	}
+	doctor, err := h.Repo.GetDoctorByID(doctorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor", "err": err.Error()})
+		return
+	}
+	doctorName := doctor.FirstName + " " + doctor.LastName
+	patientName := patient.FirstName + " " + patient.LastName
+
+	grant, err := h.Repo.CreateBreakGlassGrant(models.BreakGlassGrant{
+		DoctorID:      doctorID,
+		PatientID:     patientID,
+		Justification: justification,
+		IPAddress:     c.ClientIP(),
+		UserAgent:     c.Request.UserAgent(),
+		ExpiresAt:     time.Now().Add(h.BreakGlassTTL),
+	}, models.Notification{
+		RecipientRole: "patient",
+		RecipientID:   patientID,
+		Kind:          "break_glass",
+		Title:         "Dr. " + doctorName + " used emergency access to your records",
+		Body:          justification,
+		Data:          map[string]any{"doctor_id": doctorID},
+	}, models.Notification{
+		Kind:  "break_glass",
+		Title: "Dr. " + doctorName + " used emergency access to " + patientName + "'s records",
+		Body:  justification,
+		Data:  map[string]any{"doctor_id": doctorID, "patient_id": patientID},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant emergency access", "err": err.Error()})
+		return
+	}
+	log.Printf("Break-glass access %d to patient %d by doctor %d from %s", grant.ID, patientID, doctorID, grant.IPAddress)
+	grant.PatientName = patientName
+	grant.DoctorName = doctorName
+
+	c.JSON(http.StatusCreated, grant)
+}
+
+// Patient Portal Handlers
+
+// GetPatientBreakGlassAccesses lists every emergency access to the patient's
+// record and how it was reviewed.
+func (h *Handler) GetPatientBreakGlassAccesses(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	grants, err := h.Repo.GetBreakGlassGrantsByPatientID(patientID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch emergency accesses", "err": err.Error()})
+		return
+	}
+	for i := range grants {
+		grants[i].IPAddress = ""
+		grants[i].UserAgent = ""
+		grants[i].ReviewedBy = nil
+	}
+	c.JSON(http.StatusOK, grants)
+}
+
+// Admin Portal Handlers
+
+// GetBreakGlassReviewQueue lists the emergency accesses in the admin's
+// clinic waiting for review, or those already reviewed with ?reviewed=true.
+func (h *Handler) GetBreakGlassReviewQueue(c *gin.Context) {
+	admin, ok := h.currentAdmin(c)
+	if !ok {
+		return
+	}
+	reviewed, _ := strconv.ParseBool(c.Query("reviewed"))
+
+	grants, err := h.Repo.GetBreakGlassReviewQueue(admin.ClinicID, reviewed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review queue", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, grants)
+}
+
+// ReviewBreakGlass records whether an emergency access was justified. A note
+// is required when it wasn't.
+func (h *Handler) ReviewBreakGlass(c *gin.Context) {
+	admin, ok := h.currentAdmin(c)
+	if !ok {
+		return
+	}
+	grantID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid break-glass ID"})
+		return
+	}
+
+	var req struct {
+		Outcome string `json:"outcome" binding:"required"`
+		Note    string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	if req.Outcome != "justified" && req.Outcome != "unjustified" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "outcome must be justified or unjustified"})
+		return
+	}
+	note := strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(note) > maxReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Note must be at most " + strconv.Itoa(maxReasonLength) + " characters"})
+		return
+	}
+	if note == "" && req.Outcome == "unjustified" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A note is required for an unjustified access"})
+		return
+	}
+
+	err = h.Repo.ReviewBreakGlassGrant(grantID, admin.ID, admin.ClinicID, req.Outcome, note)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Emergency access not found or already reviewed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record review", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Review recorded"})
+}
@@ -0,0 +1,520 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/internal/terminology"
+)
+
+// authorizeChartEditor checks the calling doctor can see the category of the
+// patient in :id and is treating them, which changing their chart takes. On
+// failure it writes the response and returns false.
+func (h *Handler) authorizeChartEditor(c *gin.Context, category string) (int, bool) {
+	patientID, ok := h.authorizeDoctorForPatient(c, category)
+	if !ok {
+		return 0, false
+	}
+
+	treating, err := h.Repo.DoctorHasPatient(c.GetInt("userID"), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient access"})
+		return 0, false
+	}
+	if !treating {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Only doctors treating the patient can change their chart"})
+		return 0, false
+	}
+	return patientID, true
+}
+
+// chartSource is who recorded a chart entry: the patient, or the doctor when
+// doctorID is set.
+func chartSource(doctorID *int) string {
+	if doctorID != nil {
+		return repository.SourceDoctor
+	}
+	return repository.SourcePatient
+}
+
+// notFoundOnChart is the error for a chart entry that isn't there or, for a
+// patient, was recorded by a doctor.
+func notFoundOnChart(entry string, doctorID *int) string {
+	if doctorID != nil {
+		return entry + " not found"
+	}
+	return entry + " not found or recorded by a doctor"
+}
+
+// chartEntryID parses a chart entry ID param. On failure it writes the
+// response and returns false.
+func chartEntryID(c *gin.Context, param, entry string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + entry + " ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// checkDateRange writes a 400 and returns false if to is before from. Both
+// are YYYY-MM-DD, so they compare as strings.
+func checkDateRange(c *gin.Context, from, to *string, message string) bool {
+	if from != nil && to != nil && *to < *from {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return false
+	}
+	return true
+}
+
+// bindCondition reads and validates a condition from the request body. On
+// failure it writes the response and returns false.
+func bindCondition(c *gin.Context, patientID int, doctorID *int) (models.PatientCondition, bool) {
+	var req struct {
+		Name         string `json:"name" binding:"required"`
+		Code         string `json:"code"`
+		CodeSystem   string `json:"code_system"` // defaults to ICD-10-CM
+		OnsetDate    string `json:"onset_date"`
+		ResolvedDate string `json:"resolved_date"`
+		Notes        string `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return models.PatientCondition{}, false
+	}
+
+	cond := models.PatientCondition{
+		PatientID:  patientID,
+		Name:       strings.TrimSpace(req.Name),
+		Code:       strings.ToUpper(strings.TrimSpace(req.Code)),
+		Notes:      strings.TrimSpace(req.Notes),
+		Source:     chartSource(doctorID),
+		RecordedBy: doctorID,
+	}
+	if cond.Name == "" || utf8.RuneCountInString(cond.Name) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required and must be at most 255 characters"})
+		return models.PatientCondition{}, false
+	}
+	if utf8.RuneCountInString(cond.Code) > 20 || utf8.RuneCountInString(cond.Notes) > maxReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code must be at most 20 characters and notes at most " + strconv.Itoa(maxReasonLength)})
+		return models.PatientCondition{}, false
+	}
+	if cond.Code != "" {
+		cond.CodeSystem = codeSystem(req.CodeSystem)
+		if !terminology.Systems[cond.CodeSystem] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown code system " + cond.CodeSystem})
+			return models.PatientCondition{}, false
+		}
+	}
+	var err error
+	if cond.OnsetDate, err = parseDate(req.OnsetDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid onset_date", "err": err.Error()})
+		return models.PatientCondition{}, false
+	}
+	if cond.ResolvedDate, err = parseDate(req.ResolvedDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolved_date", "err": err.Error()})
+		return models.PatientCondition{}, false
+	}
+	if !checkDateRange(c, cond.OnsetDate, cond.ResolvedDate, "resolved_date can't be before onset_date") {
+		return models.PatientCondition{}, false
+	}
+	return cond, true
+}
+
+// bindMedication reads and validates a medication from the request body. On
+// failure it writes the response and returns false.
+func bindMedication(c *gin.Context, patientID int, doctorID *int) (models.PatientMedication, bool) {
+	var req struct {
+		Name      string `json:"name" binding:"required"`
+		Dose      string `json:"dose"`
+		Frequency string `json:"frequency"`
+		Route     string `json:"route"`
+		StartedOn string `json:"started_on"`
+		StoppedOn string `json:"stopped_on"`
+		Notes     string `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return models.PatientMedication{}, false
+	}
+
+	med := models.PatientMedication{
+		PatientID:  patientID,
+		Name:       strings.TrimSpace(req.Name),
+		Dose:       strings.TrimSpace(req.Dose),
+		Frequency:  strings.TrimSpace(req.Frequency),
+		Route:      strings.TrimSpace(req.Route),
+		Notes:      strings.TrimSpace(req.Notes),
+		Source:     chartSource(doctorID),
+		RecordedBy: doctorID,
+	}
+	if med.Name == "" || utf8.RuneCountInString(med.Name) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required and must be at most 255 characters"})
+		return models.PatientMedication{}, false
+	}
+	if utf8.RuneCountInString(med.Dose) > 100 || utf8.RuneCountInString(med.Frequency) > 100 || utf8.RuneCountInString(med.Route) > 50 || utf8.RuneCountInString(med.Notes) > maxReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dose and frequency must be at most 100 characters, route at most 50 and notes at most " + strconv.Itoa(maxReasonLength)})
+		return models.PatientMedication{}, false
+	}
+	var err error
+	if med.StartedOn, err = parseDate(req.StartedOn); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid started_on", "err": err.Error()})
+		return models.PatientMedication{}, false
+	}
+	if med.StoppedOn, err = parseDate(req.StoppedOn); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stopped_on", "err": err.Error()})
+		return models.PatientMedication{}, false
+	}
+	if !checkDateRange(c, med.StartedOn, med.StoppedOn, "stopped_on can't be before started_on") {
+		return models.PatientMedication{}, false
+	}
+	return med, true
+}
+
+// sendChart writes the patient's chart summary: allergies, the problem list
+// and current medications, prescribed or not. For a doctor, the sections the
+// patient hasn't given them access to are left out and listed as restricted.
+func (h *Handler) sendChart(c *gin.Context, patientID int, doctorID *int) {
+	patient, err := h.Repo.GetPatientByID(patientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch patient", "err": err.Error()})
+		return
+	}
+	chart := models.PatientChart{
+		PatientID:   patient.ID,
+		PatientName: patient.FirstName + " " + patient.LastName,
+	}
+
+	// allowed reports whether the caller may see the category, noting it as
+	// restricted when they may not
+	allowed := func(category string) (bool, error) {
+		if doctorID == nil {
+			return true, nil
+		}
+		ok, err := h.Repo.DoctorHasConsent(*doctorID, patientID, category)
+		if err == nil && !ok {
+			chart.Restricted = append(chart.Restricted, category)
+		}
+		return ok, err
+	}
+
+	if ok, err := allowed(repository.ConsentAllergies); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient access"})
+		return
+	} else if ok {
+		if chart.Allergies, err = h.Repo.GetAllergiesByPatientID(patientID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allergies", "err": err.Error()})
+			return
+		}
+	}
+
+	if ok, err := allowed(repository.ConsentConditions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient access"})
+		return
+	} else if ok {
+		if chart.Conditions, err = h.Repo.GetConditionsByPatientID(patientID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conditions", "err": err.Error()})
+			return
+		}
+	}
+
+	if ok, err := allowed(repository.ConsentMedications); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient access"})
+		return
+	} else if ok {
+		prescribed, err := h.Repo.GetPrescribedMedications(patientID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch medications", "err": err.Error()})
+			return
+		}
+		other, err := h.Repo.GetMedicationsByPatientID(patientID, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch medications", "err": err.Error()})
+			return
+		}
+		chart.Medications = append(prescribed, other...)
+	}
+
+	// A doctor always sees the results of their own orders
+	if _, err := allowed(repository.ConsentLabs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient access"})
+		return
+	}
+	if doctorID == nil {
+		chart.LabTrends, err = h.Repo.GetLabTrends(patientID, "")
+	} else {
+		chart.LabTrends, err = h.Repo.GetLabTrendsForDoctor(*doctorID, patientID, "")
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lab results", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, chart)
+}
+
+func (h *Handler) listConditions(c *gin.Context, patientID int) {
+	conditions, err := h.Repo.GetConditionsByPatientID(patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conditions", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, conditions)
+}
+
+func (h *Handler) createCondition(c *gin.Context, patientID int, doctorID *int) {
+	cond, ok := bindCondition(c, patientID, doctorID)
+	if !ok || !h.codeCondition(c, &cond) {
+		return
+	}
+
+	newID, err := h.Repo.CreateCondition(cond)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record condition", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": newID})
+}
+
+// updateCondition replaces the condition in the idParam param. Patients can
+// only change the ones they reported; a doctor's change makes it theirs.
+func (h *Handler) updateCondition(c *gin.Context, patientID int, doctorID *int, idParam string) {
+	conditionID, ok := chartEntryID(c, idParam, "condition")
+	if !ok {
+		return
+	}
+	cond, ok := bindCondition(c, patientID, doctorID)
+	if !ok || !h.codeCondition(c, &cond) {
+		return
+	}
+	cond.ID = conditionID
+
+	err := h.Repo.UpdateCondition(cond, doctorID == nil)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFoundOnChart("Condition", doctorID)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update condition", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Condition updated"})
+}
+
+func (h *Handler) deleteCondition(c *gin.Context, patientID int, doctorID *int, idParam string) {
+	conditionID, ok := chartEntryID(c, idParam, "condition")
+	if !ok {
+		return
+	}
+
+	err := h.Repo.DeleteCondition(patientID, conditionID, doctorID == nil)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFoundOnChart("Condition", doctorID)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete condition", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Condition removed"})
+}
+
+// listMedications lists the medications recorded outside prescriptions,
+// including stopped ones.
+func (h *Handler) listMedications(c *gin.Context, patientID int) {
+	meds, err := h.Repo.GetMedicationsByPatientID(patientID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch medications", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, meds)
+}
+
+func (h *Handler) createMedication(c *gin.Context, patientID int, doctorID *int) {
+	med, ok := bindMedication(c, patientID, doctorID)
+	if !ok {
+		return
+	}
+
+	newID, err := h.Repo.CreateMedication(med)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record medication", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": newID})
+}
+
+// updateMedication replaces the medication in the idParam param. Patients
+// can only change the ones they reported; a doctor's change makes it theirs.
+func (h *Handler) updateMedication(c *gin.Context, patientID int, doctorID *int, idParam string) {
+	medicationID, ok := chartEntryID(c, idParam, "medication")
+	if !ok {
+		return
+	}
+	med, ok := bindMedication(c, patientID, doctorID)
+	if !ok {
+		return
+	}
+	med.ID = medicationID
+
+	err := h.Repo.UpdateMedication(med, doctorID == nil)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFoundOnChart("Medication", doctorID)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update medication", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Medication updated"})
+}
+
+func (h *Handler) deleteMedication(c *gin.Context, patientID int, doctorID *int, idParam string) {
+	medicationID, ok := chartEntryID(c, idParam, "medication")
+	if !ok {
+		return
+	}
+
+	err := h.Repo.DeleteMedication(patientID, medicationID, doctorID == nil)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFoundOnChart("Medication", doctorID)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete medication", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Medication removed"})
+}
+
+// Patient Portal Handlers
+func (h *Handler) GetPatientChart(c *gin.Context) {
+	h.sendChart(c, c.GetInt("userID"), nil)
+}
+
+func (h *Handler) GetPatientConditions(c *gin.Context) {
+	h.listConditions(c, c.GetInt("userID"))
+}
+
+func (h *Handler) CreatePatientCondition(c *gin.Context) {
+	h.createCondition(c, c.GetInt("userID"), nil)
+}
+
+func (h *Handler) UpdatePatientCondition(c *gin.Context) {
+	h.updateCondition(c, c.GetInt("userID"), nil, "id")
+}
+
+func (h *Handler) DeletePatientCondition(c *gin.Context) {
+	h.deleteCondition(c, c.GetInt("userID"), nil, "id")
+}
+
+func (h *Handler) GetPatientMedications(c *gin.Context) {
+	h.listMedications(c, c.GetInt("userID"))
+}
+
+func (h *Handler) CreatePatientMedication(c *gin.Context) {
+	h.createMedication(c, c.GetInt("userID"), nil)
+}
+
+func (h *Handler) UpdatePatientMedication(c *gin.Context) {
+	h.updateMedication(c, c.GetInt("userID"), nil, "id")
+}
+
+func (h *Handler) DeletePatientMedication(c *gin.Context) {
+	h.deleteMedication(c, c.GetInt("userID"), nil, "id")
+}
+
+// Doctor Portal Handlers
+
+// GetPatientHistoryChart gives the doctor the patient's chart summary, with
+// the sections they have consent for.
+func (h *Handler) GetPatientHistoryChart(c *gin.Context) {
+	patientID, ok := patientParam(c)
+	if !ok {
+		return
+	}
+	doctorID := c.GetInt("userID")
+	h.sendChart(c, patientID, &doctorID)
+}
+
+func (h *Handler) GetPatientHistoryConditions(c *gin.Context) {
+	patientID, ok := h.authorizeDoctorForPatient(c, repository.ConsentConditions)
+	if !ok {
+		return
+	}
+	h.listConditions(c, patientID)
+}
+
+func (h *Handler) CreatePatientHistoryCondition(c *gin.Context) {
+	patientID, ok := h.authorizeChartEditor(c, repository.ConsentConditions)
+	if !ok {
+		return
+	}
+	doctorID := c.GetInt("userID")
+	h.createCondition(c, patientID, &doctorID)
+}
+
+func (h *Handler) UpdatePatientHistoryCondition(c *gin.Context) {
+	patientID, ok := h.authorizeChartEditor(c, repository.ConsentConditions)
+	if !ok {
+		return
+	}
+	doctorID := c.GetInt("userID")
+	h.updateCondition(c, patientID, &doctorID, "conditionId")
+}
+
+func (h *Handler) DeletePatientHistoryCondition(c *gin.Context) {
+	patientID, ok := h.authorizeChartEditor(c, repository.ConsentConditions)
+	if !ok {
+		return
+	}
+	doctorID := c.GetInt("userID")
+	h.deleteCondition(c, patientID, &doctorID, "conditionId")
+}
+
+func (h *Handler) GetPatientHistoryMedications(c *gin.Context) {
+	patientID, ok := h.authorizeDoctorForPatient(c, repository.ConsentMedications)
+	if !ok {
+		return
+	}
+	h.listMedications(c, patientID)
+}
+
+func (h *Handler) CreatePatientHistoryMedication(c *gin.Context) {
+	patientID, ok := h.authorizeChartEditor(c, repository.ConsentMedications)
+	if !ok {
+		return
+	}
+	doctorID := c.GetInt("userID")
+	h.createMedication(c, patientID, &doctorID)
+}
+
+func (h *Handler) UpdatePatientHistoryMedication(c *gin.Context) {
+	patientID, ok := h.authorizeChartEditor(c, repository.ConsentMedications)
+	if !ok {
+		return
+	}
+	doctorID := c.GetInt("userID")
+	h.updateMedication(c, patientID, &doctorID, "medicationId")
+}
+
+func (h *Handler) DeletePatientHistoryMedication(c *gin.Context) {
+	patientID, ok := h.authorizeChartEditor(c, repository.ConsentMedications)
+	if !ok {
+		return
+	}
+	doctorID := c.GetInt("userID")
+	h.deleteMedication(c, patientID, &doctorID, "medicationId")
+}
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+)
+
+// patientParam parses the :id patient param, writing a 400 when it is invalid.
+func patientParam(c *gin.Context) (int, bool) {
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return 0, false
+	}
+	return patientID, true
+}
+
+// authorizeDoctorForPatient parses the :id patient param and checks that the
+// patient lets the calling doctor see the category of their record. On
+// failure it writes the response and returns false.
+func (h *Handler) authorizeDoctorForPatient(c *gin.Context, category string) (int, bool) {
+	doctorID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return 0, false
+	}
+
+	patientID, ok := patientParam(c)
+	if !ok {
+		return 0, false
+	}
+
+	allowed, err := h.Repo.DoctorHasConsent(doctorID.(int), patientID, category)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient access"})
+		return 0, false
+	}
+	if !allowed {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "The patient hasn't given you access to their " + category, "category": category})
+		return 0, false
+	}
+
+	return patientID, true
+}
+
+// authorizeTreatingDoctor parses the :id patient param and checks that the
+// calling doctor has had an appointment with the patient, which is what
+// prescribing for them takes. On failure it writes the response and returns
+// false.
+func (h *Handler) authorizeTreatingDoctor(c *gin.Context) (int, bool) {
+	patientID, ok := patientParam(c)
+	if !ok {
+		return 0, false
+	}
+
+	allowed, err := h.Repo.DoctorHasPatient(c.GetInt("userID"), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient access"})
+		return 0, false
+	}
+	if !allowed {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You are not authorized to access this patient"})
+		return 0, false
+	}
+
+	return patientID, true
+}
+
+// authorizeDoctorForPrescription checks the calling doctor wrote the
+// prescription or the patient lets them see their prescriptions. On failure
+// it writes the response and returns false.
+func (h *Handler) authorizeDoctorForPrescription(c *gin.Context, pres models.Prescription) bool {
+	doctorID := c.GetInt("userID")
+	if pres.DoctorID == doctorID {
+		return true
+	}
+
+	allowed, err := h.Repo.DoctorHasConsent(doctorID, pres.PatientID, repository.ConsentPrescriptions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient access"})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The patient hasn't given you access to their prescriptions", "category": repository.ConsentPrescriptions})
+		return false
+	}
+	return true
+}
+
+// Patient Portal Handlers
+
+// GetPatientConsents lists who the patient has given access to. Revoked and
+// expired grants are included with ?all=true.
+func (h *Handler) GetPatientConsents(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+	all, _ := strconv.ParseBool(c.Query("all"))
+
+	consents, err := h.Repo.GetConsentsByPatientID(patientID.(int), all)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consents", "err": err.Error()})
+		return
+	}
+	for i := range consents {
+		consents[i].PatientName = ""
+	}
+	c.JSON(http.StatusOK, consents)
+}
+
+// GrantPatientConsent gives a doctor, or every doctor of a clinic, access to
+// the chosen categories of the patient's record, optionally until a given
+// time. Granting a category again replaces the earlier grant.
+func (h *Handler) GrantPatientConsent(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var req struct {
+		DoctorID   *int       `json:"doctor_id"`
+		ClinicID   *int       `json:"clinic_id"`
+		Categories []string   `json:"categories" binding:"required"`
+		ExpiresAt  *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	if (req.DoctorID == nil) == (req.ClinicID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of doctor_id and clinic_id is required"})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	var categories []string
+	seen := map[string]bool{}
+	for _, category := range req.Categories {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if !repository.ConsentCategories[category] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown category: " + category})
+			return
+		}
+		if !seen[category] {
+			seen[category] = true
+			categories = append(categories, category)
+		}
+	}
+	if len(categories) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one category is required"})
+		return
+	}
+
+	var grantee string
+	if req.DoctorID != nil {
+		doctor, err := h.Repo.GetDoctorByID(*req.DoctorID)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor", "err": err.Error()})
+			return
+		}
+		grantee = doctor.FirstName + " " + doctor.LastName
+	} else {
+		clinic, err := h.Repo.GetClinicByID(*req.ClinicID)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Clinic not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch clinic", "err": err.Error()})
+			return
+		}
+		grantee = clinic.Name
+	}
+
+	patient, err := h.Repo.GetPatientByID(patientID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch patient", "err": err.Error()})
+		return
+	}
+
+	var notify models.Notification
+	if req.DoctorID != nil {
+		notify = models.Notification{
+			RecipientRole: "doctor",
+			RecipientID:   *req.DoctorID,
+			Kind:          "consent_granted",
+			Title:         patient.FirstName + " " + patient.LastName + " gave you access to their records",
+			Body:          strings.Join(categories, ", "),
+			Data:          map[string]any{"patient_id": patient.ID, "categories": categories},
+		}
+	}
+
+	consents, err := h.Repo.GrantConsents(patient.ID, req.DoctorID, req.ClinicID, categories, req.ExpiresAt, notify)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant access", "err": err.Error()})
+		return
+	}
+	for i := range consents {
+		consents[i].GranteeName = grantee
+	}
+	c.JSON(http.StatusCreated, consents)
+}
+
+func (h *Handler) RevokePatientConsent(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+	consentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid consent ID"})
+		return
+	}
+
+	err = h.Repo.RevokeConsent(patientID.(int), consentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Consent not found or already revoked"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Access revoked"})
+}
+
+func (h *Handler) GetClinics(c *gin.Context) {
+	clinics, err := h.Repo.GetClinics()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch clinics", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, clinics)
+}
+
+// Doctor Portal Handlers
+
+// GetDoctorConsents lists the live grants to the doctor and their clinic,
+// optionally for one patient with ?patient_id=.
+func (h *Handler) GetDoctorConsents(c *gin.Context) {
+	doctorID := c.GetInt("userID")
+	var patientID int
+	if s := c.Query("patient_id"); s != "" {
+		var err error
+		if patientID, err = strconv.Atoi(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+			return
+		}
+	}
+
+	consents, err := h.Repo.GetConsentsForDoctor(doctorID, patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consents", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, consents)
+}
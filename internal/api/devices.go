@@ -0,0 +1,327 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/internal/vitals"
+	"github.com/RitwikGupta-0501/vital-watch/utils"
+)
+
+const (
+	pairingCodeLength  = 8
+	pairingCodeTTL     = 10 * time.Minute
+	maxReadingsPerPost = 500
+	// maxPairingFailures is how many wrong pairing codes a client may send
+	// in pairingFailureWindow before it is turned away. Against 32^8
+	// possible codes that live for pairingCodeTTL, that leaves guessing
+	// a live code hopeless without locking other patients out of pairing.
+	maxPairingFailures   = 10
+	pairingFailureWindow = 15 * time.Minute
+)
+
+// DeviceAuthMiddleware authenticates a paired device by the credential it was
+// issued at pairing time, sent as "Authorization: Device <credential>".
+func (h *Handler) DeviceAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "Device" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid device credential format"})
+			return
+		}
+
+		device, err := h.Repo.GetActiveDeviceByCredential(utils.HashToken(parts[1]))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid device credential"})
+			return
+		}
+
+		c.Set("deviceID", device.ID)
+		c.Set("patientID", device.PatientID)
+		c.Next()
+	}
+}
+
+// Patient Portal Handlers
+func (h *Handler) CreatePairingCode(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	code, err := utils.GenerateCode(pairingCodeLength)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate pairing code"})
+		return
+	}
+
+	expiresAt := time.Now().Add(pairingCodeTTL)
+	if err := h.Repo.CreatePairingCode(patientID.(int), utils.HashToken(code), expiresAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pairing code", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"code": code, "expires_at": expiresAt})
+}
+
+func (h *Handler) GetPatientDevices(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	devices, err := h.Repo.GetDevicesByPatientID(patientID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch devices"})
+		return
+	}
+	c.JSON(http.StatusOK, devices)
+}
+
+func (h *Handler) RevokePatientDevice(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	deviceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+
+	h.revokeDevice(c, patientID.(int), deviceID)
+}
+
+func (h *Handler) GetPatientVitals(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	h.listVitals(c, patientID.(int))
+}
+
+// Device Handlers
+func (h *Handler) PairDevice(c *gin.Context) {
+	var req struct {
+		Code   string `json:"code" binding:"required"`
+		Serial string `json:"serial" binding:"required"`
+		Model  string `json:"model" binding:"required"`
+		Type   string `json:"type" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+
+	ip := c.ClientIP()
+	failures, err := h.Repo.CountPairingFailures(ip, time.Now().Add(-pairingFailureWindow))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pair device", "err": err.Error()})
+		return
+	}
+	if failures >= maxPairingFailures {
+		c.Header("Retry-After", strconv.Itoa(int(pairingFailureWindow.Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many invalid pairing codes; try again later"})
+		return
+	}
+
+	credential, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate device credential"})
+		return
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	device, err := h.Repo.PairDevice(utils.HashToken(code), req.Serial, req.Model, req.Type, utils.HashToken(credential))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := h.Repo.RecordPairingFailure(ip, pairingFailureWindow); err != nil {
+				log.Printf("Failed to record failed pairing attempt from %s: %v", ip, err)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired pairing code"})
+			return
+		}
+		if repository.IsUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Device is already paired"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pair device", "err": err.Error()})
+		return
+	}
+
+	// The credential is only ever returned here; we keep just its hash
+	c.JSON(http.StatusCreated, gin.H{"device": device, "credential": credential})
+}
+
+func (h *Handler) SubmitDeviceReadings(c *gin.Context) {
+	deviceID := c.GetInt("deviceID")
+	patientID := c.GetInt("patientID")
+
+	var req struct {
+		Readings []struct {
+			Metric     string    `json:"metric" binding:"required"`
+			Value      float64   `json:"value"`
+			Unit       string    `json:"unit"`
+			RecordedAt time.Time `json:"recorded_at" binding:"required"`
+		} `json:"readings" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	if len(req.Readings) == 0 || len(req.Readings) > maxReadingsPerPost {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Between 1 and " + strconv.Itoa(maxReadingsPerPost) + " readings are required"})
+		return
+	}
+
+	readings := make([]models.VitalReading, 0, len(req.Readings))
+	for i, rd := range req.Readings {
+		if err := vitals.Validate(rd.Metric, rd.Value, rd.Unit); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reading at index " + strconv.Itoa(i), "err": err.Error()})
+			return
+		}
+		readings = append(readings, models.VitalReading{
+			PatientID:  patientID,
+			DeviceID:   &deviceID,
+			Metric:     rd.Metric,
+			Value:      rd.Value,
+			Unit:       vitals.Metrics[rd.Metric].Unit,
+			RecordedAt: rd.RecordedAt,
+			Source:     "device",
+		})
+	}
+
+	if err := h.Repo.CreateVitalReadings(readings); err != nil {
+		log.Printf("Failed to store readings for device %d: %v", deviceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store readings"})
+		return
+	}
+
+	if err := h.Repo.TouchDevice(deviceID); err != nil {
+		log.Printf("Failed to update last_seen_at for device %d: %v", deviceID, err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"accepted": len(readings)})
+}
+
+// Doctor Portal Handlers
+func (h *Handler) GetPatientHistoryDevices(c *gin.Context) {
+	patientID, ok := h.authorizeDoctorForPatient(c, repository.ConsentVitals)
+	if !ok {
+		return
+	}
+
+	devices, err := h.Repo.GetDevicesByPatientID(patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch devices"})
+		return
+	}
+	c.JSON(http.StatusOK, devices)
+}
+
+func (h *Handler) DoctorRevokePatientDevice(c *gin.Context) {
+	patientID, ok := h.authorizeDoctorForPatient(c, repository.ConsentVitals)
+	if !ok {
+		return
+	}
+
+	// Seeing a patient's vitals isn't enough to cut off their monitoring
+	treating, err := h.Repo.DoctorHasPatient(c.GetInt("userID"), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient access"})
+		return
+	}
+	if !treating {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Only doctors treating the patient can revoke their devices"})
+		return
+	}
+
+	deviceID, err := strconv.Atoi(c.Param("deviceId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+
+	h.revokeDevice(c, patientID, deviceID)
+}
+
+func (h *Handler) GetPatientHistoryVitals(c *gin.Context) {
+	patientID, ok := h.authorizeDoctorForPatient(c, repository.ConsentVitals)
+	if !ok {
+		return
+	}
+
+	h.listVitals(c, patientID)
+}
+
+func (h *Handler) revokeDevice(c *gin.Context, patientID, deviceID int) {
+	err := h.Repo.RevokeDevice(patientID, deviceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke device", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device revoked"})
+}
+
+// listVitals serves readings for ?metric= between ?from= and ?to= (RFC 3339),
+// defaulting to the last 30 days.
+func (h *Handler) listVitals(c *gin.Context, patientID int) {
+	from, to, err := parseTimeRange(c, 30*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time range", "err": err.Error()})
+		return
+	}
+
+	readings, err := h.Repo.GetVitalReadings(patientID, c.Query("metric"), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vitals"})
+		return
+	}
+	c.JSON(http.StatusOK, readings)
+}
+
+// parseTimeRange reads optional ?from= and ?to= RFC 3339 query params. A
+// missing to means now and a missing from means to minus def.
+func parseTimeRange(c *gin.Context, def time.Duration) (time.Time, time.Time, error) {
+	to := time.Now()
+	if s := c.Query("to"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = t
+	}
+
+	from := to.Add(-def)
+	if s := c.Query("from"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = t
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,448 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+)
+
+// documentCategories are the kinds of document the vault accepts.
+var documentCategories = map[string]bool{
+	"lab_report":        true,
+	"imaging_report":    true,
+	"discharge_summary": true,
+	"insurance_card":    true,
+	"referral_letter":   true,
+	"vaccination":       true,
+	"other":             true,
+}
+
+const (
+	maxDocumentTags      = 20
+	maxDocumentTagLength = 50
+)
+
+// parseDocumentTags splits a comma separated list into lower-case tags,
+// dropping blanks and duplicates.
+func parseDocumentTags(raw string) ([]string, error) {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, tag := range strings.Split(raw, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxDocumentTagLength {
+			return nil, fmt.Errorf("tags must be at most %d characters", maxDocumentTagLength)
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxDocumentTags {
+		return nil, fmt.Errorf("a document can have at most %d tags", maxDocumentTags)
+	}
+	return tags, nil
+}
+
+// parseDate checks an optional YYYY-MM-DD date.
+func parseDate(s string) (*string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if _, err := time.Parse(time.DateOnly, s); err != nil {
+		return nil, fmt.Errorf("%q is not a YYYY-MM-DD date", s)
+	}
+	return &s, nil
+}
+
+// cleanFileName makes a client supplied file name safe to store and echo
+// back in a Content-Disposition header.
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" {
+		return ""
+	}
+	if r := []rune(name); len(r) > 255 {
+		name = string(r[len(r)-255:])
+	}
+	return name
+}
+
+// documentFilter reads ?category=, ?tag=, ?q=, ?from= and ?to= (YYYY-MM-DD).
+func documentFilter(c *gin.Context) (repository.DocumentFilter, bool) {
+	f := repository.DocumentFilter{
+		Category: c.Query("category"),
+		Tag:      strings.ToLower(strings.TrimSpace(c.Query("tag"))),
+		Search:   strings.TrimSpace(c.Query("q")),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+	}
+	if f.Category != "" && !documentCategories[f.Category] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown category"})
+		return f, false
+	}
+	for _, d := range []string{f.From, f.To} {
+		if _, err := parseDate(d); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range", "err": err.Error()})
+			return f, false
+		}
+	}
+	return f, true
+}
+
+func documentID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// UploadPatientDocument stores a document in the patient's vault. It takes a
+// multipart form with the file, a category, and optionally a title,
+// description, comma separated tags and the document's date.
+func (h *Handler) UploadPatientDocument(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form", "err": err.Error()})
+		return
+	}
+
+	category := c.Request.FormValue("category")
+	if !documentCategories[category] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid category is required"})
+		return
+	}
+	tags, err := parseDocumentTags(c.Request.FormValue("tags"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tags", "err": err.Error()})
+		return
+	}
+	documentDate, err := parseDate(c.Request.FormValue("document_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document_date", "err": err.Error()})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required", "err": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	originalName := cleanFileName(header.Filename)
+	title := strings.TrimSpace(c.Request.FormValue("title"))
+	if title == "" {
+		title = strings.TrimSuffix(originalName, filepath.Ext(originalName))
+	}
+	if title == "" || utf8.RuneCountInString(title) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required and must be at most 255 characters"})
+		return
+	}
+
+	fileType, scanStatus, ok := h.inspectFile(c, file, header.Size)
+	if !ok {
+		return
+	}
+
+	doc := models.PatientDocument{
+		PatientID:    patientID.(int),
+		Category:     category,
+		Title:        title,
+		Description:  strings.TrimSpace(c.Request.FormValue("description")),
+		Tags:         tags,
+		DocumentDate: documentDate,
+		FileName:     fmt.Sprintf("document-%d-%s%s", patientID.(int), uuid.New().String(), fileType.Ext),
+		OriginalName: originalName,
+		ContentType:  fileType.ContentType,
+		Size:         header.Size,
+		ScanStatus:   scanStatus,
+	}
+
+	err = h.Store.Put(c.Request.Context(), doc.FileName, file, header.Size, doc.ContentType)
+	if err != nil {
+		log.Printf("Failed to upload document to storage: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file", "err": err.Error()})
+		return
+	}
+
+	doc.ID, err = h.Repo.CreateDocument(doc)
+	if err != nil {
+		log.Printf("Failed to create document in DB: %v", err)
+		// If DB save fails, roll back the upload
+		key := doc.FileName
+		go func() {
+			if delErr := h.Store.Delete(context.Background(), key); delErr != nil {
+				log.Printf("CRITICAL: Failed to rollback upload of %s: %v", key, delErr)
+			}
+		}()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create document record", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": doc.ID, "scan_status": doc.ScanStatus})
+}
+
+// GetPatientDocuments lists the patient's documents, filtered by
+// documentFilter.
+func (h *Handler) GetPatientDocuments(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+	filter, ok := documentFilter(c)
+	if !ok {
+		return
+	}
+
+	docs, err := h.Repo.GetDocumentsByPatientID(patientID.(int), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch documents", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, docs)
+}
+
+// patientDocument loads the :id document if the patient owns it.
+func (h *Handler) patientDocument(c *gin.Context) (models.PatientDocument, bool) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return models.PatientDocument{}, false
+	}
+	id, ok := documentID(c)
+	if !ok {
+		return models.PatientDocument{}, false
+	}
+
+	doc, err := h.Repo.GetDocumentForPatient(patientID.(int), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return models.PatientDocument{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch document", "err": err.Error()})
+		return models.PatientDocument{}, false
+	}
+	return doc, true
+}
+
+func (h *Handler) GetPatientDocument(c *gin.Context) {
+	doc, ok := h.patientDocument(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, doc)
+}
+
+func (h *Handler) DownloadPatientDocument(c *gin.Context) {
+	doc, ok := h.patientDocument(c)
+	if !ok {
+		return
+	}
+	h.sendDocumentFile(c, doc)
+}
+
+// DeletePatientDocument removes the document and its file, which also takes
+// it away from every doctor it was shared with.
+func (h *Handler) DeletePatientDocument(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+	id, ok := documentID(c)
+	if !ok {
+		return
+	}
+
+	fileName, err := h.Repo.DeleteDocument(patientID.(int), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete document", "err": err.Error()})
+		return
+	}
+
+	if err := h.Store.Delete(c.Request.Context(), fileName); err != nil {
+		// The record is gone, so the file can no longer be reached
+		log.Printf("Failed to delete document file %s: %v", fileName, err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Document deleted"})
+}
+
+// SharePatientDocument lets a doctor see one of the patient's documents.
+func (h *Handler) SharePatientDocument(c *gin.Context) {
+	doc, ok := h.patientDocument(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		DoctorID int `json:"doctor_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+
+	if _, err := h.Repo.GetDoctorByID(req.DoctorID); errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor", "err": err.Error()})
+		return
+	}
+	patient, err := h.Repo.GetPatientByID(doc.PatientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch patient", "err": err.Error()})
+		return
+	}
+
+	err = h.Repo.ShareDocument(doc.ID, req.DoctorID, models.Notification{
+		RecipientRole: "doctor",
+		RecipientID:   req.DoctorID,
+		Kind:          "document_shared",
+		Title:         patient.FirstName + " " + patient.LastName + " shared a document",
+		Body:          doc.Title,
+		Data:          map[string]any{"document_id": doc.ID, "patient_id": doc.PatientID},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share document", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Document shared"})
+}
+
+func (h *Handler) UnsharePatientDocument(c *gin.Context) {
+	doc, ok := h.patientDocument(c)
+	if !ok {
+		return
+	}
+	doctorID, err := strconv.Atoi(c.Param("doctorId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+
+	err = h.Repo.UnshareDocument(doc.ID, doctorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document isn't shared with this doctor"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unshare document", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Document unshared"})
+}
+
+// GetPatientHistoryDocuments lists the documents a patient has shared with
+// the doctor, filtered by documentFilter.
+func (h *Handler) GetPatientHistoryDocuments(c *gin.Context) {
+	doctorID := c.GetInt("userID")
+	patientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+	filter, ok := documentFilter(c)
+	if !ok {
+		return
+	}
+
+	docs, err := h.Repo.GetDocumentsSharedWithDoctor(doctorID, patientID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch documents", "err": err.Error()})
+		return
+	}
+	for i := range docs {
+		docs[i].SharedWith = nil
+	}
+	c.JSON(http.StatusOK, docs)
+}
+
+// DoctorDownloadDocument serves a document the patient shared with the
+// doctor, or any of theirs when they let the doctor see their documents.
+func (h *Handler) DoctorDownloadDocument(c *gin.Context) {
+	doctorID := c.GetInt("userID")
+	id, ok := documentID(c)
+	if !ok {
+		return
+	}
+
+	doc, err := h.Repo.GetDocumentForDoctor(doctorID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch document", "err": err.Error()})
+		return
+	}
+	setAuditPatient(c, doc.PatientID)
+	h.sendDocumentFile(c, doc)
+}
+
+// sendDocumentFile streams a document the caller may see, or returns a
+// presigned link to it with ?presigned=true.
+func (h *Handler) sendDocumentFile(c *gin.Context, doc models.PatientDocument) {
+	if !checkQuarantine(c, doc.ScanStatus) {
+		return
+	}
+
+	name := doc.OriginalName
+	if name == "" {
+		name = doc.FileName
+	}
+	if wantsPresignedURL(c) {
+		h.sendPresignedDownload(c, doc.FileName, name)
+		return
+	}
+
+	out, err := h.Store.Get(c.Request.Context(), doc.FileName)
+	if err != nil {
+		log.Printf("Failed to get object from storage: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file"})
+		return
+	}
+	defer out.Body.Close()
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	c.Header("Content-Type", out.ContentType)
+	c.Header("Content-Length", strconv.FormatInt(out.Size, 10))
+	io.Copy(c.Writer, out.Body)
+}
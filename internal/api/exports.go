@@ -0,0 +1,135 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/utils"
+)
+
+// Patient Portal Handlers
+
+// CreateDataExport queues an export of everything held about the patient.
+// It is built in the background, and the patient is notified when it is
+// ready.
+func (h *Handler) CreateDataExport(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	id, err := h.Repo.CreateDataExport(patientID.(int))
+	if errors.Is(err, repository.ErrExportInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": "An export is already being prepared"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request export", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"id": id, "status": "pending"})
+}
+
+func (h *Handler) GetDataExports(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	exports, err := h.Repo.GetDataExportsByPatientID(patientID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exports", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, exports)
+}
+
+// CreateDataExportLink issues a download token for a ready export that works
+// without logging in until ExportLinkTTL passes. Issuing another replaces it.
+// The token is POSTed to url, as a form field, so it never appears in a URL
+// or the access logs.
+func (h *Handler) CreateDataExportLink(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+	exportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate link"})
+		return
+	}
+	expiresAt := time.Now().Add(h.ExportLinkTTL)
+
+	err = h.Repo.SetDataExportLink(patientID.(int), exportID, utils.HashToken(token), expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found or not ready"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create link", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"url":        strings.TrimRight(h.PublicBaseURL, "/") + "/api/exports/download",
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+}
+
+// Public Handlers
+
+// DownloadDataExport streams the export a download token points to. The
+// token is the credential, so the download is audited as the patient's own.
+func (h *Handler) DownloadDataExport(c *gin.Context) {
+	var req struct {
+		Token string `form:"token" json:"token" binding:"required"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	setAuditAction(c, "read")
+
+	exp, err := h.Repo.GetDataExportByLink(utils.HashToken(req.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Download link is invalid or has expired"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch export", "err": err.Error()})
+		return
+	}
+	c.Set("role", "patient")
+	c.Set("userID", exp.PatientID)
+
+	out, err := h.Store.Get(c.Request.Context(), exp.FileName)
+	if err != nil {
+		log.Printf("Failed to get object from storage: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file"})
+		return
+	}
+	defer out.Body.Close()
+
+	c.Header("Content-Disposition", "attachment; filename=health-record-"+exp.CreatedAt.Format("2006-01-02")+".zip")
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Length", strconv.FormatInt(out.Size, 10))
+	io.Copy(c.Writer, out.Body)
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/filecheck"
+)
+
+// Malware scan statuses of prescription files. Pending and infected files are
+// quarantined: they are kept, but can't be downloaded.
+const (
+	scanNotScanned = "not_scanned"
+	scanPending    = "pending"
+	scanClean      = "clean"
+	scanInfected   = "infected"
+)
+
+// inspectFile identifies an uploaded prescription file from its content
+// rather than what the client claimed, rejects unsafe PDFs and, unless
+// scanning runs in the background, scans it for malware. It returns the
+// file's type and scan status, and rewinds body.
+func (h *Handler) inspectFile(c *gin.Context, body io.ReadSeeker, size int64) (filecheck.Type, string, bool) {
+	if size > h.MaxUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large", "max_size": h.MaxUploadSize})
+		return filecheck.Type{}, "", false
+	}
+
+	head := make([]byte, filecheck.SniffLen)
+	n, err := io.ReadFull(body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file", "err": err.Error()})
+		return filecheck.Type{}, "", false
+	}
+	fileType, err := filecheck.Sniff(head[:n])
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported file type, expected a PDF, PNG, JPEG or HEIC file"})
+		return filecheck.Type{}, "", false
+	}
+	if !rewind(c, body) {
+		return filecheck.Type{}, "", false
+	}
+
+	if fileType == filecheck.PDF {
+		if err := filecheck.CheckPDF(body); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "PDF was rejected", "err": err.Error()})
+			return filecheck.Type{}, "", false
+		}
+		if !rewind(c, body) {
+			return filecheck.Type{}, "", false
+		}
+	}
+
+	switch {
+	case h.Scanner == nil:
+		return fileType, scanNotScanned, true
+	case h.ScanAsync:
+		return fileType, scanPending, true
+	}
+
+	verdict, err := h.Scanner.Scan(c.Request.Context(), body)
+	if err != nil {
+		log.Printf("Malware scan failed: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Malware scanning is unavailable, try again later"})
+		return filecheck.Type{}, "", false
+	}
+	if verdict.Infected {
+		log.Printf("Rejected upload from user %d: malware scan found %s", c.GetInt("userID"), verdict.Threat)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "File failed the malware scan"})
+		return filecheck.Type{}, "", false
+	}
+	if !rewind(c, body) {
+		return filecheck.Type{}, "", false
+	}
+	return fileType, scanClean, true
+}
+
+func rewind(c *gin.Context, body io.Seeker) bool {
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file", "err": err.Error()})
+		return false
+	}
+	return true
+}
+
+// checkQuarantine writes an error and returns false if a file with the given
+// scan status is quarantined.
+func checkQuarantine(c *gin.Context, scanStatus string) bool {
+	switch scanStatus {
+	case scanPending:
+		c.JSON(http.StatusConflict, gin.H{"error": "File is still being scanned for malware, try again shortly"})
+		return false
+	case scanInfected:
+		c.JSON(http.StatusForbidden, gin.H{"error": "File was quarantined after failing a malware scan"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,315 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/formulary"
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+)
+
+// prescribedDrugs is what the formulary checks look at: the line items, or
+// the medication text for prescriptions without any.
+func prescribedDrugs(pres *models.Prescription) []string {
+	if len(pres.Items) == 0 {
+		return []string{pres.Medication}
+	}
+	names := make([]string, len(pres.Items))
+	for i, it := range pres.Items {
+		names[i] = it.DrugName
+	}
+	return names
+}
+
+// prescriptionWarnings runs the formulary checks for pres against the
+// patient's other active prescriptions, leaving out exclude.
+func (h *Handler) prescriptionWarnings(pres *models.Prescription, exclude []int) ([]models.PrescriptionWarning, error) {
+	if h.Formulary == nil {
+		return nil, nil
+	}
+
+	if pres.PreviousID != nil {
+		exclude = append(exclude, *pres.PreviousID)
+	}
+	active, err := h.Repo.GetActiveMedications(pres.PatientID, exclude)
+	if err != nil {
+		return nil, err
+	}
+	allergies, err := h.Repo.GetAllergiesByPatientID(pres.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	return h.Formulary.Check(prescribedDrugs(pres), active, allergies), nil
+}
+
+// checkPrescriptionSafety runs the formulary checks before pres is stored.
+// Warnings above minor stop the request with a 409 listing them, unless the
+// doctor gave an override reason, in which case the override is recorded on
+// pres. All warnings are left on pres.Warnings for the response.
+func (h *Handler) checkPrescriptionSafety(c *gin.Context, pres *models.Prescription, overrideReason string, exclude []int) bool {
+	warnings, err := h.prescriptionWarnings(pres, exclude)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check prescription", "err": err.Error()})
+		return false
+	}
+	pres.Warnings = warnings
+
+	blocking := formulary.Blocking(warnings)
+	if len(blocking) == 0 {
+		return true
+	}
+
+	if strings.TrimSpace(overrideReason) == "" {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":             "Prescription has warnings that need to be overridden",
+			"requires_override": true,
+			"warnings":          warnings,
+		})
+		return false
+	}
+	reason, ok := validateReason(overrideReason)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Override reason is too long"})
+		return false
+	}
+
+	pres.SafetyOverride = &models.SafetyOverride{Reason: reason, Warnings: blocking}
+	return true
+}
+
+// CheckPrescription runs the formulary checks for a draft without creating
+// anything, so the UI can show warnings as the doctor types.
+func (h *Handler) CheckPrescription(c *gin.Context) {
+	patientID, ok := h.authorizeTreatingDoctor(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Items      []models.PrescriptionItem `json:"items"`
+		Medication string                    `json:"medication"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	if len(req.Items) == 0 && strings.TrimSpace(req.Medication) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either medication or items is required"})
+		return
+	}
+
+	pres := models.Prescription{PatientID: patientID, Items: req.Items, Medication: req.Medication}
+	warnings, err := h.prescriptionWarnings(&pres, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check prescription", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"warnings":          warnings,
+		"requires_override": len(formulary.Blocking(warnings)) > 0,
+	})
+}
+
+// SearchFormulary finds drugs by name or alias prefix for autocomplete.
+func (h *Handler) SearchFormulary(c *gin.Context) {
+	if h.Formulary == nil {
+		c.JSON(http.StatusOK, []formulary.Drug{})
+		return
+	}
+	c.JSON(http.StatusOK, h.Formulary.Search(c.Query("q"), 20))
+}
+
+func (h *Handler) GetPatientAllergies(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+	h.listAllergies(c, patientID.(int))
+}
+
+func (h *Handler) CreatePatientAllergy(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+	h.createAllergy(c, patientID.(int), nil)
+}
+
+// UpdatePatientAllergy changes an allergy the patient reported; ones a doctor
+// recorded are left to doctors.
+func (h *Handler) UpdatePatientAllergy(c *gin.Context) {
+	h.updateAllergy(c, c.GetInt("userID"), nil, "id")
+}
+
+func (h *Handler) DeletePatientAllergy(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	allergyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid allergy ID"})
+		return
+	}
+
+	err = h.Repo.DeleteAllergy(patientID.(int), allergyID, true)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Allergy not found or recorded by a doctor"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete allergy", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Allergy removed"})
+}
+
+func (h *Handler) GetPatientHistoryAllergies(c *gin.Context) {
+	patientID, ok := h.authorizeDoctorForPatient(c, repository.ConsentAllergies)
+	if !ok {
+		return
+	}
+	h.listAllergies(c, patientID)
+}
+
+func (h *Handler) CreatePatientHistoryAllergy(c *gin.Context) {
+	patientID, ok := h.authorizeChartEditor(c, repository.ConsentAllergies)
+	if !ok {
+		return
+	}
+	doctorID := c.GetInt("userID")
+	h.createAllergy(c, patientID, &doctorID)
+}
+
+func (h *Handler) UpdatePatientHistoryAllergy(c *gin.Context) {
+	patientID, ok := h.authorizeChartEditor(c, repository.ConsentAllergies)
+	if !ok {
+		return
+	}
+	doctorID := c.GetInt("userID")
+	h.updateAllergy(c, patientID, &doctorID, "allergyId")
+}
+
+func (h *Handler) DeletePatientHistoryAllergy(c *gin.Context) {
+	patientID, ok := h.authorizeChartEditor(c, repository.ConsentAllergies)
+	if !ok {
+		return
+	}
+	allergyID, err := strconv.Atoi(c.Param("allergyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid allergy ID"})
+		return
+	}
+
+	err = h.Repo.DeleteAllergy(patientID, allergyID, false)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Allergy not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete allergy", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Allergy removed"})
+}
+
+func (h *Handler) listAllergies(c *gin.Context, patientID int) {
+	allergies, err := h.Repo.GetAllergiesByPatientID(patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allergies", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, allergies)
+}
+
+var allergySeverities = map[string]bool{"": true, "mild": true, "moderate": true, "severe": true}
+
+// bindAllergy reads and validates an allergy from the request body. On
+// failure it writes the response and returns false.
+func bindAllergy(c *gin.Context, patientID int, doctorID *int) (models.PatientAllergy, bool) {
+	var req struct {
+		Substance string `json:"substance" binding:"required"`
+		Reaction  string `json:"reaction"`
+		Severity  string `json:"severity"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return models.PatientAllergy{}, false
+	}
+
+	substance := strings.TrimSpace(req.Substance)
+	if substance == "" || utf8.RuneCountInString(substance) > 255 || utf8.RuneCountInString(req.Reaction) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Substance is required and must be at most 255 characters"})
+		return models.PatientAllergy{}, false
+	}
+	severity := strings.ToLower(strings.TrimSpace(req.Severity))
+	if !allergySeverities[severity] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Severity must be mild, moderate or severe"})
+		return models.PatientAllergy{}, false
+	}
+
+	return models.PatientAllergy{
+		PatientID:  patientID,
+		Substance:  substance,
+		Reaction:   strings.TrimSpace(req.Reaction),
+		Severity:   severity,
+		Source:     chartSource(doctorID),
+		RecordedBy: doctorID,
+	}, true
+}
+
+// createAllergy records an allergy for the patient, reported by them or, with
+// doctorID, by that doctor.
+func (h *Handler) createAllergy(c *gin.Context, patientID int, doctorID *int) {
+	allergy, ok := bindAllergy(c, patientID, doctorID)
+	if !ok {
+		return
+	}
+
+	newID, err := h.Repo.CreateAllergy(allergy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record allergy", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": newID})
+}
+
+// updateAllergy replaces the allergy in the idParam param. Patients can only
+// change the ones they reported; a doctor's change makes it theirs.
+func (h *Handler) updateAllergy(c *gin.Context, patientID int, doctorID *int, idParam string) {
+	allergyID, err := strconv.Atoi(c.Param(idParam))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid allergy ID"})
+		return
+	}
+	allergy, ok := bindAllergy(c, patientID, doctorID)
+	if !ok {
+		return
+	}
+	allergy.ID = allergyID
+
+	err = h.Repo.UpdateAllergy(allergy, doctorID == nil)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFoundOnChart("Allergy", doctorID)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update allergy", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Allergy updated"})
+}
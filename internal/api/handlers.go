@@ -1,35 +1,62 @@
 package api
 
 import (
-	"context"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
 
+	"github.com/RitwikGupta-0501/vital-watch/internal/filecheck"
+	"github.com/RitwikGupta-0501/vital-watch/internal/formulary"
+	"github.com/RitwikGupta-0501/vital-watch/internal/labs"
 	"github.com/RitwikGupta-0501/vital-watch/internal/models"
 	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/internal/rxpdf"
+	"github.com/RitwikGupta-0501/vital-watch/internal/signing"
+	"github.com/RitwikGupta-0501/vital-watch/internal/storage"
 	"github.com/RitwikGupta-0501/vital-watch/utils"
 )
 
 var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
 
 type Handler struct {
-	Repo       *repository.Repository
-	S3Client   *s3.Client
-	BucketName string
+	Repo  *repository.Repository
+	Store storage.Store
+	// Clinic is printed on generated prescriptions, and PublicBaseURL is
+	// where their verification links point.
+	Clinic        rxpdf.Clinic
+	PublicBaseURL string
+	// Signer signs every prescription at creation; PrescriptionValidity is
+	// how long one stays valid, or zero for no expiry.
+	Signer               *signing.Signer
+	PrescriptionValidity time.Duration
+	// Formulary backs the interaction and allergy checks on new prescriptions
+	Formulary *formulary.Formulary
+	// Labs is the catalogue of lab test panels doctors can order
+	Labs *labs.Catalog
+	// PresignTTL is how long presigned download and upload URLs stay valid,
+	// and MaxUploadSize caps prescription files in bytes.
+	PresignTTL    time.Duration
+	MaxUploadSize int64
+	// Scanner, if set, scans prescription files before they are stored. With
+	// ScanAsync they are stored quarantined and scanned in the background.
+	Scanner   filecheck.Scanner
+	ScanAsync bool
+	// BreakGlassTTL is how long emergency access to a patient's record lasts
+	BreakGlassTTL time.Duration
+	// ExportLinkTTL is how long a data export download link works
+	ExportLinkTTL time.Duration
+	// Retention is how long closed accounts' data is kept
+	Retention repository.RetentionPolicy
+	// RequireSignedNote stops appointments being completed before their
+	// encounter note is signed
+	RequireSignedNote bool
 }
 
 func (h *Handler) Ping(c *gin.Context) {
@@ -85,6 +112,21 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// RequireRole rejects requests whose token role is not one of roles. It must
+// run after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You are not allowed to access this resource"})
+	}
+}
+
 // Generic Handlers
 func (h *Handler) Login(c *gin.Context) {
 	var req struct {
@@ -108,6 +150,8 @@ func (h *Handler) Login(c *gin.Context) {
 		user, err = h.Repo.GetPatientByEmail(req.Email)
 	case "doctor":
 		user, err = h.Repo.GetDoctorByEmail(req.Email)
+	case "admin":
+		user, err = h.Repo.GetAdminByEmail(req.Email)
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
 		return
@@ -218,6 +262,14 @@ func (h *Handler) GetUserProfile(c *gin.Context) {
 		}
 		c.JSON(http.StatusOK, doctor)
 
+	case "admin":
+		admin, err := h.Repo.GetAdminByID(userID.(int))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Admin profile not found"})
+			return
+		}
+		c.JSON(http.StatusOK, admin)
+
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user role"})
 	}
@@ -255,7 +307,7 @@ func (h *Handler) GetPatientPrescriptions(c *gin.Context) {
 		return
 	}
 
-	prescriptions, err := h.Repo.GetPrescriptionsByPatientID(patientID.(int))
+	prescriptions, err := h.Repo.GetPrescriptionsByPatientID(patientID.(int), c.Query("q"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescriptions"})
 		return
@@ -301,29 +353,37 @@ func (h *Handler) DownloadPrescription(c *gin.Context) {
 	}
 
 	// SECURITY CHECK: Verify this patient owns this file
-	_, err := h.Repo.GetPrescriptionByFilename(patientID.(int), filename)
+	pres, err := h.Repo.GetPrescriptionByFilename(patientID.(int), filename)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "You are not authorized to download this file"})
 		return
 	}
 
-	// Get the file from S3
-	getObjectInput := &s3.GetObjectInput{
-		Bucket: aws.String(h.BucketName),
-		Key:    aws.String(filename),
+	if !checkQuarantine(c, pres.ScanStatus) {
+		return
 	}
-	out, err := h.S3Client.GetObject(context.TODO(), getObjectInput)
+	if wantsPresignedURL(c) {
+		setVerificationHeaders(c, pres)
+		setVersionHeaders(c, pres)
+		h.sendPresignedDownload(c, filename, downloadFilename(pres, filename))
+		return
+	}
+
+	// Get the file from storage
+	out, err := h.Store.Get(c.Request.Context(), filename)
 	if err != nil {
-		log.Printf("Failed to get object from S3: %v", err)
+		log.Printf("Failed to get object from storage: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file"})
 		return
 	}
 	defer out.Body.Close()
 
 	// Set headers to tell the browser to download it
-	c.Header("Content-Disposition", "attachment; filename="+filename)
-	c.Header("Content-Type", *out.ContentType)
-	c.Header("Content-Length", strconv.FormatInt(*out.ContentLength, 10))
+	c.Header("Content-Disposition", "attachment; filename="+downloadFilename(pres, filename))
+	c.Header("Content-Type", out.ContentType)
+	c.Header("Content-Length", strconv.FormatInt(out.Size, 10))
+	setVerificationHeaders(c, pres)
+	setVersionHeaders(c, pres)
 
 	// Stream the file
 	io.Copy(c.Writer, out.Body)
@@ -355,7 +415,8 @@ func (h *Handler) GetDoctorPatients(c *gin.Context) {
 		return
 	}
 
-	patients, err := h.Repo.GetPatientsByDoctorID(doctorID.(int))
+	// ?sort=risk puts the patients with the highest NEWS2 risk first
+	patients, err := h.Repo.GetPatientsByDoctorID(doctorID.(int), c.Query("sort") == "risk")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch patients"})
 		return
@@ -400,7 +461,7 @@ func (h *Handler) GetPatientHistoryPrescriptions(c *gin.Context) {
 		return
 	}
 
-	prescriptions, err := h.Repo.GetPrescriptionsForPatient(doctorID.(int), patientID)
+	prescriptions, err := h.Repo.GetPrescriptionsForPatient(doctorID.(int), patientID, c.Query("q"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescriptions"})
 		return
@@ -422,76 +483,56 @@ func (h *Handler) CreatePrescription(c *gin.Context) {
 	}
 
 	patientIDStr := c.Request.FormValue("patientID")
-	medication := c.Request.FormValue("medication")
-	notes := c.Request.FormValue("notes")
 
 	patientID, err := strconv.Atoi(patientIDStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patientID", "err": err.Error()})
 		return
 	}
+	setAuditPatient(c, patientID)
 
-	file, header, err := c.Request.FormFile("file")
+	hasPatient, err := h.Repo.DoctorHasPatient(doctorID.(int), patientID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required", "err": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient", "err": err.Error()})
 		return
 	}
-	defer file.Close()
-
-	// Generate a unique filename
-	ext := filepath.Ext(header.Filename) // Get .pdf or .jpg
-	uniqueFilename := fmt.Sprintf("prescription-%s-%s%s", patientIDStr, uuid.New().String(), ext)
-
-	// Upload to S3
-	putObjectInput := &s3.PutObjectInput{
-		Bucket:        aws.String(h.BucketName),
-		Key:           aws.String(uniqueFilename),
-		Body:          file,
-		ContentLength: aws.Int64(header.Size),
-		ContentType:   aws.String(header.Header.Get("Content-Type")),
-	}
-
-	_, err = h.S3Client.PutObject(context.TODO(), putObjectInput)
-	if err != nil {
-		log.Printf("Failed to upload file to S3: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file", "err": err.Error()})
+	if !hasPatient {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to prescribe for this patient"})
 		return
 	}
 
-	// Save metadata to database
-	newID, err := h.Repo.CreatePrescription(patientID, doctorID.(int), medication, notes, uniqueFilename)
-	if err != nil {
-		log.Printf("Failed to create prescription in DB: %v", err)
-		// If DB save fails, roll back S3 upload
-		go func() {
-			log.Printf("Rolling back S3 upload for key: %s", uniqueFilename)
-			deleteObjectInput := &s3.DeleteObjectInput{
-				Bucket: aws.String(h.BucketName),
-				Key:    aws.String(uniqueFilename),
-			}
-			_, delErr := h.S3Client.DeleteObject(context.TODO(), deleteObjectInput)
-			if delErr != nil {
-				log.Printf("CRITICAL: Failed to rollback S3 upload: %v", delErr)
-			}
-		}()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create prescription record", "err": err.Error()})
+	pres := models.Prescription{
+		PatientID: patientID,
+		DoctorID:  doctorID.(int),
+	}
+	newID, ok := h.uploadPrescription(c, &pres)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"id": newID, "filename": uniqueFilename})
+	c.JSON(http.StatusCreated, gin.H{"id": newID, "filename": pres.FileName, "verification_code": pres.VerificationCode, "warnings": pres.Warnings})
 }
 
 func (h *Handler) MarkAppointmentAsCompleted(c *gin.Context) {
-	appointmentIDStr := c.Param("id")
-	appointmentID, err := strconv.Atoi(appointmentIDStr)
-
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+	appt, ok := h.doctorAppointment(c)
+	if !ok {
 		return
 	}
+	appointmentID := appt.ID
 
-	err = h.Repo.UpdateAppointmentAsCompleted(appointmentID)
-	if err != nil {
+	if h.RequireSignedNote {
+		signed, err := h.Repo.AppointmentHasSignedNote(appointmentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the encounter note", "err": err.Error()})
+			return
+		}
+		if !signed {
+			c.JSON(http.StatusConflict, gin.H{"error": "Sign the encounter note before completing the appointment"})
+			return
+		}
+	}
+
+	if err := h.Repo.UpdateAppointmentAsCompleted(appointmentID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark appointment as completed", "err": err.Error()})
 		return
 	}
@@ -509,29 +550,38 @@ func (h *Handler) DoctorDownloadPrescription(c *gin.Context) {
 	}
 
 	// SECURITY CHECK: Verify this doctor is associated with this file
-	_, err := h.Repo.GetPrescriptionByFilenameForDoctor(doctorID.(int), filename)
+	pres, err := h.Repo.GetPrescriptionByFilenameForDoctor(doctorID.(int), filename)
 	if err != nil {
 		log.Printf("Doctor download auth failed: %v", err)
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "You are not authorized to download this file"})
 		return
 	}
+	setAuditPatient(c, pres.PatientID)
 
-	// Get the file from S3
-	getObjectInput := &s3.GetObjectInput{
-		Bucket: aws.String(h.BucketName),
-		Key:    aws.String(filename),
+	if !checkQuarantine(c, pres.ScanStatus) {
+		return
 	}
-	out, err := h.S3Client.GetObject(context.TODO(), getObjectInput)
+	if wantsPresignedURL(c) {
+		setVerificationHeaders(c, pres)
+		setVersionHeaders(c, pres)
+		h.sendPresignedDownload(c, filename, downloadFilename(pres, filename))
+		return
+	}
+
+	// Get the file from storage
+	out, err := h.Store.Get(c.Request.Context(), filename)
 	if err != nil {
-		log.Printf("Failed to get object from S3: %v", err)
+		log.Printf("Failed to get object from storage: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file"})
 		return
 	}
 	defer out.Body.Close()
 
-	c.Header("Content-Disposition", "attachment; filename="+filename)
-	c.Header("Content-Type", *out.ContentType)
-	c.Header("Content-Length", strconv.FormatInt(*out.ContentLength, 10))
+	c.Header("Content-Disposition", "attachment; filename="+downloadFilename(pres, filename))
+	c.Header("Content-Type", out.ContentType)
+	c.Header("Content-Length", strconv.FormatInt(out.Size, 10))
+	setVerificationHeaders(c, pres)
+	setVersionHeaders(c, pres)
 
 	io.Copy(c.Writer, out.Body)
 }
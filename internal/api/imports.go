@@ -0,0 +1,230 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/importer"
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/vitals"
+)
+
+const (
+	maxImportSize       = 1 << 30 // 1 GB, Apple Health exports get big
+	importBatchSize     = 500
+	maxImportErrorsKept = 100
+)
+
+func (h *Handler) CreateVitalImport(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil { // Anything over 32 MB spills to disk
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form", "err": err.Error()})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required", "err": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	format, err := importer.DetectFormat(c.Request.FormValue("format"), header.Filename)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported import format", "err": err.Error()})
+		return
+	}
+
+	// The multipart temp files are removed when the request ends, so keep our
+	// own copy for the background job
+	tmp, err := os.CreateTemp("", "vital-import-*")
+	if err != nil {
+		log.Printf("Failed to create temp file for import: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		return
+	}
+	size, err := io.Copy(tmp, file)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("Failed to copy import upload: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		return
+	}
+
+	jobID, err := h.Repo.CreateVitalImportJob(patientID.(int), string(format), header.Filename, size)
+	if err != nil {
+		os.Remove(tmp.Name())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create import job", "err": err.Error()})
+		return
+	}
+
+	job := models.VitalImportJob{
+		ID:         jobID,
+		PatientID:  patientID.(int),
+		Format:     string(format),
+		BytesTotal: size,
+	}
+	go h.runVitalImport(job, tmp.Name())
+
+	c.JSON(http.StatusAccepted, gin.H{"id": jobID, "status": "pending"})
+}
+
+func (h *Handler) GetVitalImports(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	jobs, err := h.Repo.GetVitalImportJobsByPatientID(patientID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch import jobs"})
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+func (h *Handler) GetVitalImport(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	jobID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+
+	job, err := h.Repo.GetVitalImportJob(patientID.(int), jobID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch import job"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// runVitalImport parses the uploaded file in the background, writing readings
+// in batches and recording progress on the job as it goes.
+func (h *Handler) runVitalImport(job models.VitalImportJob, path string) {
+	defer os.Remove(path)
+
+	job.Status = "completed"
+	job.Errors = []models.ImportError{}
+	defer func() {
+		if err := h.Repo.FinishVitalImportJob(job); err != nil {
+			log.Printf("Failed to record result of import job %d: %v", job.ID, err)
+		}
+	}()
+
+	f, err := os.Open(path)
+	if err != nil {
+		job.Status, job.ErrorMessage = "failed", "Failed to open uploaded file"
+		return
+	}
+	defer f.Close()
+
+	sink := &importSink{h: h, job: &job, file: f}
+	err = importer.Parse(importer.Format(job.Format), f, sink)
+	if err == nil {
+		err = sink.flush()
+	}
+	if err != nil {
+		log.Printf("Import job %d failed: %v", job.ID, err)
+		job.Status, job.ErrorMessage = "failed", err.Error()
+		return
+	}
+	job.BytesProcessed = job.BytesTotal
+}
+
+// importSink normalises parsed records and writes them in batches.
+type importSink struct {
+	h     *Handler
+	job   *models.VitalImportJob
+	file  *os.File
+	batch []models.VitalReading
+	seen  map[string]bool
+}
+
+func (s *importSink) Record(rec importer.Record) error {
+	value, err := vitals.Normalize(rec.Metric, rec.Value, rec.Unit)
+	if err != nil {
+		s.Error(rec.Metric+" at "+rec.RecordedAt.Format(time.RFC3339), err)
+		return nil
+	}
+
+	// Duplicates inside one batch are invisible to the NOT EXISTS check
+	key := rec.Key()
+	if s.seen == nil {
+		s.seen = make(map[string]bool, importBatchSize)
+	}
+	if s.seen[key] {
+		s.job.Duplicates++
+		return nil
+	}
+	s.seen[key] = true
+
+	s.batch = append(s.batch, models.VitalReading{
+		PatientID:  s.job.PatientID,
+		Metric:     rec.Metric,
+		Value:      value,
+		Unit:       vitals.Metrics[rec.Metric].Unit,
+		RecordedAt: rec.RecordedAt,
+		Source:     "import",
+	})
+	if len(s.batch) >= importBatchSize {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *importSink) Error(location string, err error) {
+	s.job.Failed++
+	if len(s.job.Errors) < maxImportErrorsKept {
+		s.job.Errors = append(s.job.Errors, models.ImportError{Location: location, Message: err.Error()})
+	}
+}
+
+func (s *importSink) flush() error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+
+	inserted, err := s.h.Repo.ImportVitalReadings(s.batch)
+	if err != nil {
+		return err
+	}
+	s.job.Imported += inserted
+	s.job.Duplicates += len(s.batch) - inserted
+	s.batch = s.batch[:0]
+	clear(s.seen)
+
+	// The decoders read ahead, so the file offset is a close enough measure of progress
+	if pos, err := s.file.Seek(0, io.SeekCurrent); err == nil {
+		s.job.BytesProcessed = pos
+	}
+	if err := s.h.Repo.UpdateVitalImportProgress(*s.job); err != nil {
+		log.Printf("Failed to update progress of import job %d: %v", s.job.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/labs"
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/utils"
+)
+
+// maxHL7MessageSize caps the size of an HL7 message posted to the
+// integration API.
+const maxHL7MessageSize = 1 << 20
+
+// IntegrationAuthMiddleware authenticates an external system, such as a lab,
+// by an API key an admin issued it, sent as "Authorization: ApiKey <key>".
+// The key is the actor of the requests in the audit log, and can only reach
+// the lab orders of its clinic.
+func (h *Handler) IntegrationAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "ApiKey" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key format"})
+			return
+		}
+
+		key, err := h.Repo.GetActiveIntegrationKeyByHash(utils.HashToken(parts[1]))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			return
+		}
+		if err := h.Repo.TouchIntegrationKey(key.ID); err != nil {
+			log.Printf("Failed to update last use of integration key %d: %v", key.ID, err)
+		}
+
+		c.Set("role", "integration")
+		c.Set("userID", key.ID)
+		c.Set("clinicID", key.ClinicID)
+		c.Next()
+	}
+}
+
+// Integration Handlers
+
+// sendHL7Ack writes the acknowledgement of msg.
+func sendHL7Ack(c *gin.Context, status int, msg labs.Message, code, text string) {
+	c.Data(status, "application/hl7-v2; charset=utf-8", []byte(labs.Ack(msg, code, text, time.Now())))
+}
+
+// integrationOrder loads a lab order the calling integration key may reach:
+// one placed from the key's clinic. Orders of other clinics are reported as
+// sql.ErrNoRows, as if they didn't exist.
+func (h *Handler) integrationOrder(c *gin.Context, orderID int) (models.LabOrder, error) {
+	order, err := h.Repo.GetLabOrderByID(orderID)
+	if err != nil {
+		return models.LabOrder{}, err
+	}
+	if order.ClinicID == nil || *order.ClinicID != c.GetInt("clinicID") {
+		return models.LabOrder{}, sql.ErrNoRows
+	}
+	return order, nil
+}
+
+// ReceiveHL7Results records lab results sent as an HL7 v2 ORU^R01 message
+// and answers with an HL7 ACK. Each OBR's placer order number must be the ID
+// of a lab order of the key's clinic, and the PID's patient identifiers must
+// include the order's patient ID, so results can't land in the wrong record.
+// The message is accepted or rejected as a whole, and recorded in one
+// transaction.
+func (h *Handler) ReceiveHL7Results(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxHL7MessageSize))
+	if err != nil {
+		sendHL7Ack(c, http.StatusRequestEntityTooLarge, labs.Message{}, labs.AckReject, "Message is too large")
+		return
+	}
+
+	msg, err := labs.ParseORU(string(body))
+	if err != nil {
+		sendHL7Ack(c, http.StatusBadRequest, msg, labs.AckReject, err.Error())
+		return
+	}
+
+	// Check every order and result before recording any
+	orders := make([]models.LabOrder, len(msg.Orders))
+	for i, in := range msg.Orders {
+		orderID, err := strconv.Atoi(in.PlacerOrderNumber)
+		if err != nil {
+			sendHL7Ack(c, http.StatusUnprocessableEntity, msg, labs.AckError, "Unknown placer order number "+in.PlacerOrderNumber)
+			return
+		}
+		order, err := h.integrationOrder(c, orderID)
+		if errors.Is(err, sql.ErrNoRows) {
+			sendHL7Ack(c, http.StatusUnprocessableEntity, msg, labs.AckError, "Unknown placer order number "+in.PlacerOrderNumber)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to fetch lab order %d: %v", orderID, err)
+			sendHL7Ack(c, http.StatusInternalServerError, msg, labs.AckError, "Failed to fetch lab order")
+			return
+		}
+		orders[i] = order
+		setAuditPatient(c, order.PatientID)
+
+		if !matchesPatient(in.PatientIDs, order.PatientID) {
+			sendHL7Ack(c, http.StatusUnprocessableEntity, msg, labs.AckError,
+				fmt.Sprintf("Order %d is for a different patient", order.ID))
+			return
+		}
+		if order.Status == "cancelled" {
+			sendHL7Ack(c, http.StatusUnprocessableEntity, msg, labs.AckError, fmt.Sprintf("Order %d has been cancelled", order.ID))
+			return
+		}
+		for j := range in.Results {
+			r := &msg.Orders[i].Results[j]
+			h.completeLabResult(r)
+			if err := checkLabResult(*r); err != nil {
+				sendHL7Ack(c, http.StatusUnprocessableEntity, msg, labs.AckError, err.Error())
+				return
+			}
+		}
+	}
+
+	var batches []repository.LabOrderResults
+	for i, in := range msg.Orders {
+		if len(in.Results) == 0 {
+			continue
+		}
+		b, err := h.labOrderResults(orders[i], in.Results, true)
+		if err != nil {
+			log.Printf("Failed to prepare results of lab order %d: %v", orders[i].ID, err)
+			sendHL7Ack(c, http.StatusInternalServerError, msg, labs.AckError, "Failed to record results")
+			return
+		}
+		batches = append(batches, b)
+	}
+
+	_, err = h.Repo.RecordLabResultsForOrders(batches)
+	if errors.Is(err, repository.ErrLabOrderCancelled) {
+		sendHL7Ack(c, http.StatusUnprocessableEntity, msg, labs.AckError, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to record lab results of message %s: %v", msg.ControlID, err)
+		sendHL7Ack(c, http.StatusInternalServerError, msg, labs.AckError, "Failed to record results")
+		return
+	}
+
+	sendHL7Ack(c, http.StatusOK, msg, labs.AckAccept, "")
+}
+
+// matchesPatient reports whether ids, the patient identifiers a lab sent,
+// include patientID. A message without identifiers matches no one.
+func matchesPatient(ids []string, patientID int) bool {
+	for _, id := range ids {
+		if id == strconv.Itoa(patientID) {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadIntegrationLabReport attaches a lab's PDF report, sent as the file
+// field of a multipart form, to the :orderId order of the key's clinic.
+func (h *Handler) UploadIntegrationLabReport(c *gin.Context) {
+	orderID, ok := labOrderParam(c)
+	if !ok {
+		return
+	}
+	order, err := h.integrationOrder(c, orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lab order not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lab order", "err": err.Error()})
+		return
+	}
+	setAuditPatient(c, order.PatientID)
+	if order.Status == "cancelled" {
+		c.JSON(http.StatusConflict, gin.H{"error": "The lab order has been cancelled"})
+		return
+	}
+
+	rep, ok := h.storeLabReport(c, order)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": rep.ID, "scan_status": rep.ScanStatus})
+}
+
+// Admin Portal Handlers
+
+// CreateIntegrationKey issues an API key for an external system serving one
+// clinic. The key is only ever returned here; we keep just its hash.
+func (h *Handler) CreateIntegrationKey(c *gin.Context) {
+	admin, ok := h.globalAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name     string `json:"name" binding:"required"`
+		ClinicID int    `json:"clinic_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required and must be at most 100 characters"})
+		return
+	}
+	if !h.checkClinic(c, &req.ClinicID) {
+		return
+	}
+
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+	key, err := h.Repo.CreateIntegrationKey(name, req.ClinicID, utils.HashToken(secret), admin.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"integration_key": key, "key": secret})
+}
+
+func (h *Handler) GetIntegrationKeys(c *gin.Context) {
+	if _, ok := h.globalAdmin(c); !ok {
+		return
+	}
+	keys, err := h.Repo.GetIntegrationKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *Handler) RevokeIntegrationKey(c *gin.Context) {
+	if _, ok := h.globalAdmin(c); !ok {
+		return
+	}
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	err = h.Repo.RevokeIntegrationKey(keyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found or already revoked"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
@@ -0,0 +1,592 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/filecheck"
+	"github.com/RitwikGupta-0501/vital-watch/internal/labs"
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+)
+
+const (
+	maxLabOrderPanels       = 20
+	maxLabResultsPerRequest = 200
+	maxLabNotesLength       = 1000
+)
+
+// labResultStatuses are the statuses a result can be recorded with.
+var labResultStatuses = map[string]bool{"preliminary": true, "final": true, "corrected": true}
+
+// checkLabResult checks a result fits what is stored, however it came in.
+func checkLabResult(r models.LabResult) error {
+	switch {
+	case r.AnalyteCode == "" || utf8.RuneCountInString(r.AnalyteCode) > 20:
+		return errors.New("analyte codes are required and must be at most 20 characters")
+	case utf8.RuneCountInString(r.AnalyteName) > 255:
+		return fmt.Errorf("the name of analyte %s must be at most 255 characters", r.AnalyteCode)
+	case r.Value == nil && strings.TrimSpace(r.ValueText) == "":
+		return fmt.Errorf("analyte %s has no value", r.AnalyteCode)
+	case utf8.RuneCountInString(r.Unit) > 30 || utf8.RuneCountInString(r.RefText) > 100 || utf8.RuneCountInString(r.Flag) > 5:
+		return fmt.Errorf("the unit, reference range or flag of analyte %s is too long", r.AnalyteCode)
+	case !labResultStatuses[r.Status]:
+		return fmt.Errorf("analyte %s has an unknown result status %q", r.AnalyteCode, r.Status)
+	}
+	return nil
+}
+
+// completeLabResult fills in what the result leaves out from the lab
+// catalogue and flags it against its reference range.
+func (h *Handler) completeLabResult(r *models.LabResult) {
+	h.Labs.Complete(r)
+	if r.AnalyteName == "" {
+		r.AnalyteName = r.AnalyteCode
+	}
+	if r.ObservedAt.IsZero() {
+		r.ObservedAt = time.Now()
+	}
+}
+
+// labResultsNotifications tells the ordering doctor, unless they entered the
+// results themselves, what came in and which results are abnormal, and the
+// patient once all their results are in.
+func labResultsNotifications(order models.LabOrder, patientName string, results []models.LabResult, notifyDoctor bool) func(repository.LabResultsRecorded) []models.Notification {
+	return func(out repository.LabResultsRecorded) []models.Notification {
+		var notifications []models.Notification
+		data := map[string]any{"lab_order_id": order.ID, "patient_id": order.PatientID}
+
+		if notifyDoctor && out.Recorded > 0 {
+			title, critical := "Lab results for "+patientName, false
+			var abnormal []string
+			for _, r := range results {
+				if !labs.IsAbnormal(r.Flag) {
+					continue
+				}
+				value := r.ValueText
+				if r.Value != nil {
+					value = strconv.FormatFloat(*r.Value, 'f', -1, 64)
+				}
+				abnormal = append(abnormal, fmt.Sprintf("%s %s %s (%s)", r.AnalyteName, value, r.Unit, r.Flag))
+				critical = critical || r.Flag == labs.FlagCriticalLow || r.Flag == labs.FlagCriticalHigh
+			}
+			if critical {
+				title = "Critical lab results for " + patientName
+			}
+			body := "No abnormal results."
+			if abnormal != nil {
+				body = "Abnormal results:\n" + strings.Join(abnormal, "\n")
+			}
+			if out.Status != "resulted" {
+				body += "\nSome results are still to come."
+			}
+			notifications = append(notifications, models.Notification{
+				RecipientRole: "doctor",
+				RecipientID:   order.DoctorID,
+				Kind:          "lab_results",
+				Title:         title,
+				Body:          body,
+				Data:          data,
+			})
+		}
+
+		if out.Status == "resulted" && out.PreviousStatus != "resulted" {
+			names := make([]string, len(order.Panels))
+			for i, p := range order.Panels {
+				names[i] = p.Name
+			}
+			notifications = append(notifications, models.Notification{
+				RecipientRole: "patient",
+				RecipientID:   order.PatientID,
+				Kind:          "lab_results_ready",
+				Title:         "Your lab results are ready",
+				Body:          strings.Join(names, "\n"),
+				Data:          map[string]any{"lab_order_id": order.ID},
+			})
+		}
+		return notifications
+	}
+}
+
+// labOrderResults makes the batch that records results against the order,
+// sending the notifications of labResultsNotifications.
+func (h *Handler) labOrderResults(order models.LabOrder, results []models.LabResult, notifyDoctor bool) (repository.LabOrderResults, error) {
+	patient, err := h.Repo.GetPatientByID(order.PatientID)
+	if err != nil {
+		return repository.LabOrderResults{}, err
+	}
+	return repository.LabOrderResults{
+		OrderID: order.ID,
+		Results: results,
+		Notify:  labResultsNotifications(order, patient.FirstName+" "+patient.LastName, results, notifyDoctor),
+	}, nil
+}
+
+// recordLabResults stores results against the order as labOrderResults
+// describes.
+func (h *Handler) recordLabResults(order models.LabOrder, results []models.LabResult, notifyDoctor bool) (repository.LabResultsRecorded, error) {
+	b, err := h.labOrderResults(order, results, notifyDoctor)
+	if err != nil {
+		return repository.LabResultsRecorded{}, err
+	}
+	return h.Repo.RecordLabResults(b.OrderID, b.Results, b.Notify)
+}
+
+// labOrderParam parses the :orderId param, writing a 400 when it is invalid.
+func labOrderParam(c *gin.Context) (int, bool) {
+	orderID, err := strconv.Atoi(c.Param("orderId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab order ID"})
+		return 0, false
+	}
+	return orderID, true
+}
+
+// doctorLabOrder loads the :orderId order of the :id patient. Unless
+// visible is set, the order must be the calling doctor's own; otherwise
+// the patient letting them see their labs is enough. On failure it writes
+// the response and returns false.
+func (h *Handler) doctorLabOrder(c *gin.Context, visible bool) (models.LabOrder, bool) {
+	patientID, ok := patientParam(c)
+	if !ok {
+		return models.LabOrder{}, false
+	}
+	orderID, ok := labOrderParam(c)
+	if !ok {
+		return models.LabOrder{}, false
+	}
+
+	order, err := h.Repo.GetLabOrderByID(orderID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lab order", "err": err.Error()})
+		return models.LabOrder{}, false
+	}
+	doctorID := c.GetInt("userID")
+	if errors.Is(err, sql.ErrNoRows) || order.PatientID != patientID || (!visible && order.DoctorID != doctorID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lab order not found"})
+		return models.LabOrder{}, false
+	}
+	if order.DoctorID == doctorID {
+		return order, true
+	}
+
+	allowed, err := h.Repo.DoctorHasConsent(doctorID, patientID, repository.ConsentLabs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient access"})
+		return models.LabOrder{}, false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The patient hasn't given you access to their labs", "category": repository.ConsentLabs})
+		return models.LabOrder{}, false
+	}
+	return order, true
+}
+
+// labTrendsQuery reads the optional ?analyte= LOINC code.
+func labTrendsQuery(c *gin.Context) (string, bool) {
+	analyte := strings.TrimSpace(c.Query("analyte"))
+	if utf8.RuneCountInString(analyte) > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid analyte code"})
+		return "", false
+	}
+	return analyte, true
+}
+
+// sendLabReportFile streams a lab report the caller may see, or returns a
+// presigned link to it with ?presigned=true.
+func (h *Handler) sendLabReportFile(c *gin.Context, order models.LabOrder) {
+	reportID, err := strconv.Atoi(c.Param("reportId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+	rep, err := h.Repo.GetLabReport(order.ID, reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch report", "err": err.Error()})
+		return
+	}
+
+	// Lab reports are served the same way as vault documents
+	h.sendDocumentFile(c, models.PatientDocument{
+		FileName:     rep.FileName,
+		OriginalName: rep.OriginalName,
+		ScanStatus:   rep.ScanStatus,
+	})
+}
+
+// storeLabReport stores the PDF in the multipart form's file field as a
+// report on the order. On failure it writes the response and returns false.
+func (h *Handler) storeLabReport(c *gin.Context, order models.LabOrder) (models.LabReport, bool) {
+	if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form", "err": err.Error()})
+		return models.LabReport{}, false
+	}
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required", "err": err.Error()})
+		return models.LabReport{}, false
+	}
+	defer file.Close()
+
+	fileType, scanStatus, ok := h.inspectFile(c, file, header.Size)
+	if !ok {
+		return models.LabReport{}, false
+	}
+	if fileType != filecheck.PDF {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Lab reports must be PDF files"})
+		return models.LabReport{}, false
+	}
+
+	rep := models.LabReport{
+		OrderID:      order.ID,
+		PatientID:    order.PatientID,
+		FileName:     fmt.Sprintf("lab-report-%d-%s%s", order.ID, uuid.New().String(), fileType.Ext),
+		OriginalName: cleanFileName(header.Filename),
+		ContentType:  fileType.ContentType,
+		Size:         header.Size,
+		ScanStatus:   scanStatus,
+	}
+
+	err = h.Store.Put(c.Request.Context(), rep.FileName, file, header.Size, rep.ContentType)
+	if err != nil {
+		log.Printf("Failed to upload lab report to storage: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file", "err": err.Error()})
+		return models.LabReport{}, false
+	}
+
+	rep.ID, err = h.Repo.CreateLabReport(rep)
+	if err != nil {
+		log.Printf("Failed to create lab report in DB: %v", err)
+		// If DB save fails, roll back the upload
+		key := rep.FileName
+		go func() {
+			if delErr := h.Store.Delete(context.Background(), key); delErr != nil {
+				log.Printf("CRITICAL: Failed to rollback upload of %s: %v", key, delErr)
+			}
+		}()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create lab report record", "err": err.Error()})
+		return models.LabReport{}, false
+	}
+	return rep, true
+}
+
+// Patient Portal Handlers
+
+func (h *Handler) GetPatientLabOrders(c *gin.Context) {
+	orders, err := h.Repo.GetLabOrdersByPatientID(c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lab orders", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+// GetPatientLabTrends returns the patient's numeric results over time, per
+// analyte, or just the ?analyte= one.
+func (h *Handler) GetPatientLabTrends(c *gin.Context) {
+	analyte, ok := labTrendsQuery(c)
+	if !ok {
+		return
+	}
+	trends, err := h.Repo.GetLabTrends(c.GetInt("userID"), analyte)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lab results", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, trends)
+}
+
+func (h *Handler) DownloadPatientLabReport(c *gin.Context) {
+	orderID, ok := labOrderParam(c)
+	if !ok {
+		return
+	}
+	order, err := h.Repo.GetLabOrderByID(orderID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && order.PatientID != c.GetInt("userID")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lab order not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lab order", "err": err.Error()})
+		return
+	}
+	h.sendLabReportFile(c, order)
+}
+
+// Doctor Portal Handlers
+
+// GetLabPanels lists the test panels that can be ordered.
+func (h *Handler) GetLabPanels(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Labs.Panels)
+}
+
+// CreateLabOrder orders test panels for a patient the doctor has seen,
+// optionally against one of their appointments with them.
+func (h *Handler) CreateLabOrder(c *gin.Context) {
+	patientID, ok := h.authorizeTreatingDoctor(c)
+	if !ok {
+		return
+	}
+	doctorID := c.GetInt("userID")
+
+	var req struct {
+		Panels        []string `json:"panels" binding:"required"`
+		AppointmentID *int     `json:"appointment_id"`
+		Priority      string   `json:"priority"`
+		Notes         string   `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+
+	order := models.LabOrder{
+		PatientID:     patientID,
+		DoctorID:      doctorID,
+		AppointmentID: req.AppointmentID,
+		Priority:      req.Priority,
+		Notes:         strings.TrimSpace(req.Notes),
+	}
+	switch order.Priority {
+	case "":
+		order.Priority = "routine"
+	case "routine", "urgent":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Priority must be routine or urgent"})
+		return
+	}
+	if utf8.RuneCountInString(order.Notes) > maxLabNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Notes must be at most " + strconv.Itoa(maxLabNotesLength) + " characters"})
+		return
+	}
+
+	if len(req.Panels) == 0 || len(req.Panels) > maxLabOrderPanels {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An order must have between 1 and " + strconv.Itoa(maxLabOrderPanels) + " panels"})
+		return
+	}
+	var expected []string
+	seenPanels, seenAnalytes := map[string]bool{}, map[string]bool{}
+	for _, code := range req.Panels {
+		panel := h.Labs.Panel(code)
+		if panel == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown panel " + code})
+			return
+		}
+		if seenPanels[panel.Code] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Panel " + panel.Code + " is listed more than once"})
+			return
+		}
+		seenPanels[panel.Code] = true
+		order.Panels = append(order.Panels, models.LabOrderPanel{Code: panel.Code, Name: panel.Name})
+		for _, a := range panel.Analytes {
+			if !seenAnalytes[a.Code] {
+				seenAnalytes[a.Code] = true
+				expected = append(expected, a.Code)
+			}
+		}
+	}
+
+	if req.AppointmentID != nil {
+		appt, err := h.Repo.GetAppointmentForDoctor(doctorID, *req.AppointmentID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && appt.PatientID != patientID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The appointment isn't one of yours with this patient"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment", "err": err.Error()})
+			return
+		}
+	}
+
+	newID, err := h.Repo.CreateLabOrder(order, expected)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create lab order", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": newID})
+}
+
+// GetPatientHistoryLabOrders lists the patient's lab orders with their
+// results: the doctor's own, and other doctors' if the patient lets them see
+// their labs.
+func (h *Handler) GetPatientHistoryLabOrders(c *gin.Context) {
+	patientID, ok := patientParam(c)
+	if !ok {
+		return
+	}
+	orders, err := h.Repo.GetLabOrdersForPatient(c.GetInt("userID"), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lab orders", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+// GetPatientHistoryLabTrends is GetPatientLabTrends over the orders the
+// doctor may see.
+func (h *Handler) GetPatientHistoryLabTrends(c *gin.Context) {
+	patientID, ok := patientParam(c)
+	if !ok {
+		return
+	}
+	analyte, ok := labTrendsQuery(c)
+	if !ok {
+		return
+	}
+	trends, err := h.Repo.GetLabTrendsForDoctor(c.GetInt("userID"), patientID, analyte)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lab results", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, trends)
+}
+
+// CancelLabOrder cancels one of the doctor's orders before any results have
+// come in.
+func (h *Handler) CancelLabOrder(c *gin.Context) {
+	order, ok := h.doctorLabOrder(c, false)
+	if !ok {
+		return
+	}
+	err := h.Repo.CancelLabOrder(order.DoctorID, order.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only orders with no results yet can be cancelled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel lab order", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Lab order cancelled"})
+}
+
+// EnterLabResults records results the doctor received outside of a lab
+// integration, e.g., on paper, against one of their orders. Results missing
+// a unit, name or reference range take them from the lab catalogue, and are
+// flagged unless a flag is given.
+func (h *Handler) EnterLabResults(c *gin.Context) {
+	order, ok := h.doctorLabOrder(c, false)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Results []struct {
+			AnalyteCode string     `json:"analyte_code"`
+			AnalyteName string     `json:"analyte_name"`
+			Value       *float64   `json:"value"`
+			ValueText   string     `json:"value_text"`
+			Unit        string     `json:"unit"`
+			RefLow      *float64   `json:"ref_low"`
+			RefHigh     *float64   `json:"ref_high"`
+			RefText     string     `json:"ref_text"`
+			Flag        string     `json:"flag"`
+			Status      string     `json:"status"`
+			ObservedAt  *time.Time `json:"observed_at"`
+		} `json:"results" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	if len(req.Results) == 0 || len(req.Results) > maxLabResultsPerRequest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Between 1 and " + strconv.Itoa(maxLabResultsPerRequest) + " results are required"})
+		return
+	}
+
+	results := make([]models.LabResult, 0, len(req.Results))
+	seen := map[string]bool{}
+	for _, in := range req.Results {
+		r := models.LabResult{
+			AnalyteCode: strings.TrimSpace(in.AnalyteCode),
+			AnalyteName: strings.TrimSpace(in.AnalyteName),
+			Value:       in.Value,
+			ValueText:   strings.TrimSpace(in.ValueText),
+			Unit:        strings.TrimSpace(in.Unit),
+			RefLow:      in.RefLow,
+			RefHigh:     in.RefHigh,
+			RefText:     strings.TrimSpace(in.RefText),
+			Flag:        strings.ToUpper(strings.TrimSpace(in.Flag)),
+			Status:      in.Status,
+			Source:      "manual",
+		}
+		if r.Status == "" {
+			r.Status = "final"
+		}
+		if in.ObservedAt != nil {
+			if in.ObservedAt.After(time.Now()) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "observed_at can't be in the future"})
+				return
+			}
+			r.ObservedAt = *in.ObservedAt
+		}
+		if r.AnalyteName == "" && h.Labs.Analyte(r.AnalyteCode) == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Analyte " + r.AnalyteCode + " isn't in the catalogue, so its name is required"})
+			return
+		}
+		h.completeLabResult(&r)
+		if err := checkLabResult(r); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if seen[r.AnalyteCode] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Analyte " + r.AnalyteCode + " is listed more than once"})
+			return
+		}
+		seen[r.AnalyteCode] = true
+		results = append(results, r)
+	}
+
+	out, err := h.recordLabResults(order, results, false)
+	if errors.Is(err, repository.ErrLabOrderCancelled) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The lab order has been cancelled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record lab results", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recorded": out.Recorded, "status": out.Status})
+}
+
+// UploadLabReport attaches the lab's PDF report to one of the doctor's
+// orders.
+func (h *Handler) UploadLabReport(c *gin.Context) {
+	order, ok := h.doctorLabOrder(c, false)
+	if !ok {
+		return
+	}
+	if order.Status == "cancelled" {
+		c.JSON(http.StatusConflict, gin.H{"error": "The lab order has been cancelled"})
+		return
+	}
+	rep, ok := h.storeLabReport(c, order)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": rep.ID, "scan_status": rep.ScanStatus})
+}
+
+// DoctorDownloadLabReport serves a report on an order the doctor may see.
+func (h *Handler) DoctorDownloadLabReport(c *gin.Context) {
+	order, ok := h.doctorLabOrder(c, true)
+	if !ok {
+		return
+	}
+	h.sendLabReportFile(c, order)
+}
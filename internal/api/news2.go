@@ -0,0 +1,69 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+)
+
+// Doctor Portal Handlers
+func (h *Handler) GetPatientHistoryNEWS2(c *gin.Context) {
+	patientID, ok := h.authorizeDoctorForPatient(c, repository.ConsentVitals)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseTimeRange(c, 7*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time range", "err": err.Error()})
+		return
+	}
+
+	scores, err := h.Repo.GetNEWS2ScoresByPatientID(patientID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch NEWS2 scores"})
+		return
+	}
+
+	latest, err := h.Repo.GetLatestNEWS2Score(patientID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch NEWS2 scores"})
+		return
+	}
+
+	// An expired score is still in the history, but isn't current
+	var current any
+	if err == nil && latest.ExpiredAt == nil {
+		current = latest
+	}
+	c.JSON(http.StatusOK, gin.H{"current": current, "history": scores})
+}
+
+// UpdatePatientSpO2Scale switches a patient between NEWS2 SpO2 scale 1 and the
+// scale 2 used for hypercapnic respiratory failure.
+func (h *Handler) UpdatePatientSpO2Scale(c *gin.Context) {
+	patientID, ok := h.authorizeDoctorForPatient(c, repository.ConsentVitals)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Scale int `json:"spo2_scale"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Scale != 1 && req.Scale != 2) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "spo2_scale must be 1 or 2"})
+		return
+	}
+
+	if err := h.Repo.UpdatePatientSpO2Scale(patientID, req.Scale); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update SpO2 scale", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "SpO2 scale updated"})
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/internal/terminology"
+)
+
+const (
+	maxNoteSectionLength = 20000
+	maxNoteDiagnoses     = 20
+)
+
+// noteRequest is the body of the encounter note endpoints. Reason is only
+// used by amendments.
+type noteRequest struct {
+	Subjective string `json:"subjective"`
+	Objective  string `json:"objective"`
+	Assessment string `json:"assessment"`
+	Plan       string `json:"plan"`
+	Diagnoses  []struct {
+		System      string `json:"system"` // defaults to ICD-10-CM
+		Code        string `json:"code"`
+		Description string `json:"description"`
+	} `json:"diagnoses"`
+	Reason string `json:"reason"`
+}
+
+// bindNote reads and validates an encounter note from the request body. On
+// failure it writes the response and returns false.
+func bindNote(c *gin.Context) (models.EncounterNoteContent, string, bool) {
+	var req noteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return models.EncounterNoteContent{}, "", false
+	}
+
+	content := models.EncounterNoteContent{
+		Subjective: strings.TrimSpace(req.Subjective),
+		Objective:  strings.TrimSpace(req.Objective),
+		Assessment: strings.TrimSpace(req.Assessment),
+		Plan:       strings.TrimSpace(req.Plan),
+		Diagnoses:  []models.Diagnosis{},
+	}
+	for _, section := range []string{content.Subjective, content.Objective, content.Assessment, content.Plan} {
+		if utf8.RuneCountInString(section) > maxNoteSectionLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Each section of a note must be at most " + strconv.Itoa(maxNoteSectionLength) + " characters"})
+			return models.EncounterNoteContent{}, "", false
+		}
+	}
+
+	if len(req.Diagnoses) > maxNoteDiagnoses {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A note can have at most " + strconv.Itoa(maxNoteDiagnoses) + " diagnoses"})
+		return models.EncounterNoteContent{}, "", false
+	}
+	seen := map[string]bool{}
+	for _, d := range req.Diagnoses {
+		diagnosis := models.Diagnosis{
+			System:      codeSystem(d.System),
+			Code:        strings.ToUpper(strings.TrimSpace(d.Code)),
+			Description: strings.TrimSpace(d.Description),
+		}
+		if !terminology.Systems[diagnosis.System] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown code system " + diagnosis.System})
+			return models.EncounterNoteContent{}, "", false
+		}
+		if diagnosis.Code == "" || utf8.RuneCountInString(diagnosis.Code) > 20 || utf8.RuneCountInString(diagnosis.Description) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Diagnosis codes are required and must be at most 20 characters, descriptions at most 255"})
+			return models.EncounterNoteContent{}, "", false
+		}
+		key := diagnosis.System + " " + terminology.SearchCode(diagnosis.Code)
+		if seen[key] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Diagnosis " + diagnosis.Code + " is listed more than once"})
+			return models.EncounterNoteContent{}, "", false
+		}
+		seen[key] = true
+		content.Diagnoses = append(content.Diagnoses, diagnosis)
+	}
+	return content, req.Reason, true
+}
+
+// noteIsEmpty reports whether nothing has been written in the note.
+func noteIsEmpty(content models.EncounterNoteContent) bool {
+	return content.Subjective == "" && content.Objective == "" && content.Assessment == "" &&
+		content.Plan == "" && len(content.Diagnoses) == 0
+}
+
+// doctorAppointment loads the appointment in :id and checks it is with the
+// calling doctor. On failure it writes the response and returns false.
+func (h *Handler) doctorAppointment(c *gin.Context) (models.Appointment, bool) {
+	appointmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return models.Appointment{}, false
+	}
+
+	appt, err := h.Repo.GetAppointmentForDoctor(c.GetInt("userID"), appointmentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+		return models.Appointment{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment", "err": err.Error()})
+		return models.Appointment{}, false
+	}
+	setAuditPatient(c, appt.PatientID)
+	return appt, true
+}
+
+// appointmentNote loads the note on the calling doctor's appointment in :id.
+// On failure it writes the response and returns false.
+func (h *Handler) appointmentNote(c *gin.Context) (models.EncounterNote, bool) {
+	appt, ok := h.doctorAppointment(c)
+	if !ok {
+		return models.EncounterNote{}, false
+	}
+
+	note, err := h.Repo.GetEncounterNoteByAppointmentID(appt.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The appointment has no note"})
+		return models.EncounterNote{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch note", "err": err.Error()})
+		return models.EncounterNote{}, false
+	}
+	return note, true
+}
+
+// sendAppointmentNote writes the note on the appointment as it now stands.
+func (h *Handler) sendAppointmentNote(c *gin.Context, appointmentID int) {
+	note, err := h.Repo.GetEncounterNoteByAppointmentID(appointmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch note", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, note)
+}
+
+// Patient Portal Handlers
+
+// GetPatientNotes lists the patient's signed encounter notes.
+func (h *Handler) GetPatientNotes(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	notes, err := h.Repo.GetSignedEncounterNotesByPatientID(patientID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notes", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, notes)
+}
+
+// Doctor Portal Handlers
+
+// GetPatientHistoryNotes lists the doctor's notes on the patient and, with
+// the patient's consent, other doctors' signed notes.
+func (h *Handler) GetPatientHistoryNotes(c *gin.Context) {
+	patientID, ok := patientParam(c)
+	if !ok {
+		return
+	}
+
+	notes, err := h.Repo.GetEncounterNotesForPatient(c.GetInt("userID"), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notes", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, notes)
+}
+
+func (h *Handler) GetAppointmentNote(c *gin.Context) {
+	note, ok := h.appointmentNote(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, note)
+}
+
+// SaveAppointmentNote creates or replaces the draft note on the appointment.
+// Once signed the note can only be amended.
+func (h *Handler) SaveAppointmentNote(c *gin.Context) {
+	appt, ok := h.doctorAppointment(c)
+	if !ok {
+		return
+	}
+	if appt.Status == "cancelled" {
+		c.JSON(http.StatusConflict, gin.H{"error": "The appointment was cancelled"})
+		return
+	}
+
+	content, _, ok := bindNote(c)
+	if !ok || !h.codeDiagnoses(c, content.Diagnoses) {
+		return
+	}
+
+	_, err := h.Repo.SaveEncounterNoteDraft(models.EncounterNote{
+		AppointmentID:        appt.ID,
+		PatientID:            appt.PatientID,
+		DoctorID:             appt.DoctorID,
+		EncounterNoteContent: content,
+	})
+	if errors.Is(err, repository.ErrEncounterNoteSigned) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The note has been signed; amend it instead"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save note", "err": err.Error()})
+		return
+	}
+	h.sendAppointmentNote(c, appt.ID)
+}
+
+// SignAppointmentNote signs the draft note on the appointment, after which
+// it is locked.
+func (h *Handler) SignAppointmentNote(c *gin.Context) {
+	note, ok := h.appointmentNote(c)
+	if !ok {
+		return
+	}
+	if noteIsEmpty(note.EncounterNoteContent) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An empty note can't be signed"})
+		return
+	}
+
+	_, err := h.Repo.SignEncounterNote(note.ID)
+	if errors.Is(err, repository.ErrEncounterNoteSigned) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The note has already been signed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign note", "err": err.Error()})
+		return
+	}
+	h.sendAppointmentNote(c, note.AppointmentID)
+}
+
+// AmendAppointmentNote replaces the content of the signed note on the
+// appointment. A reason is required, and what it replaced is kept.
+func (h *Handler) AmendAppointmentNote(c *gin.Context) {
+	note, ok := h.appointmentNote(c)
+	if !ok {
+		return
+	}
+
+	content, reason, ok := bindNote(c)
+	if !ok || !h.codeDiagnoses(c, content.Diagnoses) {
+		return
+	}
+	if reason, ok = validateReason(reason); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason of at most 1000 characters is required"})
+		return
+	}
+	if noteIsEmpty(content) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A signed note can't be left empty"})
+		return
+	}
+
+	err := h.Repo.AmendEncounterNote(note.ID, note.DoctorID, content, reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only signed notes can be amended; edit the draft instead"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to amend note", "err": err.Error()})
+		return
+	}
+	h.sendAppointmentNote(c, note.AppointmentID)
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+)
+
+const maxReasonLength = 1000
+
+// issuedPrescription loads the prescription named by :prescriptionId for the
+// patient in :id and checks the requesting doctor issued it and it is still
+// the active version. On failure it writes the error response and returns
+// false.
+func (h *Handler) issuedPrescription(c *gin.Context) (models.Prescription, bool) {
+	patientID, ok := patientParam(c)
+	if !ok {
+		return models.Prescription{}, false
+	}
+	doctorID := c.GetInt("userID")
+
+	prescriptionID, err := strconv.Atoi(c.Param("prescriptionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prescription ID"})
+		return models.Prescription{}, false
+	}
+
+	pres, err := h.Repo.GetPrescriptionByID(prescriptionID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && pres.PatientID != patientID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prescription not found"})
+		return models.Prescription{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescription", "err": err.Error()})
+		return models.Prescription{}, false
+	}
+
+	if pres.DoctorID != doctorID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the issuing doctor can change this prescription"})
+		return models.Prescription{}, false
+	}
+	if pres.Status != "active" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Prescription has already been amended or revoked"})
+		return models.Prescription{}, false
+	}
+	return pres, true
+}
+
+func validateReason(reason string) (string, bool) {
+	reason = strings.TrimSpace(reason)
+	return reason, reason != "" && utf8.RuneCountInString(reason) <= maxReasonLength
+}
+
+// AmendPrescription issues a new version of a prescription, which supersedes
+// the current one. A multipart body uploads a replacement file like
+// CreatePrescription; a JSON body is rendered like GeneratePrescription.
+// Either way a reason is required.
+func (h *Handler) AmendPrescription(c *gin.Context) {
+	prev, ok := h.issuedPrescription(c)
+	if !ok {
+		return
+	}
+
+	pres := models.Prescription{
+		PatientID:  prev.PatientID,
+		DoctorID:   prev.DoctorID,
+		Version:    prev.Version + 1,
+		PreviousID: &prev.ID,
+	}
+
+	var newID int
+	if c.ContentType() == "multipart/form-data" {
+		if err := c.Request.ParseMultipartForm(10 << 20); err != nil { // 10 MB Max File Size
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form", "err": err.Error()})
+			return
+		}
+		if pres.AmendmentReason, ok = validateReason(c.Request.FormValue("reason")); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A reason of at most 1000 characters is required"})
+			return
+		}
+		if newID, ok = h.uploadPrescription(c, &pres); !ok {
+			return
+		}
+	} else {
+		var req struct {
+			Reason string `json:"reason"`
+			prescriptionContent
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+			return
+		}
+		if pres.AmendmentReason, ok = validateReason(req.Reason); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A reason of at most 1000 characters is required"})
+			return
+		}
+		if newID, ok = h.generatePrescription(c, &pres, req.prescriptionContent); !ok {
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":                newID,
+		"filename":          pres.FileName,
+		"verification_code": pres.VerificationCode,
+		"version":           pres.Version,
+		"previous_id":       prev.ID,
+		"warnings":          pres.Warnings,
+	})
+}
+
+func (h *Handler) RevokePrescription(c *gin.Context) {
+	pres, ok := h.issuedPrescription(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	reason, ok := validateReason(req.Reason)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason of at most 1000 characters is required"})
+		return
+	}
+
+	err := h.Repo.RevokePrescription(pres.ID, reason)
+	if errors.Is(err, repository.ErrPrescriptionNotActive) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Prescription has already been amended or revoked"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke prescription", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Prescription revoked"})
+}
+
+func (h *Handler) GetPatientPrescriptionHistory(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	prescriptionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prescription ID"})
+		return
+	}
+
+	h.listPrescriptionHistory(c, patientID.(int), prescriptionID)
+}
+
+func (h *Handler) GetPatientHistoryPrescriptionVersions(c *gin.Context) {
+	patientID, ok := patientParam(c)
+	if !ok {
+		return
+	}
+
+	prescriptionID, err := strconv.Atoi(c.Param("prescriptionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prescription ID"})
+		return
+	}
+
+	pres, err := h.Repo.GetPrescriptionByID(prescriptionID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && pres.PatientID != patientID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prescription not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescription", "err": err.Error()})
+		return
+	}
+	if !h.authorizeDoctorForPrescription(c, pres) {
+		return
+	}
+
+	h.listPrescriptionHistory(c, patientID, prescriptionID)
+}
+
+func (h *Handler) listPrescriptionHistory(c *gin.Context, patientID, prescriptionID int) {
+	versions, err := h.Repo.GetPrescriptionHistory(prescriptionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescription history", "err": err.Error()})
+		return
+	}
+	if len(versions) == 0 || versions[0].PatientID != patientID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prescription not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// downloadFilename prefixes the file name of anything but the current version
+// so a saved copy can't be mistaken for a valid prescription.
+func downloadFilename(pres models.Prescription, filename string) string {
+	switch pres.Status {
+	case "superseded":
+		return "SUPERSEDED-" + filename
+	case "revoked":
+		return "REVOKED-" + filename
+	}
+	return filename
+}
+
+func setVersionHeaders(c *gin.Context, pres models.Prescription) {
+	if pres.Status == "" {
+		return
+	}
+	c.Header("X-Prescription-Status", pres.Status)
+	c.Header("X-Prescription-Version", strconv.Itoa(pres.Version))
+	if pres.SupersededByFile != "" {
+		c.Header("X-Superseded-By", pres.SupersededByFile)
+	}
+}
@@ -0,0 +1,354 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/internal/rxpdf"
+	"github.com/RitwikGupta-0501/vital-watch/utils"
+)
+
+const (
+	maxPrescriptionItems = 20
+	// verificationCodeLength is long enough that codes can't practically be
+	// guessed, while still being typeable from a printed prescription.
+	verificationCodeLength = 12
+)
+
+// prescriptionRoutes are the administration routes accepted on line items.
+var prescriptionRoutes = map[string]bool{
+	"oral": true, "sublingual": true, "buccal": true, "topical": true, "transdermal": true,
+	"inhaled": true, "nasal": true, "ophthalmic": true, "otic": true, "rectal": true, "vaginal": true,
+	"iv": true, "im": true, "sc": true, "intradermal": true,
+}
+
+// parsePrescriptionItems decodes and validates the optional "items" form field
+// of a prescription, a JSON array of line items.
+func parsePrescriptionItems(raw string) ([]models.PrescriptionItem, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var items []models.PrescriptionItem
+	if err := json.Unmarshal([]byte(raw), &items); err != nil {
+		return nil, fmt.Errorf("items is not a valid JSON array: %w", err)
+	}
+	if err := validatePrescriptionItems(items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func validatePrescriptionItems(items []models.PrescriptionItem) error {
+	if len(items) > maxPrescriptionItems {
+		return fmt.Errorf("a prescription can have at most %d items", maxPrescriptionItems)
+	}
+
+	for i := range items {
+		it := &items[i]
+		it.DrugName = strings.TrimSpace(it.DrugName)
+		it.Route = strings.ToLower(strings.TrimSpace(it.Route))
+		it.Form = strings.ToLower(strings.TrimSpace(it.Form))
+
+		switch {
+		case it.DrugName == "":
+			return fmt.Errorf("item %d: drug_name is required", i+1)
+		case utf8.RuneCountInString(it.DrugName) > 255:
+			return fmt.Errorf("item %d: drug_name is too long", i+1)
+		case strings.TrimSpace(it.Dose) == "":
+			return fmt.Errorf("item %d: dose is required", i+1)
+		case strings.TrimSpace(it.Frequency) == "":
+			return fmt.Errorf("item %d: frequency is required", i+1)
+		case it.Route != "" && !prescriptionRoutes[it.Route]:
+			return fmt.Errorf("item %d: unknown route %q", i+1, it.Route)
+		case it.Quantity < 0:
+			return fmt.Errorf("item %d: quantity can't be negative", i+1)
+		case it.Refills < 0 || it.Refills > 12:
+			return fmt.Errorf("item %d: refills must be between 0 and 12", i+1)
+		}
+		if utf8.RuneCountInString(it.Form) > 50 {
+			return fmt.Errorf("item %d: form is too long", i+1)
+		}
+		for name, v := range map[string]string{
+			"strength": it.Strength, "dose": it.Dose, "frequency": it.Frequency, "duration": it.Duration,
+		} {
+			if utf8.RuneCountInString(v) > 100 {
+				return fmt.Errorf("item %d: %s is too long", i+1, name)
+			}
+		}
+	}
+	return nil
+}
+
+// medicationSummary builds the legacy single-line medication field from the
+// line items, for clients that haven't moved to structured items yet.
+func medicationSummary(medication string, items []models.PrescriptionItem) (string, error) {
+	if medication != "" {
+		return medication, nil
+	}
+	if len(items) == 0 {
+		return "", errors.New("either medication or items is required")
+	}
+
+	names := make([]string, len(items))
+	for i, it := range items {
+		names[i] = strings.TrimSpace(it.DrugName + " " + it.Strength)
+	}
+	summary := strings.Join(names, "; ")
+	if r := []rune(summary); len(r) > 255 {
+		summary = string(r[:252]) + "..."
+	}
+	return summary, nil
+}
+
+// storePrescription signs and uploads the prescription file under a fresh
+// prescription-<patient>-<uuid> key and records it, filling in the file name,
+// signature and, unless already set, the verification code on pres. On
+// failure it writes the error response and returns false.
+func (h *Handler) storePrescription(c *gin.Context, pres *models.Prescription, body io.ReadSeeker, size int64, ext, contentType string) (int, bool) {
+	if !assignVerificationCode(c, pres) {
+		return 0, false
+	}
+	pres.FileName = prescriptionFileName(pres.PatientID, ext)
+
+	if !h.signPrescription(c, pres, body) {
+		return 0, false
+	}
+
+	// Upload to storage
+	err := h.Store.Put(c.Request.Context(), pres.FileName, body, size, contentType)
+	if err != nil {
+		log.Printf("Failed to upload file to storage: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file", "err": err.Error()})
+		return 0, false
+	}
+
+	// Save metadata to database
+	newID, err := h.Repo.CreatePrescription(*pres)
+	if err != nil {
+		log.Printf("Failed to create prescription in DB: %v", err)
+		// If DB save fails, roll back the upload
+		key := pres.FileName
+		go func() {
+			log.Printf("Rolling back upload for key: %s", key)
+			if delErr := h.Store.Delete(context.Background(), key); delErr != nil {
+				log.Printf("CRITICAL: Failed to rollback upload: %v", delErr)
+			}
+		}()
+		if errors.Is(err, repository.ErrPrescriptionNotActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Prescription has already been amended or revoked"})
+			return 0, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create prescription record", "err": err.Error()})
+		return 0, false
+	}
+
+	return newID, true
+}
+
+// prescriptionFileName returns a fresh storage key for one of the patient's
+// prescription files.
+func prescriptionFileName(patientID int, ext string) string {
+	return fmt.Sprintf("prescription-%d-%s%s", patientID, uuid.New().String(), ext)
+}
+
+// assignVerificationCode generates a verification code for pres unless it
+// already has one.
+func assignVerificationCode(c *gin.Context, pres *models.Prescription) bool {
+	if pres.VerificationCode != "" {
+		return true
+	}
+	code, err := utils.GenerateCode(verificationCodeLength)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate verification code"})
+		return false
+	}
+	pres.VerificationCode = code
+	return true
+}
+
+// verificationURL is the public link encoded in a prescription's QR code.
+func (h *Handler) verificationURL(code string) string {
+	return strings.TrimRight(h.PublicBaseURL, "/") + "/api/verify/" + code
+}
+
+// uploadPrescription reads a prescription's details and file from an
+// already parsed multipart form into pres and stores it.
+func (h *Handler) uploadPrescription(c *gin.Context, pres *models.Prescription) (int, bool) {
+	items, err := parsePrescriptionItems(c.Request.FormValue("items"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prescription items", "err": err.Error()})
+		return 0, false
+	}
+
+	medication, err := medicationSummary(c.Request.FormValue("medication"), items)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prescription", "err": err.Error()})
+		return 0, false
+	}
+
+	schedule, err := parseDosingSchedule(c.Request.FormValue("schedule"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dosing schedule", "err": err.Error()})
+		return 0, false
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required", "err": err.Error()})
+		return 0, false
+	}
+	defer file.Close()
+
+	fileType, scanStatus, ok := h.inspectFile(c, file, header.Size)
+	if !ok {
+		return 0, false
+	}
+
+	pres.Medication = medication
+	pres.Notes = c.Request.FormValue("notes")
+	pres.Source = "upload"
+	pres.Items = items
+	pres.Schedule = schedule
+	pres.ScanStatus = scanStatus
+	if !h.checkPrescriptionSafety(c, pres, c.Request.FormValue("override_reason"), nil) {
+		return 0, false
+	}
+	return h.storePrescription(c, pres, file, header.Size, fileType.Ext, fileType.ContentType)
+}
+
+// prescriptionContent is the JSON body of a prescription rendered by the
+// server.
+type prescriptionContent struct {
+	Items    []models.PrescriptionItem `json:"items" binding:"required"`
+	Notes    string                    `json:"notes"`
+	Schedule json.RawMessage           `json:"schedule"`
+	// OverrideReason acknowledges the formulary warnings from a previous
+	// attempt
+	OverrideReason string `json:"override_reason"`
+
+	// excludeFromChecks are active prescriptions this one repeats, which
+	// would otherwise be flagged as duplicates
+	excludeFromChecks []int
+}
+
+// generatePrescription fills pres from content, renders it as a PDF and
+// stores it.
+func (h *Handler) generatePrescription(c *gin.Context, pres *models.Prescription, content prescriptionContent) (int, bool) {
+	if len(content.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one item is required"})
+		return 0, false
+	}
+	if err := validatePrescriptionItems(content.Items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prescription items", "err": err.Error()})
+		return 0, false
+	}
+
+	schedule, err := parseDosingSchedule(string(content.Schedule))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dosing schedule", "err": err.Error()})
+		return 0, false
+	}
+
+	doctor, err := h.Repo.GetDoctorByID(pres.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch doctor", "err": err.Error()})
+		return 0, false
+	}
+	patient, err := h.Repo.GetPatientByID(pres.PatientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch patient", "err": err.Error()})
+		return 0, false
+	}
+
+	pres.Medication, _ = medicationSummary("", content.Items)
+	pres.Notes = content.Notes
+	pres.Source = "generated"
+	pres.Items = content.Items
+	pres.Schedule = schedule
+	if !h.checkPrescriptionSafety(c, pres, content.OverrideReason, content.excludeFromChecks) {
+		return 0, false
+	}
+
+	// The verification code has to be on the PDF, so generate it up front and
+	// render into a buffer; storePrescription keeps a code it's given.
+	if !assignVerificationCode(c, pres) {
+		return 0, false
+	}
+	code := pres.VerificationCode
+
+	var buf bytes.Buffer
+	err = rxpdf.Render(&buf, rxpdf.Document{
+		Clinic:           h.Clinic,
+		Doctor:           doctor,
+		Patient:          patient,
+		Items:            pres.Items,
+		Notes:            pres.Notes,
+		IssuedAt:         time.Now(),
+		VerificationCode: code,
+		VerificationURL:  h.verificationURL(code),
+		Version:          pres.Version,
+	})
+	if err != nil {
+		log.Printf("Failed to render prescription PDF: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render prescription"})
+		return 0, false
+	}
+
+	return h.storePrescription(c, pres, bytes.NewReader(buf.Bytes()), int64(buf.Len()), ".pdf", "application/pdf")
+}
+
+// GeneratePrescription renders a clinic-branded PDF from structured line
+// items and stores it like an uploaded prescription.
+func (h *Handler) GeneratePrescription(c *gin.Context) {
+	doctorID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var req struct {
+		PatientID int `json:"patient_id" binding:"required"`
+		prescriptionContent
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	setAuditPatient(c, req.PatientID)
+
+	hasPatient, err := h.Repo.DoctorHasPatient(doctorID.(int), req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient", "err": err.Error()})
+		return
+	}
+	if !hasPatient {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to prescribe for this patient"})
+		return
+	}
+
+	pres := models.Prescription{
+		PatientID: req.PatientID,
+		DoctorID:  doctorID.(int),
+	}
+	newID, ok := h.generatePrescription(c, &pres, req.prescriptionContent)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": newID, "filename": pres.FileName, "verification_code": pres.VerificationCode, "warnings": pres.Warnings})
+}
@@ -0,0 +1,374 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+)
+
+// Refill request statuses.
+const (
+	refillPending        = "pending"
+	refillApproved       = "approved"
+	refillDenied         = "denied"
+	refillVisitRequested = "visit_requested"
+)
+
+// CreateRefillRequest lets a patient ask the issuing doctor to repeat one of
+// their current prescriptions.
+func (h *Handler) CreateRefillRequest(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	prescriptionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prescription ID"})
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+			return
+		}
+	}
+	note := strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(note) > maxReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Note is too long"})
+		return
+	}
+
+	pres, err := h.Repo.GetPrescriptionByID(prescriptionID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && pres.PatientID != patientID.(int)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prescription not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescription", "err": err.Error()})
+		return
+	}
+	if pres.Status != "active" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only the current version of a prescription can be refilled"})
+		return
+	}
+
+	patient, err := h.Repo.GetPatientByID(pres.PatientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch patient", "err": err.Error()})
+		return
+	}
+
+	newID, err := h.Repo.CreateRefillRequest(models.RefillRequest{
+		PrescriptionID: pres.ID,
+		PatientID:      pres.PatientID,
+		DoctorID:       pres.DoctorID,
+		PatientNote:    note,
+	}, models.Notification{
+		RecipientRole: "doctor",
+		RecipientID:   pres.DoctorID,
+		Kind:          "refill_requested",
+		Title:         "Refill requested by " + patient.FirstName + " " + patient.LastName,
+		Body:          pres.Medication,
+		Data:          map[string]any{"prescription_id": pres.ID, "patient_id": pres.PatientID},
+	})
+	if repository.IsUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A refill request for this prescription is already pending"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create refill request", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": newID})
+}
+
+func (h *Handler) GetPatientRefillRequests(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	requests, err := h.Repo.GetRefillRequestsByPatientID(patientID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch refill requests", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// GetDoctorRefillRequests returns the doctor's queue. It defaults to pending
+// requests; ?status=all returns every request.
+func (h *Handler) GetDoctorRefillRequests(c *gin.Context) {
+	doctorID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	status := c.DefaultQuery("status", refillPending)
+	if status == "all" {
+		status = ""
+	}
+
+	requests, err := h.Repo.GetRefillRequestsForDoctor(doctorID.(int), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch refill requests", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// pendingRefillRequest loads the :id request from the doctor's queue and
+// checks it is still open. On failure it writes the error response and
+// returns false.
+func (h *Handler) pendingRefillRequest(c *gin.Context) (models.RefillRequest, bool) {
+	doctorID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return models.RefillRequest{}, false
+	}
+
+	requestID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid refill request ID"})
+		return models.RefillRequest{}, false
+	}
+
+	rr, err := h.Repo.GetRefillRequestForDoctor(doctorID.(int), requestID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Refill request not found"})
+		return models.RefillRequest{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch refill request", "err": err.Error()})
+		return models.RefillRequest{}, false
+	}
+	setAuditPatient(c, rr.PatientID)
+	if rr.Status != refillPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Refill request has already been resolved"})
+		return models.RefillRequest{}, false
+	}
+	return rr, true
+}
+
+// resolveRefillRequest records the outcome and notifies the patient. On
+// failure it writes the error response and returns false.
+func (h *Handler) resolveRefillRequest(c *gin.Context, rr models.RefillRequest, res repository.RefillResolution, title string) (*int, bool) {
+	data := map[string]any{"refill_request_id": rr.ID, "prescription_id": rr.PrescriptionID}
+	if res.NewPrescriptionID != nil {
+		data["new_prescription_id"] = *res.NewPrescriptionID
+	}
+
+	appointmentID, err := h.Repo.ResolveRefillRequest(rr.ID, res, models.Notification{
+		RecipientRole: "patient",
+		RecipientID:   rr.PatientID,
+		Kind:          "refill_" + res.Status,
+		Title:         title,
+		Body:          strings.TrimSpace(rr.Medication + "\n" + res.Reason),
+		Data:          data,
+	})
+	if errors.Is(err, repository.ErrRefillNotPending) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Refill request has already been resolved"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update refill request", "err": err.Error()})
+		return nil, false
+	}
+	return appointmentID, true
+}
+
+// ApproveRefillRequest issues a new prescription linked to the request. The
+// body may override the items, notes and schedule; by default the original
+// items and notes are repeated without a schedule.
+func (h *Handler) ApproveRefillRequest(c *gin.Context) {
+	rr, ok := h.pendingRefillRequest(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Items          []models.PrescriptionItem `json:"items"`
+		Notes          *string                   `json:"notes"`
+		Schedule       json.RawMessage           `json:"schedule"`
+		OverrideReason string                    `json:"override_reason"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+			return
+		}
+	}
+
+	orig, err := h.Repo.GetPrescriptionByID(rr.PrescriptionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescription", "err": err.Error()})
+		return
+	}
+
+	content := prescriptionContent{
+		Items:             req.Items,
+		Notes:             orig.Notes,
+		Schedule:          req.Schedule,
+		OverrideReason:    req.OverrideReason,
+		excludeFromChecks: []int{orig.ID},
+	}
+	if content.Items == nil {
+		content.Items = orig.Items
+	}
+	if req.Notes != nil {
+		content.Notes = *req.Notes
+	}
+	if len(content.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The original prescription has no line items; include items to approve it"})
+		return
+	}
+
+	pres := models.Prescription{
+		PatientID: rr.PatientID,
+		DoctorID:  rr.DoctorID,
+	}
+	newID, ok := h.generatePrescription(c, &pres, content)
+	if !ok {
+		return
+	}
+
+	_, ok = h.resolveRefillRequest(c, rr, repository.RefillResolution{
+		Status:            refillApproved,
+		NewPrescriptionID: &newID,
+	}, "Your refill request was approved")
+	if !ok {
+		// Another approval won the race; don't leave a second live copy behind
+		if err := h.Repo.RevokePrescription(newID, "Duplicate refill approval"); err != nil {
+			log.Printf("Failed to revoke duplicate refill prescription %d: %v", newID, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Refill approved", "prescription_id": newID, "filename": pres.FileName, "warnings": pres.Warnings})
+}
+
+func (h *Handler) DenyRefillRequest(c *gin.Context) {
+	rr, ok := h.pendingRefillRequest(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	reason, ok := validateReason(req.Reason)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason of at most 1000 characters is required"})
+		return
+	}
+
+	_, ok = h.resolveRefillRequest(c, rr, repository.RefillResolution{
+		Status: refillDenied,
+		Reason: reason,
+	}, "Your refill request was declined")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Refill denied"})
+}
+
+// RequestVisitForRefill answers a refill request by proposing an appointment,
+// which stays pending until the patient confirms it.
+func (h *Handler) RequestVisitForRefill(c *gin.Context) {
+	rr, ok := h.pendingRefillRequest(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		StartTime time.Time `json:"start_time" binding:"required"`
+		EndTime   time.Time `json:"end_time" binding:"required"`
+		Type      string    `json:"type"`
+		Reason    string    `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	if !req.EndTime.After(req.StartTime) || req.StartTime.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The visit must be in the future and end after it starts"})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if utf8.RuneCountInString(reason) > maxReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is too long"})
+		return
+	}
+
+	appointmentID, ok := h.resolveRefillRequest(c, rr, repository.RefillResolution{
+		Status: refillVisitRequested,
+		Reason: reason,
+		Appointment: &models.Appointment{
+			PatientID: rr.PatientID,
+			DoctorID:  rr.DoctorID,
+			StartTime: req.StartTime,
+			EndTime:   req.EndTime,
+			Type:      req.Type,
+			Status:    "pending",
+		},
+	}, "Your doctor would like to see you before refilling")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Visit requested", "appointment_id": appointmentID})
+}
+
+// ConfirmAppointment accepts an appointment a doctor proposed.
+func (h *Handler) ConfirmAppointment(c *gin.Context) {
+	patientID, ok := c.Get("userID")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	appointmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	err = h.Repo.ConfirmAppointment(patientID.(int), appointmentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending appointment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm appointment", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Appointment confirmed"})
+}
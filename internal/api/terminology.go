@@ -0,0 +1,228 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/terminology"
+)
+
+const (
+	defaultTerminologyResults = 20
+	maxTerminologyResults     = 100
+)
+
+// terminologyQuery reads the ?system= and ?limit= params shared by the code
+// searches. On failure it writes the response and returns false.
+func terminologyQuery(c *gin.Context) (system string, limit int, ok bool) {
+	system = c.Query("system")
+	if system != "" && !terminology.Systems[system] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown code system " + system})
+		return "", 0, false
+	}
+
+	limit = defaultTerminologyResults
+	if s := c.Query("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 || limit > maxTerminologyResults {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxTerminologyResults)})
+			return "", 0, false
+		}
+	}
+	return system, limit, true
+}
+
+// prefixTSQuery turns search text into a tsquery matching descriptions that
+// have every word, the last ones possibly still being typed. Anything but
+// letters and digits separates words, so the query is always valid.
+func prefixTSQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = strings.ToLower(w) + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// SearchTerminologyCodes looks codes up by how they start, e.g., ?prefix=E11.6,
+// with or without the dot.
+func (h *Handler) SearchTerminologyCodes(c *gin.Context) {
+	system, limit, ok := terminologyQuery(c)
+	if !ok {
+		return
+	}
+	prefix := terminology.SearchCode(c.Query("prefix"))
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prefix is required"})
+		return
+	}
+
+	codes, err := h.Repo.SearchTerminologyByPrefix(c.GetInt("userID"), system, prefix, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search codes", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, codes)
+}
+
+// SearchTerminology looks codes up by the words of their descriptions, e.g.,
+// ?q=type 2 diab.
+func (h *Handler) SearchTerminology(c *gin.Context) {
+	system, limit, ok := terminologyQuery(c)
+	if !ok {
+		return
+	}
+	tsquery := prefixTSQuery(c.Query("q"))
+	if tsquery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	codes, err := h.Repo.SearchTerminology(c.GetInt("userID"), system, tsquery, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search codes", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, codes)
+}
+
+func (h *Handler) GetTerminologyFavourites(c *gin.Context) {
+	codes, err := h.Repo.GetTerminologyFavourites(c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch favourite codes", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, codes)
+}
+
+func (h *Handler) AddTerminologyFavourite(c *gin.Context) {
+	code, err := h.Repo.AddTerminologyFavourite(c.GetInt("userID"), c.Param("system"), c.Param("code"))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Code not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add favourite code", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+func (h *Handler) RemoveTerminologyFavourite(c *gin.Context) {
+	err := h.Repo.RemoveTerminologyFavourite(c.GetInt("userID"), c.Param("system"), c.Param("code"))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Code is not a favourite"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove favourite code", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Favourite code removed"})
+}
+
+// codeSystem is the code system a client asked for, ICD-10-CM when it
+// didn't say.
+func codeSystem(system string) string {
+	if system = strings.ToLower(strings.TrimSpace(system)); system == "" {
+		return terminology.SystemICD10CM
+	}
+	return system
+}
+
+// lookupCodes checks each of codes is an active code of the system that can
+// be coded with, and returns them as the terminology writes them, keyed by
+// the reduced code. Until the system has been imported there is nothing to
+// check against, so no codes of it are accepted. On failure it writes the
+// response and returns false.
+func (h *Handler) lookupCodes(c *gin.Context, system string, codes []string) (map[string]models.TerminologyCode, bool) {
+	if len(codes) == 0 {
+		return nil, true
+	}
+
+	loaded, err := h.Repo.HasTerminology(system)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check codes", "err": err.Error()})
+		return nil, false
+	}
+	if !loaded {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Codes can't be checked until the " + system + " code system is imported"})
+		return nil, false
+	}
+
+	found, err := h.Repo.LookupTerminologyCodes(system, codes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check codes", "err": err.Error()})
+		return nil, false
+	}
+	var unknown, categories []string
+	for _, code := range codes {
+		t, ok := found[terminology.SearchCode(code)]
+		switch {
+		case !ok:
+			unknown = append(unknown, code)
+		case !t.Billable:
+			categories = append(categories, t.Code)
+		}
+	}
+	if unknown != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown codes: " + strings.Join(unknown, ", "), "codes": unknown})
+		return nil, false
+	}
+	if categories != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "These codes are categories; use a more specific code: " + strings.Join(categories, ", "), "codes": categories})
+		return nil, false
+	}
+	return found, true
+}
+
+// codeDiagnoses validates the diagnoses' codes, writing them as the
+// terminology does and filling in missing descriptions. On failure it writes
+// the response and returns false.
+func (h *Handler) codeDiagnoses(c *gin.Context, diagnoses []models.Diagnosis) bool {
+	codes := map[string][]string{}
+	for _, d := range diagnoses {
+		codes[d.System] = append(codes[d.System], d.Code)
+	}
+	found := map[string]map[string]models.TerminologyCode{}
+	for system, systemCodes := range codes {
+		var ok bool
+		if found[system], ok = h.lookupCodes(c, system, systemCodes); !ok {
+			return false
+		}
+	}
+	for i, d := range diagnoses {
+		if t, ok := found[d.System][terminology.SearchCode(d.Code)]; ok {
+			diagnoses[i].Code = t.Code
+			if d.Description == "" {
+				diagnoses[i].Description = t.Description
+			}
+		}
+	}
+	return true
+}
+
+// codeCondition validates the condition's code, if it has one, and writes
+// it as the terminology does. On failure it writes the response and returns
+// false.
+func (h *Handler) codeCondition(c *gin.Context, cond *models.PatientCondition) bool {
+	if cond.Code == "" {
+		return true
+	}
+	found, ok := h.lookupCodes(c, cond.CodeSystem, []string{cond.Code})
+	if !ok {
+		return false
+	}
+	if t, ok := found[terminology.SearchCode(cond.Code)]; ok {
+		cond.Code = t.Code
+	}
+	return true
+}
@@ -0,0 +1,294 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/filecheck"
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/internal/storage"
+)
+
+// uploadConfirmWindow is how long a doctor has to upload and confirm a file
+// after asking for an upload URL. Unconfirmed files are deleted afterwards.
+const uploadConfirmWindow = time.Hour
+
+// presigner returns the store's Presigner, or writes a 501 and returns false
+// when the configured backend can't presign.
+func (h *Handler) presigner(c *gin.Context) (storage.Presigner, bool) {
+	presigner, ok := h.Store.(storage.Presigner)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "The configured storage doesn't support presigned URLs"})
+		return nil, false
+	}
+	return presigner, true
+}
+
+// wantsPresignedURL reports whether a download asked for a link instead of
+// the file, with ?presigned=true.
+func wantsPresignedURL(c *gin.Context) bool {
+	presigned, _ := strconv.ParseBool(c.Query("presigned"))
+	return presigned
+}
+
+// sendPresignedDownload responds with a short-lived link to a stored file
+// the caller has already been authorized for.
+func (h *Handler) sendPresignedDownload(c *gin.Context, key, downloadName string) {
+	presigner, ok := h.presigner(c)
+	if !ok {
+		return
+	}
+
+	expiresAt := time.Now().Add(h.PresignTTL)
+	url, err := presigner.PresignGet(c.Request.Context(), key, h.PresignTTL, downloadName)
+	if err != nil {
+		log.Printf("Failed to presign download of %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create download link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"url": url, "expires_at": expiresAt})
+}
+
+// CreatePrescriptionUpload hands the doctor a presigned PUT URL to upload a
+// prescription file to directly. The file becomes a prescription once the
+// upload is confirmed.
+func (h *Handler) CreatePrescriptionUpload(c *gin.Context) {
+	doctorID := c.GetInt("userID")
+
+	var req struct {
+		PatientID   int    `json:"patient_id" binding:"required"`
+		ContentType string `json:"content_type" binding:"required"`
+		Size        int64  `json:"size" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+
+	fileType, ok := filecheck.Types[req.ContentType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported file type, expected a PDF, PNG, JPEG or HEIC file"})
+		return
+	}
+	if req.Size <= 0 || req.Size > h.MaxUploadSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is too large", "max_size": h.MaxUploadSize})
+		return
+	}
+
+	presigner, ok := h.presigner(c)
+	if !ok {
+		return
+	}
+	setAuditPatient(c, req.PatientID)
+
+	hasPatient, err := h.Repo.DoctorHasPatient(doctorID, req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify patient", "err": err.Error()})
+		return
+	}
+	if !hasPatient {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to prescribe for this patient"})
+		return
+	}
+
+	upload := models.PrescriptionUpload{
+		DoctorID:    doctorID,
+		PatientID:   req.PatientID,
+		FileName:    prescriptionFileName(req.PatientID, fileType.Ext),
+		ContentType: req.ContentType,
+		Size:        req.Size,
+		ExpiresAt:   time.Now().Add(uploadConfirmWindow),
+	}
+	urlExpiresAt := time.Now().Add(h.PresignTTL)
+	url, err := presigner.PresignPut(c.Request.Context(), upload.FileName, upload.ContentType, upload.Size, h.PresignTTL)
+	if err != nil {
+		log.Printf("Failed to presign upload of %s: %v", upload.FileName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload link"})
+		return
+	}
+
+	upload.ID, err = h.Repo.CreatePrescriptionUpload(upload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":     upload.ID,
+		"url":    url,
+		"method": http.MethodPut,
+		// The URL is signed for these headers, so the upload must send them
+		"headers": gin.H{
+			"Content-Type":   upload.ContentType,
+			"Content-Length": strconv.FormatInt(upload.Size, 10),
+		},
+		"expires_at": urlExpiresAt,
+		"confirm_by": upload.ExpiresAt,
+	})
+}
+
+// ConfirmPrescriptionUpload checks the uploaded file against what was
+// declared, signs it and records the prescription.
+func (h *Handler) ConfirmPrescriptionUpload(c *gin.Context) {
+	doctorID := c.GetInt("userID")
+	uploadID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload ID"})
+		return
+	}
+
+	var req struct {
+		Medication     string                    `json:"medication"`
+		Items          []models.PrescriptionItem `json:"items"`
+		Notes          string                    `json:"notes"`
+		Schedule       json.RawMessage           `json:"schedule"`
+		OverrideReason string                    `json:"override_reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "err": err.Error()})
+		return
+	}
+	if err := validatePrescriptionItems(req.Items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prescription items", "err": err.Error()})
+		return
+	}
+	medication, err := medicationSummary(req.Medication, req.Items)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prescription", "err": err.Error()})
+		return
+	}
+	schedule, err := parseDosingSchedule(string(req.Schedule))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dosing schedule", "err": err.Error()})
+		return
+	}
+
+	upload, err := h.Repo.GetPrescriptionUploadForDoctor(doctorID, uploadID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch upload", "err": err.Error()})
+		return
+	}
+	setAuditPatient(c, upload.PatientID)
+	if upload.PrescriptionID != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload has already been confirmed", "prescription_id": *upload.PrescriptionID})
+		return
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "Upload has expired, start a new one"})
+		return
+	}
+
+	file, ok := h.fetchUpload(c, upload)
+	if !ok {
+		return
+	}
+	defer removeTempFile(file)
+
+	fileType, scanStatus, ok := h.inspectFile(c, file, upload.Size)
+	if !ok {
+		return
+	}
+	if fileType.ContentType != upload.ContentType {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "File type doesn't match the declared type"})
+		return
+	}
+
+	pres := models.Prescription{
+		PatientID:  upload.PatientID,
+		DoctorID:   doctorID,
+		Medication: medication,
+		Notes:      req.Notes,
+		FileName:   upload.FileName,
+		Source:     "upload",
+		Items:      req.Items,
+		Schedule:   schedule,
+		ScanStatus: scanStatus,
+	}
+	if !h.checkPrescriptionSafety(c, &pres, req.OverrideReason, nil) {
+		return
+	}
+	if !assignVerificationCode(c, &pres) {
+		return
+	}
+	if !h.signPrescription(c, &pres, file) {
+		return
+	}
+
+	newID, err := h.Repo.CreateUploadedPrescription(upload.ID, pres)
+	if errors.Is(err, repository.ErrUploadConfirmed) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload has already been confirmed"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create prescription for upload %d: %v", upload.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create prescription record", "err": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": newID, "filename": pres.FileName, "verification_code": pres.VerificationCode, "warnings": pres.Warnings})
+}
+
+// fetchUpload copies an uploaded file from storage to a temporary file, so it
+// can be inspected and hashed, after checking it has the declared size.
+// Rejected files are left for the cleanup job, and can be replaced by
+// uploading again while the URL is valid.
+func (h *Handler) fetchUpload(c *gin.Context, upload models.PrescriptionUpload) (*os.File, bool) {
+	out, err := h.Store.Get(c.Request.Context(), upload.FileName)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusConflict, gin.H{"error": "File hasn't been uploaded yet"})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Failed to get uploaded object %s: %v", upload.FileName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file"})
+		return nil, false
+	}
+	defer out.Body.Close()
+
+	if out.Size != upload.Size {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "File size doesn't match the declared size"})
+		return nil, false
+	}
+
+	file, err := os.CreateTemp("", "prescription-upload-*")
+	if err != nil {
+		log.Printf("Failed to create temporary file: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file"})
+		return nil, false
+	}
+	n, err := io.Copy(file, io.LimitReader(out.Body, upload.Size+1))
+	if err == nil && n != upload.Size {
+		err = fmt.Errorf("read %d bytes, expected %d", n, upload.Size)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		removeTempFile(file)
+		log.Printf("Failed to copy uploaded object %s: %v", upload.FileName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file"})
+		return nil, false
+	}
+	return file, true
+}
+
+func removeTempFile(file *os.File) {
+	file.Close()
+	os.Remove(file.Name())
+}
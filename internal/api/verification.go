@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/signing"
+)
+
+// Prescription verification statuses.
+const (
+	verificationActive  = "active"
+	verificationRevoked = "revoked"
+	verificationExpired = "expired"
+	// verificationSuperseded means the prescription was amended and a newer
+	// version should be presented instead.
+	verificationSuperseded = "superseded"
+	// verificationInvalid means the signature or file no longer matches what
+	// was issued.
+	verificationInvalid = "invalid"
+)
+
+// signPrescription hashes the file, stamps the expiry and signs pres. The
+// body is rewound afterwards so it can still be uploaded.
+func (h *Handler) signPrescription(c *gin.Context, pres *models.Prescription, body io.ReadSeeker) bool {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file", "err": err.Error()})
+		return false
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file", "err": err.Error()})
+		return false
+	}
+
+	pres.ContentHash = hex.EncodeToString(hash.Sum(nil))
+	h.stampSignature(pres)
+	return true
+}
+
+// stampSignature stamps the expiry and signs pres, whose content hash must
+// already be set.
+func (h *Handler) stampSignature(pres *models.Prescription) {
+	if h.PrescriptionValidity > 0 {
+		expiresAt := time.Now().Add(h.PrescriptionValidity).Truncate(time.Second)
+		pres.ExpiresAt = &expiresAt
+	}
+	pres.Signature = h.Signer.Sign(signingPayload(*pres))
+	pres.SigningKeyID = h.Signer.KeyID
+}
+
+func signingPayload(pres models.Prescription) signing.Payload {
+	return signing.Payload{
+		VerificationCode: pres.VerificationCode,
+		DoctorID:         pres.DoctorID,
+		PatientID:        pres.PatientID,
+		ContentHash:      pres.ContentHash,
+		ExpiresAt:        pres.ExpiresAt,
+	}
+}
+
+// normalizeVerificationCode undoes the spacing and case changes people make
+// when typing a code off a printout.
+func normalizeVerificationCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+// VerifyPrescription is public: pharmacies call it with the code printed on a
+// prescription. It re-checks the signature, which covers the file's content
+// hash, and reports only the issuing doctor and status, never patient
+// details. The stored file itself isn't fetched, so anonymous callers can't
+// drive storage reads; VerifyPrescriptionFile does that for admins.
+func (h *Handler) VerifyPrescription(c *gin.Context) {
+	h.verifyPrescription(c, false)
+}
+
+// VerifyPrescriptionFile is VerifyPrescription for admins, additionally
+// re-hashing the stored file to check it still matches what was signed.
+func (h *Handler) VerifyPrescriptionFile(c *gin.Context) {
+	if _, ok := h.globalAdmin(c); !ok {
+		return
+	}
+	h.verifyPrescription(c, true)
+}
+
+func (h *Handler) verifyPrescription(c *gin.Context, checkFile bool) {
+	code := normalizeVerificationCode(c.Param("code"))
+
+	pres, doctor, err := h.Repo.GetPrescriptionByVerificationCode(code)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"valid": false, "error": "Prescription not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify prescription"})
+		return
+	}
+
+	fileMatches := true
+	if checkFile && pres.Signature != "" {
+		fileHash, err := h.hashStoredFile(c.Request.Context(), pres.FileName)
+		if err != nil {
+			log.Printf("Failed to hash %s for verification: %v", pres.FileName, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify prescription"})
+			return
+		}
+		if fileHash != pres.ContentHash {
+			log.Printf("Prescription %d failed verification: stored file hash doesn't match", pres.ID)
+			fileMatches = false
+		}
+	}
+
+	status := verificationActive
+	switch {
+	case pres.Signature == "" || !h.Signer.Verify(signingPayload(pres), pres.SigningKeyID, pres.Signature):
+		status = verificationInvalid
+	case !fileMatches:
+		status = verificationInvalid
+	case pres.ScanStatus == scanInfected:
+		status = verificationInvalid
+	case pres.RevokedAt != nil:
+		status = verificationRevoked
+	case pres.Status == "superseded":
+		status = verificationSuperseded
+	case pres.ExpiresAt != nil && time.Now().After(*pres.ExpiresAt):
+		status = verificationExpired
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":             status == verificationActive,
+		"status":            status,
+		"verification_code": pres.VerificationCode,
+		"issued_at":         pres.CreatedAt,
+		"expires_at":        pres.ExpiresAt,
+		"revoked_at":        pres.RevokedAt,
+		"doctor": gin.H{
+			"name":      "Dr. " + doctor.FirstName + " " + doctor.LastName,
+			"specialty": doctor.Specialty,
+		},
+		// Lets the holder of the file compare it byte for byte
+		"content_sha256": pres.ContentHash,
+		"key_id":         pres.SigningKeyID,
+	})
+}
+
+func (h *Handler) hashStoredFile(ctx context.Context, key string) (string, error) {
+	out, err := h.Store.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer out.Body.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, out.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// setVerificationHeaders lets downloaders check a file against
+// /api/verify/:code without parsing it.
+func setVerificationHeaders(c *gin.Context, pres models.Prescription) {
+	if pres.VerificationCode != "" {
+		c.Header("X-Prescription-Verification-Code", pres.VerificationCode)
+	}
+	if pres.ContentHash != "" {
+		c.Header("X-Content-SHA256", pres.ContentHash)
+	}
+}
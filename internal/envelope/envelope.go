@@ -0,0 +1,53 @@
+// Package envelope implements envelope encryption of stored files. Each file
+// is encrypted with its own random data key, and only a copy of the data key
+// wrapped (encrypted) by a master key is kept. Master keys live with a
+// KeyProvider, such as a KMS, and rotating one only means re-wrapping data
+// keys rather than re-encrypting files.
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// DataKeySize is the length of data keys, for AES-256.
+const DataKeySize = 32
+
+// ErrUnknownKey is returned when unwrapping with a master key the provider
+// doesn't have.
+var ErrUnknownKey = errors.New("unknown master key")
+
+// KeyProvider holds the master keys data keys are wrapped with.
+type KeyProvider interface {
+	// CurrentKeyID is the master key new data keys are wrapped with.
+	CurrentKeyID() string
+	// Wrap encrypts a data key with the current master key and returns that
+	// key's ID along with the result.
+	Wrap(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+	// Unwrap decrypts a data key wrapped by the master key keyID.
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// NewDataKey returns a random data key.
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, DataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != DataKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", DataKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,86 @@
+package envelope
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LocalKeyProvider wraps data keys with AES-256-GCM master keys read from a
+// file. It is meant for development; production deployments should keep
+// master keys in a KMS.
+//
+// The file is JSON naming the current key and holding every key still in
+// use, base64 encoded:
+//
+//	{"current": "2026-10", "keys": {"2026-10": "...", "2026-01": "..."}}
+//
+// To rotate, add a key, make it current and restart; the old key can be
+// removed once every data key has been re-wrapped.
+type LocalKeyProvider struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// LoadLocalKeyProvider reads master keys from path.
+func LoadLocalKeyProvider(path string) (*LocalKeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Current string            `json:"current"`
+		Keys    map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	p := &LocalKeyProvider{current: file.Current, keys: make(map[string]cipher.AEAD, len(file.Keys))}
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", id, err)
+		}
+		if p.keys[id], err = newGCM(key); err != nil {
+			return nil, fmt.Errorf("key %s: %w", id, err)
+		}
+	}
+	if _, ok := p.keys[p.current]; !ok {
+		return nil, fmt.Errorf("current key %q is not in %s", p.current, path)
+	}
+	return p, nil
+}
+
+func (p *LocalKeyProvider) CurrentKeyID() string {
+	return p.current
+}
+
+// Wrap seals the data key with the current master key, binding the key ID as
+// associated data. The nonce is prepended to the result.
+func (p *LocalKeyProvider) Wrap(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	aead := p.keys[p.current]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(dataKey)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return p.current, aead.Seal(nonce, nonce, dataKey, []byte(p.current)), nil
+}
+
+func (p *LocalKeyProvider) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, []byte(keyID))
+}
@@ -0,0 +1,107 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeKeys(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func encodedKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, DataKeySize))
+}
+
+func TestLoadLocalKeyProvider(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"invalid JSON", `{"current": `, "parse"},
+		{"bad base64", `{"current": "a", "keys": {"a": "not base64!"}}`, "key a"},
+		{"short key", `{"current": "a", "keys": {"a": "` + base64.StdEncoding.EncodeToString([]byte("short")) + `"}}`, "key a: key must be 32 bytes"},
+		{"missing current", `{"current": "b", "keys": {"a": "` + encodedKey(1) + `"}}`, `current key "b" is not in`},
+		{"no keys", `{"current": "a"}`, `current key "a" is not in`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadLocalKeyProvider(writeKeys(t, tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadLocalKeyProvider error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadLocalKeyProvider(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadLocalKeyProvider of a missing file succeeded")
+	}
+}
+
+func TestLocalWrapUnwrap(t *testing.T) {
+	ctx := context.Background()
+	old, err := LoadLocalKeyProvider(writeKeys(t, `{"current": "2026-01", "keys": {"2026-01": "`+encodedKey(1)+`"}}`))
+	if err != nil {
+		t.Fatalf("LoadLocalKeyProvider: %v", err)
+	}
+	dataKey := testKey(t)
+
+	keyID, wrapped, err := old.Wrap(ctx, dataKey)
+	if err != nil || keyID != "2026-01" || old.CurrentKeyID() != "2026-01" {
+		t.Fatalf("Wrap = %q, %v", keyID, err)
+	}
+	if bytes.Contains(wrapped, dataKey) {
+		t.Error("wrapped key contains the data key")
+	}
+	if _, again, _ := old.Wrap(ctx, dataKey); bytes.Equal(again, wrapped) {
+		t.Error("wrapping twice gave the same result")
+	}
+	if got, err := old.Unwrap(ctx, keyID, wrapped); err != nil || !bytes.Equal(got, dataKey) {
+		t.Errorf("Unwrap = %x, %v; want %x", got, err, dataKey)
+	}
+
+	// After rotation the old key still unwraps, and new keys use the new one
+	rotated, err := LoadLocalKeyProvider(writeKeys(t,
+		`{"current": "2026-10", "keys": {"2026-10": "`+encodedKey(2)+`", "2026-01": "`+encodedKey(1)+`"}}`))
+	if err != nil {
+		t.Fatalf("LoadLocalKeyProvider: %v", err)
+	}
+	if got, err := rotated.Unwrap(ctx, keyID, wrapped); err != nil || !bytes.Equal(got, dataKey) {
+		t.Errorf("Unwrap after rotation = %x, %v; want %x", got, err, dataKey)
+	}
+	newID, rewrapped, err := rotated.Wrap(ctx, dataKey)
+	if err != nil || newID != "2026-10" {
+		t.Fatalf("Wrap after rotation = %q, %v", newID, err)
+	}
+	if got, err := rotated.Unwrap(ctx, newID, rewrapped); err != nil || !bytes.Equal(got, dataKey) {
+		t.Errorf("Unwrap of a re-wrapped key = %x, %v", got, err)
+	}
+
+	// The key ID is bound to the wrapped key
+	if _, err := rotated.Unwrap(ctx, newID, wrapped); err == nil {
+		t.Error("Unwrap under the wrong key ID succeeded")
+	}
+	if _, err := old.Unwrap(ctx, newID, rewrapped); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Unwrap with a removed key = %v, want ErrUnknownKey", err)
+	}
+	if _, err := old.Unwrap(ctx, keyID, wrapped[:5]); err == nil {
+		t.Error("Unwrap of a truncated key succeeded")
+	}
+	tampered := append([]byte(nil), wrapped...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := old.Unwrap(ctx, keyID, tampered); err == nil {
+		t.Error("Unwrap of a tampered key succeeded")
+	}
+}
@@ -0,0 +1,162 @@
+package envelope
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Encrypted files start with a format marker, followed by the plaintext in
+// chunks of up to chunkSize bytes, each sealed with AES-GCM. Chunk nonces
+// are the chunk's index plus a flag on the final chunk, so reordered,
+// dropped or truncated chunks fail to decrypt. Data keys are never reused,
+// so the counter nonces can't repeat under a key.
+const (
+	magic     = "VWE1"
+	chunkSize = 64 << 10
+	overhead  = 16 // GCM tag
+)
+
+// ErrCorrupt is returned when encrypted data has been truncated or altered.
+var ErrCorrupt = errors.New("encrypted data is corrupt")
+
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// readChunk fills buf from r and reports whether it was the last chunk.
+func readChunk(r *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, true, nil
+	}
+	if err != nil {
+		return n, false, err
+	}
+	if _, err := r.Peek(1); errors.Is(err, io.EOF) {
+		return n, true, nil
+	} else if err != nil {
+		return n, false, err
+	}
+	return n, false, nil
+}
+
+// Encrypt writes src to dst encrypted with dataKey.
+func Encrypt(dst io.Writer, src io.Reader, dataKey []byte) error {
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(dst, magic); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(src)
+	buf := make([]byte, chunkSize, chunkSize+overhead)
+	for counter := uint64(0); ; counter++ {
+		n, last, err := readChunk(r, buf[:chunkSize])
+		if err != nil {
+			return err
+		}
+		if _, err := dst.Write(aead.Seal(buf[:0], chunkNonce(counter, last), buf[:n], nil)); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Decrypt returns a reader of src decrypted with dataKey. Reads fail with
+// ErrCorrupt if the data doesn't authenticate.
+func Decrypt(src io.Reader, dataKey []byte) (io.Reader, error) {
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(src)
+	header := make([]byte, len(magic))
+	_, err = io.ReadFull(r, header)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || string(header) != magic {
+		return nil, ErrCorrupt
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{aead: aead, src: r, buf: make([]byte, chunkSize+overhead)}, nil
+}
+
+type decryptReader struct {
+	aead    cipher.AEAD
+	src     *bufio.Reader
+	buf     []byte
+	counter uint64
+	out     []byte // decrypted bytes not yet read
+	done    bool
+	err     error
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.done {
+			return 0, io.EOF
+		}
+		d.err = d.next()
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+func (d *decryptReader) next() error {
+	n, last, err := readChunk(d.src, d.buf)
+	if err != nil {
+		return err
+	}
+	if n < overhead {
+		return ErrCorrupt
+	}
+	plain, err := d.aead.Open(d.buf[:0], chunkNonce(d.counter, last), d.buf[:n], nil)
+	if err != nil {
+		return ErrCorrupt
+	}
+	d.out = plain
+	d.counter++
+	d.done = last
+	return nil
+}
+
+// EncryptedSize is the size of a file of n bytes once encrypted.
+func EncryptedSize(n int64) int64 {
+	chunks := (n + chunkSize - 1) / chunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return int64(len(magic)) + n + chunks*overhead
+}
+
+// DecryptedSize is the size of the plaintext of an encrypted file of n
+// bytes.
+func DecryptedSize(n int64) int64 {
+	n -= int64(len(magic))
+	if n < overhead {
+		return 0
+	}
+	full, rest := n/(chunkSize+overhead), n%(chunkSize+overhead)
+	size := full * chunkSize
+	if rest > overhead {
+		size += rest - overhead
+	}
+	return size
+}
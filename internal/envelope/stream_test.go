@@ -0,0 +1,155 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := NewDataKey()
+	if err != nil {
+		t.Fatalf("NewDataKey: %v", err)
+	}
+	return key
+}
+
+func encrypt(t *testing.T, plain, key []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Encrypt(&buf, bytes.NewReader(plain), key); err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func decrypt(sealed, key []byte) ([]byte, error) {
+	r, err := Decrypt(bytes.NewReader(sealed), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestRoundTrip(t *testing.T) {
+	key := testKey(t)
+	for _, size := range []int{0, 1, 1000, chunkSize - 1, chunkSize, chunkSize + 1, 3 * chunkSize, 3*chunkSize + 12345} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		sealed := encrypt(t, plain, key)
+		if got := int64(len(sealed)); got != EncryptedSize(int64(size)) {
+			t.Errorf("%d bytes: encrypted to %d, EncryptedSize says %d", size, got, EncryptedSize(int64(size)))
+		}
+		if got := DecryptedSize(int64(len(sealed))); got != int64(size) {
+			t.Errorf("%d bytes: DecryptedSize = %d", size, got)
+		}
+
+		got, err := decrypt(sealed, key)
+		if err != nil {
+			t.Errorf("%d bytes: decrypt: %v", size, err)
+		} else if !bytes.Equal(got, plain) {
+			t.Errorf("%d bytes: decrypted data differs", size)
+		}
+	}
+}
+
+func TestDecryptSmallReads(t *testing.T) {
+	key := testKey(t)
+	plain := bytes.Repeat([]byte("vital-watch "), chunkSize/4)
+	r, err := Decrypt(bytes.NewReader(encrypt(t, plain, key)), key)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+
+	var got []byte
+	p := make([]byte, 7)
+	for {
+		n, err := r.Read(p)
+		got = append(got, p[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+	}
+	if !bytes.Equal(got, plain) {
+		t.Error("decrypted data differs")
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	key := testKey(t)
+	plain := make([]byte, 3*chunkSize+100)
+	rand.Read(plain)
+	sealed := encrypt(t, plain, key)
+
+	chunk := chunkSize + overhead
+	first := len(magic)
+	chunks := func(order ...int) []byte {
+		out := []byte(magic)
+		for _, i := range order {
+			start := first + i*chunk
+			end := min(start+chunk, len(sealed))
+			out = append(out, sealed[start:end]...)
+		}
+		return out
+	}
+	flip := func(i int) []byte {
+		out := append([]byte(nil), sealed...)
+		out[i] ^= 1
+		return out
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"no magic", sealed[first:]},
+		{"wrong magic", append([]byte("VWE0"), sealed[first:]...)},
+		{"header only", []byte(magic)},
+		{"flipped bit in the first chunk", flip(first + 10)},
+		{"flipped bit in the last tag", flip(len(sealed) - 1)},
+		{"truncated mid chunk", sealed[:len(sealed)-50]},
+		// Cut at a chunk boundary, so the last chunk left wasn't sealed as last
+		{"last chunk dropped", chunks(0, 1, 2)},
+		{"middle chunk dropped", chunks(0, 2, 3)},
+		{"chunks swapped", chunks(1, 0, 2, 3)},
+		{"chunk repeated", chunks(0, 0, 1, 2, 3)},
+		{"trailing data", append(append([]byte(nil), sealed...), 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decrypt(tt.data, key)
+			if !errors.Is(err, ErrCorrupt) {
+				t.Errorf("decrypt = %d bytes, %v; want ErrCorrupt", len(got), err)
+			}
+		})
+	}
+
+	if got, err := decrypt(sealed, testKey(t)); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("decrypt with the wrong key = %d bytes, %v; want ErrCorrupt", len(got), err)
+	}
+}
+
+func TestBadKeySize(t *testing.T) {
+	short := make([]byte, 16)
+	if err := Encrypt(io.Discard, bytes.NewReader([]byte("x")), short); err == nil {
+		t.Error("Encrypt accepted a 16 byte key")
+	}
+	if _, err := Decrypt(bytes.NewReader([]byte(magic)), short); err == nil {
+		t.Error("Decrypt accepted a 16 byte key")
+	}
+}
+
+func TestNewDataKey(t *testing.T) {
+	a, b := testKey(t), testKey(t)
+	if len(a) != DataKeySize || bytes.Equal(a, b) {
+		t.Errorf("data keys %x and %x", a, b)
+	}
+}
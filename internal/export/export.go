@@ -0,0 +1,235 @@
+// Package export writes a patient's data out as a ZIP archive: a JSON file
+// per kind of record, the stored prescription, document and lab report files,
+// and an HTML summary a person can read without any tooling.
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/storage"
+)
+
+// Data is everything held about a patient that goes in their export.
+type Data struct {
+	GeneratedAt   time.Time
+	Patient       models.Patient
+	Appointments  []models.Appointment
+	Notes         []models.EncounterNote
+	LabOrders     []models.LabOrder
+	LabReports    []models.LabReport
+	Prescriptions []models.Prescription
+	Allergies     []models.PatientAllergy
+	Conditions    []models.PatientCondition
+	Medications   []models.PatientMedication
+	Vitals        []models.VitalReading
+	Alerts        []models.VitalAlert
+	Documents     []models.PatientDocument
+	Consents      []models.PatientConsent
+	Messages      []models.Notification
+}
+
+// OmittedFile is a stored file left out of the archive, and why.
+type OmittedFile struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Write writes the archive of d to w, reading the prescription, document and
+// lab report files from store. Quarantined and missing files are left out and
+// listed in omitted_files.json and the summary rather than failing the export.
+func Write(ctx context.Context, w io.Writer, store storage.Store, d Data) error {
+	zw := zip.NewWriter(w)
+
+	records := []struct {
+		name  string
+		value any
+	}{
+		{"profile.json", d.Patient},
+		{"appointments.json", d.Appointments},
+		{"notes.json", d.Notes},
+		{"labs.json", d.LabOrders},
+		{"prescriptions.json", d.Prescriptions},
+		{"allergies.json", d.Allergies},
+		{"conditions.json", d.Conditions},
+		{"medications.json", d.Medications},
+		{"vitals.json", d.Vitals},
+		{"alerts.json", d.Alerts},
+		{"documents.json", d.Documents},
+		{"consents.json", d.Consents},
+		{"messages.json", d.Messages},
+	}
+	for _, r := range records {
+		if err := writeJSON(zw, r.name, d.GeneratedAt, r.value); err != nil {
+			return err
+		}
+	}
+
+	var omitted []OmittedFile
+	for _, p := range d.Prescriptions {
+		if p.FileName == "" {
+			continue
+		}
+		name := "files/prescriptions/" + path.Base(p.FileName)
+		if reason, err := copyFile(ctx, zw, store, p.FileName, name, p.ScanStatus, d.GeneratedAt); err != nil {
+			return err
+		} else if reason != "" {
+			omitted = append(omitted, OmittedFile{Name: name, Reason: reason})
+		}
+	}
+	for _, doc := range d.Documents {
+		name := "files/documents/" + documentFileName(doc)
+		if reason, err := copyFile(ctx, zw, store, doc.FileName, name, doc.ScanStatus, d.GeneratedAt); err != nil {
+			return err
+		} else if reason != "" {
+			omitted = append(omitted, OmittedFile{Name: name, Reason: reason})
+		}
+	}
+	for _, rep := range d.LabReports {
+		name := "files/lab_reports/" + labReportFileName(rep)
+		if reason, err := copyFile(ctx, zw, store, rep.FileName, name, rep.ScanStatus, d.GeneratedAt); err != nil {
+			return err
+		} else if reason != "" {
+			omitted = append(omitted, OmittedFile{Name: name, Reason: reason})
+		}
+	}
+	if omitted == nil {
+		omitted = []OmittedFile{}
+	}
+	if err := writeJSON(zw, "omitted_files.json", d.GeneratedAt, omitted); err != nil {
+		return err
+	}
+
+	f, err := create(zw, "summary.html", d.GeneratedAt)
+	if err != nil {
+		return err
+	}
+	if err := summaryTemplate.Execute(f, newSummary(d, omitted)); err != nil {
+		return fmt.Errorf("rendering summary: %w", err)
+	}
+
+	return zw.Close()
+}
+
+func create(zw *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+}
+
+func writeJSON(zw *zip.Writer, name string, modified time.Time, value any) error {
+	f, err := create(zw, name, modified)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// copyFile copies the stored file at key into the archive as name. It
+// returns why the file was left out instead, if it was.
+func copyFile(ctx context.Context, zw *zip.Writer, store storage.Store, key, name, scanStatus string, modified time.Time) (string, error) {
+	switch scanStatus {
+	case "pending":
+		return "The file is waiting for a malware scan", nil
+	case "infected":
+		return "The file failed a malware scan", nil
+	}
+
+	out, err := store.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "The file is no longer in storage", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", key, err)
+	}
+	defer out.Body.Close()
+
+	f, err := create(zw, name, modified)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, out.Body); err != nil {
+		return "", fmt.Errorf("copying %s: %w", key, err)
+	}
+	return "", nil
+}
+
+// documentFileName names a document's file after what the patient uploaded,
+// prefixed with its ID since those needn't be unique.
+func documentFileName(doc models.PatientDocument) string {
+	return archiveFileName(doc.ID, doc.OriginalName, doc.FileName)
+}
+
+// labReportFileName names a lab report's file like documentFileName, with
+// its order's ID as well.
+func labReportFileName(rep models.LabReport) string {
+	return "order-" + strconv.Itoa(rep.OrderID) + "-" + archiveFileName(rep.ID, rep.OriginalName, rep.FileName)
+}
+
+// archiveFileName is the original name of an uploaded file, or its storage
+// key without one, made safe for the archive and prefixed with id.
+func archiveFileName(id int, originalName, key string) string {
+	name := originalName
+	if name == "" {
+		name = path.Base(key)
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	return strconv.Itoa(id) + "-" + name
+}
+
+// vitalSummary is how many readings of a metric there are and the latest.
+type vitalSummary struct {
+	Metric string
+	Count  int
+	Latest models.VitalReading
+}
+
+type summary struct {
+	Data
+	VitalSummaries []vitalSummary
+	Omitted        []OmittedFile
+}
+
+func newSummary(d Data, omitted []OmittedFile) summary {
+	byMetric := map[string]*vitalSummary{}
+	for _, v := range d.Vitals {
+		s, ok := byMetric[v.Metric]
+		if !ok {
+			s = &vitalSummary{Metric: v.Metric}
+			byMetric[v.Metric] = s
+		}
+		s.Count++
+		if v.RecordedAt.After(s.Latest.RecordedAt) {
+			s.Latest = v
+		}
+	}
+	vitals := make([]vitalSummary, 0, len(byMetric))
+	for _, s := range byMetric {
+		vitals = append(vitals, *s)
+	}
+	sort.Slice(vitals, func(i, j int) bool { return vitals[i].Metric < vitals[j].Metric })
+
+	return summary{Data: d, VitalSummaries: vitals, Omitted: omitted}
+}
@@ -0,0 +1,115 @@
+package export
+
+import (
+	"html/template"
+	"time"
+)
+
+var summaryTemplate = template.Must(template.New("summary").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("2 Jan 2006 15:04 MST") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Health record of {{.Patient.FirstName}} {{.Patient.LastName}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+</style>
+</head>
+<body>
+<h1>Health record of {{.Patient.FirstName}} {{.Patient.LastName}}</h1>
+<p>Exported {{date .GeneratedAt}}. The JSON files in this archive hold the complete records; this page summarises them.</p>
+
+<h2>Profile</h2>
+<table>
+<tr><th>Name</th><td>{{.Patient.FirstName}} {{.Patient.LastName}}</td></tr>
+<tr><th>Email</th><td>{{.Patient.Email}}</td></tr>
+<tr><th>Registered</th><td>{{date .Patient.CreatedAt}}</td></tr>
+</table>
+
+<h2>Appointments ({{len .Appointments}})</h2>
+{{if .Appointments}}<table>
+<tr><th>Start</th><th>Doctor</th><th>Type</th><th>Status</th></tr>
+{{range .Appointments}}<tr><td>{{date .StartTime}}</td><td>{{.DoctorName}}</td><td>{{.Type}}</td><td>{{.Status}}</td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+
+<h2>Encounter notes ({{len .Notes}})</h2>
+{{if .Notes}}<table>
+<tr><th>Signed</th><th>Doctor</th><th>Assessment</th><th>Plan</th><th>Diagnoses</th></tr>
+{{range .Notes}}<tr><td>{{date .SignedAt}}{{if .AmendedAt}}<br>amended {{date .AmendedAt}}{{end}}</td><td>{{.DoctorName}}</td><td>{{.Assessment}}</td><td>{{.Plan}}</td><td>{{range .Diagnoses}}{{.Code}} {{.Description}}<br>{{end}}</td></tr>
+{{end}}</table>
+<p>The full notes and their amendments are in notes.json.</p>{{else}}<p>None.</p>{{end}}
+
+<h2>Lab orders ({{len .LabOrders}})</h2>
+{{if .LabOrders}}<table>
+<tr><th>Ordered</th><th>Doctor</th><th>Tests</th><th>Status</th><th>Results</th></tr>
+{{range .LabOrders}}<tr><td>{{date .CreatedAt}}</td><td>{{.DoctorName}}</td><td>{{range .Panels}}{{.Name}}<br>{{end}}</td><td>{{.Status}}</td><td>{{range .Results}}{{.AnalyteName}}: {{with .Value}}{{.}}{{else}}{{.ValueText}}{{end}} {{.Unit}}{{if .RefText}} (range {{.RefText}}){{end}}{{if and .Flag (ne .Flag "N")}} [{{.Flag}}]{{end}}{{if ne .Status "final"}} {{.Status}}{{end}}<br>{{end}}</td></tr>
+{{end}}</table>
+<p>Every result is in labs.json, and the reports the labs sent are in files/lab_reports.</p>{{else}}<p>None.</p>{{end}}
+
+<h2>Prescriptions ({{len .Prescriptions}})</h2>
+{{if .Prescriptions}}<table>
+<tr><th>Issued</th><th>Medication</th><th>Status</th><th>Notes</th><th>File</th></tr>
+{{range .Prescriptions}}<tr><td>{{date .CreatedAt}}</td><td>{{if .Items}}{{range .Items}}{{.DrugName}} {{.Strength}} {{.Dose}} {{.Frequency}}<br>{{end}}{{else}}{{.Medication}}{{end}}</td><td>{{.Status}}</td><td>{{.Notes}}</td><td>{{.FileName}}</td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+
+<h2>Allergies ({{len .Allergies}})</h2>
+{{if .Allergies}}<table>
+<tr><th>Substance</th><th>Reaction</th><th>Severity</th></tr>
+{{range .Allergies}}<tr><td>{{.Substance}}</td><td>{{.Reaction}}</td><td>{{.Severity}}</td></tr>
+{{end}}</table>{{else}}<p>None recorded.</p>{{end}}
+
+<h2>Conditions ({{len .Conditions}})</h2>
+{{if .Conditions}}<table>
+<tr><th>Condition</th><th>Status</th><th>Onset</th><th>Resolved</th><th>Notes</th></tr>
+{{range .Conditions}}<tr><td>{{.Name}}{{if .Code}} ({{.Code}}){{end}}</td><td>{{.Status}}</td><td>{{with .OnsetDate}}{{.}}{{end}}</td><td>{{with .ResolvedDate}}{{.}}{{end}}</td><td>{{.Notes}}</td></tr>
+{{end}}</table>{{else}}<p>None recorded.</p>{{end}}
+
+<h2>Other medications ({{len .Medications}})</h2>
+{{if .Medications}}<table>
+<tr><th>Medication</th><th>Dose</th><th>Frequency</th><th>Started</th><th>Stopped</th></tr>
+{{range .Medications}}<tr><td>{{.Name}}</td><td>{{.Dose}}</td><td>{{.Frequency}}</td><td>{{with .StartedOn}}{{.}}{{end}}</td><td>{{with .StoppedOn}}{{.}}{{end}}</td></tr>
+{{end}}</table>{{else}}<p>None recorded.</p>{{end}}
+
+<h2>Vitals ({{len .Vitals}} readings)</h2>
+{{if .VitalSummaries}}<table>
+<tr><th>Metric</th><th>Readings</th><th>Latest</th><th>Recorded</th></tr>
+{{range .VitalSummaries}}<tr><td>{{.Metric}}</td><td>{{.Count}}</td><td>{{.Latest.Value}} {{.Latest.Unit}}</td><td>{{date .Latest.RecordedAt}}</td></tr>
+{{end}}</table>
+<p>Every reading is in vitals.json.</p>{{else}}<p>None.</p>{{end}}
+
+<h2>Alerts ({{len .Alerts}})</h2>
+{{if .Alerts}}<table>
+<tr><th>Raised</th><th>Metric</th><th>Severity</th><th>Value</th></tr>
+{{range .Alerts}}<tr><td>{{date .CreatedAt}}</td><td>{{.Metric}}</td><td>{{.Severity}}</td><td>{{.ObservedValue}}</td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+
+<h2>Documents ({{len .Documents}})</h2>
+{{if .Documents}}<table>
+<tr><th>Title</th><th>Category</th><th>Uploaded</th><th>File</th></tr>
+{{range .Documents}}<tr><td>{{.Title}}</td><td>{{.Category}}</td><td>{{date .CreatedAt}}</td><td>{{.OriginalName}}</td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+
+<h2>Consents ({{len .Consents}})</h2>
+{{if .Consents}}<table>
+<tr><th>Granted to</th><th>Category</th><th>Granted</th><th>Revoked</th></tr>
+{{range .Consents}}<tr><td>{{.GranteeName}}</td><td>{{.Category}}</td><td>{{date .GrantedAt}}</td><td>{{if .RevokedAt}}{{date .RevokedAt}}{{end}}</td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+
+<h2>Messages ({{len .Messages}})</h2>
+{{if .Messages}}<table>
+<tr><th>Sent</th><th>Title</th><th>Message</th></tr>
+{{range .Messages}}<tr><td>{{date .CreatedAt}}</td><td>{{.Title}}</td><td>{{.Body}}</td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+
+{{if .Omitted}}<h2>Files left out</h2>
+<table>
+<tr><th>File</th><th>Reason</th></tr>
+{{range .Omitted}}<tr><td>{{.Name}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,58 @@
+// Package filecheck inspects uploaded files before they are stored: it
+// identifies them by their magic bytes rather than the name or Content-Type
+// the client sent, sanity checks PDFs, and runs a pluggable malware scanner.
+package filecheck
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrUnsupportedType is returned for files that aren't one of Types.
+var ErrUnsupportedType = errors.New("unsupported file type")
+
+// Type is an accepted file type.
+type Type struct {
+	ContentType string
+	Ext         string
+}
+
+var (
+	PDF  = Type{"application/pdf", ".pdf"}
+	PNG  = Type{"image/png", ".png"}
+	JPEG = Type{"image/jpeg", ".jpg"}
+	HEIC = Type{"image/heic", ".heic"}
+)
+
+// Types are the accepted file types, by content type.
+var Types = map[string]Type{
+	PDF.ContentType:  PDF,
+	PNG.ContentType:  PNG,
+	JPEG.ContentType: JPEG,
+	HEIC.ContentType: HEIC,
+}
+
+// SniffLen is how much of the start of a file Sniff needs.
+const SniffLen = 32
+
+// heicBrands are the ISO base media file brands used by HEIC/HEIF images.
+var heicBrands = map[string]bool{
+	"heic": true, "heix": true, "heim": true, "heis": true,
+	"hevc": true, "hevx": true, "hevm": true, "hevs": true,
+	"mif1": true, "msf1": true,
+}
+
+// Sniff identifies a file from its first SniffLen bytes.
+func Sniff(head []byte) (Type, error) {
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return PDF, nil
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return PNG, nil
+	case bytes.HasPrefix(head, []byte("\xff\xd8\xff")):
+		return JPEG, nil
+	case len(head) >= 12 && string(head[4:8]) == "ftyp" && heicBrands[string(head[8:12])]:
+		return HEIC, nil
+	}
+	return Type{}, ErrUnsupportedType
+}
@@ -0,0 +1,55 @@
+package filecheck
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want Type
+	}{
+		{"PDF", "%PDF-1.7\n%\xe2\xe3\xcf\xd3", PDF},
+		{"PNG", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", PNG},
+		{"JPEG", "\xff\xd8\xff\xe0\x00\x10JFIF", JPEG},
+		{"JPEG EXIF", "\xff\xd8\xff\xe1\x00\x18Exif", JPEG},
+		{"HEIC", "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00", HEIC},
+		{"HEIF", "\x00\x00\x00\x1cftypmif1\x00\x00\x00\x00", HEIC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Sniff([]byte(tt.head))
+			if err != nil || got != tt.want {
+				t.Errorf("Sniff = %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	for name, head := range map[string]string{
+		"empty":         "",
+		"text":          "hello, world",
+		"HTML":          "<!DOCTYPE html><html>",
+		"ZIP":           "PK\x03\x04\x14\x00",
+		"MP4":           "\x00\x00\x00\x18ftypisom\x00\x00\x02\x00",
+		"short ftyp":    "\x00\x00\x00\x18ftyp",
+		"PDF elsewhere": "junk%PDF-1.7",
+		"GIF":           "GIF89a",
+	} {
+		if got, err := Sniff([]byte(head)); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("Sniff(%s) = %v, %v; want ErrUnsupportedType", name, got, err)
+		}
+	}
+}
+
+func TestTypes(t *testing.T) {
+	for contentType, typ := range Types {
+		if typ.ContentType != contentType {
+			t.Errorf("Types[%q] has content type %q", contentType, typ.ContentType)
+		}
+		if typ.Ext == "" || typ.Ext[0] != '.' {
+			t.Errorf("Types[%q] has extension %q", contentType, typ.Ext)
+		}
+	}
+}
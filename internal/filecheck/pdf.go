@@ -0,0 +1,72 @@
+package filecheck
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// pdfForbiddenNames are PDF name objects for features a scanned or printed
+// prescription never needs but malicious documents commonly use.
+var pdfForbiddenNames = map[string]string{
+	"JavaScript":    "embedded JavaScript",
+	"JS":            "embedded JavaScript",
+	"Launch":        "launch actions",
+	"EmbeddedFile":  "embedded files",
+	"EmbeddedFiles": "embedded files",
+	"RichMedia":     "rich media",
+	"XFA":           "XFA forms",
+	// Encrypted documents can't be inspected
+	"Encrypt": "encryption",
+}
+
+var pdfNamePattern = regexp.MustCompile(`/[^\s/<>\[\]()%{}]+`)
+
+// CheckPDF rejects PDFs that are truncated or use any of the features in
+// pdfForbiddenNames. It reads the whole document, so callers should cap its
+// size first. Names hidden inside compressed object streams aren't seen, so
+// this complements a malware scanner rather than replacing one.
+func CheckPDF(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return fmt.Errorf("not a PDF")
+	}
+	tail := data[max(0, len(data)-1024):]
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		return fmt.Errorf("PDF is truncated")
+	}
+
+	for _, raw := range pdfNamePattern.FindAll(data, -1) {
+		if feature, ok := pdfForbiddenNames[decodePDFName(raw[1:])]; ok {
+			return fmt.Errorf("PDFs with %s aren't accepted", feature)
+		}
+	}
+	return nil
+}
+
+// decodePDFName undoes #xx escapes, which would otherwise let /J#61vaScript
+// slip past the check.
+func decodePDFName(name []byte) string {
+	if !bytes.Contains(name, []byte("#")) {
+		return string(name)
+	}
+
+	var out []byte
+	for i := 0; i < len(name); i++ {
+		if name[i] == '#' && i+2 < len(name) {
+			if b, err := hex.DecodeString(string(name[i+1 : i+3])); err == nil {
+				out = append(out, b[0])
+				i += 2
+				continue
+			}
+		}
+		out = append(out, name[i])
+	}
+	return string(out)
+}
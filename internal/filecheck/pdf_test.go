@@ -0,0 +1,72 @@
+package filecheck
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodePDFName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"JavaScript", "JavaScript"},
+		{"J#61vaScript", "JavaScript"},
+		{"#4A#61#76#61#53#63#72#69#70#74", "JavaScript"},
+		{"JavaScrip#74", "JavaScript"},
+		{"Java#53cript", "JavaScript"},
+		{"Java#73cript", "Javascript"},
+		// Invalid or cut short escapes are left as they are
+		{"A#zzB", "A#zzB"},
+		{"AB#4", "AB#4"},
+		{"AB#", "AB#"},
+		{"#", "#"},
+		{"Type#20Name", "Type Name"},
+	}
+	for _, tt := range tests {
+		if got := decodePDFName([]byte(tt.in)); got != tt.want {
+			t.Errorf("decodePDFName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// pdf builds a minimal document with body between the header and trailer.
+func pdf(body string) string {
+	return "%PDF-1.7\n" + body + "\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n"
+}
+
+func TestCheckPDF(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string // "" if the PDF is accepted
+	}{
+		{"plain", pdf("1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj"), ""},
+		{"trailing whitespace", pdf("1 0 obj << /Type /Catalog >> endobj") + strings.Repeat("\n", 100), ""},
+		{"not a PDF", "<html>%%EOF", "not a PDF"},
+		{"truncated", "%PDF-1.7\n1 0 obj << /Type /Catalog >>", "truncated"},
+		{"EOF only early", "%PDF-1.7\n%%EOF\n" + strings.Repeat("x", 2000), "truncated"},
+		{"JavaScript", pdf("<< /S /JavaScript /JS (app.alert(1)) >>"), "embedded JavaScript"},
+		{"JS alone", pdf("<< /JS 5 0 R >>"), "embedded JavaScript"},
+		{"escaped JavaScript", pdf("<< /S /J#61vaScript >>"), "embedded JavaScript"},
+		{"escaped name after a delimiter", pdf("<</OpenAction<</S/Launch/F(cmd.exe)>>>>"), "launch actions"},
+		{"embedded file", pdf("<< /Type /EmbeddedFile >>"), "embedded files"},
+		{"embedded files tree", pdf("<< /Names << /EmbeddedFiles 3 0 R >> >>"), "embedded files"},
+		{"rich media", pdf("<< /Subtype /RichMedia >>"), "rich media"},
+		{"XFA", pdf("<< /AcroForm << /XFA 4 0 R >> >>"), "XFA forms"},
+		{"encrypted", "%PDF-1.7\ntrailer\n<< /Encrypt 9 0 R >>\n%%EOF", "encryption"},
+		// Only whole names count
+		{"similar names", pdf("<< /JavaScriptish /JSON /Launcher /EncryptMetadata false >>"), ""},
+		{"names in strings are still names", pdf("<< /Title (see /JS) >>"), "embedded JavaScript"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPDF(strings.NewReader(tt.data))
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("CheckPDF: %v, want it accepted", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("CheckPDF error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package filecheck
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Verdict is the result of a malware scan.
+type Verdict struct {
+	Infected bool
+	// Threat names what was found, when Infected
+	Threat string
+}
+
+// Scanner scans a file for malware.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (Verdict, error)
+}
+
+// Clamd scans files with a ClamAV daemon using its INSTREAM command.
+type Clamd struct {
+	// Network is "unix" or "tcp"
+	Network string
+	Address string
+	// Timeout bounds a whole scan, including streaming the file
+	Timeout time.Duration
+}
+
+// NewClamd returns a scanner for the clamd listening at address, either a
+// socket path such as /var/run/clamav/clamd.ctl or a host:port.
+func NewClamd(address string) *Clamd {
+	network := "tcp"
+	if strings.HasPrefix(address, "/") {
+		network = "unix"
+	}
+	return &Clamd{Network: network, Address: address, Timeout: 2 * time.Minute}
+}
+
+// clamdChunkSize is well below clamd's default StreamMaxLength chunking.
+const clamdChunkSize = 64 << 10
+
+func (s *Clamd) Scan(ctx context.Context, r io.Reader) (Verdict, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, s.Network, s.Address)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Verdict{}, err
+	}
+
+	// The file is sent as length-prefixed chunks, ended by a zero length
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, readErr := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return Verdict{}, err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return Verdict{}, readErr
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return Verdict{}, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && reply != "") {
+		return Verdict{}, err
+	}
+	return parseClamdReply(reply)
+}
+
+// parseClamdReply reads replies such as "stream: OK" and
+// "stream: Eicar-Test-Signature FOUND".
+func parseClamdReply(reply string) (Verdict, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	result := strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case result == "OK":
+		return Verdict{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return Verdict{Infected: true, Threat: strings.TrimSuffix(result, " FOUND")}, nil
+	}
+	return Verdict{}, fmt.Errorf("clamd: %s", reply)
+}
@@ -0,0 +1,155 @@
+package filecheck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseClamdReply(t *testing.T) {
+	tests := []struct {
+		reply   string
+		want    Verdict
+		wantErr bool
+	}{
+		{"stream: OK\x00", Verdict{}, false},
+		{"stream: OK\n", Verdict{}, false},
+		{"stream: Eicar-Test-Signature FOUND\x00", Verdict{Infected: true, Threat: "Eicar-Test-Signature"}, false},
+		{"stream: Win.Trojan.Agent-123 FOUND", Verdict{Infected: true, Threat: "Win.Trojan.Agent-123"}, false},
+		{"INSTREAM size limit exceeded. ERROR\x00", Verdict{}, true},
+		{"stream: Can't allocate memory ERROR", Verdict{}, true},
+		{"", Verdict{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseClamdReply(tt.reply)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseClamdReply(%q) = %+v, %v; want %+v, error %v", tt.reply, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNewClamd(t *testing.T) {
+	if c := NewClamd("/var/run/clamav/clamd.ctl"); c.Network != "unix" {
+		t.Errorf("socket path network = %q, want unix", c.Network)
+	}
+	if c := NewClamd("clamav:3310"); c.Network != "tcp" {
+		t.Errorf("host:port network = %q, want tcp", c.Network)
+	}
+}
+
+// fakeClamd accepts one INSTREAM scan, reassembles the streamed file and
+// answers it with reply.
+func fakeClamd(t *testing.T, reply func(file []byte) string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen on loopback: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+
+		cmd, err := r.ReadString(0)
+		if err != nil || cmd != "zINSTREAM\x00" {
+			conn.Write([]byte("UNKNOWN COMMAND\x00"))
+			return
+		}
+		var file bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if _, err := io.CopyN(&file, r, int64(size)); err != nil {
+				return
+			}
+		}
+		conn.Write([]byte(reply(file.Bytes()) + "\x00"))
+	}()
+	return ln.Addr().String()
+}
+
+func TestClamdScan(t *testing.T) {
+	const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+	reply := func(file []byte) string {
+		if bytes.Contains(file, []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE")) {
+			return "stream: Eicar-Test-Signature FOUND"
+		}
+		return "stream: OK"
+	}
+
+	tests := []struct {
+		name string
+		file string
+		want Verdict
+	}{
+		{"clean", "%PDF-1.7\n%%EOF", Verdict{}},
+		{"infected", eicar, Verdict{Infected: true, Threat: "Eicar-Test-Signature"}},
+		// Bigger than a chunk, so the file is sent in several
+		{"large", strings.Repeat("a", 3*clamdChunkSize+17) + eicar, Verdict{Infected: true, Threat: "Eicar-Test-Signature"}},
+		{"empty", "", Verdict{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := &Clamd{Network: "tcp", Address: fakeClamd(t, reply), Timeout: 5 * time.Second}
+			got, err := scanner.Scan(context.Background(), strings.NewReader(tt.file))
+			if err != nil || got != tt.want {
+				t.Errorf("Scan = %+v, %v; want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClamdScanStreamsWholeFile(t *testing.T) {
+	file := strings.Repeat("0123456789", clamdChunkSize/5)
+	var got []byte
+	addr := fakeClamd(t, func(f []byte) string {
+		got = append([]byte(nil), f...)
+		return "stream: OK"
+	})
+
+	scanner := &Clamd{Network: "tcp", Address: addr, Timeout: 5 * time.Second}
+	if _, err := scanner.Scan(context.Background(), strings.NewReader(file)); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if string(got) != file {
+		t.Errorf("clamd received %d bytes, want the %d of the file", len(got), len(file))
+	}
+}
+
+func TestClamdScanError(t *testing.T) {
+	addr := fakeClamd(t, func([]byte) string { return "INSTREAM size limit exceeded. ERROR" })
+	scanner := &Clamd{Network: "tcp", Address: addr, Timeout: 5 * time.Second}
+	if v, err := scanner.Scan(context.Background(), strings.NewReader("data")); err == nil {
+		t.Errorf("Scan = %+v, want an error", v)
+	}
+}
+
+func TestClamdScanUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen on loopback: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	scanner := &Clamd{Network: "tcp", Address: addr, Timeout: time.Second}
+	if v, err := scanner.Scan(context.Background(), strings.NewReader("data")); err == nil {
+		t.Errorf("Scan = %+v, want an error when clamd is down", v)
+	}
+}
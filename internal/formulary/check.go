@@ -0,0 +1,186 @@
+package formulary
+
+import (
+	"fmt"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Warning kinds.
+const (
+	KindDuplicate      = "duplicate"
+	KindDuplicateClass = "duplicate_class"
+	KindInteraction    = "interaction"
+	KindAllergy        = "allergy"
+	KindUnknownDrug    = "unknown_drug"
+)
+
+// Warning is one problem found with a proposed prescription.
+type Warning = models.PrescriptionWarning
+
+// Check compares the drugs being prescribed against each other, the
+// patient's active medications and their allergies. Allergies may name a
+// drug or a class such as "penicillin".
+func (f *Formulary) Check(proposed []string, active []models.ActiveMedication, allergies []models.PatientAllergy) []Warning {
+	var warnings []Warning
+
+	type entry struct {
+		drug           *Drug
+		label          string
+		prescriptionID int
+	}
+	var newDrugs []entry
+	for _, name := range proposed {
+		drugs := f.Identify(name)
+		if len(drugs) == 0 {
+			warnings = append(warnings, Warning{
+				Kind:     KindUnknownDrug,
+				Severity: SeverityInfo,
+				Drug:     name,
+				Message:  fmt.Sprintf("%s isn't in the formulary, so it couldn't be checked", name),
+			})
+		}
+		for _, d := range drugs {
+			newDrugs = append(newDrugs, entry{drug: d, label: name})
+		}
+	}
+
+	var current []entry
+	for _, a := range active {
+		for _, d := range f.Identify(a.Name) {
+			current = append(current, entry{drug: d, label: a.Name, prescriptionID: a.PrescriptionID})
+		}
+	}
+
+	// Each new drug against the active ones, then against the other new ones
+	for i, n := range newDrugs {
+		others := append(append([]entry{}, current...), newDrugs[i+1:]...)
+		for _, o := range others {
+			if w, ok := f.pairWarning(n.drug, o.drug); ok {
+				w.Drug, w.With, w.PrescriptionID = n.label, o.label, o.prescriptionID
+				warnings = append(warnings, w)
+			}
+		}
+	}
+
+	for _, n := range newDrugs {
+		for _, a := range allergies {
+			if w, ok := f.allergyWarning(n.drug, a); ok {
+				w.Drug = n.label
+				warnings = append(warnings, w)
+			}
+		}
+	}
+	return warnings
+}
+
+// pairWarning reports the most serious problem with taking a and b together.
+func (f *Formulary) pairWarning(a, b *Drug) (Warning, bool) {
+	if a == b {
+		return Warning{
+			Kind:     KindDuplicate,
+			Severity: SeverityModerate,
+			Message:  fmt.Sprintf("%s is already prescribed", a.Name),
+		}, true
+	}
+
+	var worst *Interaction
+	for i := range f.Interactions {
+		in := &f.Interactions[i]
+		if (a.matches(in.A) && b.matches(in.B)) || (a.matches(in.B) && b.matches(in.A)) {
+			if worst == nil || severityRank[in.Severity] > severityRank[worst.Severity] {
+				worst = in
+			}
+		}
+	}
+	if worst != nil {
+		return Warning{
+			Kind:     KindInteraction,
+			Severity: worst.Severity,
+			Message:  fmt.Sprintf("%s and %s: %s", a.Name, b.Name, worst.Description),
+		}, true
+	}
+
+	for _, class := range a.Classes {
+		if f.Classes[class].DuplicateTherapy && b.hasClass(class) {
+			return Warning{
+				Kind:     KindDuplicateClass,
+				Severity: SeverityModerate,
+				Message:  fmt.Sprintf("%s and %s are both %s", a.Name, b.Name, f.Classes[class].Name),
+			}, true
+		}
+	}
+	return Warning{}, false
+}
+
+func (f *Formulary) allergyWarning(d *Drug, a models.PatientAllergy) (Warning, bool) {
+	substance := normalize(a.Substance)
+	with := a.Substance
+	if a.Reaction != "" {
+		with += " (" + a.Reaction + ")"
+	}
+
+	if allergen := f.Lookup(substance); allergen == d {
+		return Warning{
+			Kind:     KindAllergy,
+			Severity: SeverityContraindicated,
+			With:     with,
+			Message:  fmt.Sprintf("Patient is allergic to %s", d.Name),
+		}, true
+	}
+
+	for _, key := range d.Classes {
+		class := f.Classes[key]
+		if classMatches(key, class, substance) {
+			return Warning{
+				Kind:     KindAllergy,
+				Severity: SeverityContraindicated,
+				With:     with,
+				Message:  fmt.Sprintf("Patient is allergic to %s, and %s is one", class.Name, d.Name),
+			}, true
+		}
+	}
+
+	// Cross-reactivity: the allergy is to a class that reacts with one of ours
+	for key, class := range f.Classes {
+		if !classMatches(key, class, substance) {
+			continue
+		}
+		for _, cross := range class.CrossReactive {
+			if d.hasClass(cross) {
+				return Warning{
+					Kind:     KindAllergy,
+					Severity: SeverityMajor,
+					With:     with,
+					Message:  fmt.Sprintf("Patient is allergic to %s, which can cross-react with %s", class.Name, f.Classes[cross].Name),
+				}, true
+			}
+		}
+	}
+
+	return Warning{}, false
+}
+
+func classMatches(key string, class Class, substance string) bool {
+	if substance == key || substance == normalize(class.Name) {
+		return true
+	}
+	for _, alias := range class.Aliases {
+		if substance == normalize(alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// Blocking returns the warnings that need an explicit override, which is
+// everything above minor.
+func Blocking(warnings []Warning) []Warning {
+	var blocking []Warning
+	for _, w := range warnings {
+		if severityRank[w.Severity] > severityRank[SeverityMinor] {
+			blocking = append(blocking, w)
+		}
+	}
+	return blocking
+}
@@ -0,0 +1,146 @@
+package formulary
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+func TestCheck(t *testing.T) {
+	f := testFormulary(t)
+	tests := []struct {
+		name      string
+		proposed  []string
+		active    []models.ActiveMedication
+		allergies []models.PatientAllergy
+		want      []Warning // Kind, Severity, Drug, With and PrescriptionID are compared
+	}{
+		{
+			name:     "nothing to warn about",
+			proposed: []string{"Amoxicillin 500 mg"},
+			active:   []models.ActiveMedication{{PrescriptionID: 1, Name: "Atorvastatin 20 mg"}},
+		},
+		{
+			name:     "unknown drug",
+			proposed: []string{"Paracetamol 1 g"},
+			want:     []Warning{{Kind: KindUnknownDrug, Severity: SeverityInfo, Drug: "Paracetamol 1 g"}},
+		},
+		{
+			name:     "already prescribed under another name",
+			proposed: []string{"Advil 200 mg"},
+			active:   []models.ActiveMedication{{PrescriptionID: 7, Name: "Ibuprofen 400 mg"}},
+			want:     []Warning{{Kind: KindDuplicate, Severity: SeverityModerate, Drug: "Advil 200 mg", With: "Ibuprofen 400 mg", PrescriptionID: 7}},
+		},
+		{
+			name:     "duplicate therapy",
+			proposed: []string{"Naproxen"},
+			active:   []models.ActiveMedication{{PrescriptionID: 3, Name: "Ibuprofen"}},
+			want:     []Warning{{Kind: KindDuplicateClass, Severity: SeverityModerate, Drug: "Naproxen", With: "Ibuprofen", PrescriptionID: 3}},
+		},
+		{
+			name:     "class interaction",
+			proposed: []string{"Naproxen"},
+			active:   []models.ActiveMedication{{PrescriptionID: 4, Name: "Apixaban"}},
+			want:     []Warning{{Kind: KindInteraction, Severity: SeverityMajor, Drug: "Naproxen", With: "Apixaban", PrescriptionID: 4}},
+		},
+		{
+			name:     "the most serious interaction wins",
+			proposed: []string{"Ibuprofen"},
+			active:   []models.ActiveMedication{{PrescriptionID: 5, Name: "Warfarin"}},
+			want:     []Warning{{Kind: KindInteraction, Severity: SeverityContraindicated, Drug: "Ibuprofen", With: "Warfarin", PrescriptionID: 5}},
+		},
+		{
+			name:     "either order",
+			proposed: []string{"Clarithromycin"},
+			active:   []models.ActiveMedication{{PrescriptionID: 6, Name: "Simvastatin"}, {PrescriptionID: 8, Name: "Atorvastatin"}},
+			want: []Warning{
+				{Kind: KindInteraction, Severity: SeverityContraindicated, Drug: "Clarithromycin", With: "Simvastatin", PrescriptionID: 6},
+				{Kind: KindInteraction, Severity: SeverityMinor, Drug: "Clarithromycin", With: "Atorvastatin", PrescriptionID: 8},
+			},
+		},
+		{
+			name:     "new drugs against each other",
+			proposed: []string{"Warfarin", "Apixaban", "Ibuprofen"},
+			want: []Warning{
+				{Kind: KindDuplicateClass, Severity: SeverityModerate, Drug: "Warfarin", With: "Apixaban"},
+				{Kind: KindInteraction, Severity: SeverityContraindicated, Drug: "Warfarin", With: "Ibuprofen"},
+				{Kind: KindInteraction, Severity: SeverityMajor, Drug: "Apixaban", With: "Ibuprofen"},
+			},
+		},
+		{
+			name:      "allergy to the drug",
+			proposed:  []string{"Coumadin 5 mg"},
+			allergies: []models.PatientAllergy{{Substance: "warfarin", Reaction: "rash"}},
+			want:      []Warning{{Kind: KindAllergy, Severity: SeverityContraindicated, Drug: "Coumadin 5 mg", With: "warfarin (rash)"}},
+		},
+		{
+			name:      "allergy to the class",
+			proposed:  []string{"Amoxicillin"},
+			allergies: []models.PatientAllergy{{Substance: "Penicillin"}},
+			want:      []Warning{{Kind: KindAllergy, Severity: SeverityContraindicated, Drug: "Amoxicillin", With: "Penicillin"}},
+		},
+		{
+			name:      "cross-reactive class",
+			proposed:  []string{"Cephalexin 500 mg"},
+			allergies: []models.PatientAllergy{{Substance: "penicillins", Reaction: "anaphylaxis"}},
+			want:      []Warning{{Kind: KindAllergy, Severity: SeverityMajor, Drug: "Cephalexin 500 mg", With: "penicillins (anaphylaxis)"}},
+		},
+		{
+			name:      "unrelated allergy",
+			proposed:  []string{"Atorvastatin"},
+			allergies: []models.PatientAllergy{{Substance: "nsaid"}, {Substance: "peanuts"}},
+		},
+		{
+			name:     "active medications outside the formulary are ignored",
+			proposed: []string{"Ibuprofen"},
+			active:   []models.ActiveMedication{{PrescriptionID: 9, Name: "Homeopathic drops"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.Check(tt.proposed, tt.active, tt.allergies)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d warnings, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if g.Kind != w.Kind || g.Severity != w.Severity || g.Drug != w.Drug || g.With != w.With || g.PrescriptionID != w.PrescriptionID {
+					t.Errorf("warning %d = %+v, want %+v", i, g, w)
+				}
+				if g.Message == "" {
+					t.Errorf("warning %d has no message", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckMessages(t *testing.T) {
+	f := testFormulary(t)
+	got := f.Check([]string{"Cefalexin"}, nil, []models.PatientAllergy{{Substance: "penicillin"}})
+	if len(got) != 1 || !strings.Contains(got[0].Message, "penicillins, which can cross-react with cephalosporins") {
+		t.Errorf("cross-reactivity warning = %+v", got)
+	}
+	got = f.Check([]string{"Naproxen", "Ibuprofen"}, nil, nil)
+	if len(got) != 1 || got[0].Message != "naproxen and ibuprofen are both NSAIDs" {
+		t.Errorf("duplicate therapy warning = %+v", got)
+	}
+}
+
+func TestBlocking(t *testing.T) {
+	warnings := []Warning{
+		{Kind: KindUnknownDrug, Severity: SeverityInfo},
+		{Kind: KindInteraction, Severity: SeverityMinor},
+		{Kind: KindDuplicate, Severity: SeverityModerate},
+		{Kind: KindInteraction, Severity: SeverityMajor},
+		{Kind: KindAllergy, Severity: SeverityContraindicated},
+	}
+	got := Blocking(warnings)
+	if len(got) != 3 || got[0].Severity != SeverityModerate || got[2].Severity != SeverityContraindicated {
+		t.Errorf("Blocking = %+v", got)
+	}
+	if got := Blocking(warnings[:2]); got != nil {
+		t.Errorf("Blocking of info and minor warnings = %+v, want none", got)
+	}
+}
@@ -0,0 +1,189 @@
+// Package formulary holds the local drug list used to check new
+// prescriptions for duplicates, interactions and allergies.
+package formulary
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severities, in increasing order.
+const (
+	SeverityInfo            = "info"
+	SeverityMinor           = "minor"
+	SeverityModerate        = "moderate"
+	SeverityMajor           = "major"
+	SeverityContraindicated = "contraindicated"
+)
+
+var severityRank = map[string]int{
+	SeverityInfo: 0, SeverityMinor: 1, SeverityModerate: 2, SeverityMajor: 3, SeverityContraindicated: 4,
+}
+
+// SeverityRank orders severities; unknown ones rank as info.
+func SeverityRank(severity string) int {
+	return severityRank[severity]
+}
+
+// Class is a therapeutic or chemical class drugs can belong to.
+type Class struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	// DuplicateTherapy flags classes where two concurrent drugs are usually
+	// a mistake, such as two NSAIDs.
+	DuplicateTherapy bool `json:"duplicate_therapy,omitempty"`
+	// CrossReactive lists classes a patient allergic to this one may also
+	// react to.
+	CrossReactive []string `json:"cross_reactive,omitempty"`
+}
+
+type Drug struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	Classes []string `json:"classes"`
+}
+
+// Interaction is a pair of drugs or classes that shouldn't be combined. A and
+// B are each a drug name or a class key.
+type Interaction struct {
+	A           string `json:"a"`
+	B           string `json:"b"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+type Formulary struct {
+	Classes      map[string]Class `json:"classes"`
+	Drugs        []Drug           `json:"drugs"`
+	Interactions []Interaction    `json:"interactions"`
+
+	byName  map[string]*Drug
+	matcher *regexp.Regexp
+}
+
+// Load reads and indexes a formulary file.
+func Load(path string) (*Formulary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f Formulary
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := f.index(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, nil
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+func (f *Formulary) index() error {
+	f.byName = make(map[string]*Drug)
+	var names []string
+	for i := range f.Drugs {
+		d := &f.Drugs[i]
+		d.Name = normalize(d.Name)
+		for _, class := range d.Classes {
+			if _, ok := f.Classes[class]; !ok {
+				return fmt.Errorf("drug %q has unknown class %q", d.Name, class)
+			}
+		}
+		for _, name := range append([]string{d.Name}, d.Aliases...) {
+			name = normalize(name)
+			if _, dup := f.byName[name]; dup {
+				return fmt.Errorf("drug name %q is listed twice", name)
+			}
+			f.byName[name] = d
+			names = append(names, regexp.QuoteMeta(name))
+		}
+	}
+
+	for _, in := range f.Interactions {
+		for _, side := range []string{in.A, in.B} {
+			_, isClass := f.Classes[side]
+			if _, isDrug := f.byName[side]; !isClass && !isDrug {
+				return fmt.Errorf("interaction refers to unknown drug or class %q", side)
+			}
+		}
+		if _, ok := severityRank[in.Severity]; !ok {
+			return fmt.Errorf("interaction %s/%s has unknown severity %q", in.A, in.B, in.Severity)
+		}
+	}
+
+	// Longest names first, so "penicillin v" wins over "penicillin"
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	if len(names) > 0 {
+		f.matcher = regexp.MustCompile(`\b(` + strings.Join(names, "|") + `)\b`)
+	}
+	return nil
+}
+
+// Lookup finds a drug by its name or an alias.
+func (f *Formulary) Lookup(name string) *Drug {
+	return f.byName[normalize(name)]
+}
+
+// Identify finds the formulary drugs mentioned in free text such as
+// "Amoxicillin 500 mg capsules".
+func (f *Formulary) Identify(text string) []*Drug {
+	if d := f.Lookup(text); d != nil {
+		return []*Drug{d}
+	}
+	if f.matcher == nil {
+		return nil
+	}
+
+	var found []*Drug
+	seen := make(map[*Drug]bool)
+	for _, m := range f.matcher.FindAllString(normalize(text), -1) {
+		if d := f.byName[m]; !seen[d] {
+			seen[d] = true
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+func (d *Drug) hasClass(class string) bool {
+	for _, c := range d.Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether key names the drug itself or one of its classes.
+func (d *Drug) matches(key string) bool {
+	return d.Name == key || d.hasClass(key)
+}
+
+// Search returns up to limit drugs whose name or an alias starts with q.
+func (f *Formulary) Search(q string, limit int) []Drug {
+	q = normalize(q)
+	results := []Drug{}
+	if q == "" {
+		return results
+	}
+	for _, d := range f.Drugs {
+		for _, name := range append([]string{d.Name}, d.Aliases...) {
+			if strings.HasPrefix(normalize(name), q) {
+				results = append(results, d)
+				break
+			}
+		}
+		if len(results) == limit {
+			break
+		}
+	}
+	return results
+}
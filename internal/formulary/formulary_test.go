@@ -0,0 +1,182 @@
+package formulary
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testFormularyJSON = `{
+	"classes": {
+		"nsaids": {"name": "NSAIDs", "aliases": ["nsaid"], "duplicate_therapy": true},
+		"anticoagulants": {"name": "anticoagulants", "duplicate_therapy": true},
+		"penicillins": {"name": "penicillins", "aliases": ["penicillin"], "cross_reactive": ["cephalosporins"]},
+		"cephalosporins": {"name": "cephalosporins", "aliases": ["cephalosporin"], "cross_reactive": ["penicillins"]},
+		"statins": {"name": "statins", "aliases": ["statin"]}
+	},
+	"drugs": [
+		{"name": "Ibuprofen", "aliases": ["Advil", "Nurofen"], "classes": ["nsaids"]},
+		{"name": "Naproxen", "classes": ["nsaids"]},
+		{"name": "Warfarin", "aliases": ["Coumadin"], "classes": ["anticoagulants"]},
+		{"name": "Apixaban", "classes": ["anticoagulants"]},
+		{"name": "Amoxicillin", "classes": ["penicillins"]},
+		{"name": "Penicillin V", "aliases": ["phenoxymethylpenicillin"], "classes": ["penicillins"]},
+		{"name": "Cefalexin", "aliases": ["cephalexin"], "classes": ["cephalosporins"]},
+		{"name": "Atorvastatin", "classes": ["statins"]},
+		{"name": "Simvastatin", "classes": ["statins"]},
+		{"name": "Clarithromycin", "classes": []}
+	],
+	"interactions": [
+		{"a": "anticoagulants", "b": "nsaids", "severity": "major", "description": "bleeding risk"},
+		{"a": "warfarin", "b": "nsaids", "severity": "contraindicated", "description": "serious bleeding risk"},
+		{"a": "simvastatin", "b": "clarithromycin", "severity": "contraindicated", "description": "myopathy"},
+		{"a": "atorvastatin", "b": "clarithromycin", "severity": "minor", "description": "raised statin levels"}
+	]
+}`
+
+func testFormulary(t *testing.T) *Formulary {
+	t.Helper()
+	var f Formulary
+	if err := json.Unmarshal([]byte(testFormularyJSON), &f); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if err := f.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	return &f
+}
+
+func TestLookup(t *testing.T) {
+	f := testFormulary(t)
+	tests := []struct {
+		name, want string
+	}{
+		{"ibuprofen", "ibuprofen"},
+		{"  IBUPROFEN ", "ibuprofen"},
+		{"Advil", "ibuprofen"},
+		{"penicillin   v", "penicillin v"},
+		{"ibuprofen 200 mg", ""},
+		{"paracetamol", ""},
+	}
+	for _, tt := range tests {
+		var got string
+		if d := f.Lookup(tt.name); d != nil {
+			got = d.Name
+		}
+		if got != tt.want {
+			t.Errorf("Lookup(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	f := testFormulary(t)
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Amoxicillin", []string{"amoxicillin"}},
+		{"Amoxicillin 500 mg capsules", []string{"amoxicillin"}},
+		{"Nurofen 200mg tablets", []string{"ibuprofen"}},
+		// The longer name wins
+		{"Penicillin V 250 mg", []string{"penicillin v"}},
+		{"Ibuprofen and naproxen", []string{"ibuprofen", "naproxen"}},
+		{"Ibuprofen (Advil) 400 mg", []string{"ibuprofen"}},
+		// Only whole words
+		{"Warfarinate", nil},
+		{"Paracetamol 1 g", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, d := range f.Identify(tt.text) {
+			got = append(got, d.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Identify(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	if got := (&Formulary{}).Identify("ibuprofen"); got != nil {
+		t.Errorf("Identify in an empty formulary = %v", got)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	f := testFormulary(t)
+	tests := []struct {
+		q     string
+		limit int
+		want  []string
+	}{
+		// Aliases match too, and results keep the formulary's order
+		{"a", 10, []string{"ibuprofen", "apixaban", "amoxicillin", "atorvastatin"}},
+		{"a", 2, []string{"ibuprofen", "apixaban"}},
+		{"CEPH", 10, []string{"cefalexin"}},
+		{"statin", 10, nil},
+		{"", 10, nil},
+		{"  ", 10, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, d := range f.Search(tt.q, tt.limit) {
+			got = append(got, d.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Search(%q, %d) = %v, want %v", tt.q, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestIndexErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"unknown class", `{"drugs": [{"name": "Ibuprofen", "classes": ["nsaids"]}]}`, `drug "ibuprofen" has unknown class "nsaids"`},
+		{"duplicate name", `{"drugs": [{"name": "Ibuprofen"}, {"name": "Brufen", "aliases": ["IBUPROFEN"]}]}`, `drug name "ibuprofen" is listed twice`},
+		{"unknown interaction side", `{"drugs": [{"name": "Ibuprofen"}], "interactions": [{"a": "ibuprofen", "b": "aspirin", "severity": "major"}]}`, `unknown drug or class "aspirin"`},
+		{"unknown severity", `{"drugs": [{"name": "Ibuprofen"}, {"name": "Naproxen"}], "interactions": [{"a": "ibuprofen", "b": "naproxen", "severity": "bad"}]}`, `unknown severity "bad"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "formulary.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadShippedFormulary(t *testing.T) {
+	f, err := Load(filepath.Join("..", "..", "data", "formulary.json"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for key, class := range f.Classes {
+		for _, cross := range class.CrossReactive {
+			if _, ok := f.Classes[cross]; !ok {
+				t.Errorf("class %s cross-reacts with unknown class %q", key, cross)
+			}
+		}
+	}
+}
+
+func TestSeverityRank(t *testing.T) {
+	order := []string{SeverityInfo, SeverityMinor, SeverityModerate, SeverityMajor, SeverityContraindicated}
+	for i := 1; i < len(order); i++ {
+		if SeverityRank(order[i-1]) >= SeverityRank(order[i]) {
+			t.Errorf("SeverityRank(%q) isn't below SeverityRank(%q)", order[i-1], order[i])
+		}
+	}
+	if SeverityRank("unknown") != SeverityRank(SeverityInfo) {
+		t.Error("unknown severities don't rank as info")
+	}
+}
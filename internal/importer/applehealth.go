@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// appleHealthTypes maps HealthKit quantity types onto our metric names. Every
+// other record type in an export (steps, workouts, ...) is ignored.
+var appleHealthTypes = map[string]string{
+	"HKQuantityTypeIdentifierHeartRate":              "heart_rate",
+	"HKQuantityTypeIdentifierRespiratoryRate":        "respiratory_rate",
+	"HKQuantityTypeIdentifierOxygenSaturation":       "spo2",
+	"HKQuantityTypeIdentifierBloodPressureSystolic":  "systolic_bp",
+	"HKQuantityTypeIdentifierBloodPressureDiastolic": "diastolic_bp",
+	"HKQuantityTypeIdentifierBodyTemperature":        "temperature",
+	"HKQuantityTypeIdentifierBloodGlucose":           "blood_glucose",
+	"HKQuantityTypeIdentifierBodyMass":               "weight",
+}
+
+const appleHealthTimeLayout = "2006-01-02 15:04:05 -0700"
+
+// parseAppleHealth walks an Apple Health export.xml token by token so that
+// multi-gigabyte exports never have to be held in memory.
+func parseAppleHealth(r io.Reader, sink Sink) error {
+	dec := xml.NewDecoder(r)
+	count := 0
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading Apple Health export: %w", err)
+		}
+
+		el, ok := tok.(xml.StartElement)
+		if !ok || el.Name.Local != "Record" {
+			continue
+		}
+		count++
+
+		attrs := make(map[string]string, len(el.Attr))
+		for _, a := range el.Attr {
+			attrs[a.Name.Local] = a.Value
+		}
+
+		metric, ok := appleHealthTypes[attrs["type"]]
+		if !ok {
+			continue
+		}
+
+		location := "record " + strconv.Itoa(count)
+		value, err := strconv.ParseFloat(attrs["value"], 64)
+		if err != nil {
+			sink.Error(location, fmt.Errorf("invalid value %q", attrs["value"]))
+			continue
+		}
+		recordedAt, err := parseTime(attrs["startDate"], []string{appleHealthTimeLayout})
+		if err != nil {
+			sink.Error(location, err)
+			continue
+		}
+
+		rec := Record{
+			Metric:     metric,
+			Value:      value,
+			Unit:       attrs["unit"],
+			RecordedAt: recordedAt,
+		}
+		if err := sink.Record(rec); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func healthRecord(typ, unit, value, start string) string {
+	return `<Record type="` + typ + `" sourceName="Watch" unit="` + unit + `" value="` + value +
+		`" startDate="` + start + `" endDate="` + start + `"/>`
+}
+
+func TestParseAppleHealth(t *testing.T) {
+	sink := parse(t, FormatAppleHealth, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE HealthData [<!ELEMENT HealthData (Record*)>]>
+<HealthData locale="en_GB">
+ <ExportDate value="2026-10-18 12:00:00 +0100"/>
+ `+healthRecord("HKQuantityTypeIdentifierHeartRate", "count/min", "72", "2026-10-18 08:00:00 +0100")+`
+ `+healthRecord("HKQuantityTypeIdentifierStepCount", "count", "5000", "2026-10-18 08:00:00 +0100")+`
+ `+healthRecord("HKQuantityTypeIdentifierOxygenSaturation", "%", "0.97", "2026-10-18 08:01:00 +0100")+`
+ `+healthRecord("HKQuantityTypeIdentifierBodyTemperature", "degF", "100.4", "2026-10-18 08:02:00 -0500")+`
+ `+healthRecord("HKQuantityTypeIdentifierBloodGlucose", "mmol&lt;180.1558800000541&gt;/L", "5.5", "2026-10-18 08:03:00 +0000")+`
+ `+healthRecord("HKQuantityTypeIdentifierBodyMass", "lb", "154", "2026-10-18 08:04:00 +0000")+`
+ <Record type="HKQuantityTypeIdentifierBloodPressureSystolic" unit="mmHg" value="120" startDate="2026-10-18 08:05:00 +0000">
+  <MetadataEntry key="HKWasUserEntered" value="1"/>
+ </Record>
+ <Workout workoutActivityType="HKWorkoutActivityTypeRunning"/>
+</HealthData>`)
+
+	checkRecords(t, sink.records, []wantRecord{
+		{"heart_rate", 72, time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC)},
+		{"spo2", 97, time.Date(2026, 10, 18, 7, 1, 0, 0, time.UTC)},
+		{"temperature", 38, time.Date(2026, 10, 18, 13, 2, 0, 0, time.UTC)},
+		{"blood_glucose", 99.1, time.Date(2026, 10, 18, 8, 3, 0, 0, time.UTC)},
+		{"weight", 69.85, time.Date(2026, 10, 18, 8, 4, 0, 0, time.UTC)},
+		{"systolic_bp", 120, time.Date(2026, 10, 18, 8, 5, 0, 0, time.UTC)},
+	})
+	checkErrors(t, sink.errors, nil)
+}
+
+func TestParseAppleHealthMalformedRecords(t *testing.T) {
+	sink := parse(t, FormatAppleHealth, `<HealthData>`+
+		healthRecord("HKQuantityTypeIdentifierHeartRate", "count/min", "", "2026-10-18 08:00:00 +0100")+
+		healthRecord("HKQuantityTypeIdentifierHeartRate", "count/min", "fast", "2026-10-18 08:00:00 +0100")+
+		// Ignored types still count towards the record numbers
+		healthRecord("HKQuantityTypeIdentifierStepCount", "count", "oops", "")+
+		healthRecord("HKQuantityTypeIdentifierHeartRate", "count/min", "72", "2026-10-18T08:00:00Z")+
+		healthRecord("HKQuantityTypeIdentifierHeartRate", "count/min", "72", "")+
+		healthRecord("HKQuantityTypeIdentifierHeartRate", "count/min", "75", "2026-10-18 08:10:00 +0000")+
+		`</HealthData>`)
+
+	checkRecords(t, sink.records, []wantRecord{{"heart_rate", 75, time.Date(2026, 10, 18, 8, 10, 0, 0, time.UTC)}})
+	checkErrors(t, sink.errors, []string{
+		`record 1: invalid value ""`,
+		`record 2: invalid value "fast"`,
+		`record 4: invalid timestamp "2026-10-18T08:00:00Z"`,
+		`record 5: invalid timestamp ""`,
+	})
+}
+
+func TestParseAppleHealthInvalidXML(t *testing.T) {
+	sink := &testSink{}
+	data := `<HealthData>` + healthRecord("HKQuantityTypeIdentifierHeartRate", "count/min", "72", "2026-10-18 08:00:00 +0000") + `<Record type="x"`
+	err := Parse(FormatAppleHealth, strings.NewReader(data), sink)
+	if err == nil || !strings.Contains(err.Error(), "reading Apple Health export") {
+		t.Errorf("Parse error = %v, want a read error", err)
+	}
+	// What came before the damage is kept
+	if len(sink.records) != 1 {
+		t.Errorf("got %d records before the error, want 1", len(sink.records))
+	}
+}
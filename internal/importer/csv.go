@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var csvColumns = []string{"metric", "value", "unit", "recorded_at"}
+
+var csvTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04"}
+
+// parseCSV reads files with a header row naming the metric, value, unit and
+// recorded_at columns, in any order.
+func parseCSV(r io.Reader, sink Sink) error {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, col := range csvColumns {
+		if _, ok := index[col]; !ok {
+			return fmt.Errorf("CSV header is missing the %q column", col)
+		}
+	}
+
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		location := "line " + strconv.Itoa(line)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				sink.Error(location, err)
+				continue
+			}
+			return err
+		}
+
+		field := func(col string) string {
+			if i := index[col]; i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		value, err := strconv.ParseFloat(field("value"), 64)
+		if err != nil {
+			sink.Error(location, fmt.Errorf("invalid value %q", field("value")))
+			continue
+		}
+		recordedAt, err := parseTime(field("recorded_at"), csvTimeLayouts)
+		if err != nil {
+			sink.Error(location, err)
+			continue
+		}
+
+		rec := Record{
+			Metric:     strings.ToLower(field("metric")),
+			Value:      value,
+			Unit:       field("unit"),
+			RecordedAt: recordedAt,
+		}
+		if err := sink.Record(rec); err != nil {
+			return err
+		}
+	}
+}
+
+func parseTime(s string, layouts []string) (time.Time, error) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSV(t *testing.T) {
+	sink := parse(t, FormatCSV, strings.Join([]string{
+		// Columns in any order and case, with extras ignored
+		" Recorded_At ,Metric,notes,Value,UNIT",
+		"2026-10-18T08:00:00+02:00,Heart_Rate,resting,72,bpm",
+		"2026-10-18 09:15:30,temperature,,98.6,degF",
+		"2026-10-18T09:20:00,spo2,,0.97,%",
+		"2026-10-18 09:25,blood_glucose,,5.5,mmol/L",
+		`2026-10-18T10:00:00Z,weight,"after breakfast, shoes on",154,lb`,
+		"2026-10-18T10:05:00Z,systolic_bp,, 120 ,",
+	}, "\n")+"\n")
+
+	checkRecords(t, sink.records, []wantRecord{
+		{"heart_rate", 72, time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC)},
+		{"temperature", 37, time.Date(2026, 10, 18, 9, 15, 30, 0, time.UTC)},
+		{"spo2", 97, time.Date(2026, 10, 18, 9, 20, 0, 0, time.UTC)},
+		{"blood_glucose", 99.1, time.Date(2026, 10, 18, 9, 25, 0, 0, time.UTC)},
+		{"weight", 69.85, time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)},
+		{"systolic_bp", 120, time.Date(2026, 10, 18, 10, 5, 0, 0, time.UTC)},
+	})
+	checkErrors(t, sink.errors, nil)
+}
+
+func TestParseCSVMalformedRows(t *testing.T) {
+	sink := parse(t, FormatCSV, strings.Join([]string{
+		"metric,value,unit,recorded_at",
+		"heart_rate,seventy,bpm,2026-10-18T08:00:00Z",
+		"heart_rate,,bpm,2026-10-18T08:00:00Z",
+		"heart_rate,72,bpm,18/10/2026 08:00",
+		"heart_rate,72,bpm,",
+		`heart_rate,"72,bpm,2026-10-18T08:00:00Z`,
+	}, "\n")+"\n")
+
+	if len(sink.records) != 0 {
+		t.Errorf("got records %+v from malformed rows", sink.records)
+	}
+	checkErrors(t, sink.errors, []string{
+		`line 2: invalid value "seventy"`,
+		`line 3: invalid value ""`,
+		`line 4: invalid timestamp "18/10/2026 08:00"`,
+		`line 5: invalid timestamp ""`,
+		`line 6: `,
+	})
+}
+
+func TestParseCSVKeepsGoing(t *testing.T) {
+	// Short rows and bad rows don't stop the rows after them
+	sink := parse(t, FormatCSV, "metric,value,unit,recorded_at\nheart_rate,72\nheart_rate,x,bpm,2026-10-18T08:00:00Z\nheart_rate,80,bpm,2026-10-18T09:00:00Z\n")
+	checkRecords(t, sink.records, []wantRecord{{"heart_rate", 80, time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)}})
+	checkErrors(t, sink.errors, []string{`line 2: invalid timestamp ""`, `line 3: invalid value "x"`})
+}
+
+func TestParseCSVHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty", "", "reading CSV header"},
+		{"missing column", "metric,value,recorded_at\nheart_rate,72,2026-10-18T08:00:00Z\n", `missing the "unit" column`},
+		{"no header", "heart_rate,72,bpm,2026-10-18T08:00:00Z\n", `missing the "metric" column`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Parse(FormatCSV, strings.NewReader(tt.data), &testSink{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// loincMetrics maps the LOINC codes used by vital-signs Observations onto our
+// metric names.
+var loincMetrics = map[string]string{
+	"8867-4":  "heart_rate",
+	"9279-1":  "respiratory_rate",
+	"2708-6":  "spo2",
+	"59408-5": "spo2",
+	"8480-6":  "systolic_bp",
+	"8462-4":  "diastolic_bp",
+	"8310-5":  "temperature",
+	"2339-0":  "blood_glucose",
+	"15074-8": "blood_glucose",
+	"29463-7": "weight",
+}
+
+type fhirCodeableConcept struct {
+	Coding []struct {
+		System string `json:"system"`
+		Code   string `json:"code"`
+	} `json:"coding"`
+}
+
+type fhirQuantity struct {
+	Value *float64 `json:"value"`
+	Unit  string   `json:"unit"`
+	Code  string   `json:"code"`
+}
+
+type fhirObservation struct {
+	ResourceType      string              `json:"resourceType"`
+	Code              fhirCodeableConcept `json:"code"`
+	ValueQuantity     *fhirQuantity       `json:"valueQuantity"`
+	EffectiveDateTime string              `json:"effectiveDateTime"`
+	EffectiveInstant  string              `json:"effectiveInstant"`
+	EffectivePeriod   *struct {
+		Start string `json:"start"`
+	} `json:"effectivePeriod"`
+	Component []struct {
+		Code          fhirCodeableConcept `json:"code"`
+		ValueQuantity *fhirQuantity       `json:"valueQuantity"`
+	} `json:"component"`
+}
+
+// parseFHIR reads a FHIR Bundle, decoding one entry at a time. Observations
+// with vital-sign LOINC codes become records, including the components of
+// panels such as blood pressure; other resources are skipped.
+func parseFHIR(r io.Reader, sink Sink) error {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("reading FHIR bundle: %w", err)
+		}
+		if key, _ := tok.(string); key != "entry" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fmt.Errorf("reading FHIR bundle: %w", err)
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+		for i := 0; dec.More(); i++ {
+			var entry struct {
+				Resource fhirObservation `json:"resource"`
+			}
+			location := "entry " + strconv.Itoa(i)
+			if err := dec.Decode(&entry); err != nil {
+				return fmt.Errorf("%s: %w", location, err)
+			}
+			if entry.Resource.ResourceType != "Observation" {
+				continue
+			}
+			if err := emitObservation(entry.Resource, location, sink); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func emitObservation(obs fhirObservation, location string, sink Sink) error {
+	effective := obs.EffectiveDateTime
+	if effective == "" {
+		effective = obs.EffectiveInstant
+	}
+	if effective == "" && obs.EffectivePeriod != nil {
+		effective = obs.EffectivePeriod.Start
+	}
+	recordedAt, err := parseTime(effective, []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"})
+	if err != nil {
+		sink.Error(location, err)
+		return nil
+	}
+
+	emit := func(code fhirCodeableConcept, q *fhirQuantity) error {
+		metric := loincMetric(code)
+		if metric == "" || q == nil {
+			return nil
+		}
+		if q.Value == nil {
+			sink.Error(location, fmt.Errorf("%s observation has no value", metric))
+			return nil
+		}
+		unit := q.Code
+		if unit == "" {
+			unit = q.Unit
+		}
+		return sink.Record(Record{Metric: metric, Value: *q.Value, Unit: unit, RecordedAt: recordedAt})
+	}
+
+	if err := emit(obs.Code, obs.ValueQuantity); err != nil {
+		return err
+	}
+	for _, comp := range obs.Component {
+		if err := emit(comp.Code, comp.ValueQuantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loincMetric(code fhirCodeableConcept) string {
+	for _, c := range code.Coding {
+		if c.System != "" && c.System != "http://loinc.org" {
+			continue
+		}
+		if metric, ok := loincMetrics[c.Code]; ok {
+			return metric
+		}
+	}
+	return ""
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading FHIR bundle: unexpected end of file")
+	}
+	if err != nil {
+		return fmt.Errorf("reading FHIR bundle: %w", err)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("reading FHIR bundle: expected %q, got %v", want, tok)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bundle wraps resources in a FHIR searchset Bundle.
+func bundle(resources ...string) string {
+	entries := make([]string, len(resources))
+	for i, r := range resources {
+		entries[i] = `{"fullUrl": "urn:uuid:` + strconv.Itoa(i) + `", "resource": ` + r + `}`
+	}
+	return `{"resourceType": "Bundle", "type": "searchset", "total": ` + strconv.Itoa(len(resources)) +
+		`, "entry": [` + strings.Join(entries, ",") + `], "meta": {"lastUpdated": "2026-10-18T12:00:00Z"}}`
+}
+
+func TestParseFHIR(t *testing.T) {
+	sink := parse(t, FormatFHIR, bundle(
+		`{"resourceType": "Observation", "status": "final",
+			"code": {"coding": [{"system": "http://loinc.org", "code": "8867-4", "display": "Heart rate"}]},
+			"valueQuantity": {"value": 72, "unit": "beats/minute", "system": "http://unitsofmeasure.org", "code": "/min"},
+			"effectiveDateTime": "2026-10-18T08:00:00+01:00"}`,
+		`{"resourceType": "Patient", "id": "42"}`,
+		// A blood pressure panel records its readings as components
+		`{"resourceType": "Observation",
+			"code": {"coding": [{"system": "http://loinc.org", "code": "85354-9"}]},
+			"component": [
+				{"code": {"coding": [{"system": "http://loinc.org", "code": "8480-6"}]}, "valueQuantity": {"value": 120, "code": "mm[Hg]"}},
+				{"code": {"coding": [{"system": "http://loinc.org", "code": "8462-4"}]}, "valueQuantity": {"value": 80, "code": "mm[Hg]"}}
+			],
+			"effectiveInstant": "2026-10-18T08:05:00.123Z"}`,
+		`{"resourceType": "Observation",
+			"code": {"coding": [{"system": "http://snomed.info/sct", "code": "431314004"}, {"system": "http://loinc.org", "code": "59408-5"}]},
+			"valueQuantity": {"value": 0.96, "unit": "%"},
+			"effectivePeriod": {"start": "2026-10-18T08:10:00", "end": "2026-10-18T08:11:00"}}`,
+		`{"resourceType": "Observation",
+			"code": {"coding": [{"code": "8310-5"}]},
+			"valueQuantity": {"value": 100.4, "code": "[degF]"},
+			"effectiveDateTime": "2026-10-19"}`,
+		`{"resourceType": "Observation",
+			"code": {"coding": [{"system": "http://loinc.org", "code": "29463-7"}]},
+			"valueQuantity": {"value": 70500, "unit": "g"},
+			"effectiveDateTime": "2026-10-19T07:00:00Z"}`,
+		// Not a vital sign, or not a LOINC code we know
+		`{"resourceType": "Observation",
+			"code": {"coding": [{"system": "http://loinc.org", "code": "718-7"}]},
+			"valueQuantity": {"value": 13.5, "unit": "g/dL"},
+			"effectiveDateTime": "2026-10-18T08:00:00Z"}`,
+		`{"resourceType": "Observation",
+			"code": {"coding": [{"system": "http://example.org/codes", "code": "8867-4"}]},
+			"valueQuantity": {"value": 60, "unit": "/min"},
+			"effectiveDateTime": "2026-10-18T08:00:00Z"}`,
+	))
+
+	checkRecords(t, sink.records, []wantRecord{
+		{"heart_rate", 72, time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC)},
+		{"systolic_bp", 120, time.Date(2026, 10, 18, 8, 5, 0, 123e6, time.UTC)},
+		{"diastolic_bp", 80, time.Date(2026, 10, 18, 8, 5, 0, 123e6, time.UTC)},
+		{"spo2", 96, time.Date(2026, 10, 18, 8, 10, 0, 0, time.UTC)},
+		{"temperature", 38, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"weight", 70.5, time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)},
+	})
+	checkErrors(t, sink.errors, nil)
+
+	// The unit code is preferred over the display unit
+	if sink.records[0].Unit != "/min" {
+		t.Errorf("heart rate unit = %q, want the UCUM code /min", sink.records[0].Unit)
+	}
+}
+
+func TestParseFHIRMalformedObservations(t *testing.T) {
+	sink := parse(t, FormatFHIR, bundle(
+		`{"resourceType": "Observation", "code": {"coding": [{"code": "8867-4"}]}, "valueQuantity": {"unit": "/min"}, "effectiveDateTime": "2026-10-18T08:00:00Z"}`,
+		`{"resourceType": "Observation", "code": {"coding": [{"code": "8867-4"}]}, "valueQuantity": {"value": 72}}`,
+		`{"resourceType": "Observation", "code": {"coding": [{"code": "8867-4"}]}, "valueQuantity": {"value": 72}, "effectiveDateTime": "18/10/2026"}`,
+		// A vital sign with no quantity at all, e.g. a data absent reason, is skipped
+		`{"resourceType": "Observation", "code": {"coding": [{"code": "8867-4"}]}, "dataAbsentReason": {"text": "not measured"}, "effectiveDateTime": "2026-10-18T08:00:00Z"}`,
+		`{"resourceType": "Observation", "code": {"coding": [{"code": "8867-4"}]}, "valueQuantity": {"value": 80}, "effectiveDateTime": "2026-10-18T09:00:00Z"}`,
+	))
+
+	checkRecords(t, sink.records, []wantRecord{{"heart_rate", 80, time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)}})
+	checkErrors(t, sink.errors, []string{
+		"entry 0: heart_rate observation has no value",
+		`entry 1: invalid timestamp ""`,
+		`entry 2: invalid timestamp "18/10/2026"`,
+	})
+}
+
+func TestParseFHIRInvalidBundle(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty", "", "unexpected end of file"},
+		{"not an object", `[{"resourceType": "Observation"}]`, `expected "{"`},
+		{"entry not a list", `{"entry": {"resource": {}}}`, `expected "["`},
+		{"broken entry", `{"entry": [{"resource": {"resourceType": "Observation", "valueQuantity": {"value": "72"}}}]}`, "entry 0"},
+		{"truncated", `{"resourceType": "Bundle", "entry": [`, "entry 0"},
+		{"broken field", `{"resourceType": }`, "reading FHIR bundle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Parse(FormatFHIR, strings.NewReader(tt.data), &testSink{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+
+	// A bundle with no entries imports nothing
+	sink := parse(t, FormatFHIR, `{"resourceType": "Bundle", "type": "collection"}`)
+	if len(sink.records) != 0 || len(sink.errors) != 0 {
+		t.Errorf("empty bundle gave %+v, %q", sink.records, sink.errors)
+	}
+}
@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format identifies the layout of an uploaded file of historical vitals.
+type Format string
+
+const (
+	FormatCSV         Format = "csv"
+	FormatAppleHealth Format = "apple_health"
+	FormatFHIR        Format = "fhir"
+)
+
+// Record is a single reading extracted from an import file. Value and Unit are
+// as found in the source; unit conversion is left to the caller.
+type Record struct {
+	Metric     string
+	Value      float64
+	Unit       string
+	RecordedAt time.Time
+}
+
+// Key identifies the reading a record is of, so the same reading found twice
+// in an import is only stored once. Timestamps are compared as instants,
+// whatever zone the source wrote them in.
+func (r Record) Key() string {
+	return r.Metric + "|" + r.RecordedAt.UTC().Format(time.RFC3339Nano)
+}
+
+// Sink receives records as they are parsed. Problems with individual entries
+// are reported through Error and do not stop the import; an error returned
+// from Record aborts it.
+type Sink interface {
+	Record(rec Record) error
+	Error(location string, err error)
+}
+
+// DetectFormat returns the explicitly requested format, or guesses one from
+// the uploaded file name when none was given.
+func DetectFormat(requested, filename string) (Format, error) {
+	switch Format(strings.ToLower(requested)) {
+	case FormatCSV, FormatAppleHealth, FormatFHIR:
+		return Format(strings.ToLower(requested)), nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported format %q", requested)
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".xml":
+		return FormatAppleHealth, nil
+	case ".json":
+		return FormatFHIR, nil
+	}
+	return "", fmt.Errorf("cannot detect format of %q", filename)
+}
+
+// Parse streams r in the given format, handing every record to sink.
+func Parse(format Format, r io.Reader, sink Sink) error {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r, sink)
+	case FormatAppleHealth:
+		return parseAppleHealth(r, sink)
+	case FormatFHIR:
+		return parseFHIR(r, sink)
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/vitals"
+)
+
+// testSink collects what a parser hands over.
+type testSink struct {
+	records []Record
+	errors  []string // "location: message"
+	fail    error    // returned from Record, to abort the parse
+}
+
+func (s *testSink) Record(rec Record) error {
+	if s.fail != nil {
+		return s.fail
+	}
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func (s *testSink) Error(location string, err error) {
+	s.errors = append(s.errors, location+": "+err.Error())
+}
+
+func parse(t *testing.T, format Format, data string) *testSink {
+	t.Helper()
+	sink := &testSink{}
+	if err := Parse(format, strings.NewReader(data), sink); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return sink
+}
+
+// wantRecord is a record after unit normalisation, which is what gets stored.
+type wantRecord struct {
+	metric string
+	value  float64
+	at     time.Time
+}
+
+func checkRecords(t *testing.T, got []Record, want []wantRecord) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		value, err := vitals.Normalize(g.Metric, g.Value, g.Unit)
+		if err != nil {
+			t.Errorf("record %d: Normalize(%s, %v, %q): %v", i, g.Metric, g.Value, g.Unit, err)
+			continue
+		}
+		if g.Metric != w.metric || value < w.value-0.01 || value > w.value+0.01 || !g.RecordedAt.Equal(w.at) {
+			t.Errorf("record %d = %s %v (%v %q) at %v, want %s %v at %v",
+				i, g.Metric, value, g.Value, g.Unit, g.RecordedAt, w.metric, w.value, w.at)
+		}
+	}
+}
+
+func checkErrors(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got errors %q, want %d", got, len(want))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("error %d = %q, want one starting %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		requested, filename string
+		want                Format
+		wantErr             bool
+	}{
+		{"csv", "export.xml", FormatCSV, false},
+		{"FHIR", "", FormatFHIR, false},
+		{"apple_health", "anything", FormatAppleHealth, false},
+		{"", "vitals.CSV", FormatCSV, false},
+		{"", "export.xml", FormatAppleHealth, false},
+		{"", "bundle.json", FormatFHIR, false},
+		{"", "vitals.xlsx", "", true},
+		{"", "", "", true},
+		{"hl7", "vitals.csv", "", true},
+	}
+	for _, tt := range tests {
+		got, err := DetectFormat(tt.requested, tt.filename)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("DetectFormat(%q, %q) = %q, %v; want %q, error %v", tt.requested, tt.filename, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if err := Parse("hl7", strings.NewReader(""), &testSink{}); err == nil {
+		t.Error("Parse of an unknown format succeeded")
+	}
+}
+
+func TestRecordKey(t *testing.T) {
+	at := time.Date(2026, 3, 29, 8, 30, 0, 0, time.UTC)
+	paris := time.FixedZone("CEST", 2*3600)
+	base := Record{Metric: "heart_rate", Value: 72, Unit: "bpm", RecordedAt: at}
+
+	tests := []struct {
+		name string
+		rec  Record
+		same bool
+	}{
+		{"identical", base, true},
+		// The key is the reading, not how it was written down
+		{"other value and unit", Record{Metric: "heart_rate", Value: 75, Unit: "count/min", RecordedAt: at}, true},
+		{"same instant in another zone", Record{Metric: "heart_rate", Value: 72, RecordedAt: at.In(paris)}, true},
+		{"other metric", Record{Metric: "respiratory_rate", Value: 72, RecordedAt: at}, false},
+		{"a second later", Record{Metric: "heart_rate", Value: 72, RecordedAt: at.Add(time.Second)}, false},
+		{"a millisecond later", Record{Metric: "heart_rate", Value: 72, RecordedAt: at.Add(time.Millisecond)}, false},
+		{"same wall clock in another zone", Record{Metric: "heart_rate", Value: 72, RecordedAt: time.Date(2026, 3, 29, 8, 30, 0, 0, paris)}, false},
+	}
+	for _, tt := range tests {
+		if same := tt.rec.Key() == base.Key(); same != tt.same {
+			t.Errorf("%s: key %q vs %q, same = %v, want %v", tt.name, tt.rec.Key(), base.Key(), same, tt.same)
+		}
+	}
+}
+
+func TestParseAbortsOnSinkError(t *testing.T) {
+	stop := errors.New("stop")
+	inputs := map[Format]string{
+		FormatCSV:         "metric,value,unit,recorded_at\nheart_rate,72,bpm,2026-10-18T08:00:00Z\n",
+		FormatAppleHealth: `<HealthData><Record type="HKQuantityTypeIdentifierHeartRate" unit="count/min" value="72" startDate="2026-10-18 08:00:00 +0000"/></HealthData>`,
+		FormatFHIR:        `{"entry": [{"resource": {"resourceType": "Observation", "code": {"coding": [{"code": "8867-4"}]}, "valueQuantity": {"value": 72}, "effectiveDateTime": "2026-10-18T08:00:00Z"}}]}`,
+	}
+	for format, data := range inputs {
+		if err := Parse(format, strings.NewReader(data), &testSink{fail: stop}); !errors.Is(err, stop) {
+			t.Errorf("%s: Parse = %v, want the sink's error", format, err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/internal/vitals"
+)
+
+// AnomalyDetector compares each patient's most recent readings with a rolling
+// per-metric, per-time-of-day baseline of their own history and raises soft
+// alerts for statistically significant deviations.
+type AnomalyDetector struct {
+	Repo *repository.Repository
+
+	// BaselineWindow is how much history, before the evidence window, the
+	// baseline is built from.
+	BaselineWindow time.Duration
+	// EvidenceWindow is the span of recent readings tested against the baseline.
+	EvidenceWindow time.Duration
+	// Threshold is the absolute modified z-score at which a deviation is flagged.
+	Threshold float64
+	// MinBaselineSamples and MinEvidenceSamples guard against flagging on too
+	// little data.
+	MinBaselineSamples int
+	MinEvidenceSamples int
+}
+
+// NewAnomalyDetector returns a detector with a 30 day baseline, a 24 hour
+// evidence window and the conventional 3.5 outlier threshold.
+func NewAnomalyDetector(repo *repository.Repository) *AnomalyDetector {
+	return &AnomalyDetector{
+		Repo:               repo,
+		BaselineWindow:     30 * 24 * time.Hour,
+		EvidenceWindow:     24 * time.Hour,
+		Threshold:          3.5,
+		MinBaselineSamples: 14,
+		MinEvidenceSamples: 3,
+	}
+}
+
+func (d *AnomalyDetector) Run(ctx context.Context) error {
+	now := time.Now()
+	windowStart := now.Add(-d.EvidenceWindow)
+
+	patientIDs, err := d.Repo.GetPatientIDsWithReadingsSince(windowStart)
+	if err != nil {
+		return err
+	}
+
+	for _, patientID := range patientIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for metric, m := range vitals.Metrics {
+			if m.Categorical {
+				continue
+			}
+			if err := d.checkMetric(patientID, metric, windowStart, now); err != nil {
+				log.Printf("Anomaly check for patient %d metric %s failed: %v", patientID, metric, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkMetric tests the median of each time-of-day bucket in the evidence
+// window against the same bucket's baseline. Using the window median rather
+// than single readings picks up sustained drift while ignoring one-off spikes.
+// Buckets are in UTC since we don't store patients' time zones.
+func (d *AnomalyDetector) checkMetric(patientID int, metric string, windowStart, windowEnd time.Time) error {
+	recent, err := d.Repo.GetVitalReadings(patientID, metric, windowStart, windowEnd)
+	if err != nil || len(recent) < d.MinEvidenceSamples {
+		return err
+	}
+	history, err := d.Repo.GetVitalReadings(patientID, metric, windowStart.Add(-d.BaselineWindow), windowStart)
+	if err != nil || len(history) < d.MinBaselineSamples {
+		return err
+	}
+
+	baselineValues := make(map[string][]float64)
+	for _, v := range history {
+		bucket := vitals.TimeOfDay(v.RecordedAt.UTC())
+		baselineValues[bucket] = append(baselineValues[bucket], v.Value)
+	}
+
+	evidence := make(map[string][]models.AlertEvidence)
+	recentValues := make(map[string][]float64)
+	for _, v := range recent {
+		bucket := vitals.TimeOfDay(v.RecordedAt.UTC())
+		recentValues[bucket] = append(recentValues[bucket], v.Value)
+		evidence[bucket] = append(evidence[bucket], models.AlertEvidence{Value: v.Value, RecordedAt: v.RecordedAt})
+	}
+
+	floor := vitals.Metrics[metric].Resolution
+	for bucket, values := range recentValues {
+		if len(values) < d.MinEvidenceSamples || len(baselineValues[bucket]) < d.MinBaselineSamples {
+			continue
+		}
+
+		baseline := vitals.ComputeBaseline(baselineValues[bucket], floor)
+		observed := vitals.Median(values)
+		score := baseline.Score(observed)
+		if score > -d.Threshold && score < d.Threshold {
+			continue
+		}
+
+		created, err := d.Repo.CreateVitalAlert(models.VitalAlert{
+			PatientID:       patientID,
+			Metric:          metric,
+			Kind:            "anomaly",
+			Severity:        "soft",
+			TimeOfDay:       bucket,
+			ObservedValue:   observed,
+			BaselineMedian:  baseline.Median,
+			BaselineMAD:     baseline.MAD,
+			BaselineSamples: baseline.Samples,
+			Score:           score,
+			WindowStart:     windowStart,
+			WindowEnd:       windowEnd,
+			Evidence:        evidence[bucket],
+		}, d.EvidenceWindow)
+		if err != nil {
+			return err
+		}
+		if created {
+			log.Printf("Raised %s anomaly alert for patient %d (%s, score %.1f)", metric, patientID, bucket, score)
+		}
+	}
+	return nil
+}
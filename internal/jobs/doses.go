@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+)
+
+// DoseScheduler materialises the due doses of every active dosing schedule a
+// little ahead of time, and marks doses nobody logged as missed.
+type DoseScheduler struct {
+	Repo *repository.Repository
+	// Horizon is how far ahead doses are generated.
+	Horizon time.Duration
+	// MissedAfter is how long a dose stays due before it counts as missed.
+	MissedAfter time.Duration
+}
+
+func NewDoseScheduler(repo *repository.Repository) *DoseScheduler {
+	return &DoseScheduler{
+		Repo:        repo,
+		Horizon:     48 * time.Hour,
+		MissedAfter: 4 * time.Hour,
+	}
+}
+
+func (s *DoseScheduler) Run(ctx context.Context) error {
+	now := time.Now()
+	from, to := now.Add(-s.MissedAfter), now.Add(s.Horizon)
+
+	schedules, err := s.Repo.GetActiveSchedules(from)
+	if err != nil {
+		return err
+	}
+
+	for _, sp := range schedules {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		times, err := DoseTimes(sp.Schedule, from, to)
+		if err != nil {
+			log.Printf("Skipping invalid schedule of prescription %d: %v", sp.PrescriptionID, err)
+			continue
+		}
+
+		doses := make([]models.MedicationDose, 0, len(times))
+		for _, t := range times {
+			doses = append(doses, models.MedicationDose{PrescriptionID: sp.PrescriptionID, PatientID: sp.PatientID, ScheduledAt: t})
+		}
+		if err := s.Repo.CreateDoses(doses); err != nil {
+			return err
+		}
+	}
+
+	missed, err := s.Repo.MarkMissedDoses(now.Add(-s.MissedAfter))
+	if err != nil {
+		return err
+	}
+	if missed > 0 {
+		log.Printf("Marked %d doses as missed", missed)
+	}
+	return nil
+}
+
+// DoseTimes returns the instants in [from, to) at which the schedule has a
+// dose due. Times of day are interpreted in the schedule's time zone, so doses
+// follow the patient's clock across DST changes.
+func DoseTimes(schedule models.DosingSchedule, from, to time.Time) ([]time.Time, error) {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.ParseInLocation("2006-01-02", schedule.StartDate, loc)
+	if err != nil {
+		return nil, err
+	}
+	var end time.Time
+	if schedule.EndDate != nil {
+		if end, err = time.ParseInLocation("2006-01-02", *schedule.EndDate, loc); err != nil {
+			return nil, err
+		}
+		end = end.AddDate(0, 0, 1) // the end date is inclusive
+	}
+
+	var times []time.Time
+	day := from.In(loc)
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if day.Before(start) || (!end.IsZero() && !day.Before(end)) {
+			continue
+		}
+		for _, tod := range schedule.Times {
+			clock, err := time.Parse("15:04", tod)
+			if err != nil {
+				return nil, err
+			}
+			t := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+			if !t.Before(from) && t.Before(to) {
+				times = append(times, t)
+			}
+		}
+	}
+	return times, nil
+}
@@ -0,0 +1,159 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+func utc(month time.Month, day, hour, min int) time.Time {
+	return time.Date(2026, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestDoseTimes(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule models.DosingSchedule
+		from, to time.Time
+		want     []time.Time
+	}{
+		{
+			name:     "twice a day",
+			schedule: models.DosingSchedule{Times: []string{"08:00", "20:00"}, Timezone: "UTC", StartDate: "2026-10-01"},
+			from:     utc(10, 18, 0, 0), to: utc(10, 20, 0, 0),
+			want: []time.Time{utc(10, 18, 8, 0), utc(10, 18, 20, 0), utc(10, 19, 8, 0), utc(10, 19, 20, 0)},
+		},
+		{
+			name:     "from is inclusive and to exclusive",
+			schedule: models.DosingSchedule{Times: []string{"08:00", "20:00"}, Timezone: "UTC", StartDate: "2026-10-01"},
+			from:     utc(10, 18, 8, 0), to: utc(10, 18, 20, 0),
+			want: []time.Time{utc(10, 18, 8, 0)},
+		},
+		{
+			name:     "local times in summer",
+			schedule: models.DosingSchedule{Times: []string{"08:00"}, Timezone: "Europe/London", StartDate: "2026-06-01"},
+			from:     utc(7, 1, 0, 0), to: utc(7, 2, 0, 0),
+			want: []time.Time{utc(7, 1, 7, 0)},
+		},
+		{
+			// The clocks go forward at 01:00 GMT on 29 March
+			name:     "spring forward keeps the wall clock",
+			schedule: models.DosingSchedule{Times: []string{"08:00", "22:00"}, Timezone: "Europe/London", StartDate: "2026-03-01"},
+			from:     utc(3, 28, 0, 0), to: utc(3, 30, 0, 0),
+			want: []time.Time{utc(3, 28, 8, 0), utc(3, 28, 22, 0), utc(3, 29, 7, 0), utc(3, 29, 21, 0)},
+		},
+		{
+			// 01:30 doesn't exist on 29 March, so the dose is taken an hour on
+			name:     "spring forward skips the missing hour",
+			schedule: models.DosingSchedule{Times: []string{"00:30", "01:30", "02:30"}, Timezone: "Europe/London", StartDate: "2026-03-01"},
+			from:     utc(3, 29, 0, 0), to: utc(3, 29, 12, 0),
+			want: []time.Time{utc(3, 29, 0, 30), utc(3, 29, 1, 30), utc(3, 29, 1, 30)},
+		},
+		{
+			// The clocks go back at 01:00 GMT on 25 October
+			name:     "fall back keeps the wall clock",
+			schedule: models.DosingSchedule{Times: []string{"08:00", "22:00"}, Timezone: "Europe/London", StartDate: "2026-10-01"},
+			from:     utc(10, 24, 0, 0), to: utc(10, 26, 0, 0),
+			want: []time.Time{utc(10, 24, 7, 0), utc(10, 24, 21, 0), utc(10, 25, 8, 0), utc(10, 25, 22, 0)},
+		},
+		{
+			name:     "New York spring forward",
+			schedule: models.DosingSchedule{Times: []string{"09:00"}, Timezone: "America/New_York", StartDate: "2026-03-01"},
+			from:     utc(3, 7, 0, 0), to: utc(3, 9, 0, 0),
+			want: []time.Time{utc(3, 7, 14, 0), utc(3, 8, 13, 0)},
+		},
+		{
+			// Local midnight falls mid-window, and days follow the patient's calendar
+			name:     "window across midnight",
+			schedule: models.DosingSchedule{Times: []string{"23:30", "00:15", "06:00"}, Timezone: "Asia/Tokyo", StartDate: "2026-10-01"},
+			from:     utc(10, 18, 13, 0), to: utc(10, 18, 16, 0), // 22:00 to 01:00 in Tokyo
+			want: []time.Time{utc(10, 18, 14, 30), utc(10, 18, 15, 15)},
+		},
+		{
+			name:     "west of UTC across midnight",
+			schedule: models.DosingSchedule{Times: []string{"21:00", "01:00"}, Timezone: "America/Los_Angeles", StartDate: "2026-10-18"},
+			from:     utc(10, 19, 0, 0), to: utc(10, 19, 12, 0), // 17:00 to 05:00 in Los Angeles
+			want: []time.Time{utc(10, 19, 4, 0), utc(10, 19, 8, 0)},
+		},
+		{
+			name:     "nothing before the start date",
+			schedule: models.DosingSchedule{Times: []string{"08:00"}, Timezone: "Europe/London", StartDate: "2026-10-20"},
+			from:     utc(10, 18, 0, 0), to: utc(10, 22, 0, 0),
+			want: []time.Time{utc(10, 20, 7, 0), utc(10, 21, 7, 0)},
+		},
+		{
+			name:     "the end date is inclusive",
+			schedule: models.DosingSchedule{Times: []string{"08:00", "23:30"}, Timezone: "Europe/London", StartDate: "2026-10-01", EndDate: strPtr("2026-10-19")},
+			from:     utc(10, 18, 0, 0), to: utc(10, 22, 0, 0),
+			want: []time.Time{utc(10, 18, 7, 0), utc(10, 18, 22, 30), utc(10, 19, 7, 0), utc(10, 19, 22, 30)},
+		},
+		{
+			name:     "ended",
+			schedule: models.DosingSchedule{Times: []string{"08:00"}, Timezone: "UTC", StartDate: "2026-10-01", EndDate: strPtr("2026-10-10")},
+			from:     utc(10, 18, 0, 0), to: utc(10, 20, 0, 0),
+		},
+		{
+			name:     "empty window",
+			schedule: models.DosingSchedule{Times: []string{"08:00"}, Timezone: "UTC", StartDate: "2026-10-01"},
+			from:     utc(10, 18, 9, 0), to: utc(10, 18, 9, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DoseTimes(tt.schedule, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("DoseTimes: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d doses %v, want %v", len(got), got, tt.want)
+			}
+			for i := range tt.want {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("dose %d at %v, want %v", i, got[i].UTC(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDoseTimesRepeatedHour(t *testing.T) {
+	// 01:30 happens twice on 25 October in London; the dose is due once
+	schedule := models.DosingSchedule{Times: []string{"01:30"}, Timezone: "Europe/London", StartDate: "2026-10-01"}
+	got, err := DoseTimes(schedule, utc(10, 24, 12, 0), utc(10, 25, 12, 0))
+	if err != nil {
+		t.Fatalf("DoseTimes: %v", err)
+	}
+	if len(got) != 1 || !(got[0].Equal(utc(10, 25, 0, 30)) || got[0].Equal(utc(10, 25, 1, 30))) {
+		t.Errorf("doses %v, want one at either 01:30", got)
+	}
+
+	// Every day around the change still has exactly one dose
+	got, err = DoseTimes(schedule, utc(10, 22, 0, 0), utc(10, 29, 0, 0))
+	if err != nil {
+		t.Fatalf("DoseTimes: %v", err)
+	}
+	if len(got) != 7 {
+		t.Errorf("got %d doses over a week, want 7: %v", len(got), got)
+	}
+}
+
+func TestDoseTimesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule models.DosingSchedule
+	}{
+		{"unknown time zone", models.DosingSchedule{Times: []string{"08:00"}, Timezone: "Mars/Olympus_Mons", StartDate: "2026-10-01"}},
+		{"bad start date", models.DosingSchedule{Times: []string{"08:00"}, Timezone: "UTC", StartDate: "01/10/2026"}},
+		{"bad end date", models.DosingSchedule{Times: []string{"08:00"}, Timezone: "UTC", StartDate: "2026-10-01", EndDate: strPtr("soon")}},
+		{"bad time", models.DosingSchedule{Times: []string{"8am"}, Timezone: "UTC", StartDate: "2026-10-01"}},
+		{"out of range time", models.DosingSchedule{Times: []string{"24:00"}, Timezone: "UTC", StartDate: "2026-10-01"}},
+	}
+	for _, tt := range tests {
+		if got, err := DoseTimes(tt.schedule, utc(10, 18, 0, 0), utc(10, 20, 0, 0)); err == nil {
+			t.Errorf("%s: DoseTimes = %v, want an error", tt.name, got)
+		}
+	}
+}
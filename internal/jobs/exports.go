@@ -0,0 +1,200 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/export"
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/internal/storage"
+	"github.com/RitwikGupta-0501/vital-watch/utils"
+)
+
+// DataExporter builds the data exports patients have asked for and deletes
+// archives once they expire.
+type DataExporter struct {
+	Repo  *repository.Repository
+	Store storage.Store
+	// Retention is how long a built archive can be downloaded before it is
+	// deleted.
+	Retention time.Duration
+}
+
+func NewDataExporter(repo *repository.Repository, store storage.Store, retention time.Duration) *DataExporter {
+	return &DataExporter{Repo: repo, Store: store, Retention: retention}
+}
+
+func (e *DataExporter) Run(ctx context.Context) error {
+	if err := e.deleteExpired(ctx); err != nil {
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		exp, err := e.Repo.ClaimPendingDataExport()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := e.build(ctx, exp); err != nil {
+			if ctx.Err() != nil {
+				// Left running, so it is picked up again after a restart
+				return ctx.Err()
+			}
+			log.Printf("Failed to build data export %d: %v", exp.ID, err)
+			err = e.Repo.FailDataExport(exp.ID, err.Error(), models.Notification{
+				RecipientRole: "patient",
+				RecipientID:   exp.PatientID,
+				Kind:          "data_export_failed",
+				Title:         "Your data export failed",
+				Body:          "We couldn't put together your data export. Please try again later.",
+				Data:          map[string]any{"export_id": exp.ID},
+			})
+			if err != nil {
+				log.Printf("Failed to record failure of data export %d: %v", exp.ID, err)
+			}
+		}
+	}
+}
+
+// build writes the archive to a temporary file, since the store needs its
+// size up front, then stores it and tells the patient it is ready.
+func (e *DataExporter) build(ctx context.Context, exp models.DataExport) error {
+	data, err := e.gather(exp.PatientID)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := export.Write(ctx, tmp, e.Store, data); err != nil {
+		return err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("exports/%d/%s.zip", exp.PatientID, token)
+	if err := e.Store.Put(ctx, key, tmp, size, "application/zip"); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(e.Retention)
+	err = e.Repo.CompleteDataExport(exp.ID, key, size, expiresAt, models.Notification{
+		RecipientRole: "patient",
+		RecipientID:   exp.PatientID,
+		Kind:          "data_export_ready",
+		Title:         "Your data export is ready",
+		Body:          "You can download it until " + expiresAt.UTC().Format("2 Jan 2006 15:04 MST") + ".",
+		Data:          map[string]any{"export_id": exp.ID},
+	})
+	if err != nil {
+		if err := e.Store.Delete(context.Background(), key); err != nil {
+			log.Printf("Failed to delete orphaned export %s: %v", key, err)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Data export %d was cancelled while it was built", exp.ID)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (e *DataExporter) gather(patientID int) (export.Data, error) {
+	d := export.Data{GeneratedAt: time.Now().UTC()}
+	var err error
+
+	if d.Patient, err = e.Repo.GetPatientByID(patientID); err != nil {
+		return d, fmt.Errorf("fetching profile: %w", err)
+	}
+	if d.Appointments, err = e.Repo.GetAppointmentsByPatientID(patientID); err != nil {
+		return d, fmt.Errorf("fetching appointments: %w", err)
+	}
+	if d.Notes, err = e.Repo.GetSignedEncounterNotesByPatientID(patientID); err != nil {
+		return d, fmt.Errorf("fetching notes: %w", err)
+	}
+	if d.LabOrders, err = e.Repo.GetLabOrdersByPatientID(patientID); err != nil {
+		return d, fmt.Errorf("fetching lab orders: %w", err)
+	}
+	if d.LabReports, err = e.Repo.GetLabReportsByPatientID(patientID); err != nil {
+		return d, fmt.Errorf("fetching lab reports: %w", err)
+	}
+	if d.Prescriptions, err = e.Repo.GetPrescriptionsByPatientID(patientID, ""); err != nil {
+		return d, fmt.Errorf("fetching prescriptions: %w", err)
+	}
+	if d.Allergies, err = e.Repo.GetAllergiesByPatientID(patientID); err != nil {
+		return d, fmt.Errorf("fetching allergies: %w", err)
+	}
+	if d.Conditions, err = e.Repo.GetConditionsByPatientID(patientID); err != nil {
+		return d, fmt.Errorf("fetching conditions: %w", err)
+	}
+	if d.Medications, err = e.Repo.GetMedicationsByPatientID(patientID, false); err != nil {
+		return d, fmt.Errorf("fetching medications: %w", err)
+	}
+	if d.Vitals, err = e.Repo.GetVitalReadings(patientID, "", time.Time{}, d.GeneratedAt); err != nil {
+		return d, fmt.Errorf("fetching vitals: %w", err)
+	}
+	if d.Alerts, err = e.Repo.GetAlertsByPatientID(patientID, true); err != nil {
+		return d, fmt.Errorf("fetching alerts: %w", err)
+	}
+	if d.Documents, err = e.Repo.GetDocumentsByPatientID(patientID, repository.DocumentFilter{}); err != nil {
+		return d, fmt.Errorf("fetching documents: %w", err)
+	}
+	if d.Consents, err = e.Repo.GetConsentsByPatientID(patientID, true); err != nil {
+		return d, fmt.Errorf("fetching consents: %w", err)
+	}
+	if d.Messages, err = e.Repo.GetAllNotifications("patient", patientID); err != nil {
+		return d, fmt.Errorf("fetching messages: %w", err)
+	}
+	return d, nil
+}
+
+func (e *DataExporter) deleteExpired(ctx context.Context) error {
+	exports, err := e.Repo.GetExpiredDataExports(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, exp := range exports {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := e.Store.Delete(ctx, exp.FileName)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Failed to delete expired export %s: %v", exp.FileName, err)
+			continue
+		}
+		if err := e.Repo.ExpireDataExport(exp.ID); err != nil {
+			log.Printf("Failed to expire data export %d: %v", exp.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/internal/vitals"
+)
+
+// NEWS2Scorer recomputes the NEWS2 early warning score of every patient with
+// new readings, or whose score rests on readings that have grown too old, and
+// raises an escalation alert when a patient moves up into a higher risk band.
+// A score none of whose readings count any more is expired.
+type NEWS2Scorer struct {
+	Repo  *repository.Repository
+	Bands vitals.NEWS2Bands
+	// Lookback is how old a reading may be and still count as current.
+	Lookback time.Duration
+}
+
+func NewNEWS2Scorer(repo *repository.Repository, bands vitals.NEWS2Bands) *NEWS2Scorer {
+	return &NEWS2Scorer{
+		Repo:     repo,
+		Bands:    bands,
+		Lookback: 24 * time.Hour,
+	}
+}
+
+func (s *NEWS2Scorer) Run(ctx context.Context) error {
+	since := time.Now().Add(-s.Lookback)
+
+	patientIDs, err := s.Repo.GetPatientIDsNeedingNEWS2(vitals.NEWS2Metrics, since)
+	if err != nil {
+		return err
+	}
+
+	for _, patientID := range patientIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.scorePatient(patientID, since); err != nil {
+			log.Printf("NEWS2 scoring for patient %d failed: %v", patientID, err)
+		}
+	}
+	return nil
+}
+
+func (s *NEWS2Scorer) scorePatient(patientID int, since time.Time) error {
+	readings, err := s.Repo.GetLatestReadings(patientID, vitals.NEWS2Metrics, since)
+	if err != nil {
+		return err
+	}
+	scale, err := s.Repo.GetPatientSpO2Scale(patientID)
+	if err != nil {
+		return err
+	}
+
+	previous, err := s.Repo.GetLatestNEWS2Score(patientID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if len(readings) == 0 {
+		if err != nil || previous.ExpiredAt != nil {
+			return nil
+		}
+		return s.Repo.ExpireNEWS2Score(previous.ID)
+	}
+	if previous.ExpiredAt != nil {
+		previous = models.NEWS2Score{}
+	}
+
+	values := make(map[string]float64, len(readings))
+	for metric, r := range readings {
+		values[metric] = r.Value
+	}
+	result := vitals.ComputeNEWS2(values, scale == 2, s.Bands)
+
+	_, err = s.Repo.CreateNEWS2Score(models.NEWS2Score{
+		PatientID: patientID,
+		Total:     result.Total,
+		Risk:      result.Risk,
+		SubScores: result.SubScores,
+		SpO2Scale: scale,
+		Complete:  len(result.Missing) == 0,
+		Inputs:    readings,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Only escalate on the way up, and never into the low band
+	if vitals.RiskRank(result.Risk) <= vitals.RiskRank(previous.Risk) || result.Risk == vitals.RiskLow {
+		return nil
+	}
+
+	evidence := make([]models.AlertEvidence, 0, len(readings))
+	var windowStart, windowEnd time.Time
+	for _, r := range readings {
+		evidence = append(evidence, models.AlertEvidence{Metric: r.Metric, Value: r.Value, RecordedAt: r.RecordedAt})
+		if windowStart.IsZero() || r.RecordedAt.Before(windowStart) {
+			windowStart = r.RecordedAt
+		}
+		if r.RecordedAt.After(windowEnd) {
+			windowEnd = r.RecordedAt
+		}
+	}
+
+	_, err = s.Repo.CreateVitalAlert(models.VitalAlert{
+		PatientID:     patientID,
+		Metric:        "news2",
+		Kind:          "news2",
+		Severity:      result.Risk,
+		ObservedValue: float64(result.Total),
+		Score:         float64(result.Total),
+		WindowStart:   windowStart,
+		WindowEnd:     windowEnd,
+		Evidence:      evidence,
+	}, 0)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("NEWS2 escalation for patient %d: %s risk (score %d)", patientID, result.Risk, result.Total)
+	return nil
+}
@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/internal/storage"
+)
+
+// RetentionPurger deletes closed accounts' data once its retention period
+// has passed: the stored files first, then the rows. With DryRun it only
+// logs what it would delete.
+type RetentionPurger struct {
+	Repo   *repository.Repository
+	Store  storage.Store
+	Policy repository.RetentionPolicy
+	DryRun bool
+}
+
+func NewRetentionPurger(repo *repository.Repository, store storage.Store, policy repository.RetentionPolicy, dryRun bool) *RetentionPurger {
+	return &RetentionPurger{Repo: repo, Store: store, Policy: policy, DryRun: dryRun}
+}
+
+func (p *RetentionPurger) Run(ctx context.Context) error {
+	candidates, err := p.Repo.GetRetentionReport(p.Policy, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, c := range candidates {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if p.DryRun {
+			log.Printf("Retention dry run: would purge %s of closed patient %d (due %s): rows %v, %d files",
+				c.Category, c.PatientID, c.DueAt.Format(time.RFC3339), c.Rows, len(c.Files))
+			continue
+		}
+
+		failed := false
+		for _, name := range c.Files {
+			err := p.Store.Delete(ctx, name)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				log.Printf("Failed to delete %s of closed patient %d: %v", name, c.PatientID, err)
+				failed = true
+			}
+		}
+		if failed {
+			// Keep the rows so the files are retried on the next run
+			continue
+		}
+
+		if err := p.Repo.PurgeClosedPatientData(c); err != nil {
+			log.Printf("Failed to purge %s of closed patient %d: %v", c.Category, c.PatientID, err)
+			continue
+		}
+		log.Printf("Purged %s of closed patient %d: rows %v, %d files", c.Category, c.PatientID, c.Rows, len(c.Files))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"log"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/envelope"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+)
+
+// KeyRewrapper moves the data keys of encrypted files onto the current master
+// key after a rotation, so old master keys can be retired. Files themselves
+// aren't touched.
+type KeyRewrapper struct {
+	Repo *repository.Repository
+	Keys envelope.KeyProvider
+	// BatchSize is how many data keys are loaded at a time.
+	BatchSize int
+}
+
+func NewKeyRewrapper(repo *repository.Repository, keys envelope.KeyProvider) *KeyRewrapper {
+	return &KeyRewrapper{
+		Repo:      repo,
+		Keys:      keys,
+		BatchSize: 500,
+	}
+}
+
+func (k *KeyRewrapper) Run(ctx context.Context) error {
+	current := k.Keys.CurrentKeyID()
+	total := 0
+	for {
+		keys, err := k.Repo.GetDataKeysToRewrap(current, k.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		rewrapped := 0
+		for _, dk := range keys {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			dataKey, err := k.Keys.Unwrap(ctx, dk.MasterKeyID, dk.WrappedKey)
+			if err != nil {
+				log.Printf("Failed to unwrap data key of %s with master key %s: %v", dk.ObjectKey, dk.MasterKeyID, err)
+				continue
+			}
+			keyID, wrapped, err := k.Keys.Wrap(ctx, dataKey)
+			if err != nil {
+				return err
+			}
+			if err := k.Repo.RewrapDataKey(dk.ObjectKey, dk.MasterKeyID, keyID, wrapped); err != nil {
+				log.Printf("Failed to rewrap data key of %s: %v", dk.ObjectKey, err)
+				continue
+			}
+			rewrapped++
+		}
+
+		total += rewrapped
+		// Stop when done, or when every remaining key is failing
+		if len(keys) < k.BatchSize || rewrapped == 0 {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("Rewrapped %d data keys with master key %s", total, current)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package jobs
+
+import (
+	"context"
+	"log"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/filecheck"
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/internal/storage"
+)
+
+// FileScanner scans uploaded files that were stored quarantined, releasing
+// the clean ones and telling the uploader about infected ones.
+type FileScanner struct {
+	Repo    *repository.Repository
+	Store   storage.Store
+	Scanner filecheck.Scanner
+	// BatchSize is how many files of each kind are scanned per run.
+	BatchSize int
+}
+
+func NewFileScanner(repo *repository.Repository, store storage.Store, scanner filecheck.Scanner) *FileScanner {
+	return &FileScanner{
+		Repo:      repo,
+		Store:     store,
+		Scanner:   scanner,
+		BatchSize: 100,
+	}
+}
+
+func (s *FileScanner) Run(ctx context.Context) error {
+	if err := s.scanPrescriptions(ctx); err != nil {
+		return err
+	}
+	if err := s.scanDocuments(ctx); err != nil {
+		return err
+	}
+	return s.scanLabReports(ctx)
+}
+
+func (s *FileScanner) scanPrescriptions(ctx context.Context) error {
+	pending, err := s.Repo.GetPrescriptionsPendingScan(s.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, pres := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		verdict, err := s.scan(ctx, pres.FileName)
+		if err != nil {
+			// Left pending, so it's retried on the next run
+			log.Printf("Failed to scan prescription %d: %v", pres.ID, err)
+			continue
+		}
+
+		status, notify := "clean", (*models.Notification)(nil)
+		if verdict.Infected {
+			log.Printf("Prescription %d quarantined: malware scan found %s", pres.ID, verdict.Threat)
+			status = "infected"
+			notify = &models.Notification{
+				RecipientRole: "doctor",
+				RecipientID:   pres.DoctorID,
+				Kind:          "prescription_quarantined",
+				Title:         "Prescription file failed a malware scan",
+				Body:          pres.Medication + "\nThe file has been quarantined. Please revoke the prescription and upload a clean copy.",
+				Data:          map[string]any{"prescription_id": pres.ID, "patient_id": pres.PatientID},
+			}
+		}
+		if err := s.Repo.RecordPrescriptionScan(pres.ID, status, verdict.Threat, notify); err != nil {
+			log.Printf("Failed to record scan of prescription %d: %v", pres.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *FileScanner) scanDocuments(ctx context.Context) error {
+	pending, err := s.Repo.GetDocumentsPendingScan(s.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		verdict, err := s.scan(ctx, doc.FileName)
+		if err != nil {
+			log.Printf("Failed to scan document %d: %v", doc.ID, err)
+			continue
+		}
+
+		status, notify := "clean", (*models.Notification)(nil)
+		if verdict.Infected {
+			log.Printf("Document %d quarantined: malware scan found %s", doc.ID, verdict.Threat)
+			status = "infected"
+			notify = &models.Notification{
+				RecipientRole: "patient",
+				RecipientID:   doc.PatientID,
+				Kind:          "document_quarantined",
+				Title:         "Uploaded document failed a malware scan",
+				Body:          doc.Title + "\nThe file has been quarantined. Please delete it and upload a clean copy.",
+				Data:          map[string]any{"document_id": doc.ID},
+			}
+		}
+		if err := s.Repo.RecordDocumentScan(doc.ID, status, verdict.Threat, notify); err != nil {
+			log.Printf("Failed to record scan of document %d: %v", doc.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *FileScanner) scanLabReports(ctx context.Context) error {
+	pending, err := s.Repo.GetLabReportsPendingScan(s.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, rep := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		verdict, err := s.scan(ctx, rep.FileName)
+		if err != nil {
+			log.Printf("Failed to scan lab report %d: %v", rep.ID, err)
+			continue
+		}
+
+		status := "clean"
+		if verdict.Infected {
+			// Sent by a lab's system rather than a user, so there is no one
+			// to ask for a clean copy
+			log.Printf("Lab report %d of order %d quarantined: malware scan found %s", rep.ID, rep.OrderID, verdict.Threat)
+			status = "infected"
+		}
+		if err := s.Repo.RecordLabReportScan(rep.ID, status, verdict.Threat, nil); err != nil {
+			log.Printf("Failed to record scan of lab report %d: %v", rep.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *FileScanner) scan(ctx context.Context, key string) (filecheck.Verdict, error) {
+	out, err := s.Store.Get(ctx, key)
+	if err != nil {
+		return filecheck.Verdict{}, err
+	}
+	defer out.Body.Close()
+	return s.Scanner.Scan(ctx, out.Body)
+}
@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/repository"
+	"github.com/RitwikGupta-0501/vital-watch/internal/storage"
+)
+
+// UploadCleaner deletes direct prescription uploads that were never
+// confirmed, along with whatever file the client put in storage.
+type UploadCleaner struct {
+	Repo  *repository.Repository
+	Store storage.Store
+}
+
+func NewUploadCleaner(repo *repository.Repository, store storage.Store) *UploadCleaner {
+	return &UploadCleaner{Repo: repo, Store: store}
+}
+
+func (u *UploadCleaner) Run(ctx context.Context) error {
+	uploads, err := u.Repo.GetExpiredPrescriptionUploads(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, upload := range uploads {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := u.Store.Delete(ctx, upload.FileName)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Failed to delete expired upload %s: %v", upload.FileName, err)
+			continue
+		}
+		if err := u.Repo.DeletePrescriptionUpload(upload.ID); err != nil {
+			log.Printf("Failed to delete expired upload %d: %v", upload.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Package labs holds the local catalogue of orderable lab test panels, works
+// out abnormal flags against reference ranges, and reads lab results sent as
+// HL7 v2 ORU messages.
+package labs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Abnormal flags, from HL7 table 0078.
+const (
+	FlagNormal       = "N"
+	FlagLow          = "L"
+	FlagHigh         = "H"
+	FlagCriticalLow  = "LL"
+	FlagCriticalHigh = "HH"
+	FlagAbnormal     = "A"
+)
+
+// Analyte is a single measurement, identified by its LOINC code, with the
+// adult reference range results are flagged against.
+type Analyte struct {
+	Code         string   `json:"code"`
+	Name         string   `json:"name"`
+	Unit         string   `json:"unit"`
+	Low          *float64 `json:"low,omitempty"`
+	High         *float64 `json:"high,omitempty"`
+	CriticalLow  *float64 `json:"critical_low,omitempty"`
+	CriticalHigh *float64 `json:"critical_high,omitempty"`
+}
+
+// Panel is a test that can be ordered, measuring one or more analytes.
+type Panel struct {
+	Code     string    `json:"code"`
+	Name     string    `json:"name"`
+	Analytes []Analyte `json:"analytes"`
+}
+
+// Catalog is the panels doctors can order, indexed by panel and analyte
+// code.
+type Catalog struct {
+	Panels []Panel `json:"panels"`
+
+	panels   map[string]*Panel
+	analytes map[string]*Analyte
+}
+
+// Load reads and indexes a catalogue file.
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := c.index(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &c, nil
+}
+
+func (c *Catalog) index() error {
+	c.panels = make(map[string]*Panel)
+	c.analytes = make(map[string]*Analyte)
+	for i := range c.Panels {
+		p := &c.Panels[i]
+		if _, dup := c.panels[p.Code]; dup {
+			return fmt.Errorf("panel %s is listed twice", p.Code)
+		}
+		if len(p.Analytes) == 0 {
+			return fmt.Errorf("panel %s has no analytes", p.Code)
+		}
+		c.panels[p.Code] = p
+
+		// An analyte can be in several panels but must be described the
+		// same way in each
+		for j := range p.Analytes {
+			a := &p.Analytes[j]
+			if prev, ok := c.analytes[a.Code]; ok && (prev.Name != a.Name || prev.Unit != a.Unit) {
+				return fmt.Errorf("analyte %s is described differently in panel %s", a.Code, p.Code)
+			}
+			c.analytes[a.Code] = a
+		}
+	}
+	return nil
+}
+
+// Panel finds a panel by its code.
+func (c *Catalog) Panel(code string) *Panel {
+	return c.panels[strings.TrimSpace(code)]
+}
+
+// Analyte finds an analyte by its LOINC code.
+func (c *Catalog) Analyte(code string) *Analyte {
+	return c.analytes[strings.TrimSpace(code)]
+}
+
+// Complete fills in what the result leaves out from the catalogue: the
+// analyte's name, unit and, if the units agree, its reference range. It then
+// flags the result if it isn't flagged already.
+func (c *Catalog) Complete(r *models.LabResult) {
+	if a := c.Analyte(r.AnalyteCode); a != nil {
+		if r.AnalyteName == "" {
+			r.AnalyteName = a.Name
+		}
+		if r.Unit == "" {
+			r.Unit = a.Unit
+		}
+		if r.RefLow == nil && r.RefHigh == nil && r.RefText == "" && strings.EqualFold(r.Unit, a.Unit) {
+			r.RefLow, r.RefHigh = a.Low, a.High
+			if r.Flag == "" && r.Value != nil {
+				r.Flag = Flag(*r.Value, a)
+			}
+		}
+	}
+	if r.Flag == "" && r.Value != nil {
+		r.Flag = Flag(*r.Value, &Analyte{Low: r.RefLow, High: r.RefHigh})
+	}
+}
+
+// Flag compares a numeric result with the analyte's critical limits and
+// reference range. It returns "" if the analyte has no range to compare
+// with.
+func Flag(value float64, a *Analyte) string {
+	switch {
+	case a.CriticalLow != nil && value < *a.CriticalLow:
+		return FlagCriticalLow
+	case a.CriticalHigh != nil && value > *a.CriticalHigh:
+		return FlagCriticalHigh
+	case a.Low != nil && value < *a.Low:
+		return FlagLow
+	case a.High != nil && value > *a.High:
+		return FlagHigh
+	case a.Low != nil || a.High != nil:
+		return FlagNormal
+	}
+	return ""
+}
+
+// IsAbnormal reports whether a flag marks a result outside its range.
+func IsAbnormal(flag string) bool {
+	return flag != "" && flag != FlagNormal
+}
@@ -0,0 +1,208 @@
+package labs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func TestFlag(t *testing.T) {
+	potassium := &Analyte{Low: ptr(3.5), High: ptr(5.1), CriticalLow: ptr(2.8), CriticalHigh: ptr(6.2)}
+	tests := []struct {
+		value float64
+		a     *Analyte
+		want  string
+	}{
+		{4.2, potassium, FlagNormal},
+		{3.5, potassium, FlagNormal},
+		{5.1, potassium, FlagNormal},
+		{3.4, potassium, FlagLow},
+		{5.2, potassium, FlagHigh},
+		{2.8, potassium, FlagLow},
+		{2.7, potassium, FlagCriticalLow},
+		{6.2, potassium, FlagHigh},
+		{6.3, potassium, FlagCriticalHigh},
+		{5.8, &Analyte{High: ptr(5.7)}, FlagHigh},
+		{1, &Analyte{High: ptr(5.7)}, FlagNormal},
+		{35, &Analyte{Low: ptr(40)}, FlagLow},
+		{12, &Analyte{}, ""},
+	}
+	for _, tt := range tests {
+		if got := Flag(tt.value, tt.a); got != tt.want {
+			t.Errorf("Flag(%v, %+v) = %q, want %q", tt.value, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestIsAbnormal(t *testing.T) {
+	for flag, want := range map[string]bool{
+		"": false, FlagNormal: false, FlagLow: true, FlagHigh: true,
+		FlagCriticalLow: true, FlagCriticalHigh: true, FlagAbnormal: true,
+	} {
+		if got := IsAbnormal(flag); got != want {
+			t.Errorf("IsAbnormal(%q) = %v, want %v", flag, got, want)
+		}
+	}
+}
+
+func testCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	c := &Catalog{Panels: []Panel{{
+		Code: "51990-0", Name: "Basic metabolic panel (BMP)",
+		Analytes: []Analyte{
+			{Code: "2823-3", Name: "Potassium", Unit: "mmol/L", Low: ptr(3.5), High: ptr(5.1), CriticalLow: ptr(2.8), CriticalHigh: ptr(6.2)},
+			{Code: "2345-7", Name: "Glucose", Unit: "mg/dL", Low: ptr(70), High: ptr(99)},
+		},
+	}}}
+	if err := c.index(); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	return c
+}
+
+func TestComplete(t *testing.T) {
+	c := testCatalog(t)
+	tests := []struct {
+		name string
+		in   models.LabResult
+		want models.LabResult
+	}{
+		{
+			name: "fills in from the catalogue",
+			in:   models.LabResult{AnalyteCode: "2823-3", Value: ptr(6.5)},
+			want: models.LabResult{AnalyteCode: "2823-3", AnalyteName: "Potassium", Unit: "mmol/L", RefLow: ptr(3.5), RefHigh: ptr(5.1), Flag: FlagCriticalHigh},
+		},
+		{
+			name: "keeps what the lab sent",
+			in:   models.LabResult{AnalyteCode: "2823-3", AnalyteName: "K+", Value: ptr(5.4), RefLow: ptr(3.6), RefHigh: ptr(5.6), Flag: FlagNormal},
+			want: models.LabResult{AnalyteCode: "2823-3", AnalyteName: "K+", Unit: "mmol/L", Value: ptr(5.4), RefLow: ptr(3.6), RefHigh: ptr(5.6), Flag: FlagNormal},
+		},
+		{
+			name: "flags against the lab's range",
+			in:   models.LabResult{AnalyteCode: "2823-3", Value: ptr(5.4), RefLow: ptr(3.6), RefHigh: ptr(5.3)},
+			want: models.LabResult{AnalyteCode: "2823-3", AnalyteName: "Potassium", Unit: "mmol/L", Value: ptr(5.4), RefLow: ptr(3.6), RefHigh: ptr(5.3), Flag: FlagHigh},
+		},
+		{
+			name: "other units don't take the catalogue's range",
+			in:   models.LabResult{AnalyteCode: "2345-7", Unit: "mmol/L", Value: ptr(5.2)},
+			want: models.LabResult{AnalyteCode: "2345-7", AnalyteName: "Glucose", Unit: "mmol/L", Value: ptr(5.2)},
+		},
+		{
+			name: "unit case doesn't matter",
+			in:   models.LabResult{AnalyteCode: "2345-7", Unit: "MG/DL", Value: ptr(120)},
+			want: models.LabResult{AnalyteCode: "2345-7", AnalyteName: "Glucose", Unit: "MG/DL", Value: ptr(120), RefLow: ptr(70), RefHigh: ptr(99), Flag: FlagHigh},
+		},
+		{
+			name: "a written range is kept as is",
+			in:   models.LabResult{AnalyteCode: "2345-7", Value: ptr(120), RefText: "see report"},
+			want: models.LabResult{AnalyteCode: "2345-7", AnalyteName: "Glucose", Unit: "mg/dL", Value: ptr(120), RefText: "see report"},
+		},
+		{
+			name: "text results aren't flagged",
+			in:   models.LabResult{AnalyteCode: "2345-7", ValueText: "Haemolysed"},
+			want: models.LabResult{AnalyteCode: "2345-7", AnalyteName: "Glucose", Unit: "mg/dL", ValueText: "Haemolysed", RefLow: ptr(70), RefHigh: ptr(99)},
+		},
+		{
+			name: "analytes outside the catalogue",
+			in:   models.LabResult{AnalyteCode: "1988-5", Value: ptr(12), RefHigh: ptr(5)},
+			want: models.LabResult{AnalyteCode: "1988-5", Value: ptr(12), RefHigh: ptr(5), Flag: FlagHigh},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.in
+			c.Complete(&got)
+			if got.AnalyteName != tt.want.AnalyteName || got.Unit != tt.want.Unit || got.Flag != tt.want.Flag ||
+				got.RefText != tt.want.RefText || !sameValue(got.RefLow, tt.want.RefLow) || !sameValue(got.RefHigh, tt.want.RefHigh) {
+				t.Errorf("Complete = name %q unit %q range %s-%s %q flag %q; want name %q unit %q range %s-%s %q flag %q",
+					got.AnalyteName, got.Unit, show(got.RefLow), show(got.RefHigh), got.RefText, got.Flag,
+					tt.want.AnalyteName, tt.want.Unit, show(tt.want.RefLow), show(tt.want.RefHigh), tt.want.RefText, tt.want.Flag)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	c := testCatalog(t)
+	if p := c.Panel(" 51990-0 "); p == nil || p.Name != "Basic metabolic panel (BMP)" {
+		t.Errorf("Panel(51990-0) = %+v", p)
+	}
+	if a := c.Analyte("2345-7"); a == nil || a.Name != "Glucose" {
+		t.Errorf("Analyte(2345-7) = %+v", a)
+	}
+	if p := c.Panel("58410-2"); p != nil {
+		t.Errorf("Panel(58410-2) = %+v, want nil", p)
+	}
+	if a := c.Analyte("718-7"); a != nil {
+		t.Errorf("Analyte(718-7) = %+v, want nil", a)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"invalid JSON", `{"panels": [`, "parsing"},
+		{"duplicate panel", `{"panels": [
+			{"code": "P1", "name": "One", "analytes": [{"code": "A1", "name": "A", "unit": "g/dL"}]},
+			{"code": "P1", "name": "Two", "analytes": [{"code": "A2", "name": "B", "unit": "g/dL"}]}
+		]}`, "panel P1 is listed twice"},
+		{"no analytes", `{"panels": [{"code": "P1", "name": "One", "analytes": []}]}`, "panel P1 has no analytes"},
+		{"analyte described differently", `{"panels": [
+			{"code": "P1", "name": "One", "analytes": [{"code": "A1", "name": "A", "unit": "g/dL"}]},
+			{"code": "P2", "name": "Two", "analytes": [{"code": "A1", "name": "A", "unit": "mg/dL"}]}
+		]}`, "analyte A1 is described differently in panel P2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "panels.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+
+	// An analyte may be shared by panels that describe it the same way
+	path := filepath.Join(t.TempDir(), "panels.json")
+	shared := `{"panels": [
+		{"code": "P1", "name": "One", "analytes": [{"code": "A1", "name": "A", "unit": "g/dL"}]},
+		{"code": "P2", "name": "Two", "analytes": [{"code": "A1", "name": "A", "unit": "g/dL"}]}
+	]}`
+	if err := os.WriteFile(path, []byte(shared), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err != nil {
+		t.Errorf("Load with a shared analyte: %v", err)
+	}
+}
+
+func TestLoadShippedCatalog(t *testing.T) {
+	c, err := Load(filepath.Join("..", "..", "data", "lab_panels.json"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, p := range c.Panels {
+		for _, a := range p.Analytes {
+			if a.Low != nil && a.High != nil && *a.Low > *a.High {
+				t.Errorf("%s: range %v-%v is inverted", a.Code, *a.Low, *a.High)
+			}
+			if a.CriticalLow != nil && a.Low != nil && *a.CriticalLow > *a.Low {
+				t.Errorf("%s: critical low %v is above the range", a.Code, *a.CriticalLow)
+			}
+			if a.CriticalHigh != nil && a.High != nil && *a.CriticalHigh < *a.High {
+				t.Errorf("%s: critical high %v is below the range", a.Code, *a.CriticalHigh)
+			}
+		}
+	}
+}
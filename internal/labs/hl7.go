@@ -0,0 +1,314 @@
+package labs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Message is an HL7 v2 ORU^R01 message: lab results for one or more orders.
+type Message struct {
+	ControlID           string
+	SendingApplication  string
+	SendingFacility     string
+	ReceivingFacility   string
+	ProcessingID        string
+	Version             string
+	Orders              []Order
+	fieldSep, component byte
+}
+
+// Order is the results of one order in a message, identified by the placer
+// order number the lab was given, which is our lab order ID. PatientIDs are
+// the identifiers the lab sent for the patient.
+type Order struct {
+	PlacerOrderNumber string
+	PatientIDs        []string
+	Results           []models.LabResult
+}
+
+// hl7 holds a message's delimiters.
+type hl7 struct {
+	field, component, repetition, escape, subcomponent byte
+}
+
+func (h hl7) components(field string) []string {
+	return strings.Split(field, string(h.component))
+}
+
+func (h hl7) repetitions(field string) []string {
+	return strings.Split(field, string(h.repetition))
+}
+
+// unescape decodes HL7 escape sequences in a text value.
+func (h hl7) unescape(s string) string {
+	esc := string(h.escape)
+	if !strings.Contains(s, esc) {
+		return s
+	}
+	return strings.NewReplacer(
+		esc+"F"+esc, string(h.field),
+		esc+"S"+esc, string(h.component),
+		esc+"T"+esc, string(h.subcomponent),
+		esc+"R"+esc, string(h.repetition),
+		esc+"E"+esc, esc,
+		esc+".br"+esc, "\n",
+	).Replace(s)
+}
+
+// field returns the 1-based field i of a split segment, or "".
+func field(fields []string, i int) string {
+	if i < len(fields) {
+		return fields[i]
+	}
+	return ""
+}
+
+// ParseORU reads an ORU^R01 message. Results the lab could not obtain or
+// has withdrawn are left out. The returned message has its header even when
+// parsing fails later, so the failure can be acknowledged.
+func ParseORU(data string) (Message, error) {
+	segments := strings.FieldsFunc(data, func(r rune) bool { return r == '\r' || r == '\n' })
+	if len(segments) == 0 || !strings.HasPrefix(segments[0], "MSH") || len(segments[0]) < 8 {
+		return Message{}, errors.New("message doesn't start with an MSH segment")
+	}
+
+	msh := segments[0]
+	h := hl7{field: msh[3], component: msh[4], repetition: msh[5], escape: msh[6], subcomponent: msh[7]}
+	// MSH-1 is the field separator itself, so MSH-n is at index n-1
+	header := strings.Split(msh, string(h.field))
+	msg := Message{
+		SendingApplication: field(header, 2),
+		SendingFacility:    field(header, 3),
+		ReceivingFacility:  field(header, 5),
+		ControlID:          field(header, 9),
+		ProcessingID:       field(header, 10),
+		Version:            field(header, 11),
+		fieldSep:           h.field,
+		component:          h.component,
+	}
+	if msgType := h.components(field(header, 8)); len(msgType) < 2 || msgType[0] != "ORU" || msgType[1] != "R01" {
+		return msg, fmt.Errorf("expected an ORU^R01 message, got %s", field(header, 8))
+	}
+
+	var patientIDs []string
+	var order *Order
+	var orderTime *time.Time
+	for n, segment := range segments[1:] {
+		line := n + 2
+		fields := strings.Split(segment, string(h.field))
+		switch fields[0] {
+		case "PID":
+			patientIDs = nil
+			for _, id := range h.repetitions(field(fields, 3)) {
+				if id = h.components(id)[0]; id != "" {
+					patientIDs = append(patientIDs, id)
+				}
+			}
+
+		case "OBR":
+			placer := h.components(field(fields, 2))[0]
+			if placer == "" {
+				return msg, fmt.Errorf("segment %d: OBR has no placer order number", line)
+			}
+			msg.Orders = append(msg.Orders, Order{PlacerOrderNumber: placer, PatientIDs: patientIDs})
+			order = &msg.Orders[len(msg.Orders)-1]
+			orderTime = nil
+			if ts := field(fields, 7); ts != "" {
+				t, err := parseHL7Time(ts)
+				if err != nil {
+					return msg, fmt.Errorf("segment %d: OBR-7: %w", line, err)
+				}
+				orderTime = &t
+			}
+
+		case "OBX":
+			if order == nil {
+				return msg, fmt.Errorf("segment %d: OBX before any OBR", line)
+			}
+			result, ok, err := h.observation(fields, orderTime)
+			if err != nil {
+				return msg, fmt.Errorf("segment %d: %w", line, err)
+			}
+			if !ok {
+				continue
+			}
+			// Text results can run over several OBX segments
+			if last := len(order.Results) - 1; last >= 0 && result.Value == nil &&
+				order.Results[last].AnalyteCode == result.AnalyteCode && order.Results[last].Value == nil {
+				order.Results[last].ValueText += "\n" + result.ValueText
+				continue
+			}
+			order.Results = append(order.Results, result)
+		}
+	}
+
+	if len(msg.Orders) == 0 {
+		return msg, errors.New("message has no OBR segments")
+	}
+	return msg, nil
+}
+
+// observation reads an OBX segment. It returns false for results that
+// shouldn't be recorded.
+func (h hl7) observation(fields []string, orderTime *time.Time) (models.LabResult, bool, error) {
+	r := models.LabResult{Source: "hl7"}
+
+	switch field(fields, 11) {
+	case "F":
+		r.Status = "final"
+	case "C":
+		r.Status = "corrected"
+	case "P", "R", "S", "I", "":
+		r.Status = "preliminary"
+	default:
+		// X (couldn't be obtained), D (deleted) and W (wrong) have no result
+		return r, false, nil
+	}
+
+	id := h.components(field(fields, 3))
+	r.AnalyteCode = strings.TrimSpace(id[0])
+	if r.AnalyteCode == "" {
+		return r, false, errors.New("OBX-3 has no observation identifier")
+	}
+	if len(id) > 1 {
+		r.AnalyteName = h.unescape(strings.TrimSpace(id[1]))
+	}
+
+	value := field(fields, 5)
+	switch valueType := field(fields, 2); valueType {
+	case "NM":
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return r, false, fmt.Errorf("OBX-5 of %s isn't a number: %q", r.AnalyteCode, value)
+		}
+		r.Value = &v
+	case "SN":
+		// Structured numeric: comparator^number, e.g., <^5 or ^182
+		parts := h.components(value)
+		num := strings.TrimSpace(field(parts, 1))
+		comparator := strings.TrimSpace(parts[0])
+		if v, err := strconv.ParseFloat(num, 64); err == nil && (comparator == "" || comparator == "=") {
+			r.Value = &v
+		} else {
+			r.ValueText = comparator + strings.Join(parts[1:], "")
+		}
+	default:
+		r.ValueText = h.unescape(strings.Join(h.repetitions(value), "\n"))
+	}
+	if r.Value == nil && strings.TrimSpace(r.ValueText) == "" {
+		return r, false, fmt.Errorf("OBX-5 of %s is empty", r.AnalyteCode)
+	}
+
+	r.Unit = h.unescape(strings.TrimSpace(h.components(field(fields, 6))[0]))
+	r.RefText = h.unescape(strings.TrimSpace(field(fields, 7)))
+	r.RefLow, r.RefHigh = parseRange(r.RefText)
+	r.Flag = strings.TrimSpace(h.repetitions(field(fields, 8))[0])
+
+	switch ts := field(fields, 14); {
+	case ts != "":
+		t, err := parseHL7Time(ts)
+		if err != nil {
+			return r, false, fmt.Errorf("OBX-14 of %s: %w", r.AnalyteCode, err)
+		}
+		r.ObservedAt = t
+	case orderTime != nil:
+		r.ObservedAt = *orderTime
+	}
+	return r, true, nil
+}
+
+// parseRange reads a reference range such as "3.5-5.0", "<5.7" or ">40".
+// Either end is nil when the range doesn't give it.
+func parseRange(s string) (low, high *float64) {
+	num := func(s string) *float64 {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil
+		}
+		return &v
+	}
+	switch {
+	case strings.HasPrefix(s, "<="), strings.HasPrefix(s, ">="):
+		if s[0] == '<' {
+			return nil, num(s[2:])
+		}
+		return num(s[2:]), nil
+	case strings.HasPrefix(s, "<"):
+		return nil, num(s[1:])
+	case strings.HasPrefix(s, ">"):
+		return num(s[1:]), nil
+	}
+	// Split on the dash between the numbers, not a leading minus sign
+	if i := strings.Index(s[min(1, len(s)):], "-"); i >= 0 {
+		i++
+		return num(s[:i]), num(s[i+1:])
+	}
+	return nil, nil
+}
+
+// hl7TimeLayouts are the precisions an HL7 timestamp may have, longest
+// first.
+var hl7TimeLayouts = []string{"20060102150405", "200601021504", "2006010215", "20060102"}
+
+// parseHL7Time reads an HL7 DTM such as 20261018143000.0000+0100. Without
+// a time zone it is taken as local time.
+func parseHL7Time(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	loc := time.Local
+	if i := strings.IndexAny(s, "+-"); i >= 0 {
+		zone, err := time.Parse("-0700", s[i:])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time zone in %q", s)
+		}
+		loc = zone.Location()
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+	for _, layout := range hl7TimeLayouts {
+		if len(s) == len(layout) {
+			return time.ParseInLocation(layout, s, loc)
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// ACK codes.
+const (
+	AckAccept = "AA"
+	AckError  = "AE"
+	AckReject = "AR"
+)
+
+// Ack builds the acknowledgement of msg, with the code and, for errors, the
+// reason.
+func Ack(msg Message, code, text string, now time.Time) string {
+	f, c := msg.fieldSep, msg.component
+	if f == 0 {
+		f, c = '|', '^'
+	}
+	sep := string(f)
+	processing := msg.ProcessingID
+	if processing == "" {
+		processing = "P"
+	}
+	version := msg.Version
+	if version == "" {
+		version = "2.5.1"
+	}
+	clean := strings.NewReplacer(sep, " ", string(c), " ", "\r", " ", "\n", " ").Replace
+
+	msh := strings.Join([]string{
+		"MSH", string(c) + `~\&`, "VITALWATCH", msg.ReceivingFacility, msg.SendingApplication, msg.SendingFacility,
+		now.Format("20060102150405-0700"), "", "ACK" + string(c) + "R01" + string(c) + "ACK",
+		"ACK" + msg.ControlID, processing, version,
+	}, sep)
+	msa := strings.Join([]string{"MSA", code, msg.ControlID, clean(text)}, sep)
+	return msh + "\r" + msa + "\r"
+}
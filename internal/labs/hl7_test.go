@@ -0,0 +1,242 @@
+package labs
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// oru joins segments into a message the way labs send them, one per line
+// ending in a carriage return.
+func oru(segments ...string) string {
+	return strings.Join(segments, "\r") + "\r"
+}
+
+const testMSH = `MSH|^~\&|LABSYS|LABFAC|VITALWATCH|VWFAC|20261018100000||ORU^R01|MSG0001|P|2.5.1`
+
+func TestParseORU(t *testing.T) {
+	msg, err := ParseORU(oru(
+		testMSH,
+		"PID|1||42^^^VW^MR~X99^^^LAB||Doe^Jane",
+		"OBR|1|7|LAB-9|58410-2^CBC|||20261018080000",
+		"OBX|1|NM|718-7^Hemoglobin^LN||13.5|g/dL|12.0-17.5|N|||F|||20261018093000",
+		"OBX|2|SN|2345-7^Glucose^LN||^182|mg/dL|70-99|H|||C",
+		"OBX|3|SN|1988-5^CRP^LN||<^5|mg/L|<5||||F",
+		"OBX|4|TX|11529-5^Comment^LN||Sample \\T\\ haemolysed||||||P",
+		"OBX|5|TX|11529-5^Comment^LN||retest advised||||||P",
+		"OBX|6|NM|777-3^Platelets^LN||||||||X",
+		"OBR|2|8|LAB-10|4548-4^HbA1c",
+		"OBX|1|NM|4548-4^HbA1c^LN||6.1|%|<5.7|H|||F",
+	))
+	if err != nil {
+		t.Fatalf("ParseORU: %v", err)
+	}
+
+	if msg.ControlID != "MSG0001" || msg.SendingApplication != "LABSYS" || msg.SendingFacility != "LABFAC" ||
+		msg.ReceivingFacility != "VWFAC" || msg.ProcessingID != "P" || msg.Version != "2.5.1" {
+		t.Errorf("header = %+v", msg)
+	}
+	if len(msg.Orders) != 2 {
+		t.Fatalf("got %d orders, want 2", len(msg.Orders))
+	}
+
+	first := msg.Orders[0]
+	if first.PlacerOrderNumber != "7" {
+		t.Errorf("placer order number = %q, want 7", first.PlacerOrderNumber)
+	}
+	if strings.Join(first.PatientIDs, ",") != "42,X99" {
+		t.Errorf("patient IDs = %v, want [42 X99]", first.PatientIDs)
+	}
+	// The platelets were not obtained, and the two comment segments are one result
+	if len(first.Results) != 4 {
+		t.Fatalf("got %d results, want 4: %+v", len(first.Results), first.Results)
+	}
+
+	hb := first.Results[0]
+	if hb.AnalyteCode != "718-7" || hb.AnalyteName != "Hemoglobin" || hb.Value == nil || *hb.Value != 13.5 ||
+		hb.Unit != "g/dL" || hb.Flag != "N" || hb.Status != "final" || hb.Source != "hl7" {
+		t.Errorf("hemoglobin = %+v", hb)
+	}
+	if hb.RefLow == nil || *hb.RefLow != 12 || hb.RefHigh == nil || *hb.RefHigh != 17.5 {
+		t.Errorf("hemoglobin range = %v-%v, want 12-17.5", hb.RefLow, hb.RefHigh)
+	}
+	if want := time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local); !hb.ObservedAt.Equal(want) {
+		t.Errorf("hemoglobin observed at %v, want %v", hb.ObservedAt, want)
+	}
+
+	glucose := first.Results[1]
+	if glucose.Value == nil || *glucose.Value != 182 || glucose.Status != "corrected" {
+		t.Errorf("glucose = %+v", glucose)
+	}
+	// Without OBX-14 the result was observed when the specimen was taken
+	if want := time.Date(2026, 10, 18, 8, 0, 0, 0, time.Local); !glucose.ObservedAt.Equal(want) {
+		t.Errorf("glucose observed at %v, want %v", glucose.ObservedAt, want)
+	}
+
+	crp := first.Results[2]
+	if crp.Value != nil || crp.ValueText != "<5" || crp.RefLow != nil || crp.RefHigh == nil || *crp.RefHigh != 5 {
+		t.Errorf("CRP = %+v", crp)
+	}
+
+	comment := first.Results[3]
+	if comment.ValueText != "Sample & haemolysed\nretest advised" || comment.Status != "preliminary" {
+		t.Errorf("comment = %q (%s)", comment.ValueText, comment.Status)
+	}
+
+	second := msg.Orders[1]
+	if second.PlacerOrderNumber != "8" || strings.Join(second.PatientIDs, ",") != "42,X99" {
+		t.Errorf("second order = %q for %v", second.PlacerOrderNumber, second.PatientIDs)
+	}
+	if len(second.Results) != 1 || second.Results[0].RefHigh == nil || *second.Results[0].RefHigh != 5.7 {
+		t.Errorf("second order results = %+v", second.Results)
+	}
+	if !second.Results[0].ObservedAt.IsZero() {
+		t.Errorf("HbA1c observed at %v, want zero without OBR-7 or OBX-14", second.Results[0].ObservedAt)
+	}
+}
+
+func TestParseORUCustomDelimiters(t *testing.T) {
+	msg, err := ParseORU(oru(
+		`MSH#*~\&#LABSYS#LABFAC#VITALWATCH#VWFAC#20261018100000##ORU*R01#MSG0002#P#2.5.1`,
+		"PID#1##42",
+		"OBR#1#7",
+		"OBX#1#NM#718-7*Hemoglobin##13.5#g/dL#####F",
+	))
+	if err != nil {
+		t.Fatalf("ParseORU: %v", err)
+	}
+	r := msg.Orders[0].Results
+	if len(r) != 1 || r[0].AnalyteName != "Hemoglobin" || r[0].Value == nil || *r[0].Value != 13.5 {
+		t.Errorf("results = %+v", r)
+	}
+}
+
+func TestParseORUErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty", "", "doesn't start with an MSH segment"},
+		{"no MSH", oru("PID|1||42"), "doesn't start with an MSH segment"},
+		{"wrong type", oru(`MSH|^~\&|LAB|FAC|VW|VWFAC|20261018100000||ADT^A01|1|P|2.5.1`), "expected an ORU^R01 message"},
+		{"no orders", oru(testMSH, "PID|1||42"), "no OBR segments"},
+		{"no placer", oru(testMSH, "OBR|1||LAB-9"), "segment 2: OBR has no placer order number"},
+		{"bad order time", oru(testMSH, "OBR|1|7|||||yesterday"), "segment 2: OBR-7"},
+		{"OBX first", oru(testMSH, "OBX|1|NM|718-7||13.5|g/dL|||||F"), "segment 2: OBX before any OBR"},
+		{"no identifier", oru(testMSH, "OBR|1|7", "OBX|1|NM|||13.5|g/dL|||||F"), "segment 3: OBX-3 has no observation identifier"},
+		{"not a number", oru(testMSH, "OBR|1|7", "OBX|1|NM|718-7||high|g/dL|||||F"), "OBX-5 of 718-7 isn't a number"},
+		{"empty value", oru(testMSH, "OBR|1|7", "OBX|1|TX|11529-5||||||||F"), "OBX-5 of 11529-5 is empty"},
+		{"bad observed time", oru(testMSH, "OBR|1|7", "OBX|1|NM|718-7||13.5|g/dL|||||F|||2026"), "OBX-14 of 718-7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseORU(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseORU error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseORUKeepsHeaderOnError(t *testing.T) {
+	msg, err := ParseORU(oru(testMSH, "OBX|1|NM|718-7||13.5|g/dL|||||F"))
+	if err == nil {
+		t.Fatal("ParseORU succeeded, want an error")
+	}
+	if msg.ControlID != "MSG0001" {
+		t.Errorf("control ID = %q, want MSG0001 so the error can be acknowledged", msg.ControlID)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		in        string
+		low, high *float64
+	}{
+		{"3.5-5.0", f(3.5), f(5)},
+		{"-2-2", f(-2), f(2)},
+		{"-5--1", f(-5), f(-1)},
+		{"<5.7", nil, f(5.7)},
+		{"<=200", nil, f(200)},
+		{">40", f(40), nil},
+		{">=60", f(60), nil},
+		{"Negative", nil, nil},
+		{"", nil, nil},
+		{"-", nil, nil},
+	}
+	for _, tt := range tests {
+		low, high := parseRange(tt.in)
+		if !sameValue(low, tt.low) || !sameValue(high, tt.high) {
+			t.Errorf("parseRange(%q) = %s, %s; want %s, %s", tt.in, show(low), show(high), show(tt.low), show(tt.high))
+		}
+	}
+}
+
+func TestParseHL7Time(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"20261018143005", time.Date(2026, 10, 18, 14, 30, 5, 0, time.Local)},
+		{"202610181430", time.Date(2026, 10, 18, 14, 30, 0, 0, time.Local)},
+		{"2026101814", time.Date(2026, 10, 18, 14, 0, 0, 0, time.Local)},
+		{"20261018", time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local)},
+		{"20261018143005.1234", time.Date(2026, 10, 18, 14, 30, 5, 0, time.Local)},
+		{"20261018143005+0100", time.Date(2026, 10, 18, 13, 30, 5, 0, time.UTC)},
+		{"20261018143005.5-0500", time.Date(2026, 10, 18, 19, 30, 5, 0, time.UTC)},
+		{" 20261018 ", time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := parseHL7Time(tt.in)
+		if err != nil {
+			t.Errorf("parseHL7Time(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseHL7Time(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "2026", "2026101", "20261318", "20261018+01", "20261018+ab00", "yesterday"} {
+		if got, err := parseHL7Time(in); err == nil {
+			t.Errorf("parseHL7Time(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestAck(t *testing.T) {
+	now := time.Date(2026, 10, 18, 10, 0, 5, 0, time.FixedZone("", 3600))
+
+	msg, err := ParseORU(oru(testMSH, "OBR|1|7"))
+	if err != nil {
+		t.Fatalf("ParseORU: %v", err)
+	}
+	got := Ack(msg, AckError, "Order 7 is for a different patient|really\r", now)
+	want := "MSH|^~\\&|VITALWATCH|VWFAC|LABSYS|LABFAC|20261018100005+0100||ACK^R01^ACK|ACKMSG0001|P|2.5.1\r" +
+		"MSA|AE|MSG0001|Order 7 is for a different patient really \r"
+	if got != want {
+		t.Errorf("Ack =\n%q\nwant\n%q", got, want)
+	}
+
+	// A message that couldn't be read at all is still acknowledged
+	got = Ack(Message{}, AckReject, "Message is too large", now)
+	want = "MSH|^~\\&|VITALWATCH||||20261018100005+0100||ACK^R01^ACK|ACK|P|2.5.1\r" +
+		"MSA|AR||Message is too large\r"
+	if got != want {
+		t.Errorf("Ack of an unread message =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func sameValue(a, b *float64) bool {
+	return (a == nil) == (b == nil) && (a == nil || *a == *b)
+}
+
+func show(v *float64) string {
+	if v == nil {
+		return "nil"
+	}
+	return strconv.FormatFloat(*v, 'g', -1, 64)
+}
@@ -14,6 +14,10 @@ type Patient struct {
 	LastName       string    `json:"last_name"`
 	HashedPassword string    `json:"-"`
 	CreatedAt      time.Time `json:"created_at"`
+
+	NEWS2Score      *int       `json:"news2_score,omitempty"`
+	NEWS2Risk       string     `json:"news2_risk,omitempty"`
+	NEWS2ComputedAt *time.Time `json:"news2_computed_at,omitempty"`
 }
 
 func (p Patient) GetID() int {
@@ -65,5 +69,589 @@ type Prescription struct {
 	FileName   string    `json:"file_name"`
 	CreatedAt  time.Time `json:"created_at"`
 
+	Source           string             `json:"source"`
+	VerificationCode string             `json:"verification_code,omitempty"`
+	Items            []PrescriptionItem `json:"items"`
+	Schedule         *DosingSchedule    `json:"schedule,omitempty"`
+
+	// Signing details; ContentHash is the hex SHA-256 of the stored file
+	ContentHash  string     `json:"content_hash,omitempty"`
+	Signature    string     `json:"-"`
+	SigningKeyID string     `json:"-"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+
+	// Versioning; an amendment supersedes the version it was made from
+	Status           string `json:"status"`
+	Version          int    `json:"version"`
+	PreviousID       *int   `json:"previous_id,omitempty"`
+	SupersededByID   *int   `json:"superseded_by_id,omitempty"`
+	SupersededByFile string `json:"-"`
+	AmendmentReason  string `json:"amendment_reason,omitempty"`
+	RevocationReason string `json:"revocation_reason,omitempty"`
+
+	// Malware scan state of the file; pending and infected files are
+	// quarantined
+	ScanStatus string `json:"scan_status"`
+	ScanThreat string `json:"scan_threat,omitempty"`
+
+	// Formulary check results; only set while a prescription is being created
+	Warnings       []PrescriptionWarning `json:"warnings,omitempty"`
+	SafetyOverride *SafetyOverride       `json:"safety_override,omitempty"`
+
 	DoctorName string `json:"doctorName,omitempty"`
 }
+
+// PrescriptionUpload is a file a doctor is uploading directly to storage,
+// which becomes a prescription once confirmed.
+type PrescriptionUpload struct {
+	ID             int       `json:"id"`
+	DoctorID       int       `json:"doctor_id"`
+	PatientID      int       `json:"patient_id"`
+	FileName       string    `json:"file_name"`
+	ContentType    string    `json:"content_type"`
+	Size           int64     `json:"size"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	PrescriptionID *int      `json:"prescription_id,omitempty"`
+}
+
+type PrescriptionItem struct {
+	ID        int    `json:"id"`
+	DrugName  string `json:"drug_name"`
+	Strength  string `json:"strength,omitempty"`
+	Form      string `json:"form,omitempty"`
+	Route     string `json:"route,omitempty"`
+	Dose      string `json:"dose"`
+	Frequency string `json:"frequency"`
+	Duration  string `json:"duration,omitempty"`
+	Quantity  int    `json:"quantity"`
+	Refills   int    `json:"refills"`
+}
+
+// DosingSchedule describes when a prescription's doses are due: every day
+// between StartDate and EndDate (YYYY-MM-DD) at each of Times (HH:MM) in
+// Timezone. A nil EndDate means the medication is ongoing.
+type DosingSchedule struct {
+	Times     []string `json:"times"`
+	Timezone  string   `json:"timezone"`
+	StartDate string   `json:"start_date"`
+	EndDate   *string  `json:"end_date,omitempty"`
+}
+
+type MedicationDose struct {
+	ID             int64      `json:"id"`
+	PrescriptionID int        `json:"prescription_id"`
+	PatientID      int        `json:"patient_id"`
+	Medication     string     `json:"medication"`
+	ScheduledAt    time.Time  `json:"scheduled_at"`
+	Status         string     `json:"status"`
+	LoggedAt       *time.Time `json:"logged_at,omitempty"`
+	Note           string     `json:"note,omitempty"`
+}
+
+type AdherenceStats struct {
+	Period    *time.Time `json:"period,omitempty"`
+	Taken     int        `json:"taken"`
+	Late      int        `json:"late"`
+	Skipped   int        `json:"skipped"`
+	Missed    int        `json:"missed"`
+	Due       int        `json:"due"`
+	Adherence *float64   `json:"adherence_pct"` // nil until a dose has been resolved
+}
+
+type Device struct {
+	ID         int        `json:"id"`
+	PatientID  int        `json:"patient_id"`
+	Serial     string     `json:"serial"`
+	Model      string     `json:"model"`
+	Type       string     `json:"type"`
+	PairedAt   time.Time  `json:"paired_at"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type VitalReading struct {
+	ID         int64     `json:"id"`
+	PatientID  int       `json:"patient_id"`
+	DeviceID   *int      `json:"device_id,omitempty"`
+	Metric     string    `json:"metric"`
+	Value      float64   `json:"value"`
+	Unit       string    `json:"unit"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Source     string    `json:"source"`
+}
+
+type ImportError struct {
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+type VitalImportJob struct {
+	ID             int           `json:"id"`
+	PatientID      int           `json:"patient_id"`
+	Format         string        `json:"format"`
+	FileName       string        `json:"file_name"`
+	Status         string        `json:"status"`
+	BytesTotal     int64         `json:"bytes_total"`
+	BytesProcessed int64         `json:"bytes_processed"`
+	Progress       float64       `json:"progress"`
+	Imported       int           `json:"imported"`
+	Duplicates     int           `json:"duplicates"`
+	Failed         int           `json:"failed"`
+	Errors         []ImportError `json:"errors"`
+	ErrorMessage   string        `json:"error_message,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	FinishedAt     *time.Time    `json:"finished_at,omitempty"`
+}
+
+type AlertEvidence struct {
+	Metric     string    `json:"metric,omitempty"`
+	Value      float64   `json:"value"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+type VitalAlert struct {
+	ID              int             `json:"id"`
+	PatientID       int             `json:"patient_id"`
+	Metric          string          `json:"metric"`
+	Kind            string          `json:"kind"`
+	Severity        string          `json:"severity"`
+	TimeOfDay       string          `json:"time_of_day,omitempty"`
+	ObservedValue   float64         `json:"observed_value"`
+	BaselineMedian  float64         `json:"baseline_median"`
+	BaselineMAD     float64         `json:"baseline_mad"`
+	BaselineSamples int             `json:"baseline_samples"`
+	Score           float64         `json:"score"`
+	WindowStart     time.Time       `json:"window_start"`
+	WindowEnd       time.Time       `json:"window_end"`
+	Evidence        []AlertEvidence `json:"evidence"`
+	CreatedAt       time.Time       `json:"created_at"`
+	AcknowledgedAt  *time.Time      `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy  *int            `json:"acknowledged_by,omitempty"`
+
+	PatientName string `json:"patientName,omitempty"`
+}
+
+type NEWS2Score struct {
+	ID         int                     `json:"id"`
+	PatientID  int                     `json:"patient_id"`
+	Total      int                     `json:"total"`
+	Risk       string                  `json:"risk"`
+	SubScores  map[string]int          `json:"sub_scores"`
+	SpO2Scale  int                     `json:"spo2_scale"`
+	Complete   bool                    `json:"complete"`
+	Inputs     map[string]VitalReading `json:"inputs"`
+	ComputedAt time.Time               `json:"computed_at"`
+	ExpiredAt  *time.Time              `json:"expired_at,omitempty"` // once its readings are all too old to count
+}
+
+type Notification struct {
+	ID            int            `json:"id"`
+	RecipientRole string         `json:"-"`
+	RecipientID   int            `json:"-"`
+	Kind          string         `json:"kind"`
+	Title         string         `json:"title"`
+	Body          string         `json:"body,omitempty"`
+	Data          map[string]any `json:"data"`
+	CreatedAt     time.Time      `json:"created_at"`
+	ReadAt        *time.Time     `json:"read_at,omitempty"`
+}
+
+type RefillRequest struct {
+	ID                int        `json:"id"`
+	PrescriptionID    int        `json:"prescription_id"`
+	PatientID         int        `json:"patient_id"`
+	DoctorID          int        `json:"doctor_id"`
+	Status            string     `json:"status"`
+	PatientNote       string     `json:"patient_note,omitempty"`
+	ResponseReason    string     `json:"response_reason,omitempty"`
+	NewPrescriptionID *int       `json:"new_prescription_id,omitempty"`
+	AppointmentID     *int       `json:"appointment_id,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ResolvedAt        *time.Time `json:"resolved_at,omitempty"`
+
+	Medication  string `json:"medication"`
+	PatientName string `json:"patientName,omitempty"`
+	DoctorName  string `json:"doctorName,omitempty"`
+}
+
+type PatientAllergy struct {
+	ID        int       `json:"id"`
+	PatientID int       `json:"patient_id"`
+	Substance string    `json:"substance"`
+	Reaction  string    `json:"reaction,omitempty"`
+	Severity  string    `json:"severity,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Source is who recorded it, "patient" or "doctor", and RecordedBy the
+	// doctor if it was one.
+	Source     string `json:"source,omitempty"`
+	RecordedBy *int   `json:"recorded_by,omitempty"`
+}
+
+// ActiveMedication is a drug the patient currently takes, on one of their
+// prescriptions or, with a zero PrescriptionID, recorded outside them.
+type ActiveMedication struct {
+	PrescriptionID int
+	Name           string
+}
+
+// PrescriptionWarning is a formulary check result for a new prescription.
+type PrescriptionWarning struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Drug     string `json:"drug"`
+	// With is the other active medication or the allergy involved
+	With           string `json:"with,omitempty"`
+	PrescriptionID int    `json:"prescription_id,omitempty"`
+	Message        string `json:"message"`
+}
+
+// SafetyOverride records a doctor going ahead despite blocking warnings.
+type SafetyOverride struct {
+	Reason   string                `json:"reason"`
+	Warnings []PrescriptionWarning `json:"warnings"`
+}
+
+// PatientDocument is a medical document in a patient's vault.
+type PatientDocument struct {
+	ID           int      `json:"id"`
+	PatientID    int      `json:"patient_id"`
+	Category     string   `json:"category"`
+	Title        string   `json:"title"`
+	Description  string   `json:"description,omitempty"`
+	Tags         []string `json:"tags"`
+	DocumentDate *string  `json:"document_date,omitempty"` // YYYY-MM-DD
+	FileName     string   `json:"-"`
+	OriginalName string   `json:"original_name,omitempty"`
+	ContentType  string   `json:"content_type"`
+	Size         int64    `json:"size"`
+	ScanStatus   string   `json:"scan_status"`
+	ScanThreat   string   `json:"scan_threat,omitempty"`
+	// SharedWith are the doctors who can see the document; only shown to the
+	// patient
+	SharedWith []int     `json:"shared_with,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DataKey is the wrapped encryption key of a stored file.
+type DataKey struct {
+	ObjectKey   string
+	MasterKeyID string
+	WrappedKey  []byte
+	CreatedAt   time.Time
+	RewrappedAt *time.Time
+}
+
+// Clinic is a practice doctors belong to.
+type Clinic struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+}
+
+// PatientConsent gives a doctor, or every doctor of a clinic, access to one
+// category of a patient's record.
+type PatientConsent struct {
+	ID        int        `json:"id"`
+	PatientID int        `json:"patient_id"`
+	DoctorID  *int       `json:"doctor_id,omitempty"`
+	ClinicID  *int       `json:"clinic_id,omitempty"`
+	Category  string     `json:"category"`
+	GrantedAt time.Time  `json:"granted_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+
+	// GranteeName is the doctor's or clinic's name
+	GranteeName string `json:"grantee_name"`
+	PatientName string `json:"patientName,omitempty"`
+}
+
+// Admin is a staff member overseeing a clinic, or every clinic when ClinicID
+// is nil.
+type Admin struct {
+	ID             int       `json:"id"`
+	Email          string    `json:"email"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	HashedPassword string    `json:"-"`
+	ClinicID       *int      `json:"clinic_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (a Admin) GetID() int {
+	return a.ID
+}
+func (a Admin) GetHashedPassword() string {
+	return a.HashedPassword
+}
+
+// BreakGlassGrant is emergency access a doctor took to a patient's record,
+// pending review by an admin.
+type BreakGlassGrant struct {
+	ID            int        `json:"id"`
+	DoctorID      int        `json:"doctor_id"`
+	PatientID     int        `json:"patient_id"`
+	Justification string     `json:"justification"`
+	IPAddress     string     `json:"ip_address,omitempty"`
+	UserAgent     string     `json:"user_agent,omitempty"`
+	GrantedAt     time.Time  `json:"granted_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	ReviewedBy    *int       `json:"reviewed_by,omitempty"`
+	ReviewOutcome string     `json:"review_outcome,omitempty"`
+	ReviewNote    string     `json:"review_note,omitempty"`
+
+	PatientName string `json:"patientName,omitempty"`
+	DoctorName  string `json:"doctorName,omitempty"`
+}
+
+// AuditEntry records one request that read or changed patient data.
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	ActorRole  string    `json:"actor_role"`
+	ActorID    int       `json:"actor_id"`
+	PatientID  *int      `json:"patient_id,omitempty"`
+	Resource   string    `json:"resource"`
+	ResourceID string    `json:"resource_id,omitempty"`
+	Action     string    `json:"action"`
+	Method     string    `json:"method,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Outcome    string    `json:"outcome"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	PrevHash   []byte    `json:"prev_hash,omitempty"`
+	Hash       []byte    `json:"hash,omitempty"`
+
+	ActorName string `json:"actor_name,omitempty"`
+}
+
+// DataExport is an archive of a patient's data they asked for.
+type DataExport struct {
+	ID          int        `json:"id"`
+	PatientID   int        `json:"patient_id"`
+	Status      string     `json:"status"`
+	FileName    string     `json:"-"`
+	Size        *int64     `json:"size,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// PurgeCandidate is a closed account's data whose retention period has
+// passed: how many rows of each table and which stored files will go.
+type PurgeCandidate struct {
+	PatientID int              `json:"patient_id"`
+	Category  string           `json:"category"` // "patient_data" or "clinical_records"
+	ClosedAt  time.Time        `json:"closed_at"`
+	DueAt     time.Time        `json:"due_at"`
+	Rows      map[string]int64 `json:"rows"`
+	Files     []string         `json:"files"`
+}
+
+// PatientCondition is an entry on a patient's problem list. It is active
+// until it has a ResolvedDate.
+type PatientCondition struct {
+	ID           int       `json:"id"`
+	PatientID    int       `json:"patient_id"`
+	Name         string    `json:"name"`
+	Code         string    `json:"code,omitempty"`
+	CodeSystem   string    `json:"code_system,omitempty"`   // 'icd10cm' or 'snomedct', when there is a code
+	OnsetDate    *string   `json:"onset_date,omitempty"`    // YYYY-MM-DD
+	ResolvedDate *string   `json:"resolved_date,omitempty"` // YYYY-MM-DD
+	Status       string    `json:"status"`                  // "active" or "resolved"
+	Notes        string    `json:"notes,omitempty"`
+	Source       string    `json:"source"`
+	RecordedBy   *int      `json:"recorded_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// PatientMedication is a medication the patient takes. Source "prescription"
+// entries come from their active prescriptions and are read-only here; the
+// rest were recorded by the patient or a doctor.
+type PatientMedication struct {
+	ID             int        `json:"id,omitempty"`
+	PatientID      int        `json:"patient_id"`
+	Name           string     `json:"name"`
+	Dose           string     `json:"dose,omitempty"`
+	Frequency      string     `json:"frequency,omitempty"`
+	Route          string     `json:"route,omitempty"`
+	StartedOn      *string    `json:"started_on,omitempty"` // YYYY-MM-DD
+	StoppedOn      *string    `json:"stopped_on,omitempty"` // YYYY-MM-DD
+	Notes          string     `json:"notes,omitempty"`
+	Source         string     `json:"source"`
+	RecordedBy     *int       `json:"recorded_by,omitempty"`
+	PrescriptionID *int       `json:"prescription_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// PatientChart is the clinical summary of a patient. Restricted lists the
+// sections left out because the doctor reading it lacks the patient's
+// consent; without consent to labs, LabTrends only covers the doctor's own
+// orders.
+type PatientChart struct {
+	PatientID   int                 `json:"patient_id"`
+	PatientName string              `json:"patient_name"`
+	Allergies   []PatientAllergy    `json:"allergies"`
+	Conditions  []PatientCondition  `json:"conditions"`
+	Medications []PatientMedication `json:"medications"`
+	LabTrends   []LabTrend          `json:"lab_trends"`
+	Restricted  []string            `json:"restricted,omitempty"`
+}
+
+// Diagnosis is a coded diagnosis on an encounter note.
+type Diagnosis struct {
+	System      string `json:"system,omitempty"` // 'icd10cm' or 'snomedct'; older notes don't have it
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+}
+
+// EncounterNoteContent is what a doctor writes in an encounter note, in SOAP
+// form.
+type EncounterNoteContent struct {
+	Subjective string      `json:"subjective"`
+	Objective  string      `json:"objective"`
+	Assessment string      `json:"assessment"`
+	Plan       string      `json:"plan"`
+	Diagnoses  []Diagnosis `json:"diagnoses"`
+}
+
+// EncounterNote is the clinical note on an appointment. Drafts are only seen
+// by their author; signed notes are locked and change only by amendment.
+type EncounterNote struct {
+	ID            int    `json:"id"`
+	AppointmentID int    `json:"appointment_id"`
+	PatientID     int    `json:"patient_id"`
+	DoctorID      int    `json:"doctor_id"`
+	DoctorName    string `json:"doctor_name,omitempty"`
+	EncounterNoteContent
+	Status     string                   `json:"status"` // 'draft' or 'signed'
+	SignedAt   *time.Time               `json:"signed_at,omitempty"`
+	AmendedAt  *time.Time               `json:"amended_at,omitempty"`
+	Amendments []EncounterNoteAmendment `json:"amendments"`
+	CreatedAt  time.Time                `json:"created_at"`
+	UpdatedAt  time.Time                `json:"updated_at"`
+}
+
+// EncounterNoteAmendment records a change to a signed note. Previous is the
+// note as it read before the change.
+type EncounterNoteAmendment struct {
+	ID        int                  `json:"id"`
+	DoctorID  int                  `json:"doctor_id"`
+	Reason    string               `json:"reason"`
+	Previous  EncounterNoteContent `json:"previous"`
+	AmendedAt time.Time            `json:"amended_at"`
+}
+
+// TerminologyCode is a code from a diagnosis code system such as ICD-10-CM.
+// Favourite is set in search results when the doctor searching marked it.
+type TerminologyCode struct {
+	System      string `json:"system"`
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	Billable    bool   `json:"billable"`
+	Active      bool   `json:"active"`
+	Favourite   bool   `json:"favourite"`
+}
+
+// LabOrderPanel is a test panel on a lab order.
+type LabOrderPanel struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// LabOrder is a doctor's request for lab tests. Its status moves from
+// 'ordered' to 'partial' as results come in, and to 'resulted' once every
+// analyte of its panels has a final result.
+type LabOrder struct {
+	ID            int             `json:"id"`
+	PatientID     int             `json:"patient_id"`
+	DoctorID      int             `json:"doctor_id"`
+	DoctorName    string          `json:"doctor_name,omitempty"`
+	AppointmentID *int            `json:"appointment_id,omitempty"`
+	ClinicID      *int            `json:"clinic_id,omitempty"` // the ordering doctor's clinic, whose labs may send results
+	Panels        []LabOrderPanel `json:"panels"`
+	Priority      string          `json:"priority"` // 'routine' or 'urgent'
+	Notes         string          `json:"notes,omitempty"`
+	Status        string          `json:"status"` // 'ordered', 'partial', 'resulted' or 'cancelled'
+	Results       []LabResult     `json:"results"`
+	Reports       []LabReport     `json:"reports"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ResultedAt    *time.Time      `json:"resulted_at,omitempty"`
+	CancelledAt   *time.Time      `json:"cancelled_at,omitempty"`
+}
+
+// LabResult is the result of one analyte on a lab order. Numeric results
+// have Value; others only ValueText. Flag is an HL7 abnormal flag such as
+// 'H' or 'LL'.
+type LabResult struct {
+	ID          int       `json:"id"`
+	OrderID     int       `json:"order_id"`
+	PatientID   int       `json:"patient_id"`
+	AnalyteCode string    `json:"analyte_code"` // LOINC
+	AnalyteName string    `json:"analyte_name"`
+	Value       *float64  `json:"value,omitempty"`
+	ValueText   string    `json:"value_text,omitempty"`
+	Unit        string    `json:"unit,omitempty"`
+	RefLow      *float64  `json:"ref_low,omitempty"`
+	RefHigh     *float64  `json:"ref_high,omitempty"`
+	RefText     string    `json:"ref_text,omitempty"` // the range as the lab wrote it, e.g., "<5.7"
+	Flag        string    `json:"flag,omitempty"`
+	Status      string    `json:"status"` // 'preliminary', 'final' or 'corrected'
+	Source      string    `json:"source"` // 'manual' or 'hl7'
+	ObservedAt  time.Time `json:"observed_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// LabReport is a report file, usually a PDF, attached to a lab order.
+type LabReport struct {
+	ID           int       `json:"id"`
+	OrderID      int       `json:"order_id"`
+	PatientID    int       `json:"patient_id"`
+	FileName     string    `json:"-"`
+	OriginalName string    `json:"original_name,omitempty"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	ScanStatus   string    `json:"scan_status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// LabTrendPoint is one numeric result in a LabTrend.
+type LabTrendPoint struct {
+	OrderID    int       `json:"order_id"`
+	Value      float64   `json:"value"`
+	Flag       string    `json:"flag,omitempty"`
+	Status     string    `json:"status"`
+	ObservedAt time.Time `json:"observed_at"`
+}
+
+// LabTrend is a patient's numeric results for one analyte and unit over
+// time, oldest first, with the latest reference range.
+type LabTrend struct {
+	AnalyteCode string          `json:"analyte_code"`
+	AnalyteName string          `json:"analyte_name"`
+	Unit        string          `json:"unit,omitempty"`
+	RefLow      *float64        `json:"ref_low,omitempty"`
+	RefHigh     *float64        `json:"ref_high,omitempty"`
+	Points      []LabTrendPoint `json:"points"`
+}
+
+// IntegrationKey lets an external system such as a lab post to the
+// integration API, for the lab orders of one clinic. Only a hash of the key
+// itself is kept.
+type IntegrationKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	ClinicID   int        `json:"clinic_id"`
+	CreatedBy  int        `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
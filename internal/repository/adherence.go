@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// ErrDoseNotLoggable is returned when a dose has already been logged or
+// isn't in the window in which it may be.
+var ErrDoseNotLoggable = errors.New("the dose can't be logged")
+
+// Medication Adherence Related Methods
+func createPrescriptionSchedule(tx *sql.Tx, prescriptionID int, schedule models.DosingSchedule) error {
+	times, err := json.Marshal(schedule.Times)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO prescription_schedules (prescription_id, times, timezone, start_date, end_date)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = tx.Exec(query, prescriptionID, string(times), schedule.Timezone, schedule.StartDate, schedule.EndDate)
+	return err
+}
+
+// ScheduledPrescription pairs a dosing schedule with the prescription it belongs to.
+type ScheduledPrescription struct {
+	PrescriptionID int
+	PatientID      int
+	Schedule       models.DosingSchedule
+}
+
+// GetActiveSchedules returns schedules of active prescriptions that haven't
+// ended before `since`.
+func (r *Repository) GetActiveSchedules(since time.Time) ([]ScheduledPrescription, error) {
+	query := `
+		SELECT s.prescription_id, p.patient_id, s.times, s.timezone,
+			to_char(s.start_date, 'YYYY-MM-DD'), to_char(s.end_date, 'YYYY-MM-DD')
+		FROM prescription_schedules s
+		JOIN prescriptions p ON s.prescription_id = p.id
+		JOIN patients pt ON p.patient_id = pt.id
+		WHERE p.status = 'active' AND pt.closed_at IS NULL AND (s.end_date IS NULL OR s.end_date >= $1::date - 1)
+	`
+	rows, err := r.DB.Query(query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []ScheduledPrescription
+	for rows.Next() {
+		var sp ScheduledPrescription
+		var times []byte
+		err := rows.Scan(&sp.PrescriptionID, &sp.PatientID, &times, &sp.Schedule.Timezone,
+			&sp.Schedule.StartDate, &sp.Schedule.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(times, &sp.Schedule.Times); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, sp)
+	}
+	return schedules, nil
+}
+
+// CreateDoses inserts due doses, ignoring any that were already generated.
+func (r *Repository) CreateDoses(doses []models.MedicationDose) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO medication_doses (prescription_id, patient_id, scheduled_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (prescription_id, scheduled_at) DO NOTHING
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, d := range doses {
+		if _, err := stmt.Exec(d.PrescriptionID, d.PatientID, d.ScheduledAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// MarkMissedDoses moves doses still due at `before` to missed.
+func (r *Repository) MarkMissedDoses(before time.Time) (int64, error) {
+	res, err := r.DB.Exec(`UPDATE medication_doses SET status = 'missed' WHERE status = 'due' AND scheduled_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const medicationDoseColumns = `
+	d.id, d.prescription_id, d.patient_id, p.medication, d.scheduled_at, d.status, d.logged_at, COALESCE(d.note, '')`
+
+func (r *Repository) GetDosesByPatientID(patientID int, from, to time.Time) ([]models.MedicationDose, error) {
+	query := `
+		SELECT ` + medicationDoseColumns + `
+		FROM medication_doses d
+		JOIN prescriptions p ON d.prescription_id = p.id
+		WHERE d.patient_id = $1 AND d.scheduled_at BETWEEN $2 AND $3
+		ORDER BY d.scheduled_at
+	`
+	rows, err := r.DB.Query(query, patientID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var doses []models.MedicationDose
+	for rows.Next() {
+		var d models.MedicationDose
+		err := rows.Scan(&d.ID, &d.PrescriptionID, &d.PatientID, &d.Medication, &d.ScheduledAt, &d.Status, &d.LoggedAt, &d.Note)
+		if err != nil {
+			return nil, err
+		}
+		doses = append(doses, d)
+	}
+	return doses, nil
+}
+
+func (r *Repository) GetDoseForPatient(patientID int, doseID int64) (models.MedicationDose, error) {
+	query := `
+		SELECT ` + medicationDoseColumns + `
+		FROM medication_doses d
+		JOIN prescriptions p ON d.prescription_id = p.id
+		WHERE d.id = $1 AND d.patient_id = $2
+	`
+	var d models.MedicationDose
+	err := r.DB.QueryRow(query, doseID, patientID).Scan(
+		&d.ID, &d.PrescriptionID, &d.PatientID, &d.Medication, &d.ScheduledAt, &d.Status, &d.LoggedAt, &d.Note,
+	)
+	return d, err
+}
+
+// LogDose records what the patient did about a dose that is still due or
+// was marked missed, and is scheduled between from and to. It returns
+// ErrDoseNotLoggable if the dose was already logged or is outside that
+// window.
+func (r *Repository) LogDose(patientID int, doseID int64, status, note string, from, to time.Time) error {
+	query := `
+		UPDATE medication_doses
+		SET status = $3, note = NULLIF($4, ''), logged_at = now()
+		WHERE id = $1 AND patient_id = $2 AND status IN ('due', 'missed') AND scheduled_at BETWEEN $5 AND $6
+	`
+	res, err := r.DB.Exec(query, doseID, patientID, status, note, from, to)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrDoseNotLoggable
+	}
+	return nil
+}
+
+// GetAdherence summarises dose outcomes for a prescription between from and
+// to, overall and per period ('day', 'week' or 'month').
+func (r *Repository) GetAdherence(prescriptionID int, period string, from, to time.Time) (models.AdherenceStats, []models.AdherenceStats, error) {
+	query := `
+		SELECT date_trunc($2, scheduled_at) AS period,
+			count(*) FILTER (WHERE status = 'taken'),
+			count(*) FILTER (WHERE status = 'late'),
+			count(*) FILTER (WHERE status = 'skipped'),
+			count(*) FILTER (WHERE status = 'missed'),
+			count(*) FILTER (WHERE status = 'due')
+		FROM medication_doses
+		WHERE prescription_id = $1 AND scheduled_at BETWEEN $3 AND $4
+		GROUP BY period
+		ORDER BY period
+	`
+	rows, err := r.DB.Query(query, prescriptionID, period, from, to)
+	if err != nil {
+		return models.AdherenceStats{}, nil, err
+	}
+	defer rows.Close()
+
+	var overall models.AdherenceStats
+	var periods []models.AdherenceStats
+	for rows.Next() {
+		var s models.AdherenceStats
+		var start time.Time
+		if err := rows.Scan(&start, &s.Taken, &s.Late, &s.Skipped, &s.Missed, &s.Due); err != nil {
+			return models.AdherenceStats{}, nil, err
+		}
+		s.Period = &start
+		s.Adherence = adherencePct(s)
+		periods = append(periods, s)
+
+		overall.Taken += s.Taken
+		overall.Late += s.Late
+		overall.Skipped += s.Skipped
+		overall.Missed += s.Missed
+		overall.Due += s.Due
+	}
+	overall.Adherence = adherencePct(overall)
+
+	return overall, periods, rows.Err()
+}
+
+// adherencePct is the share of resolved doses that were taken, late or not.
+// Doses that are still due don't count either way.
+func adherencePct(s models.AdherenceStats) *float64 {
+	resolved := s.Taken + s.Late + s.Skipped + s.Missed
+	if resolved == 0 {
+		return nil
+	}
+	pct := 100 * float64(s.Taken+s.Late) / float64(resolved)
+	return &pct
+}
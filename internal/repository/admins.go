@@ -0,0 +1,33 @@
+package repository
+
+import "github.com/RitwikGupta-0501/vital-watch/internal/models"
+
+// Admin Related Methods
+func (r *Repository) CreateAdmin(firstName, lastName, email, hashedPassword string, clinicID *int) (int, error) {
+	query := `
+		INSERT INTO admins (firstName, lastName, email, hashedPassword, clinic_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	var newID int
+	err := r.DB.QueryRow(query, firstName, lastName, email, hashedPassword, clinicID).Scan(&newID)
+	return newID, err
+}
+
+func (r *Repository) GetAdminByEmail(email string) (models.Admin, error) {
+	query := `SELECT id, firstName, lastName, email, hashedPassword, clinic_id, createdAt FROM admins WHERE email=$1`
+
+	var admin models.Admin
+	err := r.DB.QueryRow(query, email).Scan(&admin.ID, &admin.FirstName, &admin.LastName, &admin.Email,
+		&admin.HashedPassword, &admin.ClinicID, &admin.CreatedAt)
+	return admin, err
+}
+
+func (r *Repository) GetAdminByID(id int) (models.Admin, error) {
+	query := `SELECT id, firstName, lastName, email, hashedPassword, clinic_id, createdAt FROM admins WHERE id=$1`
+
+	var admin models.Admin
+	err := r.DB.QueryRow(query, id).Scan(&admin.ID, &admin.FirstName, &admin.LastName, &admin.Email,
+		&admin.HashedPassword, &admin.ClinicID, &admin.CreatedAt)
+	return admin, err
+}
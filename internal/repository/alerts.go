@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Vital Alert Related Methods
+func (r *Repository) GetPatientIDsWithReadingsSince(since time.Time) ([]int, error) {
+	query := `SELECT DISTINCT patient_id FROM vital_readings WHERE recorded_at >= $1`
+
+	rows, err := r.DB.Query(query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// CreateVitalAlert stores an alert unless an unacknowledged alert of the same
+// kind for the same metric and time of day was raised within dedupeWindow. It
+// reports whether a new alert was created.
+func (r *Repository) CreateVitalAlert(alert models.VitalAlert, dedupeWindow time.Duration) (bool, error) {
+	evidence, err := json.Marshal(alert.Evidence)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO vital_alerts (
+			patient_id, metric, kind, severity, time_of_day, observed_value, baseline_median,
+			baseline_mad, baseline_samples, score, window_start, window_end, evidence
+		)
+		SELECT $1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12, $13
+		WHERE NOT EXISTS (
+			SELECT 1 FROM vital_alerts
+			WHERE patient_id = $1 AND metric = $2 AND kind = $3 AND COALESCE(time_of_day, '') = $5
+				AND acknowledged_at IS NULL AND created_at > now() - make_interval(secs => $14)
+		)
+	`
+	res, err := r.DB.Exec(query,
+		alert.PatientID, alert.Metric, alert.Kind, alert.Severity, alert.TimeOfDay, alert.ObservedValue, alert.BaselineMedian,
+		alert.BaselineMAD, alert.BaselineSamples, alert.Score, alert.WindowStart, alert.WindowEnd, string(evidence),
+		dedupeWindow.Seconds(),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+const vitalAlertColumns = `
+	va.id, va.patient_id, va.metric, va.kind, va.severity, COALESCE(va.time_of_day, ''),
+	COALESCE(va.observed_value, 0), COALESCE(va.baseline_median, 0), COALESCE(va.baseline_mad, 0),
+	COALESCE(va.baseline_samples, 0), COALESCE(va.score, 0), va.window_start, va.window_end, va.evidence,
+	va.created_at, va.acknowledged_at, va.acknowledged_by, p.firstName, p.lastName`
+
+func scanVitalAlerts(rows *sql.Rows) ([]models.VitalAlert, error) {
+	defer rows.Close()
+
+	var alerts []models.VitalAlert
+	for rows.Next() {
+		var a models.VitalAlert
+		var evidence []byte
+		var firstName, lastName string
+		err := rows.Scan(
+			&a.ID, &a.PatientID, &a.Metric, &a.Kind, &a.Severity, &a.TimeOfDay,
+			&a.ObservedValue, &a.BaselineMedian, &a.BaselineMAD,
+			&a.BaselineSamples, &a.Score, &a.WindowStart, &a.WindowEnd, &evidence,
+			&a.CreatedAt, &a.AcknowledgedAt, &a.AcknowledgedBy, &firstName, &lastName,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(evidence, &a.Evidence); err != nil {
+			return nil, err
+		}
+		a.PatientName = firstName + " " + lastName
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+// GetAlertsForDoctor returns alerts for every patient who lets the doctor see
+// their vitals.
+func (r *Repository) GetAlertsForDoctor(doctorID int, includeAcknowledged bool) ([]models.VitalAlert, error) {
+	query := `
+		SELECT ` + vitalAlertColumns + `
+		FROM vital_alerts va
+		JOIN patients p ON va.patient_id = p.id
+		WHERE ` + consentClause("$1", "va.patient_id", ConsentVitals) + `
+			AND ($2 OR va.acknowledged_at IS NULL)
+		ORDER BY va.created_at DESC
+	`
+	rows, err := r.DB.Query(query, doctorID, includeAcknowledged)
+	if err != nil {
+		return nil, err
+	}
+	return scanVitalAlerts(rows)
+}
+
+func (r *Repository) GetAlertsByPatientID(patientID int, includeAcknowledged bool) ([]models.VitalAlert, error) {
+	query := `
+		SELECT ` + vitalAlertColumns + `
+		FROM vital_alerts va
+		JOIN patients p ON va.patient_id = p.id
+		WHERE va.patient_id = $1 AND ($2 OR va.acknowledged_at IS NULL)
+		ORDER BY va.created_at DESC
+	`
+	rows, err := r.DB.Query(query, patientID, includeAcknowledged)
+	if err != nil {
+		return nil, err
+	}
+	return scanVitalAlerts(rows)
+}
+
+// AcknowledgeAlert marks an alert as handled. It returns sql.ErrNoRows if the
+// alert doesn't exist, is already acknowledged or belongs to a patient who
+// doesn't let the doctor see their vitals.
+func (r *Repository) AcknowledgeAlert(doctorID, alertID int) error {
+	query := `
+		UPDATE vital_alerts
+		SET acknowledged_at = now(), acknowledged_by = $1
+		WHERE id = $2 AND acknowledged_at IS NULL
+			AND ` + consentClause("$1", "vital_alerts.patient_id", ConsentVitals) + `
+	`
+	res, err := r.DB.Exec(query, doctorID, alertID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Allergy and Formulary Check Related Methods
+func (r *Repository) CreateAllergy(a models.PatientAllergy) (int, error) {
+	query := `
+		INSERT INTO patient_allergies (patient_id, substance, reaction, severity, source, recorded_by)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6)
+		RETURNING id
+	`
+	var newID int
+	err := r.DB.QueryRow(query, a.PatientID, a.Substance, a.Reaction, a.Severity, a.Source, a.RecordedBy).Scan(&newID)
+	return newID, err
+}
+
+func (r *Repository) GetAllergiesByPatientID(patientID int) ([]models.PatientAllergy, error) {
+	query := `
+		SELECT id, patient_id, substance, COALESCE(reaction, ''), COALESCE(severity, ''), created_at,
+			COALESCE(source, ''), recorded_by
+		FROM patient_allergies
+		WHERE patient_id = $1
+		ORDER BY substance
+	`
+	rows, err := r.DB.Query(query, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allergies := []models.PatientAllergy{}
+	for rows.Next() {
+		var a models.PatientAllergy
+		err := rows.Scan(&a.ID, &a.PatientID, &a.Substance, &a.Reaction, &a.Severity, &a.CreatedAt,
+			&a.Source, &a.RecordedBy)
+		if err != nil {
+			return nil, err
+		}
+		allergies = append(allergies, a)
+	}
+	return allergies, rows.Err()
+}
+
+// UpdateAllergy replaces the allergy's details and who recorded them. With
+// selfReportedOnly it only touches allergies a doctor didn't record. It
+// returns sql.ErrNoRows if the patient has no such allergy.
+func (r *Repository) UpdateAllergy(a models.PatientAllergy, selfReportedOnly bool) error {
+	query := `
+		UPDATE patient_allergies
+		SET substance = $3, reaction = NULLIF($4, ''), severity = NULLIF($5, ''),
+			source = $6, recorded_by = $7, updated_at = now()
+		WHERE id = $1 AND patient_id = $2 AND (NOT $8 OR ` + selfReportedClause + `)
+	`
+	res, err := r.DB.Exec(query, a.ID, a.PatientID, a.Substance, a.Reaction, a.Severity,
+		a.Source, a.RecordedBy, selfReportedOnly)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteAllergy returns sql.ErrNoRows if the patient has no such allergy, or
+// with selfReportedOnly, none a doctor didn't record.
+func (r *Repository) DeleteAllergy(patientID, allergyID int, selfReportedOnly bool) error {
+	query := `DELETE FROM patient_allergies WHERE id = $1 AND patient_id = $2 AND (NOT $3 OR ` + selfReportedClause + `)`
+	res, err := r.DB.Exec(query, allergyID, patientID, selfReportedOnly)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetActiveMedications lists the drugs on a patient's current, unexpired
+// prescriptions, skipping the ones in exclude, and the ones recorded as taken
+// outside them with a zero PrescriptionID. Prescriptions without line items
+// contribute their medication text.
+func (r *Repository) GetActiveMedications(patientID int, exclude []int) ([]models.ActiveMedication, error) {
+	if exclude == nil {
+		exclude = []int{}
+	}
+
+	query := `
+		SELECT prescription_id, name FROM (
+			SELECT p.id AS prescription_id, COALESCE(i.drug_name, p.medication) AS name, i.position
+			FROM prescriptions p
+			LEFT JOIN prescription_items i ON i.prescription_id = p.id
+			WHERE p.patient_id = $1 AND p.status = 'active'
+				AND (p.expires_at IS NULL OR p.expires_at > now())
+				AND NOT (p.id = ANY($2))
+			UNION ALL
+			SELECT 0, m.name, m.id
+			FROM patient_medications m
+			WHERE m.patient_id = $1 AND (m.stopped_on IS NULL OR m.stopped_on > current_date)
+		) meds
+		ORDER BY prescription_id = 0, prescription_id, position
+	`
+	rows, err := r.DB.Query(query, patientID, exclude)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var meds []models.ActiveMedication
+	for rows.Next() {
+		var m models.ActiveMedication
+		if err := rows.Scan(&m.PrescriptionID, &m.Name); err != nil {
+			return nil, err
+		}
+		meds = append(meds, m)
+	}
+	return meds, rows.Err()
+}
+
+func createSafetyOverride(tx *sql.Tx, prescriptionID, doctorID int, o models.SafetyOverride) error {
+	warnings, err := json.Marshal(o.Warnings)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO prescription_overrides (prescription_id, doctor_id, reason, warnings)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err = tx.Exec(query, prescriptionID, doctorID, o.Reason, string(warnings))
+	return err
+}
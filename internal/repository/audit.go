@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Audit Log Related Methods
+
+// auditChainLock is the advisory lock key serializing appends to the audit
+// log, so each entry chains onto the one before it.
+const auditChainLock = 0x61756469
+
+// auditGenesisHash is the previous hash of the first entry.
+var auditGenesisHash = make([]byte, sha256.Size)
+
+// auditHash chains e onto the previous entry's hash. It covers every recorded
+// field, so changing any of them, or removing or reordering entries, breaks
+// the chain from there on.
+func auditHash(prev []byte, e models.AuditEntry) ([]byte, error) {
+	fields, err := json.Marshal([]any{
+		e.OccurredAt.UTC().Format(time.RFC3339Nano), e.ActorRole, e.ActorID, e.PatientID,
+		e.Resource, e.ResourceID, e.Action, e.Method, e.Path, e.StatusCode, e.Outcome,
+		e.IPAddress, e.UserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write(prev)
+	h.Write(fields)
+	return h.Sum(nil), nil
+}
+
+// AppendAuditEntry adds e to the end of the audit log.
+func (r *Repository) AppendAuditEntry(e models.AuditEntry) error {
+	// Postgres keeps microseconds, and the hash must match what is read back
+	e.OccurredAt = e.OccurredAt.UTC().Truncate(time.Microsecond)
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return err
+	}
+
+	prev := auditGenesisHash
+	err = tx.QueryRow(`SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&prev)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	hash, err := auditHash(prev, e)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO audit_log (
+			occurred_at, actor_role, actor_id, patient_id, resource, resource_id, action,
+			method, path, status_code, outcome, ip_address, user_agent, prev_hash, hash
+		)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11, NULLIF($12, ''), NULLIF($13, ''), $14, $15)
+	`, e.OccurredAt, e.ActorRole, e.ActorID, e.PatientID, e.Resource, e.ResourceID, e.Action,
+		e.Method, e.Path, e.StatusCode, e.Outcome, e.IPAddress, e.UserAgent, prev, hash)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AuditFilter narrows an audit log listing. Zero fields match everything.
+// ClinicID limits it to entries by the clinic's doctors, and BeforeID pages
+// back from an earlier listing's last ID.
+type AuditFilter struct {
+	PatientID *int
+	ActorRole string
+	ActorID   *int
+	Resource  string
+	Action    string
+	From      time.Time
+	To        time.Time
+	ClinicID  *int
+	BeforeID  int64
+	Limit     int
+}
+
+const auditColumns = `
+	a.id, a.occurred_at, a.actor_role, a.actor_id, a.patient_id, a.resource, COALESCE(a.resource_id, ''),
+	a.action, a.method, a.path, a.status_code, a.outcome, COALESCE(a.ip_address, ''), COALESCE(a.user_agent, ''),
+	a.prev_hash, a.hash,
+	COALESCE(CASE a.actor_role
+		WHEN 'patient' THEN (SELECT firstName || ' ' || lastName FROM patients WHERE id = a.actor_id)
+		WHEN 'doctor' THEN (SELECT firstName || ' ' || lastName FROM doctors WHERE id = a.actor_id)
+		WHEN 'admin' THEN (SELECT firstName || ' ' || lastName FROM admins WHERE id = a.actor_id)
+	END, '')`
+
+func scanAuditEntry(row interface{ Scan(...any) error }) (models.AuditEntry, error) {
+	var e models.AuditEntry
+	err := row.Scan(&e.ID, &e.OccurredAt, &e.ActorRole, &e.ActorID, &e.PatientID, &e.Resource, &e.ResourceID,
+		&e.Action, &e.Method, &e.Path, &e.StatusCode, &e.Outcome, &e.IPAddress, &e.UserAgent,
+		&e.PrevHash, &e.Hash, &e.ActorName)
+	return e, err
+}
+
+func (r *Repository) queryAuditEntries(query string, args ...any) ([]models.AuditEntry, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetAuditEntries returns the entries matching f, newest first.
+func (r *Repository) GetAuditEntries(f AuditFilter) ([]models.AuditEntry, error) {
+	query := `
+		SELECT ` + auditColumns + `
+		FROM audit_log a
+		WHERE ($1::int IS NULL OR a.patient_id = $1)
+			AND ($2 = '' OR a.actor_role = $2)
+			AND ($3::int IS NULL OR a.actor_id = $3)
+			AND ($4 = '' OR a.resource = $4)
+			AND ($5 = '' OR a.action = $5)
+			AND ($6::timestamptz IS NULL OR a.occurred_at >= $6)
+			AND ($7::timestamptz IS NULL OR a.occurred_at < $7)
+			AND ($8::int IS NULL OR (a.actor_role = 'doctor' AND a.actor_id IN (SELECT id FROM doctors WHERE clinic_id = $8)))
+			AND ($9 = 0 OR a.id < $9)
+		ORDER BY a.id DESC
+		LIMIT $10
+	`
+	return r.queryAuditEntries(query, f.PatientID, f.ActorRole, f.ActorID, f.Resource, f.Action,
+		nullTime(f.From), nullTime(f.To), f.ClinicID, f.BeforeID, f.Limit)
+}
+
+// GetPatientAccessLog returns who else read or changed the patient's data,
+// leaving out the patient's own devices, newest first, paging back from
+// beforeID when it isn't zero.
+func (r *Repository) GetPatientAccessLog(patientID int, beforeID int64, limit int) ([]models.AuditEntry, error) {
+	query := `
+		SELECT ` + auditColumns + `
+		FROM audit_log a
+		WHERE a.patient_id = $1 AND a.actor_role <> 'device' AND NOT (a.actor_role = 'patient' AND a.actor_id = $1)
+			AND ($2 = 0 OR a.id < $2)
+		ORDER BY a.id DESC
+		LIMIT $3
+	`
+	return r.queryAuditEntries(query, patientID, beforeID, limit)
+}
+
+// VerifyAuditChain recomputes every entry's hash in order. It returns how
+// many entries it checked and the ID of the first one whose hash or link to
+// the previous entry doesn't match, or zero when the whole chain is intact.
+func (r *Repository) VerifyAuditChain() (checked int64, brokenID int64, err error) {
+	rows, err := r.DB.Query(`SELECT ` + auditColumns + ` FROM audit_log a ORDER BY a.id`)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	prev := auditGenesisHash
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return checked, 0, err
+		}
+		checked++
+
+		hash, err := auditHash(prev, e)
+		if err != nil {
+			return checked, 0, err
+		}
+		if !bytes.Equal(e.PrevHash, prev) || !bytes.Equal(e.Hash, hash) {
+			return checked, e.ID, nil
+		}
+		prev = e.Hash
+	}
+	return checked, 0, rows.Err()
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
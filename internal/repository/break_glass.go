@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Break-Glass Related Methods
+
+// breakGlassClause is true while the doctor in doctorExpr holds emergency
+// access to the patient in patientExpr.
+func breakGlassClause(doctorExpr, patientExpr string) string {
+	return `EXISTS (
+		SELECT 1 FROM break_glass_grants bg
+		WHERE bg.doctor_id = ` + doctorExpr + ` AND bg.patient_id = ` + patientExpr + ` AND bg.expires_at > now()
+	)`
+}
+
+// CreateBreakGlassGrant records emergency access to the patient's record
+// until g.ExpiresAt, notifying the patient and the admins of the doctor's
+// clinic along with those overseeing every clinic. The patient notification
+// is sent as is; adminNotify is copied for each admin.
+func (r *Repository) CreateBreakGlassGrant(g models.BreakGlassGrant, patientNotify, adminNotify models.Notification) (models.BreakGlassGrant, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return models.BreakGlassGrant{}, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO break_glass_grants (doctor_id, patient_id, justification, ip_address, user_agent, expires_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6)
+		RETURNING id, granted_at
+	`, g.DoctorID, g.PatientID, g.Justification, g.IPAddress, g.UserAgent, g.ExpiresAt).Scan(&g.ID, &g.GrantedAt)
+	if err != nil {
+		return models.BreakGlassGrant{}, err
+	}
+
+	if err := createNotification(tx, patientNotify); err != nil {
+		return models.BreakGlassGrant{}, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT id FROM admins
+		WHERE clinic_id IS NULL OR clinic_id = (SELECT clinic_id FROM doctors WHERE id = $1)
+	`, g.DoctorID)
+	if err != nil {
+		return models.BreakGlassGrant{}, err
+	}
+	var adminIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return models.BreakGlassGrant{}, err
+		}
+		adminIDs = append(adminIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.BreakGlassGrant{}, err
+	}
+
+	adminNotify.RecipientRole = "admin"
+	adminNotify.Data["break_glass_id"] = g.ID
+	for _, id := range adminIDs {
+		adminNotify.RecipientID = id
+		if err := createNotification(tx, adminNotify); err != nil {
+			return models.BreakGlassGrant{}, err
+		}
+	}
+
+	return g, tx.Commit()
+}
+
+const breakGlassColumns = `
+	bg.id, bg.doctor_id, bg.patient_id, bg.justification, COALESCE(bg.ip_address, ''), COALESCE(bg.user_agent, ''),
+	bg.granted_at, bg.expires_at, bg.reviewed_at, bg.reviewed_by, COALESCE(bg.review_outcome, ''), COALESCE(bg.review_note, ''),
+	p.firstName || ' ' || p.lastName, d.firstName || ' ' || d.lastName`
+
+const breakGlassJoins = `
+	JOIN patients p ON bg.patient_id = p.id
+	JOIN doctors d ON bg.doctor_id = d.id`
+
+func (r *Repository) queryBreakGlassGrants(query string, args ...any) ([]models.BreakGlassGrant, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []models.BreakGlassGrant
+	for rows.Next() {
+		var g models.BreakGlassGrant
+		err := rows.Scan(&g.ID, &g.DoctorID, &g.PatientID, &g.Justification, &g.IPAddress, &g.UserAgent,
+			&g.GrantedAt, &g.ExpiresAt, &g.ReviewedAt, &g.ReviewedBy, &g.ReviewOutcome, &g.ReviewNote,
+			&g.PatientName, &g.DoctorName)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+// GetBreakGlassGrantsByPatientID returns every emergency access to the
+// patient's record, newest first.
+func (r *Repository) GetBreakGlassGrantsByPatientID(patientID int) ([]models.BreakGlassGrant, error) {
+	query := `
+		SELECT ` + breakGlassColumns + `
+		FROM break_glass_grants bg` + breakGlassJoins + `
+		WHERE bg.patient_id = $1
+		ORDER BY bg.granted_at DESC
+	`
+	return r.queryBreakGlassGrants(query, patientID)
+}
+
+// GetBreakGlassReviewQueue returns emergency accesses by doctors of the
+// clinic, or of every clinic when clinicID is nil. Unreviewed ones come
+// oldest first; with reviewed, those already reviewed come newest first.
+func (r *Repository) GetBreakGlassReviewQueue(clinicID *int, reviewed bool) ([]models.BreakGlassGrant, error) {
+	order := "bg.granted_at"
+	if reviewed {
+		order = "bg.reviewed_at DESC"
+	}
+	query := `
+		SELECT ` + breakGlassColumns + `
+		FROM break_glass_grants bg` + breakGlassJoins + `
+		WHERE ($1::int IS NULL OR d.clinic_id = $1) AND (bg.reviewed_at IS NOT NULL) = $2
+		ORDER BY ` + order
+	return r.queryBreakGlassGrants(query, clinicID, reviewed)
+}
+
+// ReviewBreakGlassGrant records an admin's verdict on an emergency access. It
+// returns sql.ErrNoRows if the grant doesn't exist, was by a doctor outside
+// the admin's clinic or has already been reviewed.
+func (r *Repository) ReviewBreakGlassGrant(grantID, adminID int, clinicID *int, outcome, note string) error {
+	res, err := r.DB.Exec(`
+		UPDATE break_glass_grants bg
+		SET reviewed_at = now(), reviewed_by = $2, review_outcome = $4, review_note = NULLIF($5, '')
+		FROM doctors d
+		WHERE bg.id = $1 AND bg.doctor_id = d.id AND bg.reviewed_at IS NULL
+			AND ($3::int IS NULL OR d.clinic_id = $3)
+	`, grantID, adminID, clinicID, outcome, note)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Patient Chart Related Methods
+
+// Who recorded a chart entry.
+const (
+	SourcePatient = "patient"
+	SourceDoctor  = "doctor"
+)
+
+// selfReportedClause matches chart entries no doctor recorded, which the
+// patient may still change.
+const selfReportedClause = `source IS DISTINCT FROM 'doctor'`
+
+func (r *Repository) CreateCondition(cond models.PatientCondition) (int, error) {
+	query := `
+		INSERT INTO patient_conditions (patient_id, name, code, code_system, onset_date, resolved_date, notes, source, recorded_by)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5::date, $6::date, NULLIF($7, ''), $8, $9)
+		RETURNING id
+	`
+	var newID int
+	err := r.DB.QueryRow(query, cond.PatientID, cond.Name, cond.Code, cond.CodeSystem, cond.OnsetDate, cond.ResolvedDate,
+		cond.Notes, cond.Source, cond.RecordedBy).Scan(&newID)
+	return newID, err
+}
+
+const conditionColumns = `
+	id, patient_id, name, COALESCE(code, ''), COALESCE(code_system, ''), to_char(onset_date, 'YYYY-MM-DD'), to_char(resolved_date, 'YYYY-MM-DD'),
+	CASE WHEN resolved_date IS NULL THEN 'active' ELSE 'resolved' END,
+	COALESCE(notes, ''), source, recorded_by, created_at, updated_at`
+
+// GetConditionsByPatientID returns the patient's problem list, active
+// conditions first, then the most recent.
+func (r *Repository) GetConditionsByPatientID(patientID int) ([]models.PatientCondition, error) {
+	query := `
+		SELECT ` + conditionColumns + `
+		FROM patient_conditions
+		WHERE patient_id = $1
+		ORDER BY resolved_date IS NOT NULL, COALESCE(onset_date, created_at::date) DESC, id DESC
+	`
+	rows, err := r.DB.Query(query, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conditions := []models.PatientCondition{}
+	for rows.Next() {
+		var cond models.PatientCondition
+		err := rows.Scan(&cond.ID, &cond.PatientID, &cond.Name, &cond.Code, &cond.CodeSystem, &cond.OnsetDate, &cond.ResolvedDate,
+			&cond.Status, &cond.Notes, &cond.Source, &cond.RecordedBy, &cond.CreatedAt, &cond.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions, rows.Err()
+}
+
+// UpdateCondition replaces the condition's details and who recorded them.
+// With selfReportedOnly it only touches conditions a doctor didn't record. It
+// returns sql.ErrNoRows if the patient has no such condition.
+func (r *Repository) UpdateCondition(cond models.PatientCondition, selfReportedOnly bool) error {
+	query := `
+		UPDATE patient_conditions
+		SET name = $3, code = NULLIF($4, ''), code_system = NULLIF($5, ''), onset_date = $6::date, resolved_date = $7::date,
+			notes = NULLIF($8, ''), source = $9, recorded_by = $10, updated_at = now()
+		WHERE id = $1 AND patient_id = $2 AND (NOT $11 OR ` + selfReportedClause + `)
+	`
+	res, err := r.DB.Exec(query, cond.ID, cond.PatientID, cond.Name, cond.Code, cond.CodeSystem, cond.OnsetDate, cond.ResolvedDate,
+		cond.Notes, cond.Source, cond.RecordedBy, selfReportedOnly)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteCondition returns sql.ErrNoRows if the patient has no such
+// condition, or with selfReportedOnly, none a doctor didn't record.
+func (r *Repository) DeleteCondition(patientID, conditionID int, selfReportedOnly bool) error {
+	query := `DELETE FROM patient_conditions WHERE id = $1 AND patient_id = $2 AND (NOT $3 OR ` + selfReportedClause + `)`
+	res, err := r.DB.Exec(query, conditionID, patientID, selfReportedOnly)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *Repository) CreateMedication(med models.PatientMedication) (int, error) {
+	query := `
+		INSERT INTO patient_medications (
+			patient_id, name, dose, frequency, route, started_on, stopped_on, notes, source, recorded_by
+		)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6::date, $7::date, NULLIF($8, ''), $9, $10)
+		RETURNING id
+	`
+	var newID int
+	err := r.DB.QueryRow(query, med.PatientID, med.Name, med.Dose, med.Frequency, med.Route,
+		med.StartedOn, med.StoppedOn, med.Notes, med.Source, med.RecordedBy).Scan(&newID)
+	return newID, err
+}
+
+// GetMedicationsByPatientID returns the medications recorded for the patient
+// outside their prescriptions, current ones first. With currentOnly it leaves
+// out the ones they have stopped.
+func (r *Repository) GetMedicationsByPatientID(patientID int, currentOnly bool) ([]models.PatientMedication, error) {
+	query := `
+		SELECT id, patient_id, name, COALESCE(dose, ''), COALESCE(frequency, ''), COALESCE(route, ''),
+			to_char(started_on, 'YYYY-MM-DD'), to_char(stopped_on, 'YYYY-MM-DD'), COALESCE(notes, ''),
+			source, recorded_by, created_at, updated_at
+		FROM patient_medications
+		WHERE patient_id = $1 AND ($2 = false OR stopped_on IS NULL OR stopped_on > current_date)
+		ORDER BY stopped_on IS NOT NULL, name
+	`
+	rows, err := r.DB.Query(query, patientID, currentOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meds := []models.PatientMedication{}
+	for rows.Next() {
+		var med models.PatientMedication
+		err := rows.Scan(&med.ID, &med.PatientID, &med.Name, &med.Dose, &med.Frequency, &med.Route,
+			&med.StartedOn, &med.StoppedOn, &med.Notes,
+			&med.Source, &med.RecordedBy, &med.CreatedAt, &med.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		meds = append(meds, med)
+	}
+	return meds, rows.Err()
+}
+
+// GetPrescribedMedications lists the drugs on the patient's current,
+// unexpired prescriptions as medications. Prescriptions without line items
+// contribute their medication text.
+func (r *Repository) GetPrescribedMedications(patientID int) ([]models.PatientMedication, error) {
+	query := `
+		SELECT p.id, p.patient_id, p.doctor_id, COALESCE(i.drug_name, p.medication),
+			COALESCE(NULLIF(concat_ws(' ', i.strength, i.dose), ''), ''), COALESCE(i.frequency, ''), COALESCE(i.route, ''),
+			to_char(p.created_at, 'YYYY-MM-DD'), p.created_at
+		FROM prescriptions p
+		LEFT JOIN prescription_items i ON i.prescription_id = p.id
+		WHERE p.patient_id = $1 AND p.status = 'active'
+			AND (p.expires_at IS NULL OR p.expires_at > now())
+		ORDER BY p.created_at DESC, p.id, i.position
+	`
+	rows, err := r.DB.Query(query, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meds := []models.PatientMedication{}
+	for rows.Next() {
+		med := models.PatientMedication{Source: "prescription"}
+		var prescriptionID, doctorID int
+		err := rows.Scan(&prescriptionID, &med.PatientID, &doctorID, &med.Name,
+			&med.Dose, &med.Frequency, &med.Route, &med.StartedOn, &med.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		med.PrescriptionID = &prescriptionID
+		med.RecordedBy = &doctorID
+		meds = append(meds, med)
+	}
+	return meds, rows.Err()
+}
+
+// UpdateMedication replaces the medication's details and who recorded them.
+// With selfReportedOnly it only touches medications a doctor didn't record.
+// It returns sql.ErrNoRows if the patient has no such medication.
+func (r *Repository) UpdateMedication(med models.PatientMedication, selfReportedOnly bool) error {
+	query := `
+		UPDATE patient_medications
+		SET name = $3, dose = NULLIF($4, ''), frequency = NULLIF($5, ''), route = NULLIF($6, ''),
+			started_on = $7::date, stopped_on = $8::date, notes = NULLIF($9, ''),
+			source = $10, recorded_by = $11, updated_at = now()
+		WHERE id = $1 AND patient_id = $2 AND (NOT $12 OR ` + selfReportedClause + `)
+	`
+	res, err := r.DB.Exec(query, med.ID, med.PatientID, med.Name, med.Dose, med.Frequency, med.Route,
+		med.StartedOn, med.StoppedOn, med.Notes, med.Source, med.RecordedBy, selfReportedOnly)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteMedication returns sql.ErrNoRows if the patient has no such
+// medication, or with selfReportedOnly, none a doctor didn't record.
+func (r *Repository) DeleteMedication(patientID, medicationID int, selfReportedOnly bool) error {
+	query := `DELETE FROM patient_medications WHERE id = $1 AND patient_id = $2 AND (NOT $3 OR ` + selfReportedClause + `)`
+	res, err := r.DB.Exec(query, medicationID, patientID, selfReportedOnly)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
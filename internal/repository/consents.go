@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Patient Consent Related Methods
+
+// Categories of a patient's record that access is granted to. A doctor always
+// sees the appointments and prescriptions they were part of; consent covers
+// everything else.
+const (
+	ConsentAppointments  = "appointments"  // appointments with other doctors
+	ConsentPrescriptions = "prescriptions" // prescriptions by other doctors, their versions and adherence
+	ConsentAllergies     = "allergies"
+	ConsentConditions    = "conditions"  // the problem list
+	ConsentMedications   = "medications" // current medications, prescribed or not
+	ConsentVitals        = "vitals"      // readings, devices, alerts and NEWS2 scores
+	ConsentDocuments     = "documents"
+	ConsentNotes         = "notes" // signed encounter notes by other doctors
+	ConsentLabs          = "labs"  // lab orders by other doctors and their results
+)
+
+var ConsentCategories = map[string]bool{
+	ConsentAppointments:  true,
+	ConsentPrescriptions: true,
+	ConsentAllergies:     true,
+	ConsentConditions:    true,
+	ConsentMedications:   true,
+	ConsentVitals:        true,
+	ConsentDocuments:     true,
+	ConsentNotes:         true,
+	ConsentLabs:          true,
+}
+
+// consentClause is true when the patient in patientExpr has an unexpired,
+// unrevoked grant of the category to the doctor in doctorExpr or to their
+// clinic, or the doctor holds emergency access to the patient. category must
+// be one of the constants above, never user input.
+func consentClause(doctorExpr, patientExpr, category string) string {
+	return `(EXISTS (
+		SELECT 1 FROM patient_consents pc
+		WHERE pc.patient_id = ` + patientExpr + ` AND pc.category = '` + category + `'
+			AND pc.revoked_at IS NULL AND (pc.expires_at IS NULL OR pc.expires_at > now())
+			AND (pc.doctor_id = ` + doctorExpr + `
+				OR pc.clinic_id = (SELECT clinic_id FROM doctors WHERE id = ` + doctorExpr + `))
+	) OR ` + breakGlassClause(doctorExpr, patientExpr) + `)`
+}
+
+// DoctorHasConsent reports whether the patient currently lets the doctor see
+// the category of their record, or the doctor has broken the glass.
+func (r *Repository) DoctorHasConsent(doctorID, patientID int, category string) (bool, error) {
+	query := `SELECT ` + consentClause("$1", "$2", category)
+	var ok bool
+	err := r.DB.QueryRow(query, doctorID, patientID).Scan(&ok)
+	return ok, err
+}
+
+// GrantConsents gives the doctor or clinic access to each category until
+// expiresAt, or until revoked when it is nil, replacing any earlier grant of
+// the same category. Exactly one of doctorID and clinicID must be set; the
+// doctor is notified of a direct grant.
+func (r *Repository) GrantConsents(patientID int, doctorID, clinicID *int, categories []string, expiresAt *time.Time, notify models.Notification) ([]models.PatientConsent, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var consents []models.PatientConsent
+	for _, category := range categories {
+		_, err := tx.Exec(`
+			UPDATE patient_consents SET revoked_at = now()
+			WHERE patient_id = $1 AND category = $2 AND revoked_at IS NULL
+				AND (doctor_id = $3 OR clinic_id = $4)
+		`, patientID, category, doctorID, clinicID)
+		if err != nil {
+			return nil, err
+		}
+
+		c := models.PatientConsent{PatientID: patientID, DoctorID: doctorID, ClinicID: clinicID, Category: category, ExpiresAt: expiresAt}
+		err = tx.QueryRow(`
+			INSERT INTO patient_consents (patient_id, doctor_id, clinic_id, category, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, granted_at
+		`, patientID, doctorID, clinicID, category, expiresAt).Scan(&c.ID, &c.GrantedAt)
+		if err != nil {
+			return nil, err
+		}
+		consents = append(consents, c)
+	}
+
+	if doctorID != nil {
+		if err := createNotification(tx, notify); err != nil {
+			return nil, err
+		}
+	}
+	return consents, tx.Commit()
+}
+
+// RevokeConsent ends a grant. It returns sql.ErrNoRows if the patient has no
+// such grant or it is already revoked.
+func (r *Repository) RevokeConsent(patientID, consentID int) error {
+	res, err := r.DB.Exec(`
+		UPDATE patient_consents SET revoked_at = now()
+		WHERE id = $1 AND patient_id = $2 AND revoked_at IS NULL
+	`, consentID, patientID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+const consentColumns = `
+	pc.id, pc.patient_id, pc.doctor_id, pc.clinic_id, pc.category, pc.granted_at, pc.expires_at, pc.revoked_at,
+	COALESCE(d.firstName || ' ' || d.lastName, cl.name), p.firstName || ' ' || p.lastName`
+
+const consentJoins = `
+	JOIN patients p ON pc.patient_id = p.id
+	LEFT JOIN doctors d ON pc.doctor_id = d.id
+	LEFT JOIN clinics cl ON pc.clinic_id = cl.id`
+
+func (r *Repository) queryConsents(query string, args ...any) ([]models.PatientConsent, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var consents []models.PatientConsent
+	for rows.Next() {
+		var c models.PatientConsent
+		err := rows.Scan(&c.ID, &c.PatientID, &c.DoctorID, &c.ClinicID, &c.Category, &c.GrantedAt, &c.ExpiresAt, &c.RevokedAt,
+			&c.GranteeName, &c.PatientName)
+		if err != nil {
+			return nil, err
+		}
+		consents = append(consents, c)
+	}
+	return consents, rows.Err()
+}
+
+// GetConsentsByPatientID returns the patient's grants, newest first. Revoked
+// and expired ones are only included with includeInactive.
+func (r *Repository) GetConsentsByPatientID(patientID int, includeInactive bool) ([]models.PatientConsent, error) {
+	query := `
+		SELECT ` + consentColumns + `
+		FROM patient_consents pc` + consentJoins + `
+		WHERE pc.patient_id = $1
+			AND ($2 OR (pc.revoked_at IS NULL AND (pc.expires_at IS NULL OR pc.expires_at > now())))
+		ORDER BY pc.granted_at DESC, pc.id DESC
+	`
+	return r.queryConsents(query, patientID, includeInactive)
+}
+
+// GetConsentsForDoctor returns the live grants to the doctor or their clinic,
+// optionally only those of one patient when patientID isn't zero.
+func (r *Repository) GetConsentsForDoctor(doctorID, patientID int) ([]models.PatientConsent, error) {
+	query := `
+		SELECT ` + consentColumns + `
+		FROM patient_consents pc` + consentJoins + `
+		WHERE ($2 = 0 OR pc.patient_id = $2)
+			AND pc.revoked_at IS NULL AND (pc.expires_at IS NULL OR pc.expires_at > now())
+			AND (pc.doctor_id = $1 OR pc.clinic_id = (SELECT clinic_id FROM doctors WHERE id = $1))
+		ORDER BY p.lastName, p.firstName, pc.category
+	`
+	return r.queryConsents(query, doctorID, patientID)
+}
+
+// Clinic Related Methods
+func (r *Repository) GetClinics() ([]models.Clinic, error) {
+	rows, err := r.DB.Query(`SELECT id, name, COALESCE(address, '') FROM clinics ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clinics []models.Clinic
+	for rows.Next() {
+		var cl models.Clinic
+		if err := rows.Scan(&cl.ID, &cl.Name, &cl.Address); err != nil {
+			return nil, err
+		}
+		clinics = append(clinics, cl)
+	}
+	return clinics, rows.Err()
+}
+
+func (r *Repository) GetClinicByID(id int) (models.Clinic, error) {
+	var cl models.Clinic
+	err := r.DB.QueryRow(`SELECT id, name, COALESCE(address, '') FROM clinics WHERE id = $1`, id).Scan(&cl.ID, &cl.Name, &cl.Address)
+	return cl, err
+}
+
+func (r *Repository) CreateClinic(name, address string) (int, error) {
+	var newID int
+	err := r.DB.QueryRow(`INSERT INTO clinics (name, address) VALUES ($1, NULLIF($2, '')) RETURNING id`, name, address).Scan(&newID)
+	return newID, err
+}
+
+// SetDoctorClinic moves the doctor to the clinic, or out of any with nil. It
+// returns sql.ErrNoRows if the doctor doesn't exist.
+func (r *Repository) SetDoctorClinic(doctorID int, clinicID *int) error {
+	res, err := r.DB.Exec(`UPDATE doctors SET clinic_id = $2 WHERE id = $1`, doctorID, clinicID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
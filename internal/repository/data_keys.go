@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// File Data Key Related Methods
+
+// SaveDataKey stores the wrapped data key of an object, replacing any key the
+// object had.
+func (r *Repository) SaveDataKey(key models.DataKey) error {
+	query := `
+		INSERT INTO file_data_keys (object_key, master_key_id, wrapped_key)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (object_key) DO UPDATE
+		SET master_key_id = EXCLUDED.master_key_id, wrapped_key = EXCLUDED.wrapped_key,
+			created_at = now(), rewrapped_at = NULL
+	`
+	_, err := r.DB.Exec(query, key.ObjectKey, key.MasterKeyID, key.WrappedKey)
+	return err
+}
+
+const dataKeyColumns = `object_key, master_key_id, wrapped_key, created_at, rewrapped_at`
+
+func scanDataKey(row interface{ Scan(...any) error }) (models.DataKey, error) {
+	var key models.DataKey
+	err := row.Scan(&key.ObjectKey, &key.MasterKeyID, &key.WrappedKey, &key.CreatedAt, &key.RewrappedAt)
+	return key, err
+}
+
+// GetDataKey returns nil if the object has no data key, i.e. it isn't
+// encrypted.
+func (r *Repository) GetDataKey(objectKey string) (*models.DataKey, error) {
+	query := `SELECT ` + dataKeyColumns + ` FROM file_data_keys WHERE object_key = $1`
+	key, err := scanDataKey(r.DB.QueryRow(query, objectKey))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *Repository) DeleteDataKey(objectKey string) error {
+	_, err := r.DB.Exec(`DELETE FROM file_data_keys WHERE object_key = $1`, objectKey)
+	return err
+}
+
+// GetDataKeysToRewrap returns up to limit data keys wrapped with a master key
+// other than currentKeyID.
+func (r *Repository) GetDataKeysToRewrap(currentKeyID string, limit int) ([]models.DataKey, error) {
+	query := `
+		SELECT ` + dataKeyColumns + `
+		FROM file_data_keys
+		WHERE master_key_id <> $1
+		ORDER BY created_at
+		LIMIT $2
+	`
+	rows, err := r.DB.Query(query, currentKeyID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.DataKey
+	for rows.Next() {
+		key, err := scanDataKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RewrapDataKey replaces an object's wrapped data key, as long as it is still
+// wrapped with oldKeyID. It returns sql.ErrNoRows otherwise.
+func (r *Repository) RewrapDataKey(objectKey, oldKeyID, newKeyID string, wrapped []byte) error {
+	query := `
+		UPDATE file_data_keys SET master_key_id = $1, wrapped_key = $2, rewrapped_at = now()
+		WHERE object_key = $3 AND master_key_id = $4
+	`
+	res, err := r.DB.Exec(query, newKeyID, wrapped, objectKey, oldKeyID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
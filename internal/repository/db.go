@@ -2,8 +2,11 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
+
 	"github.com/RitwikGupta-0501/vital-watch/internal/models"
 )
 
@@ -12,6 +15,12 @@ type Repository struct {
 	DB *sql.DB
 }
 
+// IsUniqueViolation reports whether err was caused by a unique constraint.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 // Patient Related Methods
 func (r *Repository) CreatePatient(firstName, lastName, email, hashedPassword string) (int, error) {
 	query := `
@@ -56,12 +65,29 @@ func (r *Repository) GetPatientByID(id int) (models.Patient, error) {
 	return user, nil
 }
 
-func (r *Repository) GetPatientsByDoctorID(doctorID int) ([]models.Patient, error) {
+// GetPatientsByDoctorID returns the doctor's patients, those they have an
+// appointment with or who share their appointments with them, with their
+// latest NEWS2 score. With sortByRisk the highest-risk patients come first.
+func (r *Repository) GetPatientsByDoctorID(doctorID int, sortByRisk bool) ([]models.Patient, error) {
+	orderBy := "p.lastName, p.firstName"
+	if sortByRisk {
+		orderBy = `
+			CASE n.risk WHEN 'high' THEN 3 WHEN 'medium' THEN 2 WHEN 'low-medium' THEN 1 WHEN 'low' THEN 0 END DESC NULLS LAST,
+			n.total DESC NULLS LAST, p.lastName, p.firstName`
+	}
+
 	query := `
-		SELECT DISTINCT p.id, p.firstName, p.lastName, p.email, p.createdAt
+		SELECT p.id, p.firstName, p.lastName, p.email, p.createdAt, n.total, n.risk, n.computed_at
 		FROM patients p
-		JOIN appointments a ON p.id = a.patient_id
-		WHERE a.doctor_id = $1`
+		LEFT JOIN LATERAL (
+			SELECT total, risk, computed_at, expired_at FROM news2_scores
+			WHERE patient_id = p.id
+			ORDER BY computed_at DESC
+			LIMIT 1
+		) n ON n.expired_at IS NULL
+		WHERE p.id IN (SELECT patient_id FROM appointments WHERE doctor_id = $1)
+			OR ` + consentClause("$1", "p.id", ConsentAppointments) + `
+		ORDER BY ` + orderBy
 
 	rows, err := r.DB.Query(query, doctorID)
 	if err != nil {
@@ -72,10 +98,13 @@ func (r *Repository) GetPatientsByDoctorID(doctorID int) ([]models.Patient, erro
 	var patients []models.Patient
 	for rows.Next() {
 		var patient models.Patient
-		err := rows.Scan(&patient.ID, &patient.FirstName, &patient.LastName, &patient.Email, &patient.CreatedAt)
+		var risk sql.NullString
+		err := rows.Scan(&patient.ID, &patient.FirstName, &patient.LastName, &patient.Email, &patient.CreatedAt,
+			&patient.NEWS2Score, &risk, &patient.NEWS2ComputedAt)
 		if err != nil {
 			return nil, err
 		}
+		patient.NEWS2Risk = risk.String
 		patients = append(patients, patient)
 	}
 
@@ -113,10 +142,10 @@ func (r *Repository) GetDoctorByEmail(email string) (models.Doctor, error) {
 }
 
 func (r *Repository) GetDoctorByID(id int) (models.Doctor, error) {
-	query := `SELECT id, firstName, lastName, email, hashedPassword FROM doctors WHERE id=$1`
+	query := `SELECT id, firstName, lastName, email, hashedPassword, specialty FROM doctors WHERE id=$1`
 
 	var user models.Doctor
-	err := r.DB.QueryRow(query, id).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.HashedPassword)
+	err := r.DB.QueryRow(query, id).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.HashedPassword, &user.Specialty)
 	if err != nil {
 		return models.Doctor{}, err
 	}
@@ -241,9 +270,7 @@ func (r *Repository) GetAppointmentsForPatient(doctorID int, patientID int) ([]m
 			d.firstName, d.lastName, d.specialty
 		FROM appointments a
 		JOIN doctors d ON a.doctor_id = d.id
-		WHERE a.patient_id = $1 AND a.doctor_id IN (
-			SELECT doctor_id FROM appointments WHERE patient_id = $1 AND doctor_id = $2
-		)
+		WHERE a.patient_id = $1 AND (a.doctor_id = $2 OR ` + consentClause("$2", "$1", ConsentAppointments) + `)
 		ORDER BY a.start_time DESC
 	`
 	rows, err := r.DB.Query(query, patientID, doctorID)
@@ -278,30 +305,122 @@ func (r *Repository) UpdateAppointmentAsCompleted(appointmentID int) error {
 	return err
 }
 
+// GetAppointmentForDoctor returns sql.ErrNoRows unless the appointment is
+// with the doctor.
+func (r *Repository) GetAppointmentForDoctor(doctorID, appointmentID int) (models.Appointment, error) {
+	query := `
+		SELECT id, patient_id, doctor_id, start_time, end_time, status, COALESCE(appointment_type, '')
+		FROM appointments
+		WHERE id = $1 AND doctor_id = $2
+	`
+	var appt models.Appointment
+	err := r.DB.QueryRow(query, appointmentID, doctorID).Scan(&appt.ID, &appt.PatientID, &appt.DoctorID,
+		&appt.StartTime, &appt.EndTime, &appt.Status, &appt.Type)
+	return appt, err
+}
+
 // Prescription Related Methods
-func (r *Repository) CreatePrescription(patientID int, doctorID int, medication, notes, fileName string) (int, error) {
+// CreatePrescription stores a prescription along with its line items and
+// dosing schedule, if it has one. When PreviousID is set the new row is an
+// amendment and the previous version is marked superseded; that fails with
+// ErrPrescriptionNotActive if it was already amended or revoked.
+func (r *Repository) CreatePrescription(pres models.Prescription) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	newID, err := createPrescription(tx, pres)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return newID, nil
+}
+
+// createPrescription inserts a prescription with its items, safety override
+// and schedule, superseding the version it amends.
+func createPrescription(tx *sql.Tx, pres models.Prescription) (int, error) {
 	query := `
-		INSERT INTO prescriptions (patient_id, doctor_id, medication, notes, file_name)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO prescriptions (
+			patient_id, doctor_id, medication, notes, file_name, source, verification_code,
+			content_hash, signature, signing_key_id, expires_at,
+			version, previous_id, amendment_reason, scan_status, scanned_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), $11,
+			$12, $13, NULLIF($14, ''), COALESCE(NULLIF($15, ''), 'not_scanned'), CASE WHEN $15 = 'clean' THEN now() END)
 		RETURNING id
 	`
+	version := pres.Version
+	if version == 0 {
+		version = 1
+	}
 	var newID int
-	err := r.DB.QueryRow(query, patientID, doctorID, medication, notes, fileName).Scan(&newID)
+	err := tx.QueryRow(query,
+		pres.PatientID, pres.DoctorID, pres.Medication, pres.Notes, pres.FileName, pres.Source, pres.VerificationCode,
+		pres.ContentHash, pres.Signature, pres.SigningKeyID, pres.ExpiresAt,
+		version, pres.PreviousID, pres.AmendmentReason, pres.ScanStatus,
+	).Scan(&newID)
 	if err != nil {
 		return 0, err
 	}
+
+	if pres.PreviousID != nil {
+		if err := retirePrescription(tx, *pres.PreviousID, "superseded", ""); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := createPrescriptionItems(tx, newID, pres.Items); err != nil {
+		return 0, err
+	}
+
+	if pres.SafetyOverride != nil {
+		if err := createSafetyOverride(tx, newID, pres.DoctorID, *pres.SafetyOverride); err != nil {
+			return 0, err
+		}
+	}
+
+	if pres.Schedule != nil {
+		if err := createPrescriptionSchedule(tx, newID, *pres.Schedule); err != nil {
+			return 0, err
+		}
+	}
 	return newID, nil
 }
 
-func (r *Repository) GetPrescriptionsByPatientID(patientID int) ([]models.Prescription, error) {
-	query := `
-		SELECT p.id, p.patient_id, p.doctor_id, p.medication, p.notes, p.file_name, p.created_at, d.firstName, d.lastName
-		FROM prescriptions p
-		JOIN doctors d ON p.doctor_id = d.id
-		WHERE p.patient_id = $1
-		ORDER BY p.created_at DESC
-	`
-	rows, err := r.DB.Query(query, patientID)
+const prescriptionColumns = `
+	p.id, p.patient_id, p.doctor_id, p.medication, p.notes, p.file_name, p.created_at,
+	p.source, COALESCE(p.verification_code, ''), COALESCE(p.content_hash, ''), p.expires_at, p.revoked_at,
+	p.status, p.version, p.previous_id, (SELECT n.id FROM prescriptions n WHERE n.previous_id = p.id),
+	COALESCE(p.amendment_reason, ''), COALESCE(p.revocation_reason, ''),
+	p.scan_status, COALESCE(p.scan_threat, ''),
+	d.firstName, d.lastName`
+
+func scanPrescription(row interface{ Scan(...any) error }) (models.Prescription, error) {
+	var pres models.Prescription
+	var docFirstName, docLastName string
+	err := row.Scan(&pres.ID, &pres.PatientID, &pres.DoctorID, &pres.Medication, &pres.Notes, &pres.FileName, &pres.CreatedAt,
+		&pres.Source, &pres.VerificationCode, &pres.ContentHash, &pres.ExpiresAt, &pres.RevokedAt,
+		&pres.Status, &pres.Version, &pres.PreviousID, &pres.SupersededByID,
+		&pres.AmendmentReason, &pres.RevocationReason,
+		&pres.ScanStatus, &pres.ScanThreat,
+		&docFirstName, &docLastName)
+	if err != nil {
+		return models.Prescription{}, err
+	}
+	pres.DoctorName = docFirstName + " " + docLastName
+	return pres, nil
+}
+
+// queryPrescriptions runs a query selecting prescriptionColumns and attaches
+// each prescription's line items.
+func (r *Repository) queryPrescriptions(query string, args ...any) ([]models.Prescription, error) {
+	rows, err := r.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -309,70 +428,99 @@ func (r *Repository) GetPrescriptionsByPatientID(patientID int) ([]models.Prescr
 
 	var prescriptions []models.Prescription
 	for rows.Next() {
-		var pres models.Prescription
-		var docFirstName, docLastName string
-		err := rows.Scan(&pres.ID, &pres.PatientID, &pres.DoctorID, &pres.Medication, &pres.Notes, &pres.FileName, &pres.CreatedAt, &docFirstName, &docLastName)
+		pres, err := scanPrescription(rows)
 		if err != nil {
 			return nil, err
 		}
-		pres.DoctorName = docFirstName + " " + docLastName
 		prescriptions = append(prescriptions, pres)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachPrescriptionItems(prescriptions); err != nil {
+		return nil, err
+	}
 	return prescriptions, nil
 }
 
+// GetPrescriptionsByPatientID lists a patient's prescriptions, optionally
+// filtered by a search term matched against medications and drug names.
+func (r *Repository) GetPrescriptionsByPatientID(patientID int, search string) ([]models.Prescription, error) {
+	query := `
+		SELECT ` + prescriptionColumns + `
+		FROM prescriptions p
+		JOIN doctors d ON p.doctor_id = d.id
+		WHERE p.patient_id = $1 AND ` + prescriptionSearchClause(2) + `
+		ORDER BY p.created_at DESC
+	`
+	return r.queryPrescriptions(query, patientID, escapeLike(search))
+}
+
+func (r *Repository) GetPrescriptionByID(id int) (models.Prescription, error) {
+	query := `
+		SELECT ` + prescriptionColumns + `
+		FROM prescriptions p
+		JOIN doctors d ON p.doctor_id = d.id
+		WHERE p.id = $1
+	`
+	pres, err := scanPrescription(r.DB.QueryRow(query, id))
+	if err != nil {
+		return models.Prescription{}, err
+	}
+
+	prescriptions := []models.Prescription{pres}
+	if err := r.attachPrescriptionItems(prescriptions); err != nil {
+		return models.Prescription{}, err
+	}
+	return prescriptions[0], nil
+}
+
+// prescriptionDownloadColumns are what the download handlers need to label
+// the file, including the file name of any newer version.
+const prescriptionDownloadColumns = `
+	p.id, COALESCE(p.verification_code, ''), COALESCE(p.content_hash, ''), p.status, p.version,
+	COALESCE((SELECT n.file_name FROM prescriptions n WHERE n.previous_id = p.id), ''), p.scan_status, p.patient_id`
+
 func (r *Repository) GetPrescriptionByFilename(patientID int, filename string) (models.Prescription, error) {
-	query := `SELECT id FROM prescriptions WHERE patient_id = $1 AND file_name = $2`
+	query := `
+		SELECT ` + prescriptionDownloadColumns + `
+		FROM prescriptions p WHERE p.patient_id = $1 AND p.file_name = $2
+	`
 
 	var pres models.Prescription
-	err := r.DB.QueryRow(query, patientID, filename).Scan(&pres.ID)
+	err := r.DB.QueryRow(query, patientID, filename).Scan(
+		&pres.ID, &pres.VerificationCode, &pres.ContentHash, &pres.Status, &pres.Version, &pres.SupersededByFile,
+		&pres.ScanStatus, &pres.PatientID,
+	)
 
 	// This will correctly return sql.ErrNoRows if not found/not owned
 	return pres, err
 }
 
-func (r *Repository) GetPrescriptionsForPatient(doctorID int, patientID int) ([]models.Prescription, error) {
+func (r *Repository) GetPrescriptionsForPatient(doctorID int, patientID int, search string) ([]models.Prescription, error) {
 	query := `
-		SELECT 
-			p.id, p.patient_id, p.doctor_id, p.medication, p.notes, p.file_name, p.created_at, 
-			d.firstName, d.lastName
+		SELECT ` + prescriptionColumns + `
 		FROM prescriptions p
 		JOIN doctors d ON p.doctor_id = d.id
-		WHERE p.patient_id = $1 AND p.patient_id IN (
-			SELECT patient_id FROM appointments WHERE patient_id = $1 AND doctor_id = $2
-		)
+		WHERE p.patient_id = $1 AND (p.doctor_id = $2 OR ` + consentClause("$2", "$1", ConsentPrescriptions) + `)
+			AND ` + prescriptionSearchClause(3) + `
 		ORDER BY p.created_at DESC
 	`
-	rows, err := r.DB.Query(query, patientID, doctorID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var prescriptions []models.Prescription
-	for rows.Next() {
-		var pres models.Prescription
-		var docFirstName, docLastName string
-		err := rows.Scan(&pres.ID, &pres.PatientID, &pres.DoctorID, &pres.Medication, &pres.Notes, &pres.FileName, &pres.CreatedAt, &docFirstName, &docLastName)
-		if err != nil {
-			return nil, err
-		}
-		pres.DoctorName = docFirstName + " " + docLastName
-		prescriptions = append(prescriptions, pres)
-	}
-	return prescriptions, nil
+	return r.queryPrescriptions(query, patientID, doctorID, escapeLike(search))
 }
 
 func (r *Repository) GetPrescriptionByFilenameForDoctor(doctorID int, filename string) (models.Prescription, error) {
 	query := `
-		SELECT p.id 
+		SELECT ` + prescriptionDownloadColumns + `
 		FROM prescriptions p
-		JOIN appointments a ON p.patient_id = a.patient_id
-		WHERE p.file_name = $1 AND a.doctor_id = $2
-		LIMIT 1
+		WHERE p.file_name = $1
+			AND (p.doctor_id = $2 OR ` + consentClause("$2", "p.patient_id", ConsentPrescriptions) + `)
 	`
 	var pres models.Prescription
-	err := r.DB.QueryRow(query, filename, doctorID).Scan(&pres.ID)
+	err := r.DB.QueryRow(query, filename, doctorID).Scan(
+		&pres.ID, &pres.VerificationCode, &pres.ContentHash, &pres.Status, &pres.Version, &pres.SupersededByFile,
+		&pres.ScanStatus, &pres.PatientID,
+	)
 
 	return pres, err
 }
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Device Related Methods
+func (r *Repository) CreatePairingCode(patientID int, codeHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO device_pairing_codes (patient_id, code_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`
+	_, err := r.DB.Exec(query, patientID, codeHash, expiresAt)
+	return err
+}
+
+// PairDevice consumes a pairing code and registers the device against the
+// patient that generated it. It returns sql.ErrNoRows if the code is unknown,
+// already used or expired.
+func (r *Repository) PairDevice(codeHash, serial, model, deviceType, credentialHash string) (models.Device, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return models.Device{}, err
+	}
+	defer tx.Rollback()
+
+	var patientID int
+	err = tx.QueryRow(`
+		UPDATE device_pairing_codes
+		SET used_at = now()
+		WHERE code_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING patient_id
+	`, codeHash).Scan(&patientID)
+	if err != nil {
+		return models.Device{}, err
+	}
+
+	device := models.Device{
+		PatientID: patientID,
+		Serial:    serial,
+		Model:     model,
+		Type:      deviceType,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO devices (patient_id, serial, model, device_type, credential_hash)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, paired_at
+	`, patientID, serial, model, deviceType, credentialHash).Scan(&device.ID, &device.PairedAt)
+	if err != nil {
+		return models.Device{}, err
+	}
+
+	return device, tx.Commit()
+}
+
+// RecordPairingFailure records a wrong pairing code from ip and forgets
+// failures older than keepFor.
+func (r *Repository) RecordPairingFailure(ip string, keepFor time.Duration) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO device_pairing_failures (ip_address) VALUES ($1)`, ip); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM device_pairing_failures WHERE attempted_at < $1`, time.Now().Add(-keepFor))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountPairingFailures counts the wrong pairing codes ip has sent since.
+func (r *Repository) CountPairingFailures(ip string, since time.Time) (int, error) {
+	var n int
+	err := r.DB.QueryRow(`
+		SELECT count(*) FROM device_pairing_failures WHERE ip_address = $1 AND attempted_at > $2
+	`, ip, since).Scan(&n)
+	return n, err
+}
+
+// GetActiveDeviceByCredential looks up a non-revoked device by the hash of its credential.
+func (r *Repository) GetActiveDeviceByCredential(credentialHash string) (models.Device, error) {
+	query := `
+		SELECT id, patient_id, serial, model, device_type, paired_at, last_seen_at
+		FROM devices
+		WHERE credential_hash = $1 AND revoked_at IS NULL
+	`
+	var d models.Device
+	err := r.DB.QueryRow(query, credentialHash).Scan(&d.ID, &d.PatientID, &d.Serial, &d.Model, &d.Type, &d.PairedAt, &d.LastSeenAt)
+	return d, err
+}
+
+func (r *Repository) TouchDevice(deviceID int) error {
+	_, err := r.DB.Exec(`UPDATE devices SET last_seen_at = now() WHERE id = $1`, deviceID)
+	return err
+}
+
+func (r *Repository) GetDevicesByPatientID(patientID int) ([]models.Device, error) {
+	query := `
+		SELECT id, patient_id, serial, model, device_type, paired_at, last_seen_at, revoked_at
+		FROM devices
+		WHERE patient_id = $1
+		ORDER BY paired_at DESC
+	`
+	rows, err := r.DB.Query(query, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []models.Device
+	for rows.Next() {
+		var d models.Device
+		err := rows.Scan(&d.ID, &d.PatientID, &d.Serial, &d.Model, &d.Type, &d.PairedAt, &d.LastSeenAt, &d.RevokedAt)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+// RevokeDevice revokes one of the patient's devices. It returns sql.ErrNoRows
+// if the device does not belong to the patient or is already revoked.
+func (r *Repository) RevokeDevice(patientID, deviceID int) error {
+	query := `UPDATE devices SET revoked_at = now() WHERE id = $1 AND patient_id = $2 AND revoked_at IS NULL`
+	res, err := r.DB.Exec(query, deviceID, patientID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DoctorHasPatient reports whether the doctor has had an appointment with the patient.
+func (r *Repository) DoctorHasPatient(doctorID, patientID int) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM appointments WHERE doctor_id = $1 AND patient_id = $2)`
+	var ok bool
+	err := r.DB.QueryRow(query, doctorID, patientID).Scan(&ok)
+	return ok, err
+}
+
+// Vital Reading Related Methods
+
+// CreateVitalReadings stores readings, skipping any of a metric the patient
+// already has a reading of at the same instant, such as a device resending.
+func (r *Repository) CreateVitalReadings(readings []models.VitalReading) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO vital_readings (patient_id, device_id, metric, value, unit, recorded_at, source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (patient_id, metric, recorded_at) DO NOTHING
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, v := range readings {
+		if _, err := stmt.Exec(v.PatientID, v.DeviceID, v.Metric, v.Value, v.Unit, v.RecordedAt, v.Source); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetVitalReadings returns a patient's readings between from and to, newest
+// first. An empty metric returns readings for every metric.
+func (r *Repository) GetVitalReadings(patientID int, metric string, from, to time.Time) ([]models.VitalReading, error) {
+	query := `
+		SELECT id, patient_id, device_id, metric, value, unit, recorded_at, source
+		FROM vital_readings
+		WHERE patient_id = $1 AND ($2 = '' OR metric = $2) AND recorded_at BETWEEN $3 AND $4
+		ORDER BY recorded_at DESC
+	`
+	rows, err := r.DB.Query(query, patientID, metric, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var readings []models.VitalReading
+	for rows.Next() {
+		var v models.VitalReading
+		err := rows.Scan(&v.ID, &v.PatientID, &v.DeviceID, &v.Metric, &v.Value, &v.Unit, &v.RecordedAt, &v.Source)
+		if err != nil {
+			return nil, err
+		}
+		readings = append(readings, v)
+	}
+	return readings, nil
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Patient Document Related Methods
+
+// DocumentFilter narrows a document listing. Empty fields match everything;
+// From and To are inclusive YYYY-MM-DD dates, compared with the document's
+// date or, without one, the day it was uploaded.
+type DocumentFilter struct {
+	Category string
+	Tag      string
+	Search   string
+	From     string
+	To       string
+}
+
+func (r *Repository) CreateDocument(doc models.PatientDocument) (int, error) {
+	tags, err := json.Marshal(doc.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO patient_documents (
+			patient_id, category, title, description, tags, document_date,
+			file_name, original_name, content_type, size, scan_status, scanned_at
+		)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6::date, $7, NULLIF($8, ''), $9, $10,
+			$11, CASE WHEN $11 = 'clean' THEN now() END)
+		RETURNING id
+	`
+	var newID int
+	err = r.DB.QueryRow(query,
+		doc.PatientID, doc.Category, doc.Title, doc.Description, tags, doc.DocumentDate,
+		doc.FileName, doc.OriginalName, doc.ContentType, doc.Size, doc.ScanStatus,
+	).Scan(&newID)
+	return newID, err
+}
+
+const documentColumns = `
+	pd.id, pd.patient_id, pd.category, pd.title, COALESCE(pd.description, ''), pd.tags,
+	to_char(pd.document_date, 'YYYY-MM-DD'), pd.file_name, COALESCE(pd.original_name, ''),
+	pd.content_type, pd.size, pd.scan_status, COALESCE(pd.scan_threat, ''),
+	COALESCE((SELECT json_agg(s.doctor_id ORDER BY s.doctor_id) FROM document_shares s WHERE s.document_id = pd.id), '[]'),
+	pd.created_at`
+
+func scanDocument(row interface{ Scan(...any) error }) (models.PatientDocument, error) {
+	var doc models.PatientDocument
+	var tags, sharedWith []byte
+	err := row.Scan(&doc.ID, &doc.PatientID, &doc.Category, &doc.Title, &doc.Description, &tags,
+		&doc.DocumentDate, &doc.FileName, &doc.OriginalName,
+		&doc.ContentType, &doc.Size, &doc.ScanStatus, &doc.ScanThreat,
+		&sharedWith, &doc.CreatedAt)
+	if err != nil {
+		return models.PatientDocument{}, err
+	}
+	if err := json.Unmarshal(tags, &doc.Tags); err != nil {
+		return models.PatientDocument{}, err
+	}
+	if err := json.Unmarshal(sharedWith, &doc.SharedWith); err != nil {
+		return models.PatientDocument{}, err
+	}
+	return doc, nil
+}
+
+// documentFilterClause matches f, using the query arguments from arg on.
+func documentFilterClause(f DocumentFilter, arg int) (string, []any) {
+	n := func(i int) string { return "$" + strconv.Itoa(arg+i) }
+	clause := `
+		(` + n(0) + ` = '' OR pd.category = ` + n(0) + `)
+		AND (` + n(1) + ` = '' OR pd.tags ? ` + n(1) + `)
+		AND (` + n(2) + ` = '' OR pd.title ILIKE '%' || ` + n(2) + ` || '%' OR pd.description ILIKE '%' || ` + n(2) + ` || '%')
+		AND (` + n(3) + ` = '' OR COALESCE(pd.document_date, pd.created_at::date) >= NULLIF(` + n(3) + `, '')::date)
+		AND (` + n(4) + ` = '' OR COALESCE(pd.document_date, pd.created_at::date) <= NULLIF(` + n(4) + `, '')::date)`
+	return clause, []any{f.Category, f.Tag, escapeLike(f.Search), f.From, f.To}
+}
+
+func (r *Repository) queryDocuments(query string, args ...any) ([]models.PatientDocument, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []models.PatientDocument
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// GetDocumentsByPatientID returns the patient's documents matching f, newest
+// first.
+func (r *Repository) GetDocumentsByPatientID(patientID int, f DocumentFilter) ([]models.PatientDocument, error) {
+	clause, args := documentFilterClause(f, 2)
+	query := `
+		SELECT ` + documentColumns + `
+		FROM patient_documents pd
+		WHERE pd.patient_id = $1 AND ` + clause + `
+		ORDER BY COALESCE(pd.document_date, pd.created_at::date) DESC, pd.id DESC
+	`
+	return r.queryDocuments(query, append([]any{patientID}, args...)...)
+}
+
+// documentSharedClause is true when the document pd has been shared with the
+// doctor in $1.
+const documentSharedClause = `EXISTS (SELECT 1 FROM document_shares ds WHERE ds.document_id = pd.id AND ds.doctor_id = $1)`
+
+// GetDocumentsSharedWithDoctor returns the patient's documents matching f
+// that have been shared with the doctor, or all of them when the patient lets
+// the doctor see their documents.
+func (r *Repository) GetDocumentsSharedWithDoctor(doctorID, patientID int, f DocumentFilter) ([]models.PatientDocument, error) {
+	clause, args := documentFilterClause(f, 3)
+	query := `
+		SELECT ` + documentColumns + `
+		FROM patient_documents pd
+		WHERE pd.patient_id = $2 AND ` + clause + `
+			AND (` + documentSharedClause + ` OR ` + consentClause("$1", "$2", ConsentDocuments) + `)
+		ORDER BY COALESCE(pd.document_date, pd.created_at::date) DESC, pd.id DESC
+	`
+	return r.queryDocuments(query, append([]any{doctorID, patientID}, args...)...)
+}
+
+// GetDocumentForPatient returns sql.ErrNoRows unless the patient owns the
+// document.
+func (r *Repository) GetDocumentForPatient(patientID, documentID int) (models.PatientDocument, error) {
+	query := `SELECT ` + documentColumns + ` FROM patient_documents pd WHERE pd.id = $1 AND pd.patient_id = $2`
+	return scanDocument(r.DB.QueryRow(query, documentID, patientID))
+}
+
+// GetDocumentForDoctor returns sql.ErrNoRows unless the document has been
+// shared with the doctor or the patient lets them see all their documents.
+func (r *Repository) GetDocumentForDoctor(doctorID, documentID int) (models.PatientDocument, error) {
+	query := `
+		SELECT ` + documentColumns + `
+		FROM patient_documents pd
+		WHERE pd.id = $2
+			AND (` + documentSharedClause + ` OR ` + consentClause("$1", "pd.patient_id", ConsentDocuments) + `)
+	`
+	return scanDocument(r.DB.QueryRow(query, doctorID, documentID))
+}
+
+// DeleteDocument deletes the patient's document and its shares, returning the
+// file name so the file can be removed from storage.
+func (r *Repository) DeleteDocument(patientID, documentID int) (string, error) {
+	var fileName string
+	err := r.DB.QueryRow(`
+		DELETE FROM patient_documents WHERE id = $1 AND patient_id = $2
+		RETURNING file_name
+	`, documentID, patientID).Scan(&fileName)
+	return fileName, err
+}
+
+// ShareDocument lets the doctor see the document, notifying them the first
+// time it is shared.
+func (r *Repository) ShareDocument(documentID, doctorID int, notify models.Notification) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO document_shares (document_id, doctor_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, documentID, doctorID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		if err := createNotification(tx, notify); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UnshareDocument returns sql.ErrNoRows if the document wasn't shared with
+// the doctor.
+func (r *Repository) UnshareDocument(documentID, doctorID int) error {
+	res, err := r.DB.Exec(`DELETE FROM document_shares WHERE document_id = $1 AND doctor_id = $2`, documentID, doctorID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
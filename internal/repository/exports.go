@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// ErrExportInProgress is returned when the patient already has an export
+// waiting to be built.
+var ErrExportInProgress = errors.New("an export is already in progress")
+
+// Data Export Related Methods
+func (r *Repository) CreateDataExport(patientID int) (int, error) {
+	var newID int
+	err := r.DB.QueryRow(`
+		INSERT INTO data_exports (patient_id)
+		SELECT $1
+		WHERE NOT EXISTS (
+			SELECT 1 FROM data_exports WHERE patient_id = $1 AND status IN ('pending', 'running')
+		)
+		RETURNING id
+	`, patientID).Scan(&newID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrExportInProgress
+	}
+	return newID, err
+}
+
+const dataExportColumns = `
+	id, patient_id, status, COALESCE(file_name, ''), size, COALESCE(error, ''),
+	created_at, started_at, completed_at, expires_at`
+
+func scanDataExport(row interface{ Scan(...any) error }) (models.DataExport, error) {
+	var e models.DataExport
+	err := row.Scan(&e.ID, &e.PatientID, &e.Status, &e.FileName, &e.Size, &e.Error,
+		&e.CreatedAt, &e.StartedAt, &e.CompletedAt, &e.ExpiresAt)
+	return e, err
+}
+
+func (r *Repository) GetDataExportsByPatientID(patientID int) ([]models.DataExport, error) {
+	rows, err := r.DB.Query(`
+		SELECT `+dataExportColumns+` FROM data_exports
+		WHERE patient_id = $1
+		ORDER BY created_at DESC
+	`, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := []models.DataExport{}
+	for rows.Next() {
+		e, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+	return exports, rows.Err()
+}
+
+// ClaimPendingDataExport marks the oldest pending export as running and
+// returns it, or sql.ErrNoRows when there is none.
+func (r *Repository) ClaimPendingDataExport() (models.DataExport, error) {
+	return scanDataExport(r.DB.QueryRow(`
+		UPDATE data_exports SET status = 'running', started_at = now()
+		WHERE id = (
+			SELECT id FROM data_exports WHERE status = 'pending'
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + dataExportColumns))
+}
+
+// CompleteDataExport records the built archive and notifies the patient. It
+// returns sql.ErrNoRows if the export was cancelled while it was built.
+func (r *Repository) CompleteDataExport(exportID int, fileName string, size int64, expiresAt time.Time, notify models.Notification) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE data_exports
+		SET status = 'ready', file_name = $2, size = $3, completed_at = now(), expires_at = $4
+		WHERE id = $1 AND status = 'running'
+	`, exportID, fileName, size, expiresAt)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	if err := createNotification(tx, notify); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FailDataExport records why an export couldn't be built and notifies the
+// patient.
+func (r *Repository) FailDataExport(exportID int, message string, notify models.Notification) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE data_exports SET status = 'failed', error = $2, completed_at = now()
+		WHERE id = $1
+	`, exportID, message)
+	if err != nil {
+		return err
+	}
+	if err := createNotification(tx, notify); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RequeueInterruptedDataExports puts exports left running by a previous
+// process back in the queue.
+func (r *Repository) RequeueInterruptedDataExports() error {
+	_, err := r.DB.Exec(`UPDATE data_exports SET status = 'pending', started_at = NULL WHERE status = 'running'`)
+	return err
+}
+
+// SetDataExportLink replaces the download link of the patient's ready
+// export. It returns sql.ErrNoRows unless the patient has such an export
+// that hasn't expired.
+func (r *Repository) SetDataExportLink(patientID, exportID int, tokenHash string, expiresAt time.Time) error {
+	res, err := r.DB.Exec(`
+		UPDATE data_exports SET link_token_hash = $3, link_expires_at = $4
+		WHERE id = $1 AND patient_id = $2 AND status = 'ready' AND expires_at > now()
+	`, exportID, patientID, tokenHash, expiresAt)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetDataExportByLink returns the ready export whose unexpired download link
+// has the token, or sql.ErrNoRows.
+func (r *Repository) GetDataExportByLink(tokenHash string) (models.DataExport, error) {
+	return scanDataExport(r.DB.QueryRow(`
+		SELECT `+dataExportColumns+` FROM data_exports
+		WHERE link_token_hash = $1 AND link_expires_at > now() AND status = 'ready' AND expires_at > now()
+	`, tokenHash))
+}
+
+// GetExpiredDataExports returns ready exports whose archives are due for
+// deletion.
+func (r *Repository) GetExpiredDataExports(now time.Time) ([]models.DataExport, error) {
+	rows, err := r.DB.Query(`
+		SELECT `+dataExportColumns+` FROM data_exports
+		WHERE status = 'ready' AND expires_at <= $1
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []models.DataExport
+	for rows.Next() {
+		e, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+	return exports, rows.Err()
+}
+
+// ExpireDataExport records that the export's archive has been deleted.
+func (r *Repository) ExpireDataExport(exportID int) error {
+	_, err := r.DB.Exec(`
+		UPDATE data_exports SET status = 'expired', link_token_hash = NULL, link_expires_at = NULL
+		WHERE id = $1
+	`, exportID)
+	return err
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Vital Import Related Methods
+func (r *Repository) CreateVitalImportJob(patientID int, format, fileName string, bytesTotal int64) (int, error) {
+	query := `
+		INSERT INTO vital_import_jobs (patient_id, format, file_name, bytes_total)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+	var newID int
+	err := r.DB.QueryRow(query, patientID, format, fileName, bytesTotal).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+	return newID, nil
+}
+
+func (r *Repository) UpdateVitalImportProgress(job models.VitalImportJob) error {
+	query := `
+		UPDATE vital_import_jobs
+		SET status = 'running', bytes_processed = $2, imported = $3, duplicates = $4, failed = $5
+		WHERE id = $1
+	`
+	_, err := r.DB.Exec(query, job.ID, job.BytesProcessed, job.Imported, job.Duplicates, job.Failed)
+	return err
+}
+
+// FinishVitalImportJob records the final counters, error report and status of a job.
+func (r *Repository) FinishVitalImportJob(job models.VitalImportJob) error {
+	errs, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE vital_import_jobs
+		SET status = $2, bytes_processed = $3, imported = $4, duplicates = $5, failed = $6,
+			errors = $7, error_message = NULLIF($8, ''), finished_at = now()
+		WHERE id = $1
+	`
+	_, err = r.DB.Exec(query, job.ID, job.Status, job.BytesProcessed, job.Imported, job.Duplicates, job.Failed, string(errs), job.ErrorMessage)
+	return err
+}
+
+// FailInterruptedVitalImportJobs marks jobs left pending or running by a
+// previous process as failed, since their in-memory workers are gone.
+func (r *Repository) FailInterruptedVitalImportJobs() error {
+	query := `
+		UPDATE vital_import_jobs
+		SET status = 'failed', error_message = 'Import was interrupted by a server restart', finished_at = now()
+		WHERE status IN ('pending', 'running')
+	`
+	_, err := r.DB.Exec(query)
+	return err
+}
+
+const vitalImportJobColumns = `
+	id, patient_id, format, COALESCE(file_name, ''), status, bytes_total, bytes_processed,
+	imported, duplicates, failed, errors, COALESCE(error_message, ''), created_at, finished_at`
+
+func scanVitalImportJob(row interface{ Scan(...any) error }) (models.VitalImportJob, error) {
+	var job models.VitalImportJob
+	var errs []byte
+	err := row.Scan(
+		&job.ID, &job.PatientID, &job.Format, &job.FileName, &job.Status, &job.BytesTotal, &job.BytesProcessed,
+		&job.Imported, &job.Duplicates, &job.Failed, &errs, &job.ErrorMessage, &job.CreatedAt, &job.FinishedAt,
+	)
+	if err != nil {
+		return models.VitalImportJob{}, err
+	}
+	if err := json.Unmarshal(errs, &job.Errors); err != nil {
+		return models.VitalImportJob{}, err
+	}
+	if job.BytesTotal > 0 {
+		job.Progress = float64(job.BytesProcessed) / float64(job.BytesTotal)
+	}
+	return job, nil
+}
+
+func (r *Repository) GetVitalImportJob(patientID, jobID int) (models.VitalImportJob, error) {
+	query := `SELECT ` + vitalImportJobColumns + ` FROM vital_import_jobs WHERE id = $1 AND patient_id = $2`
+	return scanVitalImportJob(r.DB.QueryRow(query, jobID, patientID))
+}
+
+func (r *Repository) GetVitalImportJobsByPatientID(patientID int) ([]models.VitalImportJob, error) {
+	query := `SELECT ` + vitalImportJobColumns + ` FROM vital_import_jobs WHERE patient_id = $1 ORDER BY created_at DESC`
+	rows, err := r.DB.Query(query, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.VitalImportJob
+	for rows.Next() {
+		job, err := scanVitalImportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// ImportVitalReadings inserts a batch of readings, skipping any that duplicate
+// an existing reading of the same metric at the same instant. It returns the
+// number of rows actually inserted.
+func (r *Repository) ImportVitalReadings(readings []models.VitalReading) (int, error) {
+	if len(readings) == 0 {
+		return 0, nil
+	}
+
+	var values strings.Builder
+	args := make([]any, 0, len(readings)*6)
+	for i, v := range readings {
+		if i > 0 {
+			values.WriteString(", ")
+		}
+		n := i * 6
+		fmt.Fprintf(&values, "($%d::int, $%d::text, $%d::double precision, $%d::text, $%d::timestamptz, $%d::text)",
+			n+1, n+2, n+3, n+4, n+5, n+6)
+		args = append(args, v.PatientID, v.Metric, v.Value, v.Unit, v.RecordedAt, v.Source)
+	}
+
+	query := `
+		INSERT INTO vital_readings (patient_id, metric, value, unit, recorded_at, source)
+		SELECT v.patient_id, v.metric, v.value, v.unit, v.recorded_at, v.source
+		FROM (VALUES ` + values.String() + `) AS v (patient_id, metric, value, unit, recorded_at, source)
+		ON CONFLICT (patient_id, metric, recorded_at) DO NOTHING
+	`
+	res, err := r.DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
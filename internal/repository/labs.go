@@ -0,0 +1,495 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// ErrLabOrderCancelled is returned when results are recorded against an order
+// that has been cancelled.
+var ErrLabOrderCancelled = errors.New("the lab order has been cancelled")
+
+// Lab Order Related Methods
+
+// CreateLabOrder orders the panels, whose analytes' LOINC codes are
+// expectedAnalytes; the order is resulted once each has a final result.
+func (r *Repository) CreateLabOrder(order models.LabOrder, expectedAnalytes []string) (int, error) {
+	panels, err := json.Marshal(order.Panels)
+	if err != nil {
+		return 0, err
+	}
+	expected, err := json.Marshal(expectedAnalytes)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO lab_orders (patient_id, doctor_id, appointment_id, clinic_id, panels, expected_analytes, priority, notes)
+		VALUES ($1, $2, $3, (SELECT clinic_id FROM doctors WHERE id = $2), $4, $5, $6, NULLIF($7, ''))
+		RETURNING id
+	`
+	var newID int
+	err = r.DB.QueryRow(query, order.PatientID, order.DoctorID, order.AppointmentID,
+		panels, expected, order.Priority, order.Notes).Scan(&newID)
+	return newID, err
+}
+
+const labOrderColumns = `
+	o.id, o.patient_id, o.doctor_id, d.firstName || ' ' || d.lastName, o.appointment_id, o.clinic_id,
+	o.panels, o.priority, COALESCE(o.notes, ''), o.status,
+	COALESCE((
+		SELECT json_agg(json_build_object(
+			'id', lr.id, 'order_id', lr.order_id, 'patient_id', lr.patient_id,
+			'analyte_code', lr.analyte_code, 'analyte_name', lr.analyte_name,
+			'value', lr.value, 'value_text', COALESCE(lr.value_text, ''), 'unit', COALESCE(lr.unit, ''),
+			'ref_low', lr.ref_low, 'ref_high', lr.ref_high, 'ref_text', COALESCE(lr.ref_text, ''),
+			'flag', COALESCE(lr.flag, ''), 'status', lr.status, 'source', lr.source,
+			'observed_at', lr.observed_at, 'updated_at', lr.updated_at
+		) ORDER BY lr.id)
+		FROM lab_results lr WHERE lr.order_id = o.id
+	), '[]'),
+	COALESCE((
+		SELECT json_agg(json_build_object(
+			'id', rep.id, 'order_id', rep.order_id, 'patient_id', rep.patient_id,
+			'original_name', COALESCE(rep.original_name, ''), 'content_type', rep.content_type,
+			'size', rep.size, 'scan_status', rep.scan_status, 'created_at', rep.created_at
+		) ORDER BY rep.id)
+		FROM lab_reports rep WHERE rep.order_id = o.id
+	), '[]'),
+	o.created_at, o.resulted_at, o.cancelled_at`
+
+func scanLabOrder(row interface{ Scan(...any) error }) (models.LabOrder, error) {
+	var order models.LabOrder
+	var panels, results, reports []byte
+	err := row.Scan(&order.ID, &order.PatientID, &order.DoctorID, &order.DoctorName, &order.AppointmentID, &order.ClinicID,
+		&panels, &order.Priority, &order.Notes, &order.Status, &results, &reports,
+		&order.CreatedAt, &order.ResultedAt, &order.CancelledAt)
+	if err != nil {
+		return models.LabOrder{}, err
+	}
+	if err := json.Unmarshal(panels, &order.Panels); err != nil {
+		return models.LabOrder{}, err
+	}
+	if err := json.Unmarshal(results, &order.Results); err != nil {
+		return models.LabOrder{}, err
+	}
+	if err := json.Unmarshal(reports, &order.Reports); err != nil {
+		return models.LabOrder{}, err
+	}
+	return order, nil
+}
+
+func (r *Repository) queryLabOrders(query string, args ...any) ([]models.LabOrder, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []models.LabOrder{}
+	for rows.Next() {
+		order, err := scanLabOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+// GetLabOrderByID returns sql.ErrNoRows if there is no such order.
+func (r *Repository) GetLabOrderByID(orderID int) (models.LabOrder, error) {
+	query := `
+		SELECT ` + labOrderColumns + `
+		FROM lab_orders o
+		JOIN doctors d ON d.id = o.doctor_id
+		WHERE o.id = $1
+	`
+	return scanLabOrder(r.DB.QueryRow(query, orderID))
+}
+
+// GetLabOrdersByPatientID returns the patient's lab orders with their
+// results, newest first.
+func (r *Repository) GetLabOrdersByPatientID(patientID int) ([]models.LabOrder, error) {
+	query := `
+		SELECT ` + labOrderColumns + `
+		FROM lab_orders o
+		JOIN doctors d ON d.id = o.doctor_id
+		WHERE o.patient_id = $1
+		ORDER BY o.created_at DESC, o.id DESC
+	`
+	return r.queryLabOrders(query, patientID)
+}
+
+// labOrderVisibleClause is true when the doctor in $1 placed the order o or
+// the patient lets them see their lab results.
+var labOrderVisibleClause = `(o.doctor_id = $1 OR ` + consentClause("$1", "o.patient_id", ConsentLabs) + `)`
+
+// GetLabOrdersForPatient returns the patient's lab orders the doctor may see:
+// their own, and other doctors' if the patient lets them see their labs.
+func (r *Repository) GetLabOrdersForPatient(doctorID, patientID int) ([]models.LabOrder, error) {
+	query := `
+		SELECT ` + labOrderColumns + `
+		FROM lab_orders o
+		JOIN doctors d ON d.id = o.doctor_id
+		WHERE o.patient_id = $2 AND ` + labOrderVisibleClause + `
+		ORDER BY o.created_at DESC, o.id DESC
+	`
+	return r.queryLabOrders(query, doctorID, patientID)
+}
+
+// CancelLabOrder cancels the doctor's order. It returns sql.ErrNoRows unless
+// the order is theirs and no results have come in yet.
+func (r *Repository) CancelLabOrder(doctorID, orderID int) error {
+	query := `
+		UPDATE lab_orders SET status = 'cancelled', cancelled_at = now()
+		WHERE id = $1 AND doctor_id = $2 AND status = 'ordered'
+	`
+	res, err := r.DB.Exec(query, orderID, doctorID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// LabResultsRecorded is what recording a batch of results did to an order.
+type LabResultsRecorded struct {
+	Recorded       int    // results stored; preliminary ones arriving after a final one are not
+	PreviousStatus string // the order's status before
+	Status         string // and after
+}
+
+// LabOrderResults is a batch of results for one order, with the
+// notifications to send once they are recorded.
+type LabOrderResults struct {
+	OrderID int
+	Results []models.LabResult
+	Notify  func(LabResultsRecorded) []models.Notification // may be nil
+}
+
+// RecordLabResults stores results against the order, replacing any earlier
+// result for the same analyte: a preliminary result never replaces a final
+// one, and a final result that changes one becomes a correction. The order is
+// then 'resulted' if each analyte it expects has a final result, and
+// otherwise 'partial'. notify, if given, is called with the outcome and its
+// notifications are sent in the same transaction. It returns sql.ErrNoRows
+// if there is no such order and ErrLabOrderCancelled if it was cancelled.
+func (r *Repository) RecordLabResults(orderID int, results []models.LabResult, notify func(LabResultsRecorded) []models.Notification) (LabResultsRecorded, error) {
+	out, err := r.RecordLabResultsForOrders([]LabOrderResults{{OrderID: orderID, Results: results, Notify: notify}})
+	if err != nil {
+		return LabResultsRecorded{}, err
+	}
+	return out[0], nil
+}
+
+// RecordLabResultsForOrders records each batch as RecordLabResults does, all
+// in one transaction: if any order can't take its results, none are stored.
+// Errors name the order they are about.
+func (r *Repository) RecordLabResultsForOrders(batches []LabOrderResults) ([]LabResultsRecorded, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	outs := make([]LabResultsRecorded, len(batches))
+	for i, b := range batches {
+		out, err := recordLabResults(tx, b.OrderID, b.Results, b.Notify)
+		if err != nil {
+			return nil, fmt.Errorf("lab order %d: %w", b.OrderID, err)
+		}
+		outs[i] = out
+	}
+	return outs, tx.Commit()
+}
+
+func recordLabResults(tx *sql.Tx, orderID int, results []models.LabResult, notify func(LabResultsRecorded) []models.Notification) (LabResultsRecorded, error) {
+	var out LabResultsRecorded
+	var patientID int
+	err := tx.QueryRow(`SELECT patient_id, status FROM lab_orders WHERE id = $1 FOR UPDATE`, orderID).
+		Scan(&patientID, &out.PreviousStatus)
+	if err != nil {
+		return LabResultsRecorded{}, err
+	}
+	if out.PreviousStatus == "cancelled" {
+		return LabResultsRecorded{}, ErrLabOrderCancelled
+	}
+
+	query := `
+		INSERT INTO lab_results (
+			order_id, patient_id, analyte_code, analyte_name, value, value_text, unit,
+			ref_low, ref_high, ref_text, flag, status, source, observed_at
+		)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9, NULLIF($10, ''), NULLIF($11, ''), $12, $13, $14)
+		ON CONFLICT (order_id, analyte_code) DO UPDATE
+		SET analyte_name = EXCLUDED.analyte_name, value = EXCLUDED.value, value_text = EXCLUDED.value_text,
+			unit = EXCLUDED.unit, ref_low = EXCLUDED.ref_low, ref_high = EXCLUDED.ref_high,
+			ref_text = EXCLUDED.ref_text, flag = EXCLUDED.flag, source = EXCLUDED.source,
+			observed_at = EXCLUDED.observed_at, updated_at = now(),
+			status = CASE
+				WHEN lab_results.status <> 'preliminary' AND EXCLUDED.status = 'final'
+					AND (lab_results.value, lab_results.value_text) IS DISTINCT FROM (EXCLUDED.value, EXCLUDED.value_text)
+				THEN 'corrected'
+				ELSE EXCLUDED.status
+			END
+		WHERE NOT (lab_results.status <> 'preliminary' AND EXCLUDED.status = 'preliminary')
+	`
+	for _, res := range results {
+		row, err := tx.Exec(query, orderID, patientID, res.AnalyteCode, res.AnalyteName, res.Value, res.ValueText,
+			res.Unit, res.RefLow, res.RefHigh, res.RefText, res.Flag, res.Status, res.Source, res.ObservedAt)
+		if err != nil {
+			return LabResultsRecorded{}, err
+		}
+		n, err := row.RowsAffected()
+		if err != nil {
+			return LabResultsRecorded{}, err
+		}
+		out.Recorded += int(n)
+	}
+
+	err = tx.QueryRow(`
+		UPDATE lab_orders o
+		SET status = CASE WHEN NOT EXISTS (
+				SELECT 1 FROM jsonb_array_elements_text(o.expected_analytes) e(code)
+				WHERE NOT EXISTS (
+					SELECT 1 FROM lab_results lr
+					WHERE lr.order_id = o.id AND lr.analyte_code = e.code AND lr.status <> 'preliminary'
+				)
+			) THEN 'resulted' ELSE 'partial' END
+		WHERE o.id = $1
+		RETURNING o.status
+	`, orderID).Scan(&out.Status)
+	if err != nil {
+		return LabResultsRecorded{}, err
+	}
+	if out.Status == "resulted" && out.PreviousStatus != "resulted" {
+		if _, err := tx.Exec(`UPDATE lab_orders SET resulted_at = now() WHERE id = $1`, orderID); err != nil {
+			return LabResultsRecorded{}, err
+		}
+	}
+
+	if notify != nil {
+		for _, n := range notify(out) {
+			if err := createNotification(tx, n); err != nil {
+				return LabResultsRecorded{}, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// Lab Report Related Methods
+
+func (r *Repository) CreateLabReport(report models.LabReport) (int, error) {
+	query := `
+		INSERT INTO lab_reports (order_id, patient_id, file_name, original_name, content_type, size, scan_status, scanned_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, CASE WHEN $7 = 'clean' THEN now() END)
+		RETURNING id
+	`
+	var newID int
+	err := r.DB.QueryRow(query, report.OrderID, report.PatientID, report.FileName, report.OriginalName,
+		report.ContentType, report.Size, report.ScanStatus).Scan(&newID)
+	return newID, err
+}
+
+const labReportColumns = `
+	id, order_id, patient_id, file_name, COALESCE(original_name, ''), content_type, size, scan_status, created_at`
+
+func scanLabReport(row interface{ Scan(...any) error }) (models.LabReport, error) {
+	var rep models.LabReport
+	err := row.Scan(&rep.ID, &rep.OrderID, &rep.PatientID, &rep.FileName, &rep.OriginalName,
+		&rep.ContentType, &rep.Size, &rep.ScanStatus, &rep.CreatedAt)
+	return rep, err
+}
+
+// GetLabReport returns sql.ErrNoRows unless the report is on the order.
+func (r *Repository) GetLabReport(orderID, reportID int) (models.LabReport, error) {
+	query := `SELECT ` + labReportColumns + ` FROM lab_reports WHERE id = $1 AND order_id = $2`
+	return scanLabReport(r.DB.QueryRow(query, reportID, orderID))
+}
+
+// GetLabReportsByPatientID returns the reports on the patient's lab orders,
+// oldest first.
+func (r *Repository) GetLabReportsByPatientID(patientID int) ([]models.LabReport, error) {
+	query := `SELECT ` + labReportColumns + ` FROM lab_reports WHERE patient_id = $1 ORDER BY created_at, id`
+	return r.queryLabReports(query, patientID)
+}
+
+// GetLabReportsPendingScan returns up to limit quarantined lab reports
+// waiting for a malware scan, oldest first.
+func (r *Repository) GetLabReportsPendingScan(limit int) ([]models.LabReport, error) {
+	query := `
+		SELECT ` + labReportColumns + `
+		FROM lab_reports
+		WHERE scan_status = 'pending'
+		ORDER BY created_at
+		LIMIT $1
+	`
+	return r.queryLabReports(query, limit)
+}
+
+func (r *Repository) queryLabReports(query string, args ...any) ([]models.LabReport, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []models.LabReport
+	for rows.Next() {
+		rep, err := scanLabReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, rep)
+	}
+	return reports, rows.Err()
+}
+
+// RecordLabReportScan is RecordPrescriptionScan for lab reports.
+func (r *Repository) RecordLabReportScan(reportID int, status, threat string, notify *models.Notification) error {
+	return r.recordScan("lab_reports", reportID, status, threat, notify)
+}
+
+// Lab Trend Related Methods
+
+// GetLabTrends returns the patient's numeric results, one trend per analyte
+// and unit, or only the analyte's when analyteCode is set. Results of
+// cancelled orders are left out.
+func (r *Repository) GetLabTrends(patientID int, analyteCode string) ([]models.LabTrend, error) {
+	query := `
+		SELECT lr.analyte_code, lr.analyte_name, COALESCE(lr.unit, ''), lr.ref_low, lr.ref_high,
+			lr.order_id, lr.value, COALESCE(lr.flag, ''), lr.status, lr.observed_at
+		FROM lab_results lr
+		JOIN lab_orders o ON o.id = lr.order_id
+		WHERE lr.patient_id = $1 AND lr.value IS NOT NULL AND o.status <> 'cancelled'
+			AND ($2::text = '' OR lr.analyte_code = $2)
+		ORDER BY lr.analyte_code, COALESCE(lr.unit, ''), lr.observed_at, lr.id
+	`
+	return r.queryLabTrends(query, patientID, analyteCode)
+}
+
+// GetLabTrendsForDoctor is GetLabTrends limited to the orders the doctor may
+// see.
+func (r *Repository) GetLabTrendsForDoctor(doctorID, patientID int, analyteCode string) ([]models.LabTrend, error) {
+	query := `
+		SELECT lr.analyte_code, lr.analyte_name, COALESCE(lr.unit, ''), lr.ref_low, lr.ref_high,
+			lr.order_id, lr.value, COALESCE(lr.flag, ''), lr.status, lr.observed_at
+		FROM lab_results lr
+		JOIN lab_orders o ON o.id = lr.order_id
+		WHERE lr.patient_id = $2 AND lr.value IS NOT NULL AND o.status <> 'cancelled'
+			AND ($3::text = '' OR lr.analyte_code = $3) AND ` + labOrderVisibleClause + `
+		ORDER BY lr.analyte_code, COALESCE(lr.unit, ''), lr.observed_at, lr.id
+	`
+	return r.queryLabTrends(query, doctorID, patientID, analyteCode)
+}
+
+// queryLabTrends groups results ordered by analyte, unit and time into
+// trends. Each trend is named and ranged after its latest result.
+func (r *Repository) queryLabTrends(query string, args ...any) ([]models.LabTrend, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trends := []models.LabTrend{}
+	for rows.Next() {
+		var t models.LabTrend
+		var p models.LabTrendPoint
+		err := rows.Scan(&t.AnalyteCode, &t.AnalyteName, &t.Unit, &t.RefLow, &t.RefHigh,
+			&p.OrderID, &p.Value, &p.Flag, &p.Status, &p.ObservedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if last := len(trends) - 1; last >= 0 && trends[last].AnalyteCode == t.AnalyteCode && trends[last].Unit == t.Unit {
+			trends[last].AnalyteName, trends[last].RefLow, trends[last].RefHigh = t.AnalyteName, t.RefLow, t.RefHigh
+			trends[last].Points = append(trends[last].Points, p)
+			continue
+		}
+		t.Points = []models.LabTrendPoint{p}
+		trends = append(trends, t)
+	}
+	return trends, rows.Err()
+}
+
+// Integration Key Related Methods
+
+func (r *Repository) CreateIntegrationKey(name string, clinicID int, keyHash string, adminID int) (models.IntegrationKey, error) {
+	key := models.IntegrationKey{Name: name, ClinicID: clinicID, CreatedBy: adminID}
+	err := r.DB.QueryRow(`
+		INSERT INTO integration_api_keys (name, clinic_id, key_hash, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, name, clinicID, keyHash, adminID).Scan(&key.ID, &key.CreatedAt)
+	return key, err
+}
+
+func (r *Repository) GetIntegrationKeys() ([]models.IntegrationKey, error) {
+	query := `
+		SELECT id, name, COALESCE(clinic_id, 0), created_by, created_at, last_used_at, revoked_at
+		FROM integration_api_keys
+		ORDER BY revoked_at IS NOT NULL, created_at DESC
+	`
+	rows, err := r.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.IntegrationKey{}
+	for rows.Next() {
+		var k models.IntegrationKey
+		if err := rows.Scan(&k.ID, &k.Name, &k.ClinicID, &k.CreatedBy, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// GetActiveIntegrationKeyByHash looks up a non-revoked key by its hash.
+func (r *Repository) GetActiveIntegrationKeyByHash(keyHash string) (models.IntegrationKey, error) {
+	query := `
+		SELECT id, name, clinic_id, created_by, created_at, last_used_at
+		FROM integration_api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL
+	`
+	var k models.IntegrationKey
+	err := r.DB.QueryRow(query, keyHash).Scan(&k.ID, &k.Name, &k.ClinicID, &k.CreatedBy, &k.CreatedAt, &k.LastUsedAt)
+	return k, err
+}
+
+func (r *Repository) TouchIntegrationKey(keyID int) error {
+	_, err := r.DB.Exec(`UPDATE integration_api_keys SET last_used_at = now() WHERE id = $1`, keyID)
+	return err
+}
+
+// RevokeIntegrationKey returns sql.ErrNoRows if the key doesn't exist or is
+// already revoked.
+func (r *Repository) RevokeIntegrationKey(keyID int) error {
+	res, err := r.DB.Exec(`UPDATE integration_api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, keyID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// NEWS2 Related Methods
+
+// GetPatientIDsNeedingNEWS2 returns patients with readings of the given
+// metrics recorded since `since` that arrived after their last NEWS2 score,
+// and those whose last score, unless expired, was computed from a reading
+// recorded before `since`, which no longer counts.
+func (r *Repository) GetPatientIDsNeedingNEWS2(metrics []string, since time.Time) ([]int, error) {
+	query := `
+		SELECT DISTINCT vr.patient_id
+		FROM vital_readings vr
+		WHERE vr.metric = ANY($1) AND vr.recorded_at >= $2
+			AND vr.created_at > COALESCE(
+				(SELECT max(n.computed_at) FROM news2_scores n WHERE n.patient_id = vr.patient_id),
+				'-infinity'
+			)
+		UNION
+		SELECT n.patient_id
+		FROM (
+			SELECT DISTINCT ON (patient_id) patient_id, inputs, expired_at
+			FROM news2_scores
+			ORDER BY patient_id, computed_at DESC
+		) n
+		WHERE n.expired_at IS NULL AND EXISTS (
+			SELECT 1 FROM jsonb_each(n.inputs) i WHERE (i.value->>'recorded_at')::timestamptz < $2
+		)
+	`
+	rows, err := r.DB.Query(query, metrics, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// GetLatestReadings returns the most recent reading of each metric recorded
+// since `since`, keyed by metric.
+func (r *Repository) GetLatestReadings(patientID int, metrics []string, since time.Time) (map[string]models.VitalReading, error) {
+	query := `
+		SELECT DISTINCT ON (metric) id, patient_id, device_id, metric, value, unit, recorded_at, source
+		FROM vital_readings
+		WHERE patient_id = $1 AND metric = ANY($2) AND recorded_at >= $3
+		ORDER BY metric, recorded_at DESC
+	`
+	rows, err := r.DB.Query(query, patientID, metrics, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	latest := make(map[string]models.VitalReading)
+	for rows.Next() {
+		var v models.VitalReading
+		err := rows.Scan(&v.ID, &v.PatientID, &v.DeviceID, &v.Metric, &v.Value, &v.Unit, &v.RecordedAt, &v.Source)
+		if err != nil {
+			return nil, err
+		}
+		latest[v.Metric] = v
+	}
+	return latest, nil
+}
+
+func (r *Repository) GetPatientSpO2Scale(patientID int) (int, error) {
+	var scale int
+	err := r.DB.QueryRow(`SELECT news2_spo2_scale FROM patients WHERE id = $1`, patientID).Scan(&scale)
+	return scale, err
+}
+
+func (r *Repository) UpdatePatientSpO2Scale(patientID, scale int) error {
+	_, err := r.DB.Exec(`UPDATE patients SET news2_spo2_scale = $2 WHERE id = $1`, patientID, scale)
+	return err
+}
+
+func (r *Repository) CreateNEWS2Score(score models.NEWS2Score) (int, error) {
+	inputs, err := json.Marshal(score.Inputs)
+	if err != nil {
+		return 0, err
+	}
+
+	// Parameters that weren't scored are stored as NULL rather than 0
+	sub := func(metric string) *int {
+		if s, ok := score.SubScores[metric]; ok {
+			return &s
+		}
+		return nil
+	}
+
+	query := `
+		INSERT INTO news2_scores (
+			patient_id, total, risk, respiratory_rate_score, spo2_score, supplemental_oxygen_score,
+			systolic_bp_score, heart_rate_score, consciousness_score, temperature_score,
+			spo2_scale, complete, inputs
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id
+	`
+	var newID int
+	err = r.DB.QueryRow(query,
+		score.PatientID, score.Total, score.Risk, sub("respiratory_rate"), sub("spo2"), sub("supplemental_oxygen"),
+		sub("systolic_bp"), sub("heart_rate"), sub("consciousness"), sub("temperature"),
+		score.SpO2Scale, score.Complete, string(inputs),
+	).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+	return newID, nil
+}
+
+const news2ScoreColumns = `
+	id, patient_id, total, risk, respiratory_rate_score, spo2_score, supplemental_oxygen_score,
+	systolic_bp_score, heart_rate_score, consciousness_score, temperature_score,
+	spo2_scale, complete, inputs, computed_at, expired_at`
+
+func scanNEWS2Score(row interface{ Scan(...any) error }) (models.NEWS2Score, error) {
+	var s models.NEWS2Score
+	var resp, spo2, oxygen, systolic, pulse, consciousness, temp sql.NullInt64
+	var inputs []byte
+	err := row.Scan(
+		&s.ID, &s.PatientID, &s.Total, &s.Risk, &resp, &spo2, &oxygen,
+		&systolic, &pulse, &consciousness, &temp,
+		&s.SpO2Scale, &s.Complete, &inputs, &s.ComputedAt, &s.ExpiredAt,
+	)
+	if err != nil {
+		return models.NEWS2Score{}, err
+	}
+
+	s.SubScores = make(map[string]int)
+	for metric, v := range map[string]sql.NullInt64{
+		"respiratory_rate": resp, "spo2": spo2, "supplemental_oxygen": oxygen,
+		"systolic_bp": systolic, "heart_rate": pulse, "consciousness": consciousness, "temperature": temp,
+	} {
+		if v.Valid {
+			s.SubScores[metric] = int(v.Int64)
+		}
+	}
+
+	if err := json.Unmarshal(inputs, &s.Inputs); err != nil {
+		return models.NEWS2Score{}, err
+	}
+	return s, nil
+}
+
+// ExpireNEWS2Score marks a score as no longer current.
+func (r *Repository) ExpireNEWS2Score(scoreID int) error {
+	_, err := r.DB.Exec(`UPDATE news2_scores SET expired_at = now() WHERE id = $1 AND expired_at IS NULL`, scoreID)
+	return err
+}
+
+// GetLatestNEWS2Score returns sql.ErrNoRows if the patient has never been scored.
+func (r *Repository) GetLatestNEWS2Score(patientID int) (models.NEWS2Score, error) {
+	query := `SELECT ` + news2ScoreColumns + ` FROM news2_scores WHERE patient_id = $1 ORDER BY computed_at DESC LIMIT 1`
+	return scanNEWS2Score(r.DB.QueryRow(query, patientID))
+}
+
+func (r *Repository) GetNEWS2ScoresByPatientID(patientID int, from, to time.Time) ([]models.NEWS2Score, error) {
+	query := `
+		SELECT ` + news2ScoreColumns + `
+		FROM news2_scores
+		WHERE patient_id = $1 AND computed_at BETWEEN $2 AND $3
+		ORDER BY computed_at DESC
+	`
+	rows, err := r.DB.Query(query, patientID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []models.NEWS2Score
+	for rows.Next() {
+		s, err := scanNEWS2Score(rows)
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, s)
+	}
+	return scores, nil
+}
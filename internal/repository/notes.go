@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// ErrEncounterNoteSigned is returned when a draft-only change is made to a
+// note that has already been signed.
+var ErrEncounterNoteSigned = errors.New("the encounter note has been signed")
+
+// Encounter Note Related Methods
+
+// SaveEncounterNoteDraft creates the draft note on the appointment, or
+// replaces its content if there is one already. It returns
+// ErrEncounterNoteSigned if the note has been signed.
+func (r *Repository) SaveEncounterNoteDraft(note models.EncounterNote) (int, error) {
+	diagnoses, err := json.Marshal(note.Diagnoses)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO encounter_notes (appointment_id, patient_id, doctor_id, subjective, objective, assessment, plan, diagnoses)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8)
+		ON CONFLICT (appointment_id) DO UPDATE
+		SET subjective = EXCLUDED.subjective, objective = EXCLUDED.objective, assessment = EXCLUDED.assessment,
+			plan = EXCLUDED.plan, diagnoses = EXCLUDED.diagnoses, updated_at = now()
+		WHERE encounter_notes.status = 'draft'
+		RETURNING id
+	`
+	var id int
+	err = r.DB.QueryRow(query, note.AppointmentID, note.PatientID, note.DoctorID,
+		note.Subjective, note.Objective, note.Assessment, note.Plan, diagnoses).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrEncounterNoteSigned
+	}
+	return id, err
+}
+
+const encounterNoteColumns = `
+	n.id, n.appointment_id, n.patient_id, n.doctor_id, d.firstName || ' ' || d.lastName,
+	COALESCE(n.subjective, ''), COALESCE(n.objective, ''), COALESCE(n.assessment, ''), COALESCE(n.plan, ''),
+	n.diagnoses, n.status, n.signed_at, n.amended_at,
+	COALESCE((
+		SELECT json_agg(json_build_object(
+			'id', a.id, 'doctor_id', a.doctor_id, 'reason', a.reason, 'amended_at', a.amended_at,
+			'previous', json_build_object(
+				'subjective', COALESCE(a.subjective, ''), 'objective', COALESCE(a.objective, ''),
+				'assessment', COALESCE(a.assessment, ''), 'plan', COALESCE(a.plan, ''), 'diagnoses', a.diagnoses
+			)
+		) ORDER BY a.id)
+		FROM encounter_note_amendments a WHERE a.note_id = n.id
+	), '[]'),
+	n.created_at, n.updated_at`
+
+func scanEncounterNote(row interface{ Scan(...any) error }) (models.EncounterNote, error) {
+	var note models.EncounterNote
+	var diagnoses, amendments []byte
+	err := row.Scan(&note.ID, &note.AppointmentID, &note.PatientID, &note.DoctorID, &note.DoctorName,
+		&note.Subjective, &note.Objective, &note.Assessment, &note.Plan,
+		&diagnoses, &note.Status, &note.SignedAt, &note.AmendedAt, &amendments,
+		&note.CreatedAt, &note.UpdatedAt)
+	if err != nil {
+		return models.EncounterNote{}, err
+	}
+	if err := json.Unmarshal(diagnoses, &note.Diagnoses); err != nil {
+		return models.EncounterNote{}, err
+	}
+	if err := json.Unmarshal(amendments, &note.Amendments); err != nil {
+		return models.EncounterNote{}, err
+	}
+	return note, nil
+}
+
+func (r *Repository) queryEncounterNotes(query string, args ...any) ([]models.EncounterNote, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []models.EncounterNote{}
+	for rows.Next() {
+		note, err := scanEncounterNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// GetEncounterNoteByAppointmentID returns sql.ErrNoRows if the appointment
+// has no note.
+func (r *Repository) GetEncounterNoteByAppointmentID(appointmentID int) (models.EncounterNote, error) {
+	query := `
+		SELECT ` + encounterNoteColumns + `
+		FROM encounter_notes n
+		JOIN doctors d ON d.id = n.doctor_id
+		WHERE n.appointment_id = $1
+	`
+	return scanEncounterNote(r.DB.QueryRow(query, appointmentID))
+}
+
+// GetSignedEncounterNotesByPatientID returns the patient's signed notes,
+// newest first.
+func (r *Repository) GetSignedEncounterNotesByPatientID(patientID int) ([]models.EncounterNote, error) {
+	query := `
+		SELECT ` + encounterNoteColumns + `
+		FROM encounter_notes n
+		JOIN doctors d ON d.id = n.doctor_id
+		WHERE n.patient_id = $1 AND n.status = 'signed'
+		ORDER BY n.signed_at DESC, n.id DESC
+	`
+	return r.queryEncounterNotes(query, patientID)
+}
+
+// GetEncounterNotesForPatient returns the patient's notes the doctor may
+// read: their own, drafts included, and other doctors' signed notes if the
+// patient lets them see those.
+func (r *Repository) GetEncounterNotesForPatient(doctorID, patientID int) ([]models.EncounterNote, error) {
+	query := `
+		SELECT ` + encounterNoteColumns + `
+		FROM encounter_notes n
+		JOIN doctors d ON d.id = n.doctor_id
+		WHERE n.patient_id = $2
+			AND (n.doctor_id = $1 OR (n.status = 'signed' AND ` + consentClause("$1", "$2", ConsentNotes) + `))
+		ORDER BY n.status = 'signed', COALESCE(n.signed_at, n.updated_at) DESC, n.id DESC
+	`
+	return r.queryEncounterNotes(query, doctorID, patientID)
+}
+
+// AppointmentHasSignedNote reports whether the appointment's note has been
+// signed.
+func (r *Repository) AppointmentHasSignedNote(appointmentID int) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM encounter_notes WHERE appointment_id = $1 AND status = 'signed')`
+	var signed bool
+	err := r.DB.QueryRow(query, appointmentID).Scan(&signed)
+	return signed, err
+}
+
+// SignEncounterNote signs the draft note, locking it. It returns
+// ErrEncounterNoteSigned if it was already signed.
+func (r *Repository) SignEncounterNote(noteID int) (time.Time, error) {
+	query := `
+		UPDATE encounter_notes SET status = 'signed', signed_at = now(), updated_at = now()
+		WHERE id = $1 AND status = 'draft'
+		RETURNING signed_at
+	`
+	var signedAt time.Time
+	err := r.DB.QueryRow(query, noteID).Scan(&signedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, ErrEncounterNoteSigned
+	}
+	return signedAt, err
+}
+
+// AmendEncounterNote replaces the content of the signed note, keeping what
+// it replaced as an amendment along with the reason. It returns
+// sql.ErrNoRows if the note isn't signed.
+func (r *Repository) AmendEncounterNote(noteID, doctorID int, content models.EncounterNoteContent, reason string) error {
+	diagnoses, err := json.Marshal(content.Diagnoses)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var signed bool
+	err = tx.QueryRow(`SELECT status = 'signed' FROM encounter_notes WHERE id = $1 FOR UPDATE`, noteID).Scan(&signed)
+	if err != nil {
+		return err
+	}
+	if !signed {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO encounter_note_amendments (note_id, doctor_id, reason, subjective, objective, assessment, plan, diagnoses)
+		SELECT id, $2, $3, subjective, objective, assessment, plan, diagnoses
+		FROM encounter_notes WHERE id = $1
+	`, noteID, doctorID, reason)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE encounter_notes
+		SET subjective = NULLIF($2, ''), objective = NULLIF($3, ''), assessment = NULLIF($4, ''), plan = NULLIF($5, ''),
+			diagnoses = $6, amended_at = now(), updated_at = now()
+		WHERE id = $1
+	`, noteID, content.Subjective, content.Objective, content.Assessment, content.Plan, diagnoses)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
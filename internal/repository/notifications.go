@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Notification Related Methods
+
+// execer is satisfied by both *sql.DB and *sql.Tx, so notifications can be
+// written in the same transaction as the change they announce.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func createNotification(db execer, n models.Notification) error {
+	data, err := json.Marshal(n.Data)
+	if err != nil {
+		return err
+	}
+	if n.Data == nil {
+		data = []byte("{}")
+	}
+
+	query := `
+		INSERT INTO notifications (recipient_role, recipient_id, kind, title, body, data)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+	`
+	_, err = db.Exec(query, n.RecipientRole, n.RecipientID, n.Kind, n.Title, n.Body, string(data))
+	return err
+}
+
+func (r *Repository) CreateNotification(n models.Notification) error {
+	return createNotification(r.DB, n)
+}
+
+func (r *Repository) GetNotifications(role string, recipientID int, unreadOnly bool) ([]models.Notification, error) {
+	return r.queryNotifications(role, recipientID, unreadOnly, 200)
+}
+
+// GetAllNotifications returns every notification the recipient has had,
+// newest first.
+func (r *Repository) GetAllNotifications(role string, recipientID int) ([]models.Notification, error) {
+	return r.queryNotifications(role, recipientID, false, 0)
+}
+
+// queryNotifications returns at most limit notifications, or all of them
+// when limit is zero.
+func (r *Repository) queryNotifications(role string, recipientID int, unreadOnly bool, limit int) ([]models.Notification, error) {
+	query := `
+		SELECT id, recipient_role, recipient_id, kind, title, COALESCE(body, ''), data, created_at, read_at
+		FROM notifications
+		WHERE recipient_role = $1 AND recipient_id = $2 AND ($3 = false OR read_at IS NULL)
+		ORDER BY created_at DESC
+		LIMIT NULLIF($4, 0)
+	`
+	rows, err := r.DB.Query(query, role, recipientID, unreadOnly, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		var data []byte
+		err := rows.Scan(&n.ID, &n.RecipientRole, &n.RecipientID, &n.Kind, &n.Title, &n.Body, &data, &n.CreatedAt, &n.ReadAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &n.Data); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// MarkNotificationRead returns sql.ErrNoRows if the notification doesn't
+// belong to the recipient.
+func (r *Repository) MarkNotificationRead(role string, recipientID, notificationID int) error {
+	query := `
+		UPDATE notifications SET read_at = COALESCE(read_at, now())
+		WHERE id = $1 AND recipient_role = $2 AND recipient_id = $3
+	`
+	res, err := r.DB.Exec(query, notificationID, role, recipientID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *Repository) MarkAllNotificationsRead(role string, recipientID int) error {
+	query := `UPDATE notifications SET read_at = now() WHERE recipient_role = $1 AND recipient_id = $2 AND read_at IS NULL`
+	_, err := r.DB.Exec(query, role, recipientID)
+	return err
+}
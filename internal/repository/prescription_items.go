@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Prescription Item Related Methods
+func createPrescriptionItems(tx *sql.Tx, prescriptionID int, items []models.PrescriptionItem) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO prescription_items (
+			prescription_id, position, drug_name, strength, form, route, dose, frequency, duration, quantity, refills
+		)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, $8, NULLIF($9, ''), $10, $11)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, it := range items {
+		_, err := stmt.Exec(prescriptionID, i, it.DrugName, it.Strength, it.Form, it.Route, it.Dose, it.Frequency, it.Duration, it.Quantity, it.Refills)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachPrescriptionItems loads the line items of every prescription in one query.
+func (r *Repository) attachPrescriptionItems(prescriptions []models.Prescription) error {
+	if len(prescriptions) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(prescriptions))
+	byID := make(map[int]*models.Prescription, len(prescriptions))
+	for i := range prescriptions {
+		ids[i] = prescriptions[i].ID
+		prescriptions[i].Items = []models.PrescriptionItem{}
+		byID[prescriptions[i].ID] = &prescriptions[i]
+	}
+
+	query := `
+		SELECT id, prescription_id, drug_name, COALESCE(strength, ''), COALESCE(form, ''), COALESCE(route, ''),
+			dose, frequency, COALESCE(duration, ''), quantity, refills
+		FROM prescription_items
+		WHERE prescription_id = ANY($1)
+		ORDER BY prescription_id, position
+	`
+	rows, err := r.DB.Query(query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var it models.PrescriptionItem
+		var prescriptionID int
+		err := rows.Scan(&it.ID, &prescriptionID, &it.DrugName, &it.Strength, &it.Form, &it.Route,
+			&it.Dose, &it.Frequency, &it.Duration, &it.Quantity, &it.Refills)
+		if err != nil {
+			return err
+		}
+		byID[prescriptionID].Items = append(byID[prescriptionID].Items, it)
+	}
+	return rows.Err()
+}
+
+// prescriptionSearchClause matches prescriptions whose medication, notes or
+// any line item drug name contains the search term bound at argument arg. An
+// empty term matches everything.
+func prescriptionSearchClause(arg int) string {
+	clause := `($N = '' OR p.medication ILIKE '%' || $N || '%' OR p.notes ILIKE '%' || $N || '%'
+		OR EXISTS (
+			SELECT 1 FROM prescription_items i
+			WHERE i.prescription_id = p.id AND i.drug_name ILIKE '%' || $N || '%'
+		))`
+	return strings.ReplaceAll(clause, "$N", "$"+strconv.Itoa(arg))
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Prescription Upload Related Methods
+
+// ErrUploadConfirmed is returned when confirming an upload that has already
+// been recorded as a prescription.
+var ErrUploadConfirmed = errors.New("upload has already been confirmed")
+
+func (r *Repository) CreatePrescriptionUpload(upload models.PrescriptionUpload) (int, error) {
+	query := `
+		INSERT INTO prescription_uploads (doctor_id, patient_id, file_name, content_type, size, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	var newID int
+	err := r.DB.QueryRow(query,
+		upload.DoctorID, upload.PatientID, upload.FileName, upload.ContentType, upload.Size, upload.ExpiresAt,
+	).Scan(&newID)
+	return newID, err
+}
+
+const prescriptionUploadColumns = `
+	id, doctor_id, patient_id, file_name, content_type, size, created_at, expires_at, prescription_id`
+
+func scanPrescriptionUpload(row interface{ Scan(...any) error }) (models.PrescriptionUpload, error) {
+	var u models.PrescriptionUpload
+	err := row.Scan(&u.ID, &u.DoctorID, &u.PatientID, &u.FileName, &u.ContentType, &u.Size,
+		&u.CreatedAt, &u.ExpiresAt, &u.PrescriptionID)
+	return u, err
+}
+
+// GetPrescriptionUploadForDoctor returns sql.ErrNoRows unless the upload was
+// started by the doctor.
+func (r *Repository) GetPrescriptionUploadForDoctor(doctorID, uploadID int) (models.PrescriptionUpload, error) {
+	query := `SELECT ` + prescriptionUploadColumns + ` FROM prescription_uploads WHERE id = $1 AND doctor_id = $2`
+	return scanPrescriptionUpload(r.DB.QueryRow(query, uploadID, doctorID))
+}
+
+// CreateUploadedPrescription records the prescription for an upload and
+// marks the upload confirmed in one transaction.
+func (r *Repository) CreateUploadedPrescription(uploadID int, pres models.Prescription) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	newID, err := createPrescription(tx, pres)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`
+		UPDATE prescription_uploads SET prescription_id = $1
+		WHERE id = $2 AND prescription_id IS NULL
+	`, newID, uploadID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrUploadConfirmed
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return newID, nil
+}
+
+// GetExpiredPrescriptionUploads returns unconfirmed uploads that expired
+// before the given time.
+func (r *Repository) GetExpiredPrescriptionUploads(before time.Time) ([]models.PrescriptionUpload, error) {
+	query := `
+		SELECT ` + prescriptionUploadColumns + `
+		FROM prescription_uploads
+		WHERE prescription_id IS NULL AND expires_at < $1
+		ORDER BY expires_at
+	`
+	rows, err := r.DB.Query(query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []models.PrescriptionUpload
+	for rows.Next() {
+		u, err := scanPrescriptionUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, u)
+	}
+	return uploads, rows.Err()
+}
+
+// DeletePrescriptionUpload removes an unconfirmed upload.
+func (r *Repository) DeletePrescriptionUpload(uploadID int) error {
+	res, err := r.DB.Exec(`DELETE FROM prescription_uploads WHERE id = $1 AND prescription_id IS NULL`, uploadID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Prescription Versioning Related Methods
+
+// ErrPrescriptionNotActive is returned when amending or revoking a
+// prescription that has already been superseded or revoked.
+var ErrPrescriptionNotActive = errors.New("prescription is not active")
+
+// retirePrescription moves an active prescription to status and cancels its
+// doses that haven't come due yet.
+func retirePrescription(tx *sql.Tx, prescriptionID int, status, reason string) error {
+	query := `
+		UPDATE prescriptions
+		SET status = $2,
+			revoked_at = CASE WHEN $2 = 'revoked' THEN now() END,
+			revocation_reason = NULLIF($3, '')
+		WHERE id = $1 AND status = 'active'
+	`
+	res, err := tx.Exec(query, prescriptionID, status, reason)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrPrescriptionNotActive
+	}
+
+	_, err = tx.Exec(`DELETE FROM medication_doses WHERE prescription_id = $1 AND status = 'due' AND scheduled_at > now()`, prescriptionID)
+	return err
+}
+
+func (r *Repository) RevokePrescription(prescriptionID int, reason string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := retirePrescription(tx, prescriptionID, "revoked", reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetPrescriptionHistory returns every version of the prescription that id
+// belongs to, oldest first.
+func (r *Repository) GetPrescriptionHistory(id int) ([]models.Prescription, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, previous_id FROM prescriptions WHERE id = $1
+			UNION ALL
+			SELECT p.id, p.previous_id FROM prescriptions p JOIN ancestors a ON p.id = a.previous_id
+		), chain AS (
+			SELECT id FROM ancestors WHERE previous_id IS NULL
+			UNION ALL
+			SELECT p.id FROM prescriptions p JOIN chain c ON p.previous_id = c.id
+		)
+		SELECT ` + prescriptionColumns + `
+		FROM prescriptions p
+		JOIN doctors d ON p.doctor_id = d.id
+		WHERE p.id IN (SELECT id FROM chain)
+		ORDER BY p.version
+	`
+	return r.queryPrescriptions(query, id)
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Refill Request Related Methods
+
+// ErrRefillNotPending is returned when resolving a refill request that has
+// already been resolved.
+var ErrRefillNotPending = errors.New("refill request is not pending")
+
+// CreateRefillRequest stores the request and notifies the doctor in one
+// transaction. A second pending request for the same prescription fails with
+// a unique violation.
+func (r *Repository) CreateRefillRequest(req models.RefillRequest, notify models.Notification) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO refill_requests (prescription_id, patient_id, doctor_id, patient_note)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		RETURNING id
+	`
+	var newID int
+	err = tx.QueryRow(query, req.PrescriptionID, req.PatientID, req.DoctorID, req.PatientNote).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+
+	notify.Data["refill_request_id"] = newID
+	if err := createNotification(tx, notify); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return newID, nil
+}
+
+const refillRequestColumns = `
+	rr.id, rr.prescription_id, rr.patient_id, rr.doctor_id, rr.status, COALESCE(rr.patient_note, ''),
+	COALESCE(rr.response_reason, ''), rr.new_prescription_id, rr.appointment_id, rr.created_at, rr.resolved_at,
+	p.medication, pt.firstName || ' ' || pt.lastName, d.firstName || ' ' || d.lastName`
+
+const refillRequestJoins = `
+	FROM refill_requests rr
+	JOIN prescriptions p ON rr.prescription_id = p.id
+	JOIN patients pt ON rr.patient_id = pt.id
+	JOIN doctors d ON rr.doctor_id = d.id`
+
+func scanRefillRequest(row interface{ Scan(...any) error }) (models.RefillRequest, error) {
+	var rr models.RefillRequest
+	err := row.Scan(
+		&rr.ID, &rr.PrescriptionID, &rr.PatientID, &rr.DoctorID, &rr.Status, &rr.PatientNote,
+		&rr.ResponseReason, &rr.NewPrescriptionID, &rr.AppointmentID, &rr.CreatedAt, &rr.ResolvedAt,
+		&rr.Medication, &rr.PatientName, &rr.DoctorName,
+	)
+	return rr, err
+}
+
+func (r *Repository) queryRefillRequests(query string, args ...any) ([]models.RefillRequest, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []models.RefillRequest{}
+	for rows.Next() {
+		rr, err := scanRefillRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, rr)
+	}
+	return requests, rows.Err()
+}
+
+// GetRefillRequestsForDoctor returns the doctor's queue, oldest first. An
+// empty status returns requests in every state.
+func (r *Repository) GetRefillRequestsForDoctor(doctorID int, status string) ([]models.RefillRequest, error) {
+	query := `
+		SELECT ` + refillRequestColumns + refillRequestJoins + `
+		WHERE rr.doctor_id = $1 AND ($2 = '' OR rr.status = $2)
+		ORDER BY rr.created_at
+	`
+	return r.queryRefillRequests(query, doctorID, status)
+}
+
+func (r *Repository) GetRefillRequestsByPatientID(patientID int) ([]models.RefillRequest, error) {
+	query := `
+		SELECT ` + refillRequestColumns + refillRequestJoins + `
+		WHERE rr.patient_id = $1
+		ORDER BY rr.created_at DESC
+	`
+	return r.queryRefillRequests(query, patientID)
+}
+
+// GetRefillRequestForDoctor returns sql.ErrNoRows unless the request is in
+// the doctor's queue.
+func (r *Repository) GetRefillRequestForDoctor(doctorID, requestID int) (models.RefillRequest, error) {
+	query := `
+		SELECT ` + refillRequestColumns + refillRequestJoins + `
+		WHERE rr.id = $1 AND rr.doctor_id = $2
+	`
+	return scanRefillRequest(r.DB.QueryRow(query, requestID, doctorID))
+}
+
+// RefillResolution is the doctor's answer to a refill request.
+type RefillResolution struct {
+	Status            string
+	Reason            string
+	NewPrescriptionID *int
+	// Appointment, when set, is created as part of the resolution and linked
+	// to the request.
+	Appointment *models.Appointment
+}
+
+// ResolveRefillRequest records the outcome and notifies the patient in one
+// transaction, returning the id of any appointment created. It fails with
+// ErrRefillNotPending if the request was already resolved.
+func (r *Repository) ResolveRefillRequest(requestID int, res RefillResolution, notify models.Notification) (*int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var appointmentID *int
+	if appt := res.Appointment; appt != nil {
+		query := `
+			INSERT INTO appointments (patient_id, doctor_id, start_time, end_time, appointment_type, status)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`
+		var id int
+		err := tx.QueryRow(query, appt.PatientID, appt.DoctorID, appt.StartTime, appt.EndTime, appt.Type, appt.Status).Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		appointmentID = &id
+	}
+
+	query := `
+		UPDATE refill_requests
+		SET status = $2, response_reason = NULLIF($3, ''), new_prescription_id = $4, appointment_id = $5, resolved_at = $6
+		WHERE id = $1 AND status = 'pending'
+	`
+	result, err := tx.Exec(query, requestID, res.Status, res.Reason, res.NewPrescriptionID, appointmentID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrRefillNotPending
+	}
+
+	if appointmentID != nil {
+		notify.Data["appointment_id"] = *appointmentID
+	}
+	if err := createNotification(tx, notify); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return appointmentID, nil
+}
+
+// ConfirmAppointment moves a pending appointment proposed by a doctor to
+// upcoming. It returns sql.ErrNoRows if the patient has no such pending
+// appointment.
+func (r *Repository) ConfirmAppointment(patientID, appointmentID int) error {
+	query := `UPDATE appointments SET status = 'upcoming' WHERE id = $1 AND patient_id = $2 AND status = 'pending'`
+	res, err := r.DB.Exec(query, appointmentID, patientID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
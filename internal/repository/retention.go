@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Purge categories of a closed account's data.
+const (
+	PurgePatientData     = "patient_data"
+	PurgeClinicalRecords = "clinical_records"
+)
+
+// ErrPurgeChanged is returned when files were added to a purge candidate's
+// data after it was listed; it is purged on a later run instead.
+var ErrPurgeChanged = errors.New("files were added since the purge was planned")
+
+// RetentionPolicy is how long a closed account's data is kept. PatientData
+// covers what the patient recorded themselves (vitals, devices, documents and
+// exports); ClinicalRecords covers the medical record their doctors kept. The
+// audit log is never purged.
+type RetentionPolicy struct {
+	PatientData     time.Duration
+	ClinicalRecords time.Duration
+}
+
+// purgeStep deletes a closed patient's rows from a table; where selects them
+// with the patient ID as $1.
+type purgeStep struct {
+	table string
+	where string
+}
+
+// patientDataPurge deletes the data a patient recorded themselves, in an
+// order the foreign keys allow. Document shares go with their documents.
+var patientDataPurge = []purgeStep{
+	{"patient_documents", "patient_id = $1"},
+	{"data_exports", "patient_id = $1"},
+	{"vital_readings", "patient_id = $1"},
+	{"vital_import_jobs", "patient_id = $1"},
+	{"device_pairing_codes", "patient_id = $1"},
+	{"devices", "patient_id = $1"},
+	{"notifications", "recipient_role = 'patient' AND recipient_id = $1"},
+}
+
+// patientDataFiles are the stored files of a patient's own data.
+const patientDataFiles = `
+	SELECT file_name FROM patient_documents WHERE patient_id = $1
+	UNION
+	SELECT file_name FROM data_exports WHERE patient_id = $1 AND file_name IS NOT NULL AND status <> 'expired'`
+
+// clinicalRecordPurge deletes the clinical record, in an order the foreign
+// keys allow. Prescription overrides go with their prescriptions.
+var clinicalRecordPurge = []purgeStep{
+	{"medication_doses", "patient_id = $1"},
+	{"prescription_schedules", "prescription_id IN (SELECT id FROM prescriptions WHERE patient_id = $1)"},
+	{"prescription_items", "prescription_id IN (SELECT id FROM prescriptions WHERE patient_id = $1)"},
+	{"refill_requests", "patient_id = $1"},
+	{"prescription_uploads", "patient_id = $1"},
+	{"prescriptions", "patient_id = $1"},
+	{"encounter_note_amendments", "note_id IN (SELECT id FROM encounter_notes WHERE patient_id = $1)"},
+	{"encounter_notes", "patient_id = $1"},
+	{"lab_results", "patient_id = $1"},
+	{"lab_reports", "patient_id = $1"},
+	{"lab_orders", "patient_id = $1"},
+	{"appointments", "patient_id = $1"},
+	{"patient_allergies", "patient_id = $1"},
+	{"patient_conditions", "patient_id = $1"},
+	{"patient_medications", "patient_id = $1"},
+	{"vital_alerts", "patient_id = $1"},
+	{"news2_scores", "patient_id = $1"},
+	{"patient_consents", "patient_id = $1"},
+	{"break_glass_grants", "patient_id = $1"},
+}
+
+// clinicalRecordFiles are the stored prescription files and lab reports.
+const clinicalRecordFiles = `
+	SELECT file_name FROM prescriptions WHERE patient_id = $1 AND file_name IS NOT NULL AND file_name <> ''
+	UNION
+	SELECT file_name FROM prescription_uploads WHERE patient_id = $1
+	UNION
+	SELECT file_name FROM lab_reports WHERE patient_id = $1`
+
+// Account Closure Related Methods
+
+// ClosePatientAccount closes the patient's account. Their name and email are
+// replaced with a pseudonym and their password cleared, so they can't log in
+// again and records kept for retention no longer identify them directly.
+// Everyone's access through consent or emergency access ends, devices are
+// revoked, and anything still waiting on the patient is cancelled. It
+// returns sql.ErrNoRows if the account doesn't exist or is already closed.
+func (r *Repository) ClosePatientAccount(patientID int) (time.Time, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	var closedAt time.Time
+	err = tx.QueryRow(`
+		UPDATE patients
+		SET firstName = 'Former patient', lastName = '#' || id, email = 'closed-' || id || '@closed.invalid',
+			hashedPassword = '', closed_at = now()
+		WHERE id = $1 AND closed_at IS NULL
+		RETURNING closed_at
+	`, patientID).Scan(&closedAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	statements := []string{
+		`UPDATE patient_consents SET revoked_at = now() WHERE patient_id = $1 AND revoked_at IS NULL`,
+		`UPDATE break_glass_grants SET expires_at = now() WHERE patient_id = $1 AND expires_at > now()`,
+		`UPDATE devices SET revoked_at = now() WHERE patient_id = $1 AND revoked_at IS NULL`,
+		`DELETE FROM device_pairing_codes WHERE patient_id = $1`,
+		`DELETE FROM document_shares WHERE document_id IN (SELECT id FROM patient_documents WHERE patient_id = $1)`,
+		`UPDATE appointments SET status = 'cancelled' WHERE patient_id = $1 AND status = 'upcoming' AND start_time > now()`,
+		`UPDATE refill_requests SET status = 'withdrawn', resolved_at = now() WHERE patient_id = $1 AND status = 'pending'`,
+		`UPDATE lab_orders SET status = 'cancelled', cancelled_at = now() WHERE patient_id = $1 AND status = 'ordered'`,
+		`DELETE FROM medication_doses WHERE patient_id = $1 AND status = 'due' AND scheduled_at > now()`,
+		`DELETE FROM notifications WHERE recipient_role = 'patient' AND recipient_id = $1`,
+		// Built archives are deleted by the export job once they expire
+		`UPDATE data_exports SET status = 'failed', error = 'Account closed', completed_at = now()
+			WHERE patient_id = $1 AND status IN ('pending', 'running')`,
+		`UPDATE data_exports SET expires_at = now(), link_token_hash = NULL, link_expires_at = NULL
+			WHERE patient_id = $1 AND status = 'ready'`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, patientID); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return closedAt, tx.Commit()
+}
+
+// IsPatientAccountClosed reports whether the patient has closed their
+// account.
+func (r *Repository) IsPatientAccountClosed(patientID int) (bool, error) {
+	var closed bool
+	err := r.DB.QueryRow(`SELECT closed_at IS NOT NULL FROM patients WHERE id = $1`, patientID).Scan(&closed)
+	return closed, err
+}
+
+// Retention Related Methods
+
+// GetRetentionReport lists the closed accounts' data due for purging at now
+// under policy, without deleting anything.
+func (r *Repository) GetRetentionReport(policy RetentionPolicy, now time.Time) ([]models.PurgeCandidate, error) {
+	candidates := []models.PurgeCandidate{}
+	categories := []struct {
+		name      string
+		retention time.Duration
+		purgedCol string
+		steps     []purgeStep
+		files     string
+	}{
+		{PurgePatientData, policy.PatientData, "data_purged_at", patientDataPurge, patientDataFiles},
+		{PurgeClinicalRecords, policy.ClinicalRecords, "records_purged_at", clinicalRecordPurge, clinicalRecordFiles},
+	}
+	for _, category := range categories {
+		due, err := r.closedPatientsDue(category.purgedCol, now.Add(-category.retention))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range due {
+			c.Category = category.name
+			c.DueAt = c.ClosedAt.Add(category.retention)
+			if c.Rows, err = r.countPurgeRows(c.PatientID, category.steps); err != nil {
+				return nil, err
+			}
+			if c.Files, err = r.purgeFiles(c.PatientID, category.files); err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates, nil
+}
+
+// closedPatientsDue returns the accounts closed before closedBefore whose
+// purgedCol hasn't been set. purgedCol is one of the constant column names
+// above, never user input.
+func (r *Repository) closedPatientsDue(purgedCol string, closedBefore time.Time) ([]models.PurgeCandidate, error) {
+	rows, err := r.DB.Query(`
+		SELECT id, closed_at FROM patients
+		WHERE closed_at IS NOT NULL AND closed_at <= $1 AND `+purgedCol+` IS NULL
+		ORDER BY closed_at
+	`, closedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []models.PurgeCandidate
+	for rows.Next() {
+		var c models.PurgeCandidate
+		if err := rows.Scan(&c.PatientID, &c.ClosedAt); err != nil {
+			return nil, err
+		}
+		due = append(due, c)
+	}
+	return due, rows.Err()
+}
+
+func (r *Repository) countPurgeRows(patientID int, steps []purgeStep) (map[string]int64, error) {
+	counts := map[string]int64{}
+	for _, step := range steps {
+		var n int64
+		err := r.DB.QueryRow(`SELECT count(*) FROM `+step.table+` WHERE `+step.where, patientID).Scan(&n)
+		if err != nil {
+			return nil, err
+		}
+		counts[step.table] = n
+	}
+	return counts, nil
+}
+
+func (r *Repository) purgeFiles(patientID int, query string) ([]string, error) {
+	rows, err := r.DB.Query(query, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+	return files, rows.Err()
+}
+
+// PurgeClosedPatientData deletes the rows of a purge candidate and records
+// that its category has been purged. The caller deletes the stored files
+// first, so a failure here leaves nothing unaccounted for.
+func (r *Repository) PurgeClosedPatientData(c models.PurgeCandidate) error {
+	steps, purgedCol, filesQuery := patientDataPurge, "data_purged_at", patientDataFiles
+	if c.Category == PurgeClinicalRecords {
+		steps, purgedCol, filesQuery = clinicalRecordPurge, "records_purged_at", clinicalRecordFiles
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT id FROM patients WHERE id = $1 FOR UPDATE`, c.PatientID); err != nil {
+		return err
+	}
+	deleted := make(map[string]bool, len(c.Files))
+	for _, name := range c.Files {
+		deleted[name] = true
+	}
+	rows, err := tx.Query(filesQuery, c.PatientID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if !deleted[name] {
+			return ErrPurgeChanged
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if c.Category == PurgeClinicalRecords {
+		// Versions point back at the ones they amended, which would stop them
+		// being deleted together
+		if _, err := tx.Exec(`UPDATE prescriptions SET previous_id = NULL WHERE patient_id = $1`, c.PatientID); err != nil {
+			return err
+		}
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(`DELETE FROM `+step.table+` WHERE `+step.where, c.PatientID); err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.Exec(`UPDATE patients SET `+purgedCol+` = now() WHERE id = $1 AND closed_at IS NOT NULL`, c.PatientID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
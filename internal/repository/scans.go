@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// File Scan Related Methods
+
+// GetPrescriptionsPendingScan returns up to limit quarantined prescriptions
+// waiting for a malware scan, oldest first.
+func (r *Repository) GetPrescriptionsPendingScan(limit int) ([]models.Prescription, error) {
+	query := `
+		SELECT id, patient_id, doctor_id, medication, file_name
+		FROM prescriptions
+		WHERE scan_status = 'pending'
+		ORDER BY created_at
+		LIMIT $1
+	`
+	rows, err := r.DB.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prescriptions []models.Prescription
+	for rows.Next() {
+		var pres models.Prescription
+		if err := rows.Scan(&pres.ID, &pres.PatientID, &pres.DoctorID, &pres.Medication, &pres.FileName); err != nil {
+			return nil, err
+		}
+		prescriptions = append(prescriptions, pres)
+	}
+	return prescriptions, rows.Err()
+}
+
+// RecordPrescriptionScan stores the result of scanning a pending
+// prescription's file, sending notify, if given, in the same transaction.
+// It returns sql.ErrNoRows if the prescription wasn't pending.
+func (r *Repository) RecordPrescriptionScan(prescriptionID int, status, threat string, notify *models.Notification) error {
+	return r.recordScan("prescriptions", prescriptionID, status, threat, notify)
+}
+
+// GetDocumentsPendingScan returns up to limit quarantined documents waiting
+// for a malware scan, oldest first.
+func (r *Repository) GetDocumentsPendingScan(limit int) ([]models.PatientDocument, error) {
+	query := `
+		SELECT ` + documentColumns + `
+		FROM patient_documents pd
+		WHERE pd.scan_status = 'pending'
+		ORDER BY pd.created_at
+		LIMIT $1
+	`
+	return r.queryDocuments(query, limit)
+}
+
+// RecordDocumentScan is RecordPrescriptionScan for patient documents.
+func (r *Repository) RecordDocumentScan(documentID int, status, threat string, notify *models.Notification) error {
+	return r.recordScan("patient_documents", documentID, status, threat, notify)
+}
+
+// recordScan updates the scan columns shared by the tables holding uploaded
+// files.
+func (r *Repository) recordScan(table string, id int, status, threat string, notify *models.Notification) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE `+table+` SET scan_status = $1, scan_threat = NULLIF($2, ''), scanned_at = now()
+		WHERE id = $3 AND scan_status = 'pending'
+	`, status, threat, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if notify != nil {
+		if err := createNotification(tx, *notify); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
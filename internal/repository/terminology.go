@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+	"github.com/RitwikGupta-0501/vital-watch/internal/terminology"
+)
+
+// Terminology Related Methods
+
+// terminologyImportBatch is how many codes are written per statement.
+const terminologyImportBatch = 5000
+
+// ImportTerminology loads a release of the code system: new codes are added,
+// existing ones updated, and ones the release no longer has are marked
+// inactive rather than deleted, since old records may still use them. It
+// returns how many codes were marked inactive.
+func (r *Repository) ImportTerminology(system string, codes []models.TerminologyCode) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var importedAt time.Time
+	if err := tx.QueryRow(`SELECT now()`).Scan(&importedAt); err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO terminology_codes (system, code, search_code, description, billable, active, imported_at)
+		SELECT $1, c.code, c.search_code, c.description, c.billable, true, $2
+		FROM unnest($3::text[], $4::text[], $5::text[], $6::bool[]) AS c(code, search_code, description, billable)
+		ON CONFLICT (system, code) DO UPDATE
+		SET search_code = EXCLUDED.search_code, description = EXCLUDED.description, billable = EXCLUDED.billable,
+			active = true, imported_at = EXCLUDED.imported_at
+	`
+	for start := 0; start < len(codes); start += terminologyImportBatch {
+		batch := codes[start:min(start+terminologyImportBatch, len(codes))]
+		code := make([]string, len(batch))
+		searchCode := make([]string, len(batch))
+		description := make([]string, len(batch))
+		billable := make([]bool, len(batch))
+		for i, c := range batch {
+			code[i], searchCode[i], description[i], billable[i] = c.Code, terminology.SearchCode(c.Code), c.Description, c.Billable
+		}
+		if _, err := tx.Exec(query, system, importedAt, code, searchCode, description, billable); err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := tx.Exec(`
+		UPDATE terminology_codes SET active = false
+		WHERE system = $1 AND active AND imported_at < $2
+	`, system, importedAt)
+	if err != nil {
+		return 0, err
+	}
+	deactivated, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return deactivated, tx.Commit()
+}
+
+// terminologyColumns selects a code from t and whether the doctor in $1 has
+// made it a favourite.
+const terminologyColumns = `
+	t.system, t.code, t.description, t.billable, t.active,
+	EXISTS (SELECT 1 FROM doctor_code_favourites f WHERE f.doctor_id = $1 AND f.system = t.system AND f.code = t.code)`
+
+func (r *Repository) queryTerminology(query string, args ...any) ([]models.TerminologyCode, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := []models.TerminologyCode{}
+	for rows.Next() {
+		var t models.TerminologyCode
+		if err := rows.Scan(&t.System, &t.Code, &t.Description, &t.Billable, &t.Active, &t.Favourite); err != nil {
+			return nil, err
+		}
+		codes = append(codes, t)
+	}
+	return codes, rows.Err()
+}
+
+// SearchTerminologyByPrefix returns the active codes starting with prefix,
+// which must already be reduced with terminology.SearchCode, in the system
+// or in all of them when system is empty. The doctor's favourites come first,
+// then an exact match, then the shortest codes.
+func (r *Repository) SearchTerminologyByPrefix(doctorID int, system, prefix string, limit int) ([]models.TerminologyCode, error) {
+	query := `
+		SELECT ` + terminologyColumns + `
+		FROM terminology_codes t
+		WHERE t.active AND ($2::text = '' OR t.system = $2) AND t.search_code LIKE $3::text || '%'
+		ORDER BY 6 DESC, t.search_code = $3 DESC, length(t.search_code), t.search_code
+		LIMIT $4
+	`
+	return r.queryTerminology(query, doctorID, system, prefix, limit)
+}
+
+// SearchTerminology returns the active codes whose descriptions contain every
+// word of the tsquery, in the system or in all of them when system is empty.
+// The doctor's favourites come first, then the best matches, which favours
+// short descriptions, and billable codes before the categories above them.
+func (r *Repository) SearchTerminology(doctorID int, system, tsquery string, limit int) ([]models.TerminologyCode, error) {
+	query := `
+		SELECT ` + terminologyColumns + `
+		FROM terminology_codes t, to_tsquery('english', $3) q
+		WHERE t.active AND ($2::text = '' OR t.system = $2) AND t.search_vector @@ q
+		ORDER BY 6 DESC, ts_rank(t.search_vector, q, 1) DESC, t.billable DESC, length(t.description), t.code
+		LIMIT $4
+	`
+	return r.queryTerminology(query, doctorID, system, tsquery, limit)
+}
+
+// GetTerminologyFavourites returns the doctor's favourite codes.
+func (r *Repository) GetTerminologyFavourites(doctorID int) ([]models.TerminologyCode, error) {
+	query := `
+		SELECT ` + terminologyColumns + `
+		FROM doctor_code_favourites fav
+		JOIN terminology_codes t ON t.system = fav.system AND t.code = fav.code
+		WHERE fav.doctor_id = $1
+		ORDER BY t.system, t.code
+	`
+	return r.queryTerminology(query, doctorID)
+}
+
+// AddTerminologyFavourite returns sql.ErrNoRows if the system has no such
+// active code. The code may be given without its punctuation.
+func (r *Repository) AddTerminologyFavourite(doctorID int, system, code string) (models.TerminologyCode, error) {
+	query := `
+		WITH t AS (
+			SELECT system, code, description, billable, active
+			FROM terminology_codes
+			WHERE system = $2 AND search_code = $3 AND active
+		), fav AS (
+			INSERT INTO doctor_code_favourites (doctor_id, system, code)
+			SELECT $1, system, code FROM t
+			ON CONFLICT DO NOTHING
+		)
+		SELECT system, code, description, billable, active FROM t
+	`
+	t := models.TerminologyCode{Favourite: true}
+	err := r.DB.QueryRow(query, doctorID, system, terminology.SearchCode(code)).
+		Scan(&t.System, &t.Code, &t.Description, &t.Billable, &t.Active)
+	return t, err
+}
+
+// RemoveTerminologyFavourite returns sql.ErrNoRows if the code wasn't one of
+// the doctor's favourites.
+func (r *Repository) RemoveTerminologyFavourite(doctorID int, system, code string) error {
+	query := `
+		DELETE FROM doctor_code_favourites f
+		USING terminology_codes t
+		WHERE f.doctor_id = $1 AND f.system = $2 AND t.system = f.system AND t.code = f.code AND t.search_code = $3
+	`
+	res, err := r.DB.Exec(query, doctorID, system, terminology.SearchCode(code))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// HasTerminology reports whether the code system has been imported.
+func (r *Repository) HasTerminology(system string) (bool, error) {
+	var loaded bool
+	err := r.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM terminology_codes WHERE system = $1)`, system).Scan(&loaded)
+	return loaded, err
+}
+
+// LookupTerminologyCodes finds the active codes of the system matching each
+// of codes once reduced with terminology.SearchCode. The result is keyed by
+// the reduced code; codes not found are missing from it.
+func (r *Repository) LookupTerminologyCodes(system string, codes []string) (map[string]models.TerminologyCode, error) {
+	searchCodes := make([]string, len(codes))
+	for i, code := range codes {
+		searchCodes[i] = terminology.SearchCode(code)
+	}
+
+	query := `
+		SELECT search_code, system, code, description, billable, active
+		FROM terminology_codes
+		WHERE system = $1 AND active AND search_code = ANY($2)
+	`
+	rows, err := r.DB.Query(query, system, searchCodes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]models.TerminologyCode, len(codes))
+	for rows.Next() {
+		var searchCode string
+		var t models.TerminologyCode
+		if err := rows.Scan(&searchCode, &t.System, &t.Code, &t.Description, &t.Billable, &t.Active); err != nil {
+			return nil, err
+		}
+		found[searchCode] = t
+	}
+	return found, rows.Err()
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Prescription Verification Related Methods
+
+// GetPrescriptionByVerificationCode loads what's needed to check a
+// prescription's signature, along with the issuing doctor's public details.
+func (r *Repository) GetPrescriptionByVerificationCode(code string) (models.Prescription, models.Doctor, error) {
+	query := `
+		SELECT p.id, p.patient_id, p.doctor_id, p.file_name, p.created_at, p.verification_code,
+			COALESCE(p.content_hash, ''), COALESCE(p.signature, ''), COALESCE(p.signing_key_id, ''),
+			p.expires_at, p.revoked_at, p.status, p.scan_status,
+			d.firstName, d.lastName, COALESCE(d.specialty, '')
+		FROM prescriptions p
+		JOIN doctors d ON p.doctor_id = d.id
+		WHERE p.verification_code = $1
+	`
+	var pres models.Prescription
+	var doc models.Doctor
+	err := r.DB.QueryRow(query, code).Scan(
+		&pres.ID, &pres.PatientID, &pres.DoctorID, &pres.FileName, &pres.CreatedAt, &pres.VerificationCode,
+		&pres.ContentHash, &pres.Signature, &pres.SigningKeyID,
+		&pres.ExpiresAt, &pres.RevokedAt, &pres.Status, &pres.ScanStatus,
+		&doc.FirstName, &doc.LastName, &doc.Specialty,
+	)
+	doc.ID = pres.DoctorID
+	return pres, doc, err
+}
@@ -0,0 +1,172 @@
+package rxpdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/skip2/go-qrcode"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Clinic holds the branding printed in the prescription header.
+type Clinic struct {
+	Name    string
+	Address string
+	Phone   string
+}
+
+// Document is everything that goes on a rendered prescription.
+type Document struct {
+	Clinic           Clinic
+	Doctor           models.Doctor
+	Patient          models.Patient
+	Items            []models.PrescriptionItem
+	Notes            string
+	IssuedAt         time.Time
+	VerificationCode string
+	// VerificationURL is encoded in the QR code; pharmacies scan it to check
+	// the prescription is genuine.
+	VerificationURL string
+	// Version is printed when above 1, so an amended prescription can't be
+	// mistaken for the original.
+	Version int
+}
+
+const (
+	pageMargin = 15.0
+	qrSize     = 32.0
+)
+
+// Render writes the prescription as an A4 PDF to w.
+func Render(w io.Writer, doc Document) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, pageMargin+10)
+	pdf.SetTitle("Prescription "+doc.VerificationCode, true)
+	pdf.SetCreator("vital-watch", true)
+	pdf.SetAuthor("Dr. "+doc.Doctor.FirstName+" "+doc.Doctor.LastName, true)
+
+	// Core fonts are cp1252, so translate names with accents and the like
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageWidth, _ := pdf.GetPageSize()
+	contentWidth := pageWidth - 2*pageMargin
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pageMargin - 5)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, tr("Verification code "+doc.VerificationCode+" - page "+strconv.Itoa(pdf.PageNo())+"/{nb}"), "", 0, "C", false, 0, "")
+	})
+	pdf.AliasNbPages("{nb}")
+	pdf.AddPage()
+
+	// Clinic header
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.SetTextColor(20, 60, 110)
+	pdf.CellFormat(contentWidth, 8, tr(doc.Clinic.Name), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(80, 80, 80)
+	if doc.Clinic.Address != "" {
+		pdf.CellFormat(contentWidth, 5, tr(doc.Clinic.Address), "", 1, "L", false, 0, "")
+	}
+	if doc.Clinic.Phone != "" {
+		pdf.CellFormat(contentWidth, 5, tr("Tel: "+doc.Clinic.Phone), "", 1, "L", false, 0, "")
+	}
+	pdf.SetDrawColor(20, 60, 110)
+	pdf.SetLineWidth(0.6)
+	pdf.Line(pageMargin, pdf.GetY()+2, pageWidth-pageMargin, pdf.GetY()+2)
+	pdf.Ln(6)
+
+	// Prescriber and patient
+	pdf.SetTextColor(0, 0, 0)
+	half := contentWidth / 2
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(half, 6, "Prescriber", "", 0, "L", false, 0, "")
+	pdf.CellFormat(half, 6, "Patient", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(half, 5, tr("Dr. "+doc.Doctor.FirstName+" "+doc.Doctor.LastName), "", 0, "L", false, 0, "")
+	pdf.CellFormat(half, 5, tr(doc.Patient.FirstName+" "+doc.Patient.LastName), "", 1, "L", false, 0, "")
+	pdf.CellFormat(half, 5, tr(doc.Doctor.Specialty), "", 0, "L", false, 0, "")
+	pdf.CellFormat(half, 5, "Patient ID: "+strconv.Itoa(doc.Patient.ID), "", 1, "L", false, 0, "")
+	pdf.CellFormat(half, 5, "", "", 0, "L", false, 0, "")
+	pdf.CellFormat(half, 5, "Date: "+doc.IssuedAt.Format("02 Jan 2006"), "", 1, "L", false, 0, "")
+	if doc.Version > 1 {
+		pdf.CellFormat(half, 5, "", "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(half, 5, fmt.Sprintf("Amended - version %d", doc.Version), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+	}
+	pdf.Ln(6)
+
+	// Line items
+	pdf.SetFont("Helvetica", "B", 28)
+	pdf.CellFormat(contentWidth, 10, "Rx", "", 1, "L", false, 0, "")
+	for i, it := range doc.Items {
+		pdf.SetFont("Helvetica", "B", 11)
+		title := fmt.Sprintf("%d. %s", i+1, it.DrugName)
+		if it.Strength != "" {
+			title += " " + it.Strength
+		}
+		if it.Form != "" {
+			title += " (" + it.Form + ")"
+		}
+		pdf.MultiCell(contentWidth, 6, tr(title), "", "L", false)
+
+		pdf.SetFont("Helvetica", "", 10)
+		directions := it.Dose + ", " + it.Frequency
+		if it.Route != "" {
+			directions += ", " + it.Route
+		}
+		if it.Duration != "" {
+			directions += ", for " + it.Duration
+		}
+		pdf.MultiCell(contentWidth, 5, tr("Sig: "+directions), "", "L", false)
+		pdf.MultiCell(contentWidth, 5, fmt.Sprintf("Quantity: %d    Refills: %d", it.Quantity, it.Refills), "", "L", false)
+		pdf.Ln(3)
+	}
+
+	if doc.Notes != "" {
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(contentWidth, 6, "Notes", "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(contentWidth, 5, tr(doc.Notes), "", "L", false)
+	}
+
+	// Signature block and verification QR code, kept together on one page
+	blockHeight := qrSize + 10
+	_, pageHeight := pdf.GetPageSize()
+	if pdf.GetY()+blockHeight > pageHeight-pageMargin-10 {
+		pdf.AddPage()
+	}
+	pdf.Ln(8)
+	top := pdf.GetY()
+
+	qr, err := qrcode.Encode(doc.VerificationURL, qrcode.Medium, 256)
+	if err != nil {
+		return fmt.Errorf("encoding verification QR code: %w", err)
+	}
+	opts := gofpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader("verification-qr", opts, bytes.NewReader(qr))
+	pdf.ImageOptions("verification-qr", pageMargin, top, qrSize, qrSize, false, opts, 0, "")
+
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetXY(pageMargin+qrSize+4, top+qrSize-14)
+	pdf.MultiCell(half-qrSize, 4, tr("Scan to verify, or visit "+doc.VerificationURL), "", "L", false)
+
+	sigX := pageMargin + half + 10
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(0.3)
+	pdf.Line(sigX, top+qrSize-10, pageWidth-pageMargin, top+qrSize-10)
+	pdf.SetXY(sigX, top+qrSize-9)
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(0, 4, tr("Dr. "+doc.Doctor.FirstName+" "+doc.Doctor.LastName), "", 2, "L", false, 0, "")
+	pdf.CellFormat(0, 4, "Electronically issued "+doc.IssuedAt.Format("02 Jan 2006 15:04 MST"), "", 2, "L", false, 0, "")
+
+	return pdf.Output(w)
+}
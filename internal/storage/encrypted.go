@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/envelope"
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// DataKeyStore keeps the wrapped data key of each encrypted object.
+type DataKeyStore interface {
+	SaveDataKey(key models.DataKey) error
+	// GetDataKey returns nil if the object has no data key.
+	GetDataKey(objectKey string) (*models.DataKey, error)
+	DeleteDataKey(objectKey string) error
+}
+
+// EncryptedStore envelope encrypts objects before they reach Store, with a
+// fresh data key per object wrapped by Keys and kept in DataKeys. Objects
+// stored before encryption was turned on have no data key and are read
+// as-is.
+//
+// EncryptedStore can't presign URLs, since clients would see ciphertext.
+type EncryptedStore struct {
+	Store    Store
+	Keys     envelope.KeyProvider
+	DataKeys DataKeyStore
+}
+
+func NewEncryptedStore(store Store, keys envelope.KeyProvider, dataKeys DataKeyStore) *EncryptedStore {
+	return &EncryptedStore{Store: store, Keys: keys, DataKeys: dataKeys}
+}
+
+// Put encrypts the object in memory, so the backend still gets a body of
+// known size it can retry from, then saves its data key before uploading it.
+func (s *EncryptedStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	dataKey, err := envelope.NewDataKey()
+	if err != nil {
+		return err
+	}
+	keyID, wrapped, err := s.Keys.Wrap(ctx, dataKey)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if size >= 0 {
+		buf.Grow(int(envelope.EncryptedSize(size)))
+	}
+	if err := envelope.Encrypt(&buf, body, dataKey); err != nil {
+		return err
+	}
+
+	err = s.DataKeys.SaveDataKey(models.DataKey{ObjectKey: key, MasterKeyID: keyID, WrappedKey: wrapped})
+	if err != nil {
+		return err
+	}
+	if err := s.Store.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), contentType); err != nil {
+		if delErr := s.DataKeys.DeleteDataKey(key); delErr != nil {
+			log.Printf("Failed to delete data key of failed upload %s: %v", key, delErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *EncryptedStore) Get(ctx context.Context, key string) (*Object, error) {
+	dk, err := s.DataKeys.GetDataKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if dk == nil {
+		return s.Store.Get(ctx, key)
+	}
+
+	dataKey, err := s.Keys.Unwrap(ctx, dk.MasterKeyID, dk.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.Store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := envelope.Decrypt(out.Body, dataKey)
+	if err != nil {
+		out.Body.Close()
+		return nil, err
+	}
+
+	return &Object{
+		Body:        decryptedBody{Reader: plain, Closer: out.Body},
+		ContentType: out.ContentType,
+		Size:        envelope.DecryptedSize(out.Size),
+	}, nil
+}
+
+func (s *EncryptedStore) Delete(ctx context.Context, key string) error {
+	if err := s.Store.Delete(ctx, key); err != nil {
+		return err
+	}
+	return s.DataKeys.DeleteDataKey(key)
+}
+
+type decryptedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,75 @@
+// Package storage abstracts where uploaded files live, so the API can run
+// against S3, an S3-compatible server such as MinIO, the local filesystem or
+// memory.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned by Get when the key doesn't exist.
+var ErrNotFound = errors.New("object not found")
+
+// Object is a stored file. The caller must close Body.
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+}
+
+type Store interface {
+	// Put stores body under key, replacing anything already there. size may
+	// be -1 if unknown.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (*Object, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Backends selectable through Config.
+const (
+	BackendS3     = "s3"
+	BackendLocal  = "local"
+	BackendMemory = "memory"
+)
+
+type Config struct {
+	Backend string
+
+	// S3 and S3-compatible servers
+	Bucket string
+	// Endpoint overrides the AWS endpoint, e.g. http://minio:9000
+	Endpoint     string
+	UsePathStyle bool
+
+	// Local filesystem
+	LocalPath string
+}
+
+// New builds the store cfg selects.
+func New(ctx context.Context, cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case BackendS3, "":
+		return NewS3Store(ctx, cfg)
+	case BackendLocal:
+		return NewLocalStore(cfg.LocalPath)
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+}
+
+// Presigner is implemented by stores that can hand out short-lived URLs so
+// clients transfer files directly instead of through the API.
+type Presigner interface {
+	// PresignGet returns a download URL. downloadName, when set, is sent as
+	// the attachment file name.
+	PresignGet(ctx context.Context, key string, ttl time.Duration, downloadName string) (string, error)
+	// PresignPut returns an upload URL that only accepts exactly size bytes
+	// of contentType.
+	PresignPut(ctx context.Context, key string, contentType string, size int64, ttl time.Duration) (string, error)
+}
@@ -0,0 +1,179 @@
+// Package terminology reads the release files of the diagnosis code systems
+// doctors search and code with: the ICD-10-CM flat files published by CMS
+// and the SNOMED CT RF2 description files.
+package terminology
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+// Code systems.
+const (
+	SystemICD10CM = "icd10cm"
+	SystemSNOMED  = "snomedct"
+)
+
+// Systems lists the supported code systems.
+var Systems = map[string]bool{SystemICD10CM: true, SystemSNOMED: true}
+
+// Load reads the release file at path for the code system.
+func Load(system, path string) ([]models.TerminologyCode, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch system {
+	case SystemICD10CM:
+		return ParseICD10CM(f)
+	case SystemSNOMED:
+		return ParseSNOMED(f)
+	}
+	return nil, fmt.Errorf("unknown code system %q", system)
+}
+
+// SearchCode reduces a code to what is compared when searching and
+// validating: upper case, without dots or spaces. So "e11.65" and "E1165"
+// are the same code.
+func SearchCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, code)
+}
+
+// ParseICD10CM reads either ICD-10-CM flat file: the order file
+// (icd10cm_order_*.txt), which has every code including the category headers,
+// or the codes file (icd10cm_codes_*.txt), which has only the billable ones.
+// Codes are returned with their dot, e.g., E11.65.
+func ParseICD10CM(r io.Reader) ([]models.TerminologyCode, error) {
+	var codes []models.TerminologyCode
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimRight(sc.Text(), " \r")
+		if text == "" {
+			continue
+		}
+
+		var code, description string
+		billable := true
+		if isOrderLine(text) {
+			// Fixed width: order number, code, header flag, short and long
+			// descriptions, each followed by a space
+			if len(text) < 17 {
+				return nil, fmt.Errorf("line %d: too short for the order file format", line)
+			}
+			code = strings.TrimSpace(text[6:13])
+			billable = text[14] == '1'
+			description = strings.TrimSpace(text[16:min(len(text), 76)])
+			if len(text) > 77 {
+				description = strings.TrimSpace(text[77:])
+			}
+		} else {
+			var ok bool
+			code, description, ok = strings.Cut(text, " ")
+			if !ok {
+				return nil, fmt.Errorf("line %d: no description after the code", line)
+			}
+			description = strings.TrimSpace(description)
+		}
+
+		code = SearchCode(code)
+		if len(code) < 3 || description == "" {
+			return nil, fmt.Errorf("line %d: invalid code %q", line, code)
+		}
+		if len(code) > 3 {
+			code = code[:3] + "." + code[3:]
+		}
+		codes = append(codes, models.TerminologyCode{
+			System:      SystemICD10CM,
+			Code:        code,
+			Description: description,
+			Billable:    billable,
+			Active:      true,
+		})
+	}
+	return codes, sc.Err()
+}
+
+// isOrderLine reports whether the line is from the order file, which starts
+// with a five digit order number.
+func isOrderLine(text string) bool {
+	if len(text) < 6 || text[5] != ' ' {
+		return false
+	}
+	for _, c := range text[:5] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// RF2 description file columns and values.
+const (
+	snomedActive          = 2
+	snomedConceptID       = 4
+	snomedTypeID          = 6
+	snomedTerm            = 7
+	snomedColumns         = 9
+	snomedFullySpecified  = "900000000000003001"
+	snomedSemanticTagOpen = " ("
+)
+
+// snomedDiagnosisTags are the semantic tags of the concepts diagnoses are
+// coded with; procedures, substances and the rest are left out.
+var snomedDiagnosisTags = map[string]bool{"disorder": true, "finding": true}
+
+// ParseSNOMED reads an RF2 description snapshot file
+// (sct2_Description_Snapshot-en_*.txt), taking each active disorder and
+// finding concept with its fully specified name, less the semantic tag, as
+// its description.
+func ParseSNOMED(r io.Reader) ([]models.TerminologyCode, error) {
+	var codes []models.TerminologyCode
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		fields := strings.Split(strings.TrimRight(sc.Text(), "\r"), "\t")
+		if line == 1 && len(fields) > 0 && fields[0] == "id" {
+			continue
+		}
+		if len(fields) != snomedColumns {
+			return nil, fmt.Errorf("line %d: expected %d tab separated columns, got %d", line, snomedColumns, len(fields))
+		}
+		if fields[snomedActive] != "1" || fields[snomedTypeID] != snomedFullySpecified {
+			continue
+		}
+
+		term := fields[snomedTerm]
+		i := strings.LastIndex(term, snomedSemanticTagOpen)
+		if i < 0 || !strings.HasSuffix(term, ")") {
+			continue
+		}
+		tag := term[i+len(snomedSemanticTagOpen) : len(term)-1]
+		conceptID := fields[snomedConceptID]
+		if !snomedDiagnosisTags[tag] || seen[conceptID] {
+			continue
+		}
+		seen[conceptID] = true
+
+		codes = append(codes, models.TerminologyCode{
+			System:      SystemSNOMED,
+			Code:        conceptID,
+			Description: term[:i],
+			Billable:    true,
+			Active:      true,
+		})
+	}
+	return codes, sc.Err()
+}
@@ -0,0 +1,179 @@
+package terminology
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RitwikGupta-0501/vital-watch/internal/models"
+)
+
+func TestSearchCode(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"E11.65", "E1165"},
+		{"e11.65", "E1165"},
+		{" E11 .65 ", "E1165"},
+		{"E1165", "E1165"},
+		{"44054006", "44054006"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := SearchCode(tt.in); got != tt.want {
+			t.Errorf("SearchCode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// orderLine formats a line of the ICD-10-CM order file.
+func orderLine(n int, code string, billable bool, short, long string) string {
+	flag := 0
+	if billable {
+		flag = 1
+	}
+	return fmt.Sprintf("%05d %-7s %d %-60s %s", n, code, flag, short, long)
+}
+
+func TestParseICD10CM(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []models.TerminologyCode
+	}{
+		{
+			name: "order file",
+			data: strings.Join([]string{
+				orderLine(1, "E11", false, "Type 2 diabetes mellitus", "Type 2 diabetes mellitus"),
+				orderLine(2, "E1165", true, "Type 2 diabetes mellitus with hyperglycemia", "Type 2 diabetes mellitus with hyperglycemia"),
+				orderLine(3, "I10", true, "Essential (primary) hypertension", "Essential (primary) hypertension"),
+			}, "\r\n") + "\r\n",
+			want: []models.TerminologyCode{
+				{System: SystemICD10CM, Code: "E11", Description: "Type 2 diabetes mellitus", Billable: false, Active: true},
+				{System: SystemICD10CM, Code: "E11.65", Description: "Type 2 diabetes mellitus with hyperglycemia", Billable: true, Active: true},
+				{System: SystemICD10CM, Code: "I10", Description: "Essential (primary) hypertension", Billable: true, Active: true},
+			},
+		},
+		{
+			name: "order file without long descriptions",
+			data: fmt.Sprintf("%05d %-7s %d %s\n", 1, "J45909", 1, "Unspecified asthma, uncomplicated"),
+			want: []models.TerminologyCode{
+				{System: SystemICD10CM, Code: "J45.909", Description: "Unspecified asthma, uncomplicated", Billable: true, Active: true},
+			},
+		},
+		{
+			name: "codes file",
+			data: "E1165   Type 2 diabetes mellitus with hyperglycemia\nI10     Essential (primary) hypertension\n\n",
+			want: []models.TerminologyCode{
+				{System: SystemICD10CM, Code: "E11.65", Description: "Type 2 diabetes mellitus with hyperglycemia", Billable: true, Active: true},
+				{System: SystemICD10CM, Code: "I10", Description: "Essential (primary) hypertension", Billable: true, Active: true},
+			},
+		},
+		{name: "empty", data: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseICD10CM(strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("ParseICD10CM: %v", err)
+			}
+			sameCodes(t, got, tt.want)
+		})
+	}
+}
+
+func TestParseICD10CMErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"short order line", "00001 E11    0", "line 1: too short"},
+		{"no description", "I10\nE1165 Type 2 diabetes", "line 1: no description"},
+		{"short code", "I10 Hypertension\nI1 Hypertension", "line 2: invalid code"},
+		{"order line without a code", orderLine(1, "", true, "Cholera", "Cholera"), "line 1: invalid code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseICD10CM(strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseICD10CM error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// rf2 formats a line of an RF2 description file.
+func rf2(id string, active bool, conceptID, typeID, term string) string {
+	a := "0"
+	if active {
+		a = "1"
+	}
+	return strings.Join([]string{id, "20260301", a, "900000000000207008", conceptID, "en", typeID, term, "900000000000448009"}, "\t")
+}
+
+const synonym = "900000000000013009"
+
+func TestParseSNOMED(t *testing.T) {
+	data := strings.Join([]string{
+		"id\teffectiveTime\tactive\tmoduleId\tconceptId\tlanguageCode\ttypeId\tterm\tcaseSignificanceId",
+		rf2("1", true, "44054006", snomedFullySpecified, "Diabetes mellitus type 2 (disorder)"),
+		rf2("2", true, "44054006", synonym, "Type 2 diabetes mellitus"),
+		// A second active FSN for the same concept is ignored
+		rf2("3", true, "44054006", snomedFullySpecified, "Type II diabetes mellitus (disorder)"),
+		rf2("4", true, "271737000", snomedFullySpecified, "Anemia (disorder)"),
+		rf2("5", true, "386661006", snomedFullySpecified, "Fever (finding)"),
+		rf2("6", false, "38341003", snomedFullySpecified, "Hypertensive disorder, systemic arterial (disorder)"),
+		rf2("7", true, "80146002", snomedFullySpecified, "Excision of appendix (procedure)"),
+		rf2("8", true, "387517004", snomedFullySpecified, "Paracetamol (substance)"),
+		rf2("9", true, "22298006", snomedFullySpecified, "Myocardial infarction"),
+		rf2("10", true, "195967001", snomedFullySpecified, "Asthma (disorder) (disorder)"),
+	}, "\r\n") + "\r\n"
+
+	got, err := ParseSNOMED(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseSNOMED: %v", err)
+	}
+	sameCodes(t, got, []models.TerminologyCode{
+		{System: SystemSNOMED, Code: "44054006", Description: "Diabetes mellitus type 2", Billable: true, Active: true},
+		{System: SystemSNOMED, Code: "271737000", Description: "Anemia", Billable: true, Active: true},
+		{System: SystemSNOMED, Code: "386661006", Description: "Fever", Billable: true, Active: true},
+		{System: SystemSNOMED, Code: "195967001", Description: "Asthma (disorder)", Billable: true, Active: true},
+	})
+
+	_, err = ParseSNOMED(strings.NewReader(rf2("1", true, "44054006", snomedFullySpecified, "Diabetes (disorder)") + "\n1\t2\t3\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2: expected 9 tab separated columns, got 3") {
+		t.Errorf("ParseSNOMED of a short line = %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "icd10cm_codes_2027.txt")
+	if err := os.WriteFile(path, []byte("I10     Essential (primary) hypertension\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	codes, err := Load(SystemICD10CM, path)
+	if err != nil || len(codes) != 1 || codes[0].Code != "I10" {
+		t.Errorf("Load = %+v, %v", codes, err)
+	}
+	if _, err := Load("loinc", path); err == nil || !strings.Contains(err.Error(), "unknown code system") {
+		t.Errorf("Load of an unknown system = %v", err)
+	}
+	if _, err := Load(SystemICD10CM, filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
+
+func sameCodes(t *testing.T, got, want []models.TerminologyCode) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d codes, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("code %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
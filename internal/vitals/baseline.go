@@ -0,0 +1,78 @@
+package vitals
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// madScale makes the median absolute deviation comparable to a standard
+// deviation for normally distributed data (Iglewicz & Hoaglin).
+const madScale = 0.6745
+
+// Baseline is a robust summary of a patient's history for one metric.
+type Baseline struct {
+	Median  float64 `json:"median"`
+	MAD     float64 `json:"mad"`
+	Samples int     `json:"samples"`
+}
+
+// ComputeBaseline returns the median and median absolute deviation of values.
+// The MAD is floored at floor so a flat history still yields usable scores.
+func ComputeBaseline(values []float64, floor float64) Baseline {
+	if len(values) == 0 {
+		return Baseline{}
+	}
+
+	med := Median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - med)
+	}
+
+	return Baseline{
+		Median:  med,
+		MAD:     math.Max(Median(deviations), floor),
+		Samples: len(values),
+	}
+}
+
+// Score returns the modified z-score of v against the baseline. Scores beyond
+// about ±3.5 are conventionally treated as outliers.
+func (b Baseline) Score(v float64) float64 {
+	if b.MAD == 0 {
+		return 0
+	}
+	return madScale * (v - b.Median) / b.MAD
+}
+
+// Median returns the median of values without modifying the slice.
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// TimeOfDay buckets a timestamp into one of four six-hour periods so that, for
+// example, resting night-time heart rates aren't compared against daytime ones.
+func TimeOfDay(t time.Time) string {
+	switch h := t.Hour(); {
+	case h < 6:
+		return "night"
+	case h < 12:
+		return "morning"
+	case h < 18:
+		return "afternoon"
+	default:
+		return "evening"
+	}
+}
@@ -0,0 +1,97 @@
+package vitals
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{nil, 0},
+		{[]float64{7}, 7},
+		{[]float64{3, 1, 2}, 2},
+		{[]float64{4, 1, 3, 2}, 2.5},
+		{[]float64{70, 70, 70, 200}, 70},
+	}
+	for _, tt := range tests {
+		in := append([]float64(nil), tt.values...)
+		if got := Median(in); got != tt.want {
+			t.Errorf("Median(%v) = %v, want %v", tt.values, got, tt.want)
+		}
+		for i := range in {
+			if in[i] != tt.values[i] {
+				t.Errorf("Median reordered its input to %v", in)
+				break
+			}
+		}
+	}
+}
+
+func TestComputeBaseline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		floor  float64
+		want   Baseline
+	}{
+		{"empty", nil, 1, Baseline{}},
+		{"spread", []float64{60, 62, 64, 66, 100}, 1, Baseline{Median: 64, MAD: 2, Samples: 5}},
+		// One wild reading barely moves a robust baseline
+		{"outlier", []float64{70, 71, 72, 73, 250}, 1, Baseline{Median: 72, MAD: 1, Samples: 5}},
+		{"flat history is floored", []float64{36.8, 36.8, 36.8}, 0.1, Baseline{Median: 36.8, MAD: 0.1, Samples: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ComputeBaseline(tt.values, tt.floor); got != tt.want {
+				t.Errorf("ComputeBaseline = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaselineScore(t *testing.T) {
+	b := Baseline{Median: 64, MAD: 2, Samples: 20}
+	tests := []struct {
+		value, want float64
+	}{
+		{64, 0},
+		{66, madScale},
+		{62, -madScale},
+		{74.4, 3.5074},
+	}
+	for _, tt := range tests {
+		if got := b.Score(tt.value); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("Score(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	if got := (Baseline{Median: 64}).Score(100); got != 0 {
+		t.Errorf("Score without a spread = %v, want 0", got)
+	}
+}
+
+func TestTimeOfDay(t *testing.T) {
+	tests := []struct {
+		hour, minute int
+		want         string
+	}{
+		{0, 0, "night"},
+		{5, 59, "night"},
+		{6, 0, "morning"},
+		{11, 59, "morning"},
+		{12, 0, "afternoon"},
+		{17, 59, "afternoon"},
+		{18, 0, "evening"},
+		{23, 59, "evening"},
+	}
+	for _, tt := range tests {
+		at := time.Date(2026, 10, 18, tt.hour, tt.minute, 0, 0, time.UTC)
+		if got := TimeOfDay(at); got != tt.want {
+			t.Errorf("TimeOfDay(%02d:%02d) = %q, want %q", tt.hour, tt.minute, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package vitals
+
+// NEWS2 parameters, named after the metrics they are scored from.
+var NEWS2Metrics = []string{
+	"respiratory_rate", "spo2", "supplemental_oxygen", "systolic_bp", "heart_rate", "consciousness", "temperature",
+}
+
+// NEWS2 clinical risk levels, in increasing order of urgency.
+const (
+	RiskLow       = "low"
+	RiskLowMedium = "low-medium"
+	RiskMedium    = "medium"
+	RiskHigh      = "high"
+)
+
+// RiskRank orders risk levels so escalations can be detected by comparison.
+// Unknown levels rank below low.
+func RiskRank(risk string) int {
+	switch risk {
+	case RiskLow:
+		return 0
+	case RiskLowMedium:
+		return 1
+	case RiskMedium:
+		return 2
+	case RiskHigh:
+		return 3
+	}
+	return -1
+}
+
+// NEWS2Bands holds the aggregate scores at which a patient moves into the
+// medium and high risk levels. The RCP defaults are 5 and 7.
+type NEWS2Bands struct {
+	Medium int
+	High   int
+}
+
+var DefaultNEWS2Bands = NEWS2Bands{Medium: 5, High: 7}
+
+// NEWS2Result is an aggregate score together with the sub-score of each
+// parameter that was available. Missing lists parameters with no recent
+// reading; those contribute nothing, so a partial total is a lower bound.
+type NEWS2Result struct {
+	Total     int            `json:"total"`
+	SubScores map[string]int `json:"sub_scores"`
+	Missing   []string       `json:"missing,omitempty"`
+	Risk      string         `json:"risk"`
+}
+
+// ComputeNEWS2 scores the latest value of each parameter, keyed by metric
+// name. scale2 selects SpO2 scale 2 for patients with hypercapnic respiratory
+// failure whose target saturation is 88-92%.
+func ComputeNEWS2(values map[string]float64, scale2 bool, bands NEWS2Bands) NEWS2Result {
+	res := NEWS2Result{SubScores: make(map[string]int)}
+
+	onOxygen := values["supplemental_oxygen"] >= 1
+	for _, metric := range NEWS2Metrics {
+		v, ok := values[metric]
+		if !ok {
+			res.Missing = append(res.Missing, metric)
+			continue
+		}
+
+		var score int
+		switch metric {
+		case "respiratory_rate":
+			score = scoreRespiration(v)
+		case "spo2":
+			if scale2 {
+				score = scoreSpO2Scale2(v, onOxygen)
+			} else {
+				score = scoreSpO2Scale1(v)
+			}
+		case "supplemental_oxygen":
+			if onOxygen {
+				score = 2
+			}
+		case "systolic_bp":
+			score = scoreSystolic(v)
+		case "heart_rate":
+			score = scorePulse(v)
+		case "consciousness":
+			if v >= 1 {
+				score = 3
+			}
+		case "temperature":
+			score = scoreTemperature(v)
+		}
+		res.SubScores[metric] = score
+		res.Total += score
+	}
+
+	res.Risk = news2Risk(res, bands)
+	return res
+}
+
+func news2Risk(res NEWS2Result, bands NEWS2Bands) string {
+	switch {
+	case res.Total >= bands.High:
+		return RiskHigh
+	case res.Total >= bands.Medium:
+		return RiskMedium
+	}
+	// A score of 3 in any single parameter warrants an urgent review even
+	// when the aggregate is low
+	for _, s := range res.SubScores {
+		if s == 3 {
+			return RiskLowMedium
+		}
+	}
+	return RiskLow
+}
+
+func scoreRespiration(v float64) int {
+	switch {
+	case v <= 8:
+		return 3
+	case v <= 11:
+		return 1
+	case v <= 20:
+		return 0
+	case v <= 24:
+		return 2
+	}
+	return 3
+}
+
+func scoreSpO2Scale1(v float64) int {
+	switch {
+	case v <= 91:
+		return 3
+	case v <= 93:
+		return 2
+	case v <= 95:
+		return 1
+	}
+	return 0
+}
+
+func scoreSpO2Scale2(v float64, onOxygen bool) int {
+	switch {
+	case v <= 83:
+		return 3
+	case v <= 85:
+		return 2
+	case v <= 87:
+		return 1
+	case v <= 92 || !onOxygen:
+		return 0
+	case v <= 94:
+		return 1
+	case v <= 96:
+		return 2
+	}
+	return 3
+}
+
+func scoreSystolic(v float64) int {
+	switch {
+	case v <= 90:
+		return 3
+	case v <= 100:
+		return 2
+	case v <= 110:
+		return 1
+	case v <= 219:
+		return 0
+	}
+	return 3
+}
+
+func scorePulse(v float64) int {
+	switch {
+	case v <= 40:
+		return 3
+	case v <= 50:
+		return 1
+	case v <= 90:
+		return 0
+	case v <= 110:
+		return 1
+	case v <= 130:
+		return 2
+	}
+	return 3
+}
+
+func scoreTemperature(v float64) int {
+	switch {
+	case v <= 35.0:
+		return 3
+	case v <= 36.0:
+		return 1
+	case v <= 38.0:
+		return 0
+	case v <= 39.0:
+		return 1
+	}
+	return 2
+}
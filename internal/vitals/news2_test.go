@@ -0,0 +1,186 @@
+package vitals
+
+import (
+	"strings"
+	"testing"
+)
+
+// normal is a full set of readings that all score zero.
+func normal() map[string]float64 {
+	return map[string]float64{
+		"respiratory_rate": 16, "spo2": 97, "supplemental_oxygen": 0,
+		"systolic_bp": 120, "heart_rate": 70, "consciousness": 0, "temperature": 37,
+	}
+}
+
+func TestNEWS2SubScores(t *testing.T) {
+	tests := []struct {
+		metric string
+		value  float64
+		want   int
+	}{
+		{"respiratory_rate", 8, 3},
+		{"respiratory_rate", 9, 1},
+		{"respiratory_rate", 11, 1},
+		{"respiratory_rate", 12, 0},
+		{"respiratory_rate", 20, 0},
+		{"respiratory_rate", 21, 2},
+		{"respiratory_rate", 24, 2},
+		{"respiratory_rate", 25, 3},
+
+		{"spo2", 91, 3},
+		{"spo2", 92, 2},
+		{"spo2", 93, 2},
+		{"spo2", 94, 1},
+		{"spo2", 95, 1},
+		{"spo2", 96, 0},
+
+		{"supplemental_oxygen", 0, 0},
+		{"supplemental_oxygen", 1, 2},
+
+		{"systolic_bp", 90, 3},
+		{"systolic_bp", 91, 2},
+		{"systolic_bp", 100, 2},
+		{"systolic_bp", 101, 1},
+		{"systolic_bp", 110, 1},
+		{"systolic_bp", 111, 0},
+		{"systolic_bp", 219, 0},
+		{"systolic_bp", 220, 3},
+
+		{"heart_rate", 40, 3},
+		{"heart_rate", 41, 1},
+		{"heart_rate", 50, 1},
+		{"heart_rate", 51, 0},
+		{"heart_rate", 90, 0},
+		{"heart_rate", 91, 1},
+		{"heart_rate", 110, 1},
+		{"heart_rate", 111, 2},
+		{"heart_rate", 130, 2},
+		{"heart_rate", 131, 3},
+
+		{"consciousness", 0, 0},
+		{"consciousness", 1, 3},
+		{"consciousness", 4, 3},
+
+		{"temperature", 35.0, 3},
+		{"temperature", 35.1, 1},
+		{"temperature", 36.0, 1},
+		{"temperature", 36.1, 0},
+		{"temperature", 38.0, 0},
+		{"temperature", 38.1, 1},
+		{"temperature", 39.0, 1},
+		{"temperature", 39.1, 2},
+	}
+	for _, tt := range tests {
+		values := normal()
+		values[tt.metric] = tt.value
+		res := ComputeNEWS2(values, false, DefaultNEWS2Bands)
+		if got := res.SubScores[tt.metric]; got != tt.want || res.Total != tt.want {
+			t.Errorf("%s %v scored %d (total %d), want %d", tt.metric, tt.value, got, res.Total, tt.want)
+		}
+	}
+}
+
+func TestNEWS2SpO2Scale2(t *testing.T) {
+	tests := []struct {
+		spo2     float64
+		onOxygen bool
+		want     int
+	}{
+		{83, false, 3},
+		{84, false, 2},
+		{85, false, 2},
+		{86, false, 1},
+		{87, false, 1},
+		{88, false, 0},
+		{92, false, 0},
+		// High saturations only score while on oxygen
+		{97, false, 0},
+		{100, false, 0},
+		{92, true, 0},
+		{93, true, 1},
+		{94, true, 1},
+		{95, true, 2},
+		{96, true, 2},
+		{97, true, 3},
+	}
+	for _, tt := range tests {
+		values := normal()
+		values["spo2"] = tt.spo2
+		if tt.onOxygen {
+			values["supplemental_oxygen"] = 1
+		}
+		res := ComputeNEWS2(values, true, DefaultNEWS2Bands)
+		if got := res.SubScores["spo2"]; got != tt.want {
+			t.Errorf("scale 2 SpO2 %v (oxygen %v) scored %d, want %d", tt.spo2, tt.onOxygen, got, tt.want)
+		}
+	}
+
+	// Scale 1 scores the same saturation on oxygen as it would on air
+	values := normal()
+	values["supplemental_oxygen"] = 1
+	if got := ComputeNEWS2(values, false, DefaultNEWS2Bands).SubScores["spo2"]; got != 0 {
+		t.Errorf("scale 1 SpO2 97 on oxygen scored %d, want 0", got)
+	}
+}
+
+func TestNEWS2Risk(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes map[string]float64
+		bands   NEWS2Bands
+		total   int
+		want    string
+	}{
+		{"all normal", nil, DefaultNEWS2Bands, 0, RiskLow},
+		{"low aggregate", map[string]float64{"heart_rate": 95, "temperature": 38.5}, DefaultNEWS2Bands, 2, RiskLow},
+		{"single parameter of 3", map[string]float64{"consciousness": 1}, DefaultNEWS2Bands, 3, RiskLowMedium},
+		{"medium", map[string]float64{"respiratory_rate": 22, "heart_rate": 115, "temperature": 38.5}, DefaultNEWS2Bands, 5, RiskMedium},
+		{"medium including a 3", map[string]float64{"respiratory_rate": 26, "heart_rate": 120}, DefaultNEWS2Bands, 5, RiskMedium},
+		{"high", map[string]float64{"respiratory_rate": 26, "spo2": 90, "supplemental_oxygen": 1}, DefaultNEWS2Bands, 8, RiskHigh},
+		{"exactly high", map[string]float64{"respiratory_rate": 22, "heart_rate": 115, "systolic_bp": 95, "temperature": 38.5}, DefaultNEWS2Bands, 7, RiskHigh},
+		{"custom bands", map[string]float64{"respiratory_rate": 22, "heart_rate": 115}, NEWS2Bands{Medium: 3, High: 4}, 4, RiskHigh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := normal()
+			for k, v := range tt.changes {
+				values[k] = v
+			}
+			res := ComputeNEWS2(values, false, tt.bands)
+			if res.Total != tt.total || res.Risk != tt.want {
+				t.Errorf("total %d risk %q, want %d %q (sub-scores %v)", res.Total, res.Risk, tt.total, tt.want, res.SubScores)
+			}
+		})
+	}
+}
+
+func TestNEWS2Missing(t *testing.T) {
+	res := ComputeNEWS2(map[string]float64{"heart_rate": 135, "temperature": 39.5}, false, DefaultNEWS2Bands)
+	if res.Total != 5 || res.Risk != RiskMedium {
+		t.Errorf("total %d risk %q, want 5 medium", res.Total, res.Risk)
+	}
+	want := "respiratory_rate,spo2,supplemental_oxygen,systolic_bp,consciousness"
+	if got := strings.Join(res.Missing, ","); got != want {
+		t.Errorf("missing = %s, want %s", got, want)
+	}
+	if _, ok := res.SubScores["spo2"]; ok {
+		t.Error("a missing parameter has a sub-score")
+	}
+
+	if res := ComputeNEWS2(nil, false, DefaultNEWS2Bands); res.Total != 0 || res.Risk != RiskLow || len(res.Missing) != len(NEWS2Metrics) {
+		t.Errorf("no readings = %+v", res)
+	}
+	if res := ComputeNEWS2(normal(), false, DefaultNEWS2Bands); len(res.Missing) != 0 {
+		t.Errorf("full readings missing %v", res.Missing)
+	}
+}
+
+func TestRiskRank(t *testing.T) {
+	order := []string{"", RiskLow, RiskLowMedium, RiskMedium, RiskHigh}
+	for i := 1; i < len(order); i++ {
+		if RiskRank(order[i-1]) >= RiskRank(order[i]) {
+			t.Errorf("RiskRank(%q) isn't below RiskRank(%q)", order[i-1], order[i])
+		}
+	}
+}
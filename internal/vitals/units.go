@@ -0,0 +1,75 @@
+package vitals
+
+import (
+	"fmt"
+	"strings"
+)
+
+// conversion turns a value in some source unit into the metric's canonical unit.
+type conversion func(v float64) float64
+
+func identity(v float64) float64 { return v }
+
+// unitAliases maps the unit spellings used by other apps (Apple Health, UCUM in
+// FHIR, free-form CSV) onto a conversion into each metric's canonical unit.
+var unitAliases = map[string]map[string]conversion{
+	"heart_rate": {
+		"bpm": identity, "count/min": identity, "/min": identity, "{beats}/min": identity, "beats/min": identity,
+	},
+	"respiratory_rate": {
+		"breaths/min": identity, "count/min": identity, "/min": identity, "{breaths}/min": identity,
+	},
+	"spo2": {
+		"%": identity, "percent": identity,
+	},
+	"systolic_bp": {
+		"mmhg": identity, "mm[hg]": identity,
+	},
+	"diastolic_bp": {
+		"mmhg": identity, "mm[hg]": identity,
+	},
+	"temperature": {
+		"degc": identity, "cel": identity, "c": identity, "°c": identity,
+		"degf": fahrenheitToCelsius, "[degf]": fahrenheitToCelsius, "f": fahrenheitToCelsius, "°f": fahrenheitToCelsius,
+	},
+	"blood_glucose": {
+		"mg/dl": identity,
+		// Apple Health spells mmol/L with the molar mass of glucose embedded
+		"mmol/l": glucoseMmolToMg, "mmol<180.1558800000541>/l": glucoseMmolToMg,
+	},
+	"weight": {
+		"kg": identity,
+		"g":  func(v float64) float64 { return v / 1000 },
+		"lb": poundsToKilograms, "lbs": poundsToKilograms, "[lb_av]": poundsToKilograms,
+	},
+}
+
+func fahrenheitToCelsius(v float64) float64 { return (v - 32) * 5 / 9 }
+
+func poundsToKilograms(v float64) float64 { return v * 0.45359237 }
+
+func glucoseMmolToMg(v float64) float64 { return v * 18.0182 }
+
+// Normalize converts a reading into its metric's canonical unit and validates
+// the result. An empty unit is taken to mean the canonical unit.
+func Normalize(metric string, value float64, unit string) (float64, error) {
+	m, ok := Metrics[metric]
+	if !ok {
+		return 0, fmt.Errorf("unknown metric %q", metric)
+	}
+
+	if unit != "" && unit != m.Unit {
+		convert, ok := unitAliases[metric][strings.ToLower(strings.TrimSpace(unit))]
+		if !ok {
+			return 0, fmt.Errorf("unsupported unit %q for metric %q", unit, metric)
+		}
+		value = convert(value)
+	}
+
+	// Apple Health and some FHIR sources report saturation as a 0-1 fraction
+	if metric == "spo2" && value > 0 && value <= 1 {
+		value *= 100
+	}
+
+	return value, Validate(metric, value, "")
+}
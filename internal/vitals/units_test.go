@@ -0,0 +1,115 @@
+package vitals
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		metric string
+		value  float64
+		unit   string
+		want   float64
+	}{
+		{"heart_rate", 72, "", 72},
+		{"heart_rate", 72, "bpm", 72},
+		{"heart_rate", 72, "count/min", 72},
+		{"heart_rate", 72, " Count/Min ", 72},
+		{"respiratory_rate", 16, "{breaths}/min", 16},
+		{"spo2", 97, "%", 97},
+		// Saturation reported as a fraction
+		{"spo2", 0.97, "%", 97},
+		{"spo2", 1, "percent", 100},
+		{"systolic_bp", 120, "mm[Hg]", 120},
+		{"temperature", 37, "Cel", 37},
+		{"temperature", 98.6, "[degF]", 37},
+		{"temperature", 101.7, "°F", 38.72},
+		{"blood_glucose", 5.5, "mmol/L", 99.1},
+		{"blood_glucose", 5.5, "mmol<180.1558800000541>/L", 99.1},
+		{"weight", 154, "lb", 69.853},
+		{"weight", 70500, "g", 70.5},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.metric, tt.value, tt.unit)
+		if err != nil {
+			t.Errorf("Normalize(%s, %v, %q): %v", tt.metric, tt.value, tt.unit, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("Normalize(%s, %v, %q) = %v, want %v", tt.metric, tt.value, tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeErrors(t *testing.T) {
+	tests := []struct {
+		metric string
+		value  float64
+		unit   string
+	}{
+		{"steps", 1000, ""},
+		{"heart_rate", 72, "Hz"},
+		{"temperature", 310, "K"},
+		{"weight", 70, "stone"},
+		// Converted values are still checked against the sanity bounds
+		{"temperature", 150, "degF"},
+		{"blood_glucose", 80, "mmol/L"},
+		{"heart_rate", 400, "bpm"},
+		{"spo2", 0, "%"},
+	}
+	for _, tt := range tests {
+		if got, err := Normalize(tt.metric, tt.value, tt.unit); err == nil {
+			t.Errorf("Normalize(%s, %v, %q) = %v, want an error", tt.metric, tt.value, tt.unit, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		metric  string
+		value   float64
+		unit    string
+		wantErr bool
+	}{
+		{"heart_rate", 72, "", false},
+		{"heart_rate", 72, "bpm", false},
+		{"heart_rate", 10, "bpm", false},
+		{"heart_rate", 300, "bpm", false},
+		{"heart_rate", 9, "bpm", true},
+		{"heart_rate", 301, "bpm", true},
+		// Validate doesn't convert units
+		{"heart_rate", 72, "count/min", true},
+		{"temperature", 98.6, "degF", true},
+		{"consciousness", 4, "acvpu", false},
+		{"consciousness", 5, "", true},
+		{"supplemental_oxygen", 1, "bool", false},
+		{"steps", 1000, "", true},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.metric, tt.value, tt.unit); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%s, %v, %q) = %v, want error %v", tt.metric, tt.value, tt.unit, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	for name, m := range Metrics {
+		if m.Name != name {
+			t.Errorf("Metrics[%q] is named %q", name, m.Name)
+		}
+		if m.Min >= m.Max || m.Resolution <= 0 {
+			t.Errorf("Metrics[%q] has bounds %v-%v and resolution %v", name, m.Min, m.Max, m.Resolution)
+		}
+	}
+	for metric := range unitAliases {
+		if _, ok := Metrics[metric]; !ok {
+			t.Errorf("unit aliases for unknown metric %q", metric)
+		}
+	}
+	for _, metric := range NEWS2Metrics {
+		if _, ok := Metrics[metric]; !ok {
+			t.Errorf("NEWS2 parameter %q isn't a metric", metric)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package vitals
+
+import "fmt"
+
+// Metric describes a vital sign we accept readings for. Readings are always
+// stored in the metric's canonical Unit.
+type Metric struct {
+	Name string
+	Unit string
+	Min  float64
+	Max  float64
+	// Resolution is the smallest meaningful change, used as a floor for
+	// spread estimates so perfectly flat histories don't divide by zero.
+	Resolution float64
+	// Categorical metrics encode states as small integers and aren't suited
+	// to statistical baselines.
+	Categorical bool
+}
+
+// Metrics is the set of vital signs the platform understands, keyed by name.
+// Min and Max are physiological sanity bounds, not clinical thresholds.
+var Metrics = map[string]Metric{
+	"heart_rate":       {Name: "heart_rate", Unit: "bpm", Min: 10, Max: 300, Resolution: 1},
+	"respiratory_rate": {Name: "respiratory_rate", Unit: "breaths/min", Min: 1, Max: 80, Resolution: 1},
+	"spo2":             {Name: "spo2", Unit: "%", Min: 40, Max: 100, Resolution: 1},
+	"systolic_bp":      {Name: "systolic_bp", Unit: "mmHg", Min: 40, Max: 300, Resolution: 1},
+	"diastolic_bp":     {Name: "diastolic_bp", Unit: "mmHg", Min: 20, Max: 200, Resolution: 1},
+	"temperature":      {Name: "temperature", Unit: "degC", Min: 25, Max: 45, Resolution: 0.1},
+	"blood_glucose":    {Name: "blood_glucose", Unit: "mg/dL", Min: 10, Max: 1000, Resolution: 1},
+	"weight":           {Name: "weight", Unit: "kg", Min: 0.5, Max: 500, Resolution: 0.1},
+	// ACVPU: 0 alert, 1 new confusion, 2 responds to voice, 3 to pain, 4 unresponsive
+	"consciousness": {Name: "consciousness", Unit: "acvpu", Min: 0, Max: 4, Resolution: 1, Categorical: true},
+	// 1 while the patient is on supplemental oxygen, 0 on room air
+	"supplemental_oxygen": {Name: "supplemental_oxygen", Unit: "bool", Min: 0, Max: 1, Resolution: 1, Categorical: true},
+}
+
+// Validate checks that a reading refers to a known metric, is expressed in the
+// metric's canonical unit and falls within its sanity bounds. An empty unit is
+// taken to mean the canonical unit.
+func Validate(metric string, value float64, unit string) error {
+	m, ok := Metrics[metric]
+	if !ok {
+		return fmt.Errorf("unknown metric %q", metric)
+	}
+	if unit != "" && unit != m.Unit {
+		return fmt.Errorf("metric %q must be reported in %s, got %q", metric, m.Unit, unit)
+	}
+	if value < m.Min || value > m.Max {
+		return fmt.Errorf("value %v for %q is outside the plausible range %v-%v", value, metric, m.Min, m.Max)
+	}
+	return nil
+}
@@ -0,0 +1,4 @@
+-- Drop tables in reverse order
+DROP TABLE IF EXISTS vital_readings;
+DROP TABLE IF EXISTS device_pairing_codes;
+DROP TABLE IF EXISTS devices;
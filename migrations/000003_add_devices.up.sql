@@ -0,0 +1,40 @@
+-- Create the devices table
+CREATE TABLE IF NOT EXISTS devices (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id),
+    serial VARCHAR(100) NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    device_type VARCHAR(50) NOT NULL, -- e.g., 'bp_monitor', 'pulse_oximeter'
+    credential_hash VARCHAR(64) UNIQUE NOT NULL,
+    paired_at TIMESTAMPTZ DEFAULT now(),
+    last_seen_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+-- A physical device can only be actively paired to one patient at a time
+CREATE UNIQUE INDEX IF NOT EXISTS devices_active_serial_idx ON devices (serial) WHERE revoked_at IS NULL;
+
+-- Create the short-lived pairing codes table
+CREATE TABLE IF NOT EXISTS device_pairing_codes (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id),
+    code_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+-- Create the vital readings table
+CREATE TABLE IF NOT EXISTS vital_readings (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id),
+    device_id INT REFERENCES devices(id),
+    metric VARCHAR(50) NOT NULL, -- e.g., 'heart_rate', 'spo2'
+    value DOUBLE PRECISION NOT NULL,
+    unit VARCHAR(20) NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL,
+    source VARCHAR(20) NOT NULL DEFAULT 'device', -- e.g., 'device', 'manual'
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS vital_readings_patient_metric_idx ON vital_readings (patient_id, metric, recorded_at DESC);
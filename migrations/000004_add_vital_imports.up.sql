@@ -0,0 +1,19 @@
+-- Create the vital import jobs table
+CREATE TABLE IF NOT EXISTS vital_import_jobs (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id),
+    format VARCHAR(20) NOT NULL, -- e.g., 'csv', 'apple_health', 'fhir'
+    file_name VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- e.g., 'pending', 'running', 'completed', 'failed'
+    bytes_total BIGINT NOT NULL DEFAULT 0,
+    bytes_processed BIGINT NOT NULL DEFAULT 0,
+    imported INT NOT NULL DEFAULT 0,
+    duplicates INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    errors JSONB NOT NULL DEFAULT '[]',
+    error_message TEXT,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS vital_import_jobs_patient_idx ON vital_import_jobs (patient_id, created_at DESC);
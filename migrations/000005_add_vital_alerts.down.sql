@@ -0,0 +1 @@
+DROP TABLE IF EXISTS vital_alerts;
@@ -0,0 +1,22 @@
+-- Create the vital alerts table
+CREATE TABLE IF NOT EXISTS vital_alerts (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id),
+    metric VARCHAR(50) NOT NULL,
+    kind VARCHAR(20) NOT NULL, -- e.g., 'anomaly'
+    severity VARCHAR(20) NOT NULL DEFAULT 'soft', -- e.g., 'soft'
+    time_of_day VARCHAR(20),
+    observed_value DOUBLE PRECISION,
+    baseline_median DOUBLE PRECISION,
+    baseline_mad DOUBLE PRECISION,
+    baseline_samples INT,
+    score DOUBLE PRECISION,
+    window_start TIMESTAMPTZ NOT NULL,
+    window_end TIMESTAMPTZ NOT NULL,
+    evidence JSONB NOT NULL DEFAULT '[]', -- the readings in the evidence window
+    created_at TIMESTAMPTZ DEFAULT now(),
+    acknowledged_at TIMESTAMPTZ,
+    acknowledged_by INT REFERENCES doctors(id)
+);
+
+CREATE INDEX IF NOT EXISTS vital_alerts_patient_idx ON vital_alerts (patient_id, created_at DESC);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS news2_scores;
+
+ALTER TABLE patients
+DROP COLUMN news2_spo2_scale;
@@ -0,0 +1,24 @@
+-- SpO2 scale 2 is used for patients with hypercapnic respiratory failure
+ALTER TABLE patients
+ADD COLUMN news2_spo2_scale SMALLINT NOT NULL DEFAULT 1;
+
+-- Create the NEWS2 scores table
+CREATE TABLE IF NOT EXISTS news2_scores (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id),
+    total INT NOT NULL,
+    risk VARCHAR(20) NOT NULL, -- e.g., 'low', 'low-medium', 'medium', 'high'
+    respiratory_rate_score INT,
+    spo2_score INT,
+    supplemental_oxygen_score INT,
+    systolic_bp_score INT,
+    heart_rate_score INT,
+    consciousness_score INT,
+    temperature_score INT,
+    spo2_scale SMALLINT NOT NULL DEFAULT 1,
+    complete BOOLEAN NOT NULL, -- false if any parameter had no recent reading
+    inputs JSONB NOT NULL DEFAULT '{}', -- the readings that were scored
+    computed_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS news2_scores_patient_idx ON news2_scores (patient_id, computed_at DESC);
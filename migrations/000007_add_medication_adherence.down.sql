@@ -0,0 +1,3 @@
+-- Drop tables in reverse order
+DROP TABLE IF EXISTS medication_doses;
+DROP TABLE IF EXISTS prescription_schedules;
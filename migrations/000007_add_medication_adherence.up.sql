@@ -0,0 +1,24 @@
+-- Create the dosing schedules table
+CREATE TABLE IF NOT EXISTS prescription_schedules (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    prescription_id INT UNIQUE NOT NULL REFERENCES prescriptions(id),
+    times JSONB NOT NULL, -- local times of day, e.g., ["08:00", "20:00"]
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    start_date DATE NOT NULL,
+    end_date DATE, -- NULL for ongoing medication
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+-- Create the medication doses table
+CREATE TABLE IF NOT EXISTS medication_doses (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    prescription_id INT NOT NULL REFERENCES prescriptions(id),
+    patient_id INT NOT NULL REFERENCES patients(id),
+    scheduled_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'due', -- e.g., 'due', 'taken', 'late', 'skipped', 'missed'
+    logged_at TIMESTAMPTZ,
+    note TEXT,
+    UNIQUE (prescription_id, scheduled_at)
+);
+
+CREATE INDEX IF NOT EXISTS medication_doses_patient_idx ON medication_doses (patient_id, scheduled_at);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS prescription_items;
@@ -0,0 +1,18 @@
+-- Create the prescription line items table
+CREATE TABLE IF NOT EXISTS prescription_items (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    prescription_id INT NOT NULL REFERENCES prescriptions(id),
+    position INT NOT NULL, -- order of the item on the prescription
+    drug_name VARCHAR(255) NOT NULL,
+    strength VARCHAR(100), -- e.g., '500 mg'
+    form VARCHAR(50), -- e.g., 'tablet', 'capsule'
+    route VARCHAR(50), -- e.g., 'oral', 'topical'
+    dose VARCHAR(100) NOT NULL, -- e.g., '1 tablet'
+    frequency VARCHAR(100) NOT NULL, -- e.g., 'twice daily'
+    duration VARCHAR(100), -- e.g., '7 days'
+    quantity INT NOT NULL DEFAULT 0,
+    refills INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS prescription_items_prescription_idx ON prescription_items (prescription_id);
+CREATE INDEX IF NOT EXISTS prescription_items_drug_name_idx ON prescription_items (lower(drug_name));
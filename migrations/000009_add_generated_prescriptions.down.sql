@@ -0,0 +1,3 @@
+ALTER TABLE prescriptions
+DROP COLUMN verification_code,
+DROP COLUMN source;
@@ -0,0 +1,4 @@
+-- Track how a prescription file was produced and the code printed on it for verification
+ALTER TABLE prescriptions
+ADD COLUMN source VARCHAR(20) NOT NULL DEFAULT 'upload', -- e.g., 'upload', 'generated'
+ADD COLUMN verification_code VARCHAR(32) UNIQUE;
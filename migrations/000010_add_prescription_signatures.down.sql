@@ -0,0 +1,6 @@
+ALTER TABLE prescriptions
+DROP COLUMN revoked_at,
+DROP COLUMN expires_at,
+DROP COLUMN signing_key_id,
+DROP COLUMN signature,
+DROP COLUMN content_hash;
@@ -0,0 +1,7 @@
+-- Tamper-evidence for issued prescriptions
+ALTER TABLE prescriptions
+ADD COLUMN content_hash VARCHAR(64), -- hex SHA-256 of the stored file
+ADD COLUMN signature TEXT, -- base64 Ed25519 signature over the code, doctor, patient, hash and expiry
+ADD COLUMN signing_key_id VARCHAR(32),
+ADD COLUMN expires_at TIMESTAMPTZ,
+ADD COLUMN revoked_at TIMESTAMPTZ;
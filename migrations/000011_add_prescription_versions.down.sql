@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_prescriptions_previous_id;
+
+ALTER TABLE prescriptions
+DROP COLUMN revocation_reason,
+DROP COLUMN amendment_reason,
+DROP COLUMN previous_id,
+DROP COLUMN version,
+DROP COLUMN status;
@@ -0,0 +1,10 @@
+-- Amendments create a new version linked to the one they replace; nothing is deleted
+ALTER TABLE prescriptions
+ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active', -- e.g., 'active', 'superseded', 'revoked'
+ADD COLUMN version INT NOT NULL DEFAULT 1,
+ADD COLUMN previous_id INT REFERENCES prescriptions(id) ON DELETE RESTRICT,
+ADD COLUMN amendment_reason TEXT,
+ADD COLUMN revocation_reason TEXT;
+
+-- A version can only be amended once, so history stays a straight line
+CREATE UNIQUE INDEX idx_prescriptions_previous_id ON prescriptions(previous_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS refill_requests;
+DROP TABLE IF EXISTS notifications;
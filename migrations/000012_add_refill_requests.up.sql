@@ -0,0 +1,33 @@
+-- In-app notifications for patients and doctors
+CREATE TABLE IF NOT EXISTS notifications (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    recipient_role VARCHAR(20) NOT NULL, -- e.g., 'patient', 'doctor'
+    recipient_id INT NOT NULL,
+    kind VARCHAR(50) NOT NULL, -- e.g., 'refill_requested', 'refill_approved'
+    title VARCHAR(255) NOT NULL,
+    body TEXT,
+    data JSONB NOT NULL DEFAULT '{}', -- ids of the records the notification is about
+    created_at TIMESTAMPTZ DEFAULT now(),
+    read_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS notifications_recipient_idx ON notifications (recipient_role, recipient_id, created_at DESC);
+
+-- Patient requests for a repeat of an existing prescription
+CREATE TABLE IF NOT EXISTS refill_requests (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    prescription_id INT NOT NULL REFERENCES prescriptions(id),
+    patient_id INT NOT NULL REFERENCES patients(id),
+    doctor_id INT NOT NULL REFERENCES doctors(id), -- the issuing doctor, whose queue it lands in
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- e.g., 'pending', 'approved', 'denied', 'visit_requested'
+    patient_note TEXT,
+    response_reason TEXT,
+    new_prescription_id INT REFERENCES prescriptions(id), -- set when approved
+    appointment_id INT REFERENCES appointments(id), -- set when a visit was requested
+    created_at TIMESTAMPTZ DEFAULT now(),
+    resolved_at TIMESTAMPTZ
+);
+
+-- Only one open request per prescription
+CREATE UNIQUE INDEX IF NOT EXISTS refill_requests_pending_idx ON refill_requests (prescription_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS refill_requests_doctor_idx ON refill_requests (doctor_id, status, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS prescription_overrides;
+DROP TABLE IF EXISTS patient_allergies;
@@ -0,0 +1,23 @@
+-- Patient allergies, checked against the formulary when prescribing
+CREATE TABLE IF NOT EXISTS patient_allergies (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    substance VARCHAR(255) NOT NULL, -- a drug or class, e.g., 'penicillin'
+    reaction VARCHAR(255),
+    severity VARCHAR(20), -- e.g., 'mild', 'moderate', 'severe'
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS patient_allergies_patient_idx ON patient_allergies (patient_id);
+
+-- Doctors going ahead with a prescription despite formulary warnings
+CREATE TABLE IF NOT EXISTS prescription_overrides (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    prescription_id INT NOT NULL REFERENCES prescriptions(id) ON DELETE CASCADE,
+    doctor_id INT NOT NULL REFERENCES doctors(id),
+    reason TEXT NOT NULL,
+    warnings JSONB NOT NULL, -- the warnings that were overridden
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS prescription_overrides_prescription_idx ON prescription_overrides (prescription_id);
@@ -0,0 +1,15 @@
+-- Prescription files uploaded straight to storage through a presigned URL,
+-- recorded as prescriptions once the doctor confirms them
+CREATE TABLE IF NOT EXISTS prescription_uploads (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    doctor_id INT NOT NULL REFERENCES doctors(id),
+    patient_id INT NOT NULL REFERENCES patients(id),
+    file_name VARCHAR(255) NOT NULL UNIQUE, -- the storage key the client uploads to
+    content_type VARCHAR(100) NOT NULL, -- e.g., 'application/pdf'
+    size BIGINT NOT NULL, -- declared size in bytes; the upload URL only accepts exactly this
+    created_at TIMESTAMPTZ DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL, -- unconfirmed uploads are deleted after this
+    prescription_id INT REFERENCES prescriptions(id) -- set once confirmed
+);
+
+CREATE INDEX IF NOT EXISTS prescription_uploads_pending_idx ON prescription_uploads (expires_at) WHERE prescription_id IS NULL;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS prescriptions_scan_pending_idx;
+
+ALTER TABLE prescriptions
+    DROP COLUMN IF EXISTS scan_status,
+    DROP COLUMN IF EXISTS scan_threat,
+    DROP COLUMN IF EXISTS scanned_at;
@@ -0,0 +1,9 @@
+-- Malware scan state of each prescription file. Files uploaded while scanning
+-- runs in the background stay quarantined (unavailable for download) until
+-- they come back clean.
+ALTER TABLE prescriptions
+    ADD COLUMN IF NOT EXISTS scan_status VARCHAR(20) NOT NULL DEFAULT 'not_scanned', -- e.g., 'not_scanned', 'pending', 'clean', 'infected'
+    ADD COLUMN IF NOT EXISTS scan_threat VARCHAR(255), -- what the scanner found, when infected
+    ADD COLUMN IF NOT EXISTS scanned_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS prescriptions_scan_pending_idx ON prescriptions (created_at) WHERE scan_status = 'pending';
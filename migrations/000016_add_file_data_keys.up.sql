@@ -0,0 +1,11 @@
+-- Wrapped data keys of encrypted stored files. A file without a row here was
+-- stored before encryption was turned on and is read as-is.
+CREATE TABLE IF NOT EXISTS file_data_keys (
+    object_key VARCHAR(255) PRIMARY KEY, -- the file's storage key
+    master_key_id VARCHAR(100) NOT NULL, -- the master key wrapped_key is encrypted with
+    wrapped_key BYTEA NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    rewrapped_at TIMESTAMPTZ -- last time the data key moved to a new master key
+);
+
+CREATE INDEX IF NOT EXISTS file_data_keys_master_key_idx ON file_data_keys (master_key_id);
@@ -0,0 +1,32 @@
+-- Medical documents patients keep in their vault, besides prescriptions
+CREATE TABLE IF NOT EXISTS patient_documents (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id),
+    category VARCHAR(30) NOT NULL, -- e.g., 'lab_report', 'imaging_report', 'discharge_summary', 'insurance_card', 'other'
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    tags JSONB NOT NULL DEFAULT '[]', -- lower-case labels chosen by the patient
+    document_date DATE, -- when the document was issued, as opposed to uploaded
+    file_name VARCHAR(255) NOT NULL UNIQUE, -- storage key
+    original_name VARCHAR(255), -- name of the file the patient uploaded
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    scan_status VARCHAR(20) NOT NULL DEFAULT 'not_scanned', -- as on prescriptions
+    scan_threat VARCHAR(255),
+    scanned_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS patient_documents_patient_idx ON patient_documents (patient_id, category, document_date);
+CREATE INDEX IF NOT EXISTS patient_documents_tags_idx ON patient_documents USING GIN (tags);
+CREATE INDEX IF NOT EXISTS patient_documents_scan_pending_idx ON patient_documents (created_at) WHERE scan_status = 'pending';
+
+-- Doctors a patient has chosen to show a document to
+CREATE TABLE IF NOT EXISTS document_shares (
+    document_id INT NOT NULL REFERENCES patient_documents(id) ON DELETE CASCADE,
+    doctor_id INT NOT NULL REFERENCES doctors(id),
+    created_at TIMESTAMPTZ DEFAULT now(),
+    PRIMARY KEY (document_id, doctor_id)
+);
+
+CREATE INDEX IF NOT EXISTS document_shares_doctor_idx ON document_shares (doctor_id);
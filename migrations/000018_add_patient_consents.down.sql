@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS patient_consents;
+DROP INDEX IF EXISTS doctors_clinic_idx;
+ALTER TABLE doctors DROP COLUMN IF EXISTS clinic_id;
+DROP TABLE IF EXISTS clinics;
@@ -0,0 +1,30 @@
+-- Practices doctors belong to, so a patient can grant access to all of them at once
+CREATE TABLE IF NOT EXISTS clinics (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    address TEXT,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+ALTER TABLE doctors ADD COLUMN IF NOT EXISTS clinic_id INT REFERENCES clinics(id);
+
+CREATE INDEX IF NOT EXISTS doctors_clinic_idx ON doctors (clinic_id);
+
+-- Access a patient has given a doctor or a whole clinic to part of their record
+CREATE TABLE IF NOT EXISTS patient_consents (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id),
+    doctor_id INT REFERENCES doctors(id),
+    clinic_id INT REFERENCES clinics(id),
+    category VARCHAR(30) NOT NULL, -- e.g., 'prescriptions', 'appointments', 'allergies', 'vitals', 'documents'
+    granted_at TIMESTAMPTZ DEFAULT now(),
+    expires_at TIMESTAMPTZ, -- NULL to keep access until revoked
+    revoked_at TIMESTAMPTZ,
+    CHECK ((doctor_id IS NULL) <> (clinic_id IS NULL))
+);
+
+-- At most one unrevoked grant per grantee and category; granting again replaces it
+CREATE UNIQUE INDEX IF NOT EXISTS patient_consents_doctor_active_idx
+    ON patient_consents (patient_id, doctor_id, category) WHERE revoked_at IS NULL AND doctor_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS patient_consents_clinic_active_idx
+    ON patient_consents (patient_id, clinic_id, category) WHERE revoked_at IS NULL AND clinic_id IS NOT NULL;
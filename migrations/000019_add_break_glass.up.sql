@@ -0,0 +1,30 @@
+-- Staff who review emergency access; those without a clinic oversee every clinic
+CREATE TABLE IF NOT EXISTS admins (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    firstName VARCHAR(50) NOT NULL,
+    lastName VARCHAR(50) NOT NULL,
+    hashedPassword VARCHAR(255) NOT NULL,
+    email VARCHAR(100) UNIQUE NOT NULL,
+    clinic_id INT REFERENCES clinics(id),
+    createdAt TIMESTAMPTZ DEFAULT now()
+);
+
+-- Emergency access a doctor took to a patient's whole record without consent
+CREATE TABLE IF NOT EXISTS break_glass_grants (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    doctor_id INT NOT NULL REFERENCES doctors(id),
+    patient_id INT NOT NULL REFERENCES patients(id),
+    justification TEXT NOT NULL,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    granted_at TIMESTAMPTZ DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    reviewed_at TIMESTAMPTZ,
+    reviewed_by INT REFERENCES admins(id),
+    review_outcome VARCHAR(20), -- e.g., 'justified', 'unjustified'
+    review_note TEXT
+);
+
+CREATE INDEX IF NOT EXISTS break_glass_grants_access_idx ON break_glass_grants (doctor_id, patient_id, expires_at);
+CREATE INDEX IF NOT EXISTS break_glass_grants_patient_idx ON break_glass_grants (patient_id, granted_at DESC);
+CREATE INDEX IF NOT EXISTS break_glass_grants_review_idx ON break_glass_grants (granted_at) WHERE reviewed_at IS NULL;
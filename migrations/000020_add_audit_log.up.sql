@@ -0,0 +1,34 @@
+-- Every read and write of patient data. Rows can only be added, and each
+-- one's hash covers the previous hash, so edits to past rows are detectable.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    actor_role VARCHAR(20) NOT NULL, -- e.g., 'patient', 'doctor', 'admin', 'device'
+    actor_id INT NOT NULL,
+    patient_id INT, -- whose data was touched, when known
+    resource VARCHAR(50) NOT NULL, -- e.g., 'prescriptions', 'vitals', 'documents'
+    resource_id VARCHAR(255),
+    action VARCHAR(10) NOT NULL, -- 'read', 'create', 'update' or 'delete'
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    status_code INT NOT NULL,
+    outcome VARCHAR(10) NOT NULL, -- 'success', 'denied', 'failed' or 'error'
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    prev_hash BYTEA NOT NULL,
+    hash BYTEA NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_patient_idx ON audit_log (patient_id, id DESC);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor_role, actor_id, id DESC);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_change BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
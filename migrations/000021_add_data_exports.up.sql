@@ -0,0 +1,19 @@
+-- Archives of everything held about a patient, built in the background on request
+CREATE TABLE IF NOT EXISTS data_exports (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'running', 'ready', 'failed' or 'expired'
+    file_name VARCHAR(255), -- storage key of the ZIP once ready
+    size BIGINT,
+    error TEXT,
+    link_token_hash VARCHAR(64) UNIQUE, -- SHA-256 of the current download link's token
+    link_expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ -- when the archive is deleted
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_patient_idx ON data_exports (patient_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS data_exports_pending_idx ON data_exports (created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS data_exports_expiry_idx ON data_exports (expires_at) WHERE status = 'ready';
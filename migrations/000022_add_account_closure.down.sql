@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS patients_closed_idx;
+
+ALTER TABLE patients
+DROP COLUMN IF EXISTS records_purged_at,
+DROP COLUMN IF EXISTS data_purged_at,
+DROP COLUMN IF EXISTS closed_at;
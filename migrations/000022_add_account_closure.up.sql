@@ -0,0 +1,8 @@
+-- Closed accounts are pseudonymized straight away; their remaining data is
+-- purged in stages once each retention period has passed
+ALTER TABLE patients
+ADD COLUMN closed_at TIMESTAMPTZ,
+ADD COLUMN data_purged_at TIMESTAMPTZ, -- vitals, devices, documents and exports
+ADD COLUMN records_purged_at TIMESTAMPTZ; -- appointments, prescriptions and the rest of the clinical record
+
+CREATE INDEX IF NOT EXISTS patients_closed_idx ON patients (closed_at) WHERE closed_at IS NOT NULL;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS patient_medications;
+DROP TABLE IF EXISTS patient_conditions;
+
+ALTER TABLE patient_allergies
+DROP COLUMN IF EXISTS updated_at,
+DROP COLUMN IF EXISTS recorded_by,
+DROP COLUMN IF EXISTS source;
@@ -0,0 +1,44 @@
+-- Who recorded each chart entry: the patient themselves, or a doctor
+ALTER TABLE patient_allergies
+ADD COLUMN source VARCHAR(10), -- 'patient' or 'doctor'; NULL for entries recorded before this was tracked
+ADD COLUMN recorded_by INT REFERENCES doctors(id),
+ADD COLUMN updated_at TIMESTAMPTZ DEFAULT now();
+
+-- The problem list
+CREATE TABLE IF NOT EXISTS patient_conditions (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id),
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(20), -- e.g., an ICD-10 code
+    onset_date DATE,
+    resolved_date DATE, -- NULL while the condition is active
+    notes TEXT,
+    source VARCHAR(10) NOT NULL, -- 'patient' or 'doctor'
+    recorded_by INT REFERENCES doctors(id),
+    created_at TIMESTAMPTZ DEFAULT now(),
+    updated_at TIMESTAMPTZ DEFAULT now(),
+    CHECK (resolved_date IS NULL OR onset_date IS NULL OR resolved_date >= onset_date)
+);
+
+CREATE INDEX IF NOT EXISTS patient_conditions_patient_idx ON patient_conditions (patient_id);
+
+-- Medications taken outside vital-watch prescriptions, e.g., over the counter
+-- or prescribed elsewhere
+CREATE TABLE IF NOT EXISTS patient_medications (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id),
+    name VARCHAR(255) NOT NULL,
+    dose VARCHAR(100),
+    frequency VARCHAR(100),
+    route VARCHAR(50),
+    started_on DATE,
+    stopped_on DATE, -- NULL while the patient is still taking it
+    notes TEXT,
+    source VARCHAR(10) NOT NULL, -- 'patient' or 'doctor'
+    recorded_by INT REFERENCES doctors(id),
+    created_at TIMESTAMPTZ DEFAULT now(),
+    updated_at TIMESTAMPTZ DEFAULT now(),
+    CHECK (stopped_on IS NULL OR started_on IS NULL OR stopped_on >= started_on)
+);
+
+CREATE INDEX IF NOT EXISTS patient_medications_patient_idx ON patient_medications (patient_id);
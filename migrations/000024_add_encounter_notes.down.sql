@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS encounter_note_amendments;
+DROP TABLE IF EXISTS encounter_notes;
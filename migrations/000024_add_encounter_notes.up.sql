@@ -0,0 +1,37 @@
+-- Clinical notes on appointments, in SOAP form. A note is editable while it
+-- is a draft and locked once signed; changes after that are amendments.
+CREATE TABLE IF NOT EXISTS encounter_notes (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    appointment_id INT NOT NULL UNIQUE REFERENCES appointments(id),
+    patient_id INT NOT NULL REFERENCES patients(id),
+    doctor_id INT NOT NULL REFERENCES doctors(id),
+    subjective TEXT,
+    objective TEXT,
+    assessment TEXT,
+    plan TEXT,
+    diagnoses JSONB NOT NULL DEFAULT '[]', -- e.g., [{"code": "J45.909", "description": "Asthma"}]
+    status VARCHAR(10) NOT NULL DEFAULT 'draft', -- 'draft' or 'signed'
+    signed_at TIMESTAMPTZ,
+    amended_at TIMESTAMPTZ, -- when the note was last amended after signing
+    created_at TIMESTAMPTZ DEFAULT now(),
+    updated_at TIMESTAMPTZ DEFAULT now(),
+    CHECK ((status = 'signed') = (signed_at IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS encounter_notes_patient_idx ON encounter_notes (patient_id);
+
+-- Every amendment to a signed note, with the note as it read before it
+CREATE TABLE IF NOT EXISTS encounter_note_amendments (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    note_id INT NOT NULL REFERENCES encounter_notes(id),
+    doctor_id INT NOT NULL REFERENCES doctors(id),
+    reason TEXT NOT NULL,
+    subjective TEXT,
+    objective TEXT,
+    assessment TEXT,
+    plan TEXT,
+    diagnoses JSONB NOT NULL,
+    amended_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS encounter_note_amendments_note_idx ON encounter_note_amendments (note_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS doctor_code_favourites;
+DROP TABLE IF EXISTS terminology_codes;
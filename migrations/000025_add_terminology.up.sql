@@ -0,0 +1,25 @@
+-- Diagnosis code systems, loaded with `main import-terminology`
+CREATE TABLE IF NOT EXISTS terminology_codes (
+    system VARCHAR(20) NOT NULL, -- 'icd10cm' or 'snomedct'
+    code VARCHAR(20) NOT NULL, -- as written, e.g., 'E11.65'
+    search_code VARCHAR(20) NOT NULL, -- without punctuation, e.g., 'E1165'
+    description TEXT NOT NULL,
+    billable BOOLEAN NOT NULL DEFAULT TRUE, -- false for ICD-10-CM category headers, which are too broad to code with
+    active BOOLEAN NOT NULL DEFAULT TRUE, -- false once a newer release has dropped the code
+    search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', description)) STORED,
+    imported_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (system, code)
+);
+
+CREATE INDEX IF NOT EXISTS terminology_codes_search_code_idx ON terminology_codes (search_code text_pattern_ops);
+CREATE INDEX IF NOT EXISTS terminology_codes_search_vector_idx ON terminology_codes USING GIN (search_vector);
+
+-- Codes each doctor uses often, which rank first in their searches
+CREATE TABLE IF NOT EXISTS doctor_code_favourites (
+    doctor_id INT NOT NULL REFERENCES doctors(id),
+    system VARCHAR(20) NOT NULL,
+    code VARCHAR(20) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    PRIMARY KEY (doctor_id, system, code),
+    FOREIGN KEY (system, code) REFERENCES terminology_codes (system, code) ON DELETE CASCADE
+);
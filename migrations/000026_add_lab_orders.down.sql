@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS integration_api_keys;
+DROP TABLE IF EXISTS lab_reports;
+DROP TABLE IF EXISTS lab_results;
+DROP TABLE IF EXISTS lab_orders;
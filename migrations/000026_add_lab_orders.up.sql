@@ -0,0 +1,71 @@
+-- Lab tests doctors order for patients, from the panels in the lab catalogue
+CREATE TABLE IF NOT EXISTS lab_orders (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY, -- the placer order number labs send results back with
+    patient_id INT NOT NULL REFERENCES patients(id),
+    doctor_id INT NOT NULL REFERENCES doctors(id),
+    appointment_id INT REFERENCES appointments(id),
+    panels JSONB NOT NULL, -- e.g., [{"code": "58410-2", "name": "Complete blood count (CBC)"}]
+    expected_analytes JSONB NOT NULL, -- LOINC codes of the panels' analytes, e.g., ["6690-2", "718-7"]
+    priority VARCHAR(10) NOT NULL DEFAULT 'routine', -- 'routine' or 'urgent'
+    notes TEXT,
+    status VARCHAR(10) NOT NULL DEFAULT 'ordered', -- 'ordered', 'partial', 'resulted' or 'cancelled'
+    created_at TIMESTAMPTZ DEFAULT now(),
+    resulted_at TIMESTAMPTZ,
+    cancelled_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS lab_orders_patient_idx ON lab_orders (patient_id, created_at DESC);
+
+-- One result per analyte per order; a corrected result replaces the earlier one
+CREATE TABLE IF NOT EXISTS lab_results (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES lab_orders(id),
+    patient_id INT NOT NULL REFERENCES patients(id),
+    analyte_code VARCHAR(20) NOT NULL, -- LOINC
+    analyte_name VARCHAR(255) NOT NULL,
+    value DOUBLE PRECISION, -- numeric results
+    value_text TEXT, -- anything else, e.g., 'Negative' or '<5'
+    unit VARCHAR(30),
+    ref_low DOUBLE PRECISION,
+    ref_high DOUBLE PRECISION,
+    ref_text VARCHAR(100), -- the range as the lab wrote it
+    flag VARCHAR(5), -- HL7 abnormal flag, e.g., 'N', 'H', 'LL'
+    status VARCHAR(15) NOT NULL, -- 'preliminary', 'final' or 'corrected'
+    source VARCHAR(10) NOT NULL, -- 'manual' or 'hl7'
+    observed_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    updated_at TIMESTAMPTZ DEFAULT now(),
+    UNIQUE (order_id, analyte_code),
+    CHECK (value IS NOT NULL OR value_text IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS lab_results_trend_idx ON lab_results (patient_id, analyte_code, observed_at);
+
+-- Report files, usually PDFs, the lab sent for an order
+CREATE TABLE IF NOT EXISTS lab_reports (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES lab_orders(id),
+    patient_id INT NOT NULL REFERENCES patients(id),
+    file_name VARCHAR(255) NOT NULL UNIQUE, -- storage key
+    original_name VARCHAR(255),
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    scan_status VARCHAR(20) NOT NULL DEFAULT 'not_scanned', -- as on prescriptions
+    scan_threat VARCHAR(255),
+    scanned_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS lab_reports_order_idx ON lab_reports (order_id);
+CREATE INDEX IF NOT EXISTS lab_reports_scan_pending_idx ON lab_reports (created_at) WHERE scan_status = 'pending';
+
+-- Keys external systems, such as labs, use to post to the integration API
+CREATE TABLE IF NOT EXISTS integration_api_keys (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    key_hash VARCHAR(64) UNIQUE NOT NULL, -- SHA-256 of the key
+    created_by INT NOT NULL REFERENCES admins(id),
+    created_at TIMESTAMPTZ DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
@@ -0,0 +1,3 @@
+ALTER TABLE integration_api_keys DROP CONSTRAINT IF EXISTS integration_api_keys_clinic_check;
+ALTER TABLE integration_api_keys DROP COLUMN IF EXISTS clinic_id;
+ALTER TABLE lab_orders DROP COLUMN IF EXISTS clinic_id;
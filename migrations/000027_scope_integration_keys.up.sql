@@ -0,0 +1,13 @@
+-- The clinic a lab order was placed from; only that clinic's labs can send its results
+ALTER TABLE lab_orders ADD COLUMN IF NOT EXISTS clinic_id INT REFERENCES clinics(id);
+
+UPDATE lab_orders o SET clinic_id = d.clinic_id FROM doctors d WHERE d.id = o.doctor_id;
+
+-- Each integration key belongs to the labs of one clinic. Keys issued before
+-- had no clinic and could reach any order, so they are revoked.
+ALTER TABLE integration_api_keys ADD COLUMN IF NOT EXISTS clinic_id INT REFERENCES clinics(id);
+
+UPDATE integration_api_keys SET revoked_at = now() WHERE clinic_id IS NULL AND revoked_at IS NULL;
+
+ALTER TABLE integration_api_keys
+ADD CONSTRAINT integration_api_keys_clinic_check CHECK (clinic_id IS NOT NULL OR revoked_at IS NOT NULL);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS prescription_items_drug_name_idx ON prescription_items (lower(drug_name));
@@ -0,0 +1,3 @@
+-- Drug names are searched with ILIKE '%...%' within each prescription, which
+-- prescription_items_prescription_idx serves; a btree on lower(drug_name) can't
+DROP INDEX IF EXISTS prescription_items_drug_name_idx;
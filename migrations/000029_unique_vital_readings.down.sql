@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS vital_readings_patient_metric_idx ON vital_readings (patient_id, metric, recorded_at DESC);
+DROP INDEX IF EXISTS vital_readings_patient_metric_time_key;
@@ -0,0 +1,11 @@
+-- One reading per metric per instant, so concurrent imports and device
+-- submissions can't store the same reading twice. Remove any duplicates
+-- already stored, keeping the first.
+DELETE FROM vital_readings v
+USING vital_readings e
+WHERE e.patient_id = v.patient_id AND e.metric = v.metric AND e.recorded_at = v.recorded_at AND e.id < v.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS vital_readings_patient_metric_time_key ON vital_readings (patient_id, metric, recorded_at);
+
+-- The unique index serves the same lookups
+DROP INDEX IF EXISTS vital_readings_patient_metric_idx;
@@ -0,0 +1,2 @@
+ALTER TABLE patient_conditions DROP CONSTRAINT IF EXISTS patient_conditions_code_system_check;
+ALTER TABLE patient_conditions DROP COLUMN IF EXISTS code_system;
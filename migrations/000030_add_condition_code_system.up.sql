@@ -0,0 +1,16 @@
+-- The code system a condition's code is from, 'icd10cm' or 'snomedct'
+ALTER TABLE patient_conditions ADD COLUMN IF NOT EXISTS code_system VARCHAR(20);
+
+-- Codes recorded before were checked against every system; take the system
+-- they were found in, preferring ICD-10-CM as new codes do
+UPDATE patient_conditions pc
+SET code_system = COALESCE((
+    SELECT t.system FROM terminology_codes t
+    WHERE t.search_code = upper(regexp_replace(pc.code, '[^[:alnum:]]', '', 'g'))
+    ORDER BY t.system = 'icd10cm' DESC
+    LIMIT 1
+), 'icd10cm')
+WHERE pc.code IS NOT NULL;
+
+ALTER TABLE patient_conditions
+ADD CONSTRAINT patient_conditions_code_system_check CHECK (code IS NULL OR code_system IS NOT NULL);
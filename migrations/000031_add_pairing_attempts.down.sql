@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS device_pairing_failures;
+ALTER TABLE device_pairing_codes DROP COLUMN IF EXISTS failed_attempts;
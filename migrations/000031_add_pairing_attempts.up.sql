@@ -0,0 +1,14 @@
+-- Wrong pairing codes tried while each code was live. A guess can't be tied
+-- to the code it was aimed at, so every live code counts every failure, and
+-- a code stops working once too many guesses have been made against it.
+ALTER TABLE device_pairing_codes ADD COLUMN IF NOT EXISTS failed_attempts INT NOT NULL DEFAULT 0;
+
+-- Failed pairing attempts, to limit how many guesses each client can make
+CREATE TABLE IF NOT EXISTS device_pairing_failures (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    ip_address VARCHAR(45) NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS device_pairing_failures_ip_idx ON device_pairing_failures (ip_address, attempted_at);
+CREATE INDEX IF NOT EXISTS device_pairing_failures_attempted_idx ON device_pairing_failures (attempted_at);
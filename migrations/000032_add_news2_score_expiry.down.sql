@@ -0,0 +1 @@
+ALTER TABLE news2_scores DROP COLUMN IF EXISTS expired_at;
@@ -0,0 +1,3 @@
+-- Set once none of the readings a score was computed from are recent enough
+-- to count any more; an expired score no longer says anything about the patient
+ALTER TABLE news2_scores ADD COLUMN IF NOT EXISTS expired_at TIMESTAMPTZ;
@@ -0,0 +1 @@
+ALTER TABLE device_pairing_codes ADD COLUMN IF NOT EXISTS failed_attempts INT NOT NULL DEFAULT 0;
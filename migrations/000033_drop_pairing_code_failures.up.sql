@@ -0,0 +1,3 @@
+-- Failed pairing attempts are limited per client only; counting them against
+-- every live code let a few clients lock out pairing for all patients
+ALTER TABLE device_pairing_codes DROP COLUMN IF EXISTS failed_attempts;